@@ -42,6 +42,8 @@ type CacheFile interface {
 	StoreGroupExpand(group string, expand bool) error
 	LoadRuleSet(tag string) *SavedRuleSet
 	SaveRuleSet(tag string, set *SavedRuleSet) error
+	LoadUserUsage(inbound string, user string) *SavedUserUsage
+	SaveUserUsage(inbound string, user string, usage *SavedUserUsage) error
 }
 
 type SavedRuleSet struct {
@@ -101,8 +103,56 @@ func (s *SavedRuleSet) UnmarshalBinary(data []byte) error {
 	return nil
 }
 
+type SavedUserUsage struct {
+	PeriodStart time.Time
+	Upload      int64
+	Download    int64
+}
+
+func (u *SavedUserUsage) MarshalBinary() ([]byte, error) {
+	var buffer bytes.Buffer
+	err := binary.Write(&buffer, binary.BigEndian, uint8(1))
+	if err != nil {
+		return nil, err
+	}
+	err = binary.Write(&buffer, binary.BigEndian, u.PeriodStart.Unix())
+	if err != nil {
+		return nil, err
+	}
+	err = binary.Write(&buffer, binary.BigEndian, u.Upload)
+	if err != nil {
+		return nil, err
+	}
+	err = binary.Write(&buffer, binary.BigEndian, u.Download)
+	if err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+func (u *SavedUserUsage) UnmarshalBinary(data []byte) error {
+	reader := bytes.NewReader(data)
+	var version uint8
+	err := binary.Read(reader, binary.BigEndian, &version)
+	if err != nil {
+		return err
+	}
+	var periodStart int64
+	err = binary.Read(reader, binary.BigEndian, &periodStart)
+	if err != nil {
+		return err
+	}
+	u.PeriodStart = time.Unix(periodStart, 0)
+	err = binary.Read(reader, binary.BigEndian, &u.Upload)
+	if err != nil {
+		return err
+	}
+	return binary.Read(reader, binary.BigEndian, &u.Download)
+}
+
 type Tracker interface {
 	Leave()
+	SetError(err error)
 }
 
 type OutboundGroup interface {
@@ -111,6 +161,12 @@ type OutboundGroup interface {
 	All() []string
 }
 
+// NetworkOutboundGroup is an outbound group that may use a different outbound for each network.
+type NetworkOutboundGroup interface {
+	OutboundGroup
+	NowNetwork(network string) string
+}
+
 type URLTestGroup interface {
 	OutboundGroup
 	URLTest(ctx context.Context) (map[string]uint16, error)
@@ -123,6 +179,13 @@ func OutboundTag(detour Outbound) string {
 	return detour.Tag()
 }
 
+type MetricsServer interface {
+	Service
+	RoutedConnection(ctx context.Context, conn net.Conn, metadata InboundContext, outbound Outbound) (net.Conn, Tracker)
+	RoutedPacketConnection(ctx context.Context, conn N.PacketConn, metadata InboundContext, outbound Outbound) (N.PacketConn, Tracker)
+	DNSQuery(cached bool, err error)
+}
+
 type V2RayServer interface {
 	Service
 	StatsService() V2RayStatsService
@@ -131,4 +194,6 @@ type V2RayServer interface {
 type V2RayStatsService interface {
 	RoutedConnection(inbound string, outbound string, user string, conn net.Conn) net.Conn
 	RoutedPacketConnection(inbound string, outbound string, user string, conn N.PacketConn) N.PacketConn
+	RelayedConnection(inbound string, destination string, server string, conn net.Conn) net.Conn
+	RelayedPacketConnection(inbound string, destination string, server string, conn N.PacketConn) N.PacketConn
 }
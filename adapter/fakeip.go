@@ -10,11 +10,26 @@ import (
 type FakeIPStore interface {
 	Service
 	Contains(address netip.Addr) bool
-	Create(domain string, isIPv6 bool) (netip.Addr, error)
+	// Create returns the address of the domain in the pool, the default pool has an empty tag.
+	Create(pool string, domain string, isIPv6 bool) (netip.Addr, error)
 	Lookup(address netip.Addr) (string, bool)
+	// Acquire marks the address in use until the returned function is called,
+	// addresses in use are not evicted.
+	Acquire(address netip.Addr) func()
+	// Mapping returns the mapping of the address without marking it as used.
+	Mapping(address netip.Addr) (FakeIPMapping, bool)
+	Mappings() ([]FakeIPMapping, error)
+	Delete(address netip.Addr) error
 	Reset() error
 }
 
+type FakeIPMapping struct {
+	Address     netip.Addr
+	Domain      string
+	Pool        string
+	Connections int
+}
+
 type FakeIPStorage interface {
 	FakeIPMetadata() *FakeIPMetadata
 	FakeIPSaveMetadata(metadata *FakeIPMetadata) error
@@ -23,6 +38,8 @@ type FakeIPStorage interface {
 	FakeIPStoreAsync(address netip.Addr, domain string, logger logger.Logger)
 	FakeIPLoad(address netip.Addr) (string, bool)
 	FakeIPLoadDomain(domain string, isIPv6 bool) (netip.Addr, bool)
+	FakeIPLoadAll() (map[netip.Addr]string, error)
+	FakeIPDelete(address netip.Addr) error
 	FakeIPReset() error
 }
 
@@ -15,6 +15,16 @@ type FakeIPMetadata struct {
 	Inet6Range   netip.Prefix
 	Inet4Current netip.Addr
 	Inet6Current netip.Addr
+	Pools        []FakeIPPoolMetadata
+}
+
+// FakeIPPoolMetadata is the allocation state of a named pool.
+type FakeIPPoolMetadata struct {
+	Tag          string
+	Inet4Range   netip.Prefix
+	Inet6Range   netip.Prefix
+	Inet4Current netip.Addr
+	Inet6Current netip.Addr
 }
 
 func (m *FakeIPMetadata) MarshalBinary() (data []byte, err error) {
@@ -27,6 +37,22 @@ func (m *FakeIPMetadata) MarshalBinary() (data []byte, err error) {
 		common.Must(binary.Write(&buffer, binary.BigEndian, uint16(len(data))))
 		buffer.Write(data)
 	}
+	// pools are appended, so metadata without pools keeps the old format
+	if len(m.Pools) > 0 {
+		common.Must(binary.Write(&buffer, binary.BigEndian, uint16(len(m.Pools))))
+		for _, pool := range m.Pools {
+			common.Must(binary.Write(&buffer, binary.BigEndian, uint16(len(pool.Tag))))
+			buffer.WriteString(pool.Tag)
+			for _, marshaler := range []encoding.BinaryMarshaler{pool.Inet4Range, pool.Inet6Range, pool.Inet4Current, pool.Inet6Current} {
+				data, err = marshaler.MarshalBinary()
+				if err != nil {
+					return
+				}
+				common.Must(binary.Write(&buffer, binary.BigEndian, uint16(len(data))))
+				buffer.Write(data)
+			}
+		}
+	}
 	data = buffer.Bytes()
 	return
 }
@@ -34,10 +60,7 @@ func (m *FakeIPMetadata) MarshalBinary() (data []byte, err error) {
 func (m *FakeIPMetadata) UnmarshalBinary(data []byte) error {
 	reader := bytes.NewReader(data)
 	for _, unmarshaler := range []encoding.BinaryUnmarshaler{&m.Inet4Range, &m.Inet6Range, &m.Inet4Current, &m.Inet6Current} {
-		var length uint16
-		common.Must(binary.Read(reader, binary.BigEndian, &length))
-		element := make([]byte, length)
-		_, err := io.ReadFull(reader, element)
+		element, err := readFakeIPMetadataElement(reader)
 		if err != nil {
 			return err
 		}
@@ -46,5 +69,46 @@ func (m *FakeIPMetadata) UnmarshalBinary(data []byte) error {
 			return err
 		}
 	}
+	if reader.Len() == 0 {
+		return nil
+	}
+	var poolCount uint16
+	err := binary.Read(reader, binary.BigEndian, &poolCount)
+	if err != nil {
+		return err
+	}
+	m.Pools = make([]FakeIPPoolMetadata, poolCount)
+	for i := range m.Pools {
+		pool := &m.Pools[i]
+		tag, err := readFakeIPMetadataElement(reader)
+		if err != nil {
+			return err
+		}
+		pool.Tag = string(tag)
+		for _, unmarshaler := range []encoding.BinaryUnmarshaler{&pool.Inet4Range, &pool.Inet6Range, &pool.Inet4Current, &pool.Inet6Current} {
+			element, err := readFakeIPMetadataElement(reader)
+			if err != nil {
+				return err
+			}
+			err = unmarshaler.UnmarshalBinary(element)
+			if err != nil {
+				return err
+			}
+		}
+	}
 	return nil
 }
+
+func readFakeIPMetadataElement(reader io.Reader) ([]byte, error) {
+	var length uint16
+	err := binary.Read(reader, binary.BigEndian, &length)
+	if err != nil {
+		return nil, err
+	}
+	element := make([]byte, length)
+	_, err = io.ReadFull(reader, element)
+	if err != nil {
+		return nil, err
+	}
+	return element, nil
+}
@@ -6,6 +6,7 @@ import (
 	"net/netip"
 
 	"github.com/sagernet/sing-box/common/process"
+	"github.com/sagernet/sing-box/log"
 	"github.com/sagernet/sing-box/option"
 	M "github.com/sagernet/sing/common/metadata"
 	N "github.com/sagernet/sing/common/network"
@@ -48,6 +49,7 @@ type InboundContext struct {
 	ProcessInfo          *process.Info
 	QueryType            uint16
 	FakeIP               bool
+	ProxyProtocol        *ProxyProtocolInfo
 
 	// rule cache
 
@@ -69,6 +71,36 @@ func (c *InboundContext) ResetRuleCache() {
 	c.DidMatch = false
 }
 
+// LogFields returns the connection fields attached to structured log records.
+func (c *InboundContext) LogFields() log.Fields {
+	fields := log.Fields{
+		Inbound:     c.Inbound,
+		InboundType: c.InboundType,
+		Network:     c.Network,
+		User:        c.User,
+		Outbound:    c.Outbound,
+	}
+	if c.Source.IsValid() {
+		fields.Source = c.Source.String()
+	}
+	if c.Destination.IsValid() {
+		fields.Destination = c.Destination.String()
+	}
+	return fields
+}
+
+// ProxyProtocolInfo is the client information from PROXY protocol v2 TLVs of an inbound connection.
+type ProxyProtocolInfo struct {
+	ALPN        string
+	Authority   string
+	UniqueID    []byte
+	SSL         bool
+	SSLVerified bool
+	SSLVersion  string
+	SSLCipher   string
+	SSLClientCN string
+}
+
 type inboundContextKey struct{}
 
 func WithContext(ctx context.Context, inboundContext *InboundContext) context.Context {
@@ -3,7 +3,9 @@ package adapter
 import (
 	"context"
 	"net"
+	"time"
 
+	"github.com/sagernet/sing-box/option"
 	N "github.com/sagernet/sing/common/network"
 )
 
@@ -18,3 +20,53 @@ type Outbound interface {
 	NewConnection(ctx context.Context, conn net.Conn, metadata InboundContext) error
 	NewPacketConnection(ctx context.Context, conn N.PacketConn, metadata InboundContext) error
 }
+
+// OutboundManager creates, replaces and removes outbounds at runtime.
+type OutboundManager interface {
+	AddOutbound(options option.Outbound) error
+	// ReplaceOutbound replaces the outbound with the same tag, outbounds depending on it are updated.
+	ReplaceOutbound(options option.Outbound) error
+	RemoveOutbound(tag string) error
+}
+
+// DependencyUpdater is implemented by outbounds resolving dependencies on start,
+// UpdateDependency is called when a dependency is replaced at runtime.
+type DependencyUpdater interface {
+	UpdateDependency(detour Outbound)
+}
+
+// PeerOutbound is implemented by outbounds connecting to multiple peers, like WireGuard.
+type PeerOutbound interface {
+	Outbound
+	PeerStatus() []PeerStatus
+}
+
+type PeerStatus struct {
+	PublicKey     string
+	Endpoint      string
+	LastHandshake time.Time
+	Upload        uint64
+	Download      uint64
+}
+
+// CircuitOutbound is implemented by outbounds building circuits, like Tor.
+type CircuitOutbound interface {
+	Outbound
+	// NewIdentity switches to clean circuits for new connections.
+	NewIdentity() error
+	CircuitStatus() ([]CircuitStatus, error)
+}
+
+type CircuitStatus struct {
+	ID      string
+	Status  string
+	Path    []string
+	Purpose string
+}
+
+// ChainOutbound is implemented by outbounds dialing through other outbounds in order, like chain.
+type ChainOutbound interface {
+	Outbound
+	// Hops returns tags of the outbounds, starting with the one connecting to the server of the next.
+	Hops() []string
+}
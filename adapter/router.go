@@ -4,8 +4,10 @@ import (
 	"context"
 	"net/http"
 	"net/netip"
+	"time"
 
 	"github.com/sagernet/sing-box/common/geoip"
+	"github.com/sagernet/sing-box/common/ratelimit"
 	dns "github.com/sagernet/sing-dns"
 	tun "github.com/sagernet/sing-tun"
 	"github.com/sagernet/sing/common/control"
@@ -20,10 +22,13 @@ type Router interface {
 	PreStarter
 	PostStarter
 
+	Inbounds() []Inbound
+	Inbound(tag string) (Inbound, bool)
 	SortedOutboundsByDependenciesHiddify() []Outbound //hiddify
 	Outbounds() []Outbound
 	Outbound(tag string) (Outbound, bool)
 	DefaultOutbound(network string) (Outbound, error)
+	OutboundManager
 
 	FakeIPStore() FakeIPStore
 
@@ -59,6 +64,9 @@ type Router interface {
 	V2RayServer() V2RayServer
 	SetV2RayServer(server V2RayServer)
 
+	MetricsServer() MetricsServer
+	SetMetricsServer(server MetricsServer)
+
 	ResetNetwork() error
 }
 
@@ -81,6 +89,7 @@ type Rule interface {
 	Type() string
 	UpdateGeosite() error
 	Outbound() string
+	RateLimiters() ratelimit.Limiters
 }
 
 type DNSRule interface {
@@ -99,6 +108,12 @@ type RuleSet interface {
 	HeadlessRule
 }
 
+type UpdatableRuleSet interface {
+	RuleSet
+	LastUpdated() time.Time
+	LastUpdateError() error
+}
+
 type RuleSetMetadata struct {
 	ContainsProcessRule bool
 	ContainsWIFIRule    bool
@@ -0,0 +1,32 @@
+package adapter
+
+// UserManager is implemented by multi-user inbounds whose users can be changed at runtime.
+type UserManager interface {
+	Inbound
+	// Users returns users in the option format of the inbound.
+	Users() []any
+	// AddUsers adds users from a JSON array in the option format of the inbound.
+	// Added users are required to have unique names.
+	AddUsers(content []byte) error
+	// RemoveUsers removes users by name or by index and closes their connections,
+	// users without a name can only be removed by index.
+	RemoveUsers(names []string, indexes []int) error
+}
+
+// RelayManager is implemented by relay inbounds whose users are forwarded to upstream servers.
+type RelayManager interface {
+	UserManager
+	// RelayStatus returns the upstream servers of each destination.
+	RelayStatus() []RelayDestinationStatus
+}
+
+type RelayDestinationStatus struct {
+	Name    string              `json:"name"`
+	Servers []RelayServerStatus `json:"servers"`
+}
+
+type RelayServerStatus struct {
+	Server      string `json:"server"`
+	Healthy     bool   `json:"healthy"`
+	Connections int32  `json:"connections"`
+}
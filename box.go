@@ -6,14 +6,17 @@ import (
 	"io"
 	"os"
 	"runtime/debug"
+	"sync"
 	"time"
 
 	"github.com/sagernet/sing-box/adapter"
 	"github.com/sagernet/sing-box/common/taskmonitor"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/experimental"
+	"github.com/sagernet/sing-box/experimental/adminapi"
 	"github.com/sagernet/sing-box/experimental/cachefile"
 	"github.com/sagernet/sing-box/experimental/libbox/platform"
+	"github.com/sagernet/sing-box/experimental/metrics"
 	"github.com/sagernet/sing-box/inbound"
 	"github.com/sagernet/sing-box/log"
 	"github.com/sagernet/sing-box/option"
@@ -29,16 +32,21 @@ import (
 var _ adapter.Service = (*Box)(nil)
 
 type Box struct {
-	createdAt    time.Time
-	router       adapter.Router
-	inbounds     []adapter.Inbound
-	outbounds    []adapter.Outbound
-	logFactory   log.Factory
-	logger       log.ContextLogger
-	preServices1 map[string]adapter.Service
-	preServices2 map[string]adapter.Service
-	postServices map[string]adapter.Service
-	done         chan struct{}
+	createdAt         time.Time
+	ctx               context.Context
+	options           option.Options
+	platformInterface platform.Interface
+	router            *route.Router
+	reloadAccess      sync.Mutex
+	optionsStore      *outbound.OptionsStore
+	inbounds          []adapter.Inbound
+	outbounds         []adapter.Outbound
+	logFactory        log.Factory
+	logger            log.ContextLogger
+	preServices1      map[string]adapter.Service
+	preServices2      map[string]adapter.Service
+	postServices      map[string]adapter.Service
+	done              chan struct{}
 }
 
 type Options struct {
@@ -55,6 +63,9 @@ func New(options Options) (*Box, error) {
 		ctx = context.Background()
 	}
 	ctx = service.ContextWithDefaultRegistry(ctx)
+	optionsStore := outbound.NewOptionsStore()
+	optionsStore.Reset(options.Outbounds)
+	ctx = outbound.ContextWithOptionsStore(ctx, optionsStore)
 	ctx = pause.WithDefaultManager(ctx)
 	experimentalOptions := common.PtrValueOrDefault(options.Experimental)
 	applyDebugOptions(common.PtrValueOrDefault(experimentalOptions.Debug))
@@ -77,7 +88,7 @@ func New(options Options) (*Box, error) {
 	logFactory, err := log.New(log.Options{
 		Context:        ctx,
 		Options:        common.PtrValueOrDefault(options.Log),
-		Observable:     needClashAPI,
+		Observable:     needClashAPI || options.PlatformInterface != nil,
 		DefaultWriter:  defaultLogWriter,
 		BaseTime:       createdAt,
 		PlatformWriter: options.PlatformLogWriter,
@@ -194,18 +205,41 @@ func New(options Options) (*Box, error) {
 		router.SetV2RayServer(v2rayServer)
 		preServices2["v2ray api"] = v2rayServer
 	}
-	return &Box{
-		router:       router,
-		inbounds:     inbounds,
-		outbounds:    outbounds,
-		createdAt:    createdAt,
-		logFactory:   logFactory,
-		logger:       logFactory.Logger(),
-		preServices1: preServices1,
-		preServices2: preServices2,
-		postServices: postServices,
-		done:         make(chan struct{}),
-	}, nil
+	if experimentalOptions.Metrics != nil && experimentalOptions.Metrics.Listen != "" {
+		metricsServer, err := metrics.NewServer(ctx, router, logFactory.NewLogger("metrics"), *experimentalOptions.Metrics, common.Map(common.PtrValueOrDefault(options.Route).RuleSet, func(it option.RuleSet) string {
+			return it.Tag
+		}))
+		if err != nil {
+			return nil, E.Cause(err, "create metrics server")
+		}
+		router.SetMetricsServer(metricsServer)
+		preServices2["metrics"] = metricsServer
+	}
+	if experimentalOptions.AdminAPI != nil && experimentalOptions.AdminAPI.Listen != "" {
+		adminServer, err := adminapi.NewServer(ctx, router, logFactory.NewLogger("admin-api"), *experimentalOptions.AdminAPI)
+		if err != nil {
+			return nil, E.Cause(err, "create admin api server")
+		}
+		preServices2["admin api"] = adminServer
+	}
+	box := &Box{
+		ctx:               ctx,
+		options:           options.Options,
+		platformInterface: options.PlatformInterface,
+		router:            router,
+		optionsStore:      optionsStore,
+		inbounds:          inbounds,
+		outbounds:         outbounds,
+		createdAt:         createdAt,
+		logFactory:        logFactory,
+		logger:            logFactory.Logger(),
+		preServices1:      preServices1,
+		preServices2:      preServices2,
+		postServices:      postServices,
+		done:              make(chan struct{}),
+	}
+	router.SetOutboundUpdater(&box.reloadAccess, box.updateOutbound)
+	return box, nil
 }
 
 func (s *Box) PreStart() error {
@@ -278,7 +312,7 @@ func (s *Box) preStart() error {
 	if err != nil {
 		return E.Cause(err, "pre-start router")
 	}
-	err = s.startOutbounds()
+	err = s.startOutbounds(s.outbounds, make(map[string]bool))
 	if err != nil {
 		return err
 	}
@@ -343,6 +377,8 @@ func (s *Box) Close() error {
 	default:
 		close(s.done)
 	}
+	s.reloadAccess.Lock()
+	defer s.reloadAccess.Unlock()
 	monitor := taskmonitor.New(s.logger, C.StopTimeout)
 	var errors error
 	for serviceName, service := range s.postServices {
@@ -398,3 +434,7 @@ func (s *Box) Close() error {
 func (s *Box) Router() adapter.Router {
 	return s.router
 }
+
+func (s *Box) LogFactory() log.Factory {
+	return s.logFactory
+}
@@ -6,16 +6,17 @@ import (
 	"github.com/sagernet/sing-box/adapter"
 	"github.com/sagernet/sing-box/common/taskmonitor"
 	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
 	"github.com/sagernet/sing/common"
 	E "github.com/sagernet/sing/common/exceptions"
 	F "github.com/sagernet/sing/common/format"
 )
 
-func (s *Box) startOutbounds() error {
+func (s *Box) startOutbounds(outboundList []adapter.Outbound, started map[string]bool) error {
 	monitor := taskmonitor.New(s.logger, C.StartTimeout)
 	outboundTags := make(map[adapter.Outbound]string)
 	outbounds := make(map[string]adapter.Outbound)
-	for i, outboundToStart := range s.outbounds {
+	for i, outboundToStart := range outboundList {
 		var outboundTag string
 		if outboundToStart.Tag() == "" {
 			outboundTag = F.ToString(i)
@@ -28,11 +29,10 @@ func (s *Box) startOutbounds() error {
 		outboundTags[outboundToStart] = outboundTag
 		outbounds[outboundTag] = outboundToStart
 	}
-	started := make(map[string]bool)
 	for {
 		canContinue := false
 	startOne:
-		for _, outboundToStart := range s.outbounds {
+		for _, outboundToStart := range outboundList {
 			outboundTag := outboundTags[outboundToStart]
 			if started[outboundTag] {
 				continue
@@ -54,13 +54,13 @@ func (s *Box) startOutbounds() error {
 				}
 			}
 		}
-		if len(started) == len(s.outbounds) {
+		if len(started) == len(outboundList) {
 			break
 		}
 		if canContinue {
 			continue
 		}
-		currentOutbound := common.Find(s.outbounds, func(it adapter.Outbound) bool {
+		currentOutbound := common.Find(outboundList, func(it adapter.Outbound) bool {
 			return !started[outboundTags[it]]
 		})
 		var lintOutbound func(oTree []string, oCurrent adapter.Outbound) error
@@ -81,3 +81,41 @@ func (s *Box) startOutbounds() error {
 	}
 	return nil
 }
+
+// updateOutbound keeps outbounds and options in sync with outbounds changed at runtime,
+// so that the next reload compares against and reuses them.
+func (s *Box) updateOutbound(options *option.Outbound, oldOutbound adapter.Outbound, newOutbound adapter.Outbound) {
+	outboundOptions := append([]option.Outbound(nil), s.options.Outbounds...)
+	outbounds := append([]adapter.Outbound(nil), s.outbounds...)
+	index := -1
+	if oldOutbound != nil {
+		index = common.Index(outbounds, func(it adapter.Outbound) bool {
+			return it == oldOutbound
+		})
+	}
+	switch {
+	case index != -1 && index < len(outboundOptions) && options != nil:
+		outboundOptions[index] = *options
+		outbounds[index] = newOutbound
+	case index != -1 && index < len(outboundOptions):
+		outboundOptions = append(outboundOptions[:index], outboundOptions[index+1:]...)
+		outbounds = append(outbounds[:index], outbounds[index+1:]...)
+	default:
+		if index != -1 {
+			// implicit outbounds are replaced by explicit ones
+			outbounds = append(outbounds[:index], outbounds[index+1:]...)
+		}
+		if options != nil {
+			explicitLen := len(outboundOptions)
+			outboundOptions = append(outboundOptions, *options)
+			outbounds = append(outbounds[:explicitLen], append([]adapter.Outbound{newOutbound}, outbounds[explicitLen:]...)...)
+		}
+	}
+	s.options.Outbounds = outboundOptions
+	s.outbounds = outbounds
+	if options != nil {
+		s.optionsStore.Store(*options)
+	} else {
+		s.optionsStore.Delete(oldOutbound.Tag())
+	}
+}
@@ -0,0 +1,162 @@
+package box
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing-box/outbound"
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/service"
+
+	"github.com/stretchr/testify/require"
+)
+
+func startTestBox(t *testing.T, content string) *Box {
+	instance, err := New(Options{
+		Context: context.Background(),
+		Options: parseReloadOptions(t, content),
+	})
+	require.NoError(t, err)
+	require.NoError(t, instance.Start())
+	t.Cleanup(func() {
+		instance.Close()
+	})
+	return instance
+}
+
+func outboundTags(outbounds []adapter.Outbound) []string {
+	return common.Map(outbounds, adapter.Outbound.Tag)
+}
+
+func requireBefore(t *testing.T, tags []string, dependency string, dependent string) {
+	require.Less(t, common.Index(tags, func(it string) bool {
+		return it == dependency
+	}), common.Index(tags, func(it string) bool {
+		return it == dependent
+	}))
+}
+
+func TestRuntimeOutbounds(t *testing.T) {
+	t.Parallel()
+	instance := startTestBox(t, `{
+  "log": {"disabled": true},
+  "dns": {"servers": [{"tag": "remote", "address": "8.8.8.8", "detour": "dns-out"}]},
+  "outbounds": [
+    {"type": "direct", "tag": "direct"},
+    {"type": "direct", "tag": "dns-out"},
+    {"type": "direct", "tag": "rule-out"}
+  ],
+  "route": {"rules": [{"domain": "example.com", "outbound": "rule-out"}]}
+}`)
+	router := instance.Router()
+
+	selectorOptions := option.Outbound{
+		Type:            "selector",
+		Tag:             "select",
+		SelectorOptions: option.SelectorOutboundOptions{Outbounds: []string{"a"}},
+	}
+	require.Error(t, router.AddOutbound(selectorOptions))
+	require.NoError(t, router.AddOutbound(option.Outbound{Type: "direct", Tag: "a"}))
+	require.Error(t, router.AddOutbound(option.Outbound{Type: "direct", Tag: "a"}))
+	require.NoError(t, router.AddOutbound(selectorOptions))
+	require.Equal(t, []string{"direct", "dns-out", "rule-out", "a", "select"}, outboundTags(instance.outbounds))
+	require.Equal(t, []string{"direct", "dns-out", "rule-out", "a", "select"}, common.Map(instance.options.Outbounds, func(it option.Outbound) string {
+		return it.Tag
+	}))
+	requireBefore(t, outboundTags(router.SortedOutboundsByDependenciesHiddify()), "a", "select")
+
+	oldOutbound, _ := router.Outbound("a")
+	require.NoError(t, router.ReplaceOutbound(option.Outbound{Type: "direct", Tag: "a", DirectOptions: option.DirectOutboundOptions{OverrideAddress: "127.0.0.1"}}))
+	newOutbound, _ := router.Outbound("a")
+	require.NotEqual(t, oldOutbound, newOutbound)
+	selector, _ := router.Outbound("select")
+	selected, _ := selector.(*outbound.Selector).Outbound("a")
+	require.Equal(t, newOutbound, selected)
+	require.Equal(t, newOutbound, instance.outbounds[3])
+	require.Equal(t, "127.0.0.1", instance.options.Outbounds[3].DirectOptions.OverrideAddress)
+	requireBefore(t, outboundTags(router.SortedOutboundsByDependenciesHiddify()), "a", "select")
+
+	// outbounds in use are kept
+	require.Error(t, router.RemoveOutbound("a"))
+	require.Error(t, router.RemoveOutbound("dns-out"))
+	require.Error(t, router.RemoveOutbound("rule-out"))
+	require.Error(t, router.RemoveOutbound("direct"))
+
+	require.NoError(t, router.RemoveOutbound("select"))
+	require.NoError(t, router.RemoveOutbound("a"))
+	_, loaded := router.Outbound("a")
+	require.False(t, loaded)
+	require.Equal(t, []string{"direct", "dns-out", "rule-out"}, outboundTags(instance.outbounds))
+	require.Len(t, instance.options.Outbounds, 3)
+	_, loaded = instance.optionsStore.Load("a")
+	require.False(t, loaded)
+}
+
+func TestRuntimeOutboundsChain(t *testing.T) {
+	t.Parallel()
+	ctx := service.ContextWithDefaultRegistry(context.Background())
+	content := `{
+  "log": {"disabled": true},
+  "outbounds": [
+    {"type": "direct", "tag": "direct"},
+    {"type": "socks", "tag": "a", "server": "127.0.0.1", "server_port": 1080},
+    {"type": "socks", "tag": "b", "server": "127.0.0.1", "server_port": 1081},
+    {"type": "chain", "tag": "chain", "outbounds": ["a", "b"]}
+  ]
+}`
+	instance, err := New(Options{Context: ctx, Options: parseReloadOptions(t, content)})
+	require.NoError(t, err)
+	require.NoError(t, instance.Start())
+	t.Cleanup(func() {
+		instance.Close()
+	})
+	// instances sharing a service registry keep their own outbound options
+	other, err := New(Options{Context: ctx, Options: parseReloadOptions(t, `{"log": {"disabled": true}}`)})
+	require.NoError(t, err)
+	defer other.Close()
+	require.NotSame(t, instance.optionsStore, other.optionsStore)
+	_, loaded := other.optionsStore.Load("a")
+	require.False(t, loaded)
+
+	router := instance.Router()
+	require.NoError(t, router.ReplaceOutbound(option.Outbound{
+		Type:         "socks",
+		Tag:          "b",
+		SocksOptions: option.SocksOutboundOptions{ServerOptions: option.ServerOptions{Server: "127.0.0.1", ServerPort: 1082}},
+	}))
+	options, loaded := instance.optionsStore.Load("b")
+	require.True(t, loaded)
+	require.Equal(t, uint16(1082), options.SocksOptions.ServerPort)
+	require.NoError(t, router.RemoveOutbound("chain"))
+	_, loaded = instance.optionsStore.Load("chain")
+	require.False(t, loaded)
+}
+
+func TestRuntimeOutboundsReload(t *testing.T) {
+	t.Parallel()
+	content := `{
+  "log": {"disabled": true},
+  "outbounds": [{"type": "direct", "tag": "direct"}]
+}`
+	instance := startTestBox(t, content)
+	router := instance.Router()
+	require.NoError(t, router.AddOutbound(option.Outbound{Type: "direct", Tag: "a"}))
+	replaced, _ := router.Outbound("direct")
+	require.NoError(t, router.ReplaceOutbound(option.Outbound{Type: "direct", Tag: "direct", DirectOptions: option.DirectOutboundOptions{OverrideAddress: "127.0.0.1"}}))
+	current, _ := router.Outbound("direct")
+	require.NotEqual(t, replaced, current)
+
+	// reload compares against runtime changes, so the added outbound is removed
+	// and the replaced one is rebuilt from the new options
+	plan, err := instance.Reload(parseReloadOptions(t, content))
+	require.NoError(t, err)
+	require.Equal(t, []string{"a"}, plan.Outbounds.Removed)
+	require.Equal(t, []string{"direct"}, plan.Outbounds.Changed)
+	_, loaded := router.Outbound("a")
+	require.False(t, loaded)
+	reloaded, _ := router.Outbound("direct")
+	require.NotEqual(t, current, reloaded)
+	require.NotEqual(t, replaced, reloaded)
+}
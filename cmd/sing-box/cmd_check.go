@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"os"
 
 	"github.com/sagernet/sing-box"
 	"github.com/sagernet/sing-box/log"
@@ -21,7 +22,10 @@ var commandCheck = &cobra.Command{
 	Args: cobra.NoArgs,
 }
 
+var checkReloadFrom string
+
 func init() {
+	commandCheck.Flags().StringVar(&checkReloadFrom, "reload-from", "", "print components replaced by reloading from the old configuration file")
 	mainCommand.AddCommand(commandCheck)
 }
 
@@ -39,5 +43,17 @@ func check() error {
 		instance.Close()
 	}
 	cancel()
+	if err != nil || checkReloadFrom == "" {
+		return err
+	}
+	oldOptions, err := readConfigAt(checkReloadFrom)
+	if err != nil {
+		return err
+	}
+	plan, err := box.NewReloadPlan(oldOptions.options, options)
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.WriteString(plan.String() + "\n")
 	return err
 }
@@ -135,6 +135,12 @@ func mergeTLSOutboundOptions(options *option.OutboundTLSOptions) *option.Outboun
 }
 
 func mergeSSHOutboundOptions(options option.SSHOutboundOptions) option.SSHOutboundOptions {
+	options.SSHClientOptions = mergeSSHClientOptions(options.SSHClientOptions)
+	options.Jump = common.Map(options.Jump, mergeSSHClientOptions)
+	return options
+}
+
+func mergeSSHClientOptions(options option.SSHClientOptions) option.SSHClientOptions {
 	if options.PrivateKeyPath != "" {
 		if content, err := os.ReadFile(os.ExpandEnv(options.PrivateKeyPath)); err == nil {
 			options.PrivateKey = trimStringArray(strings.Split(string(content), "\n"))
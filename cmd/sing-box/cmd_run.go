@@ -122,10 +122,10 @@ func readConfigAndMerge() (option.Options, error) {
 	return mergedOptions, nil
 }
 
-func create() (*box.Box, context.CancelFunc, error) {
+func readOptions() (option.Options, error) {
 	options, err := readConfigAndMerge()
 	if err != nil {
-		return nil, nil, err
+		return option.Options{}, err
 	}
 	if disableColor {
 		if options.Log == nil {
@@ -133,6 +133,14 @@ func create() (*box.Box, context.CancelFunc, error) {
 		}
 		options.Log.DisableColor = true
 	}
+	return options, nil
+}
+
+func create() (*box.Box, context.CancelFunc, error) {
+	options, err := readOptions()
+	if err != nil {
+		return nil, nil, err
+	}
 	ctx, cancel := context.WithCancel(globalCtx)
 	instance, err := box.New(box.Options{
 		Context: ctx,
@@ -179,6 +187,9 @@ func run() error {
 		for {
 			osSignal := <-osSignals
 			if osSignal == syscall.SIGHUP {
+				if reload(instance) {
+					continue
+				}
 				err = check()
 				if err != nil {
 					log.Error(E.Cause(err, "reload service"))
@@ -198,6 +209,25 @@ func run() error {
 	}
 }
 
+// reload applies the configuration to the running instance, and returns false if a restart is required.
+func reload(instance *box.Box) bool {
+	options, err := readOptions()
+	if err != nil {
+		log.Error(E.Cause(err, "reload service"))
+		return true
+	}
+	plan, err := instance.Reload(options)
+	if plan != nil && plan.NeedRestart() {
+		log.Info("restart service: ", plan)
+		return false
+	}
+	if err != nil {
+		log.Error(E.Cause(err, "reload service"))
+	}
+	runtimeDebug.FreeOSMemory()
+	return true
+}
+
 func closeMonitor(ctx context.Context) {
 	time.Sleep(C.FatalStopTimeout)
 	select {
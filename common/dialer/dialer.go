@@ -4,6 +4,8 @@ import (
 	"time"
 
 	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/proxyproto"
+	"github.com/sagernet/sing-box/common/ratelimit"
 	"github.com/sagernet/sing-box/option"
 	"github.com/sagernet/sing-dns"
 	N "github.com/sagernet/sing/common/network"
@@ -28,6 +30,12 @@ func New(router adapter.Router, options option.DialerOptions) (N.Dialer, error)
 	} else {
 		dialer = NewDetour(router, options.Detour)
 	}
+	if options.ProxyProtocol != 0 {
+		dialer, err = proxyproto.NewDialer(dialer, options.ProxyProtocol)
+		if err != nil {
+			return nil, err
+		}
+	}
 	domainStrategy := dns.DomainStrategy(options.DomainStrategy)
 	if domainStrategy != dns.DomainStrategyAsIS || options.Detour == "" {
 		dialer = NewResolveDialer(
@@ -37,5 +45,8 @@ func New(router adapter.Router, options option.DialerOptions) (N.Dialer, error)
 			domainStrategy,
 			time.Duration(options.FallbackDelay))
 	}
+	if options.RateLimit != nil {
+		dialer = NewRateLimitDialer(dialer, ratelimit.NewLimiters(*options.RateLimit))
+	}
 	return dialer, nil
 }
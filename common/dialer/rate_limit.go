@@ -0,0 +1,44 @@
+package dialer
+
+import (
+	"context"
+	"net"
+
+	"github.com/sagernet/sing-box/common/ratelimit"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+)
+
+// RateLimitDialer shares the limiters between all connections of the dialer,
+// writes to the connections are uploads.
+type RateLimitDialer struct {
+	dialer   N.Dialer
+	limiters ratelimit.Limiters
+}
+
+func NewRateLimitDialer(dialer N.Dialer, limiters ratelimit.Limiters) N.Dialer {
+	if limiters.IsEmpty() {
+		return dialer
+	}
+	return &RateLimitDialer{dialer, limiters}
+}
+
+func (d *RateLimitDialer) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
+	conn, err := d.dialer.DialContext(ctx, network, destination)
+	if err != nil {
+		return nil, err
+	}
+	return ratelimit.NewConn(conn, d.limiters.Download, d.limiters.Upload), nil
+}
+
+func (d *RateLimitDialer) ListenPacket(ctx context.Context, destination M.Socksaddr) (net.PacketConn, error) {
+	conn, err := d.dialer.ListenPacket(ctx, destination)
+	if err != nil {
+		return nil, err
+	}
+	return ratelimit.NewNetPacketConn(conn, d.limiters.Download, d.limiters.Upload), nil
+}
+
+func (d *RateLimitDialer) Upstream() any {
+	return d.dialer
+}
@@ -0,0 +1,214 @@
+package porthopping
+
+import (
+	"context"
+	"math/rand"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing/common/buf"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+)
+
+const (
+	DefaultHopInterval = 30 * time.Second
+	// hopGracePeriod is how long the socket of the previous port keeps receiving after a hop.
+	hopGracePeriod = 5 * time.Second
+)
+
+var _ net.Conn = (*ClientConn)(nil)
+
+// ClientConn is a UDP connection which sends to a random port of the server at each interval,
+// packets from all recent ports are received.
+type ClientConn struct {
+	ctx          context.Context
+	dialer       N.Dialer
+	server       M.Socksaddr
+	ports        []uint16
+	interval     time.Duration
+	access       sync.RWMutex
+	conn         net.Conn
+	packets      chan *buf.Buffer
+	done         chan struct{}
+	closeOnce    sync.Once
+	readDeadline *deadline
+}
+
+func NewClientConn(ctx context.Context, dialer N.Dialer, server M.Socksaddr, ports []uint16, interval time.Duration) (*ClientConn, error) {
+	if interval == 0 {
+		interval = DefaultHopInterval
+	}
+	conn := &ClientConn{
+		ctx:          ctx,
+		dialer:       dialer,
+		server:       server,
+		ports:        ports,
+		interval:     interval,
+		packets:      make(chan *buf.Buffer, 64),
+		done:         make(chan struct{}),
+		readDeadline: newDeadline(),
+	}
+	err := conn.hop()
+	if err != nil {
+		return nil, err
+	}
+	go conn.loopHop()
+	return conn, nil
+}
+
+func (c *ClientConn) hop() error {
+	destination := c.server
+	destination.Port = c.ports[rand.Intn(len(c.ports))]
+	conn, err := c.dialer.DialContext(c.ctx, N.NetworkUDP, destination)
+	if err != nil {
+		return err
+	}
+	c.access.Lock()
+	select {
+	case <-c.done:
+		c.access.Unlock()
+		conn.Close()
+		return net.ErrClosed
+	default:
+	}
+	oldConn := c.conn
+	c.conn = conn
+	c.access.Unlock()
+	go c.loopRead(conn)
+	if oldConn != nil {
+		time.AfterFunc(hopGracePeriod, func() {
+			oldConn.Close()
+		})
+	}
+	return nil
+}
+
+func (c *ClientConn) loopHop() {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			// keep the current port on failure, the next hop will retry
+			_ = c.hop()
+		case <-c.done:
+			return
+		}
+	}
+}
+
+func (c *ClientConn) loopRead(conn net.Conn) {
+	for {
+		buffer := buf.NewPacket()
+		_, err := buffer.ReadOnceFrom(conn)
+		if err != nil {
+			buffer.Release()
+			return
+		}
+		select {
+		case c.packets <- buffer:
+		case <-c.done:
+			buffer.Release()
+			return
+		}
+	}
+}
+
+func (c *ClientConn) Read(p []byte) (n int, err error) {
+	select {
+	case buffer := <-c.packets:
+		n = copy(p, buffer.Bytes())
+		buffer.Release()
+		return
+	case <-c.done:
+		return 0, net.ErrClosed
+	case <-c.readDeadline.wait():
+		return 0, os.ErrDeadlineExceeded
+	}
+}
+
+func (c *ClientConn) Write(p []byte) (n int, err error) {
+	c.access.RLock()
+	conn := c.conn
+	c.access.RUnlock()
+	return conn.Write(p)
+}
+
+func (c *ClientConn) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.done)
+		c.access.RLock()
+		conn := c.conn
+		c.access.RUnlock()
+		conn.Close()
+	})
+	return nil
+}
+
+func (c *ClientConn) LocalAddr() net.Addr {
+	c.access.RLock()
+	defer c.access.RUnlock()
+	return c.conn.LocalAddr()
+}
+
+// RemoteAddr returns the server address with the first port, it does not change when hopping.
+func (c *ClientConn) RemoteAddr() net.Addr {
+	destination := c.server
+	destination.Port = c.ports[0]
+	return destination.UDPAddr()
+}
+
+func (c *ClientConn) SetDeadline(t time.Time) error {
+	c.readDeadline.set(t)
+	return nil
+}
+
+func (c *ClientConn) SetReadDeadline(t time.Time) error {
+	c.readDeadline.set(t)
+	return nil
+}
+
+func (c *ClientConn) SetWriteDeadline(t time.Time) error {
+	return nil
+}
+
+type deadline struct {
+	access  sync.Mutex
+	timer   *time.Timer
+	expired chan struct{}
+}
+
+func newDeadline() *deadline {
+	return &deadline{expired: make(chan struct{})}
+}
+
+func (d *deadline) set(t time.Time) {
+	d.access.Lock()
+	defer d.access.Unlock()
+	if d.timer != nil {
+		d.timer.Stop()
+		d.timer = nil
+	}
+	expired := make(chan struct{})
+	d.expired = expired
+	if t.IsZero() {
+		return
+	}
+	timeout := time.Until(t)
+	if timeout <= 0 {
+		close(expired)
+		return
+	}
+	d.timer = time.AfterFunc(timeout, func() {
+		close(expired)
+	})
+}
+
+func (d *deadline) wait() <-chan struct{} {
+	d.access.Lock()
+	defer d.access.Unlock()
+	return d.expired
+}
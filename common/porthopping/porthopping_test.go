@@ -0,0 +1,80 @@
+package porthopping
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePorts(t *testing.T) {
+	t.Parallel()
+	ports, err := ParsePorts([]string{"443", "20000:20002", "30000-30001"})
+	require.NoError(t, err)
+	require.Equal(t, []uint16{443, 20000, 20001, 20002, 30000, 30001}, ports)
+	for _, invalid := range []string{"0", "abc", "2000:1000", "1:70000"} {
+		_, err = ParsePorts([]string{invalid})
+		require.Error(t, err, invalid)
+	}
+}
+
+func TestParseListenPorts(t *testing.T) {
+	t.Parallel()
+	ports, err := ParseListenPorts([]string{"20000:20255"})
+	require.NoError(t, err)
+	require.Len(t, ports, MaxListenPorts)
+	_, err = ParseListenPorts([]string{"20000:30000"})
+	require.Error(t, err)
+	_, err = ParseListenPorts([]string{"443", "20000:20255"})
+	require.Error(t, err)
+}
+
+func TestServerConnReplyFromReceivingPort(t *testing.T) {
+	t.Parallel()
+	var conns []net.PacketConn
+	for i := 0; i < 2; i++ {
+		conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		require.NoError(t, err)
+		conns = append(conns, conn)
+	}
+	serverConn := NewServerConn(conns)
+	defer serverConn.Close()
+
+	client, err := net.DialUDP("udp", nil, conns[1].LocalAddr().(*net.UDPAddr))
+	require.NoError(t, err)
+	defer client.Close()
+	_, err = client.Write([]byte("ping"))
+	require.NoError(t, err)
+
+	buffer := make([]byte, 64)
+	require.NoError(t, serverConn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	n, addr, err := serverConn.ReadFrom(buffer)
+	require.NoError(t, err)
+	require.Equal(t, "ping", string(buffer[:n]))
+	_, err = serverConn.WriteTo([]byte("pong"), addr)
+	require.NoError(t, err)
+
+	require.NoError(t, client.SetReadDeadline(time.Now().Add(5*time.Second)))
+	n, err = client.Read(buffer)
+	require.NoError(t, err)
+	require.Equal(t, "pong", string(buffer[:n]))
+}
+
+func TestClientConnHopAfterClose(t *testing.T) {
+	t.Parallel()
+	server, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer server.Close()
+	serverAddr := M.SocksaddrFromNet(server.LocalAddr())
+	conn, err := NewClientConn(context.Background(), N.SystemDialer, serverAddr, []uint16{serverAddr.Port}, time.Hour)
+	require.NoError(t, err)
+	require.NoError(t, conn.Close())
+	require.ErrorIs(t, conn.hop(), net.ErrClosed)
+	_, err = conn.Read(make([]byte, 64))
+	require.ErrorIs(t, err, net.ErrClosed)
+}
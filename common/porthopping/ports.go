@@ -0,0 +1,53 @@
+package porthopping
+
+import (
+	"strconv"
+	"strings"
+
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+// MaxListenPorts limits the ports a hopping server listens on, since each port takes a socket.
+const MaxListenPorts = 256
+
+// ParseListenPorts parses the ports a hopping server listens on,
+// larger ranges should be redirected to one port by the firewall.
+func ParseListenPorts(portList []string) ([]uint16, error) {
+	ports, err := ParsePorts(portList)
+	if err != nil {
+		return nil, err
+	}
+	if len(ports) > MaxListenPorts {
+		return nil, E.New("too many ports: ", len(ports), " > ", MaxListenPorts, ", redirect the range to listen_port with the firewall instead")
+	}
+	return ports, nil
+}
+
+// ParsePorts parses ports and port ranges like "443" and "20000:30000".
+func ParsePorts(portList []string) ([]uint16, error) {
+	var ports []uint16
+	for _, portRange := range portList {
+		startString, endString, isRange := strings.Cut(portRange, ":")
+		if !isRange {
+			startString, endString, isRange = strings.Cut(portRange, "-")
+		}
+		start, err := strconv.ParseUint(startString, 10, 16)
+		if err != nil || start == 0 {
+			return nil, E.New("invalid port: ", portRange)
+		}
+		end := start
+		if isRange {
+			end, err = strconv.ParseUint(endString, 10, 16)
+			if err != nil || end < start {
+				return nil, E.New("invalid port range: ", portRange)
+			}
+		}
+		for port := start; port <= end; port++ {
+			ports = append(ports, uint16(port))
+		}
+	}
+	if len(ports) == 0 {
+		return nil, E.New("missing ports")
+	}
+	return ports, nil
+}
@@ -0,0 +1,119 @@
+package porthopping
+
+import (
+	"net"
+	"net/netip"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/buf"
+	"github.com/sagernet/sing/common/cache"
+	M "github.com/sagernet/sing/common/metadata"
+)
+
+var _ net.PacketConn = (*ServerConn)(nil)
+
+// ServerConn merges UDP sockets listening on several ports of a hopping server,
+// packets to a client are sent from the socket it sent to last.
+type ServerConn struct {
+	conns        []net.PacketConn
+	routes       *cache.LruCache[netip.AddrPort, net.PacketConn]
+	packets      chan serverPacket
+	done         chan struct{}
+	closeOnce    sync.Once
+	readDeadline *deadline
+}
+
+type serverPacket struct {
+	buffer *buf.Buffer
+	source netip.AddrPort
+	conn   net.PacketConn
+}
+
+func NewServerConn(conns []net.PacketConn) *ServerConn {
+	serverConn := &ServerConn{
+		conns: conns,
+		routes: cache.New(
+			cache.WithAge[netip.AddrPort, net.PacketConn](int64((5 * time.Minute).Seconds())),
+			cache.WithUpdateAgeOnGet[netip.AddrPort, net.PacketConn](),
+		),
+		packets:      make(chan serverPacket, 64),
+		done:         make(chan struct{}),
+		readDeadline: newDeadline(),
+	}
+	for _, conn := range conns {
+		go serverConn.loopRead(conn)
+	}
+	return serverConn
+}
+
+func (c *ServerConn) loopRead(conn net.PacketConn) {
+	for {
+		buffer := buf.NewPacket()
+		n, addr, err := conn.ReadFrom(buffer.FreeBytes())
+		if err != nil {
+			buffer.Release()
+			return
+		}
+		buffer.Truncate(n)
+		select {
+		case c.packets <- serverPacket{buffer, M.SocksaddrFromNet(addr).Unwrap().AddrPort(), conn}:
+		case <-c.done:
+			buffer.Release()
+			return
+		}
+	}
+}
+
+func (c *ServerConn) ReadFrom(p []byte) (n int, addr net.Addr, err error) {
+	select {
+	case packet := <-c.packets:
+		n = copy(p, packet.buffer.Bytes())
+		packet.buffer.Release()
+		c.routes.Store(packet.source, packet.conn)
+		return n, net.UDPAddrFromAddrPort(packet.source), nil
+	case <-c.done:
+		return 0, nil, net.ErrClosed
+	case <-c.readDeadline.wait():
+		return 0, nil, os.ErrDeadlineExceeded
+	}
+}
+
+func (c *ServerConn) WriteTo(p []byte, addr net.Addr) (n int, err error) {
+	conn, loaded := c.routes.Load(M.SocksaddrFromNet(addr).Unwrap().AddrPort())
+	if !loaded {
+		conn = c.conns[0]
+	}
+	return conn.WriteTo(p, addr)
+}
+
+func (c *ServerConn) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		close(c.done)
+		err = common.Close(common.Map(c.conns, func(it net.PacketConn) any {
+			return it
+		})...)
+	})
+	return err
+}
+
+func (c *ServerConn) LocalAddr() net.Addr {
+	return c.conns[0].LocalAddr()
+}
+
+func (c *ServerConn) SetDeadline(t time.Time) error {
+	c.readDeadline.set(t)
+	return nil
+}
+
+func (c *ServerConn) SetReadDeadline(t time.Time) error {
+	c.readDeadline.set(t)
+	return nil
+}
+
+func (c *ServerConn) SetWriteDeadline(t time.Time) error {
+	return nil
+}
@@ -15,8 +15,17 @@ import (
 
 var _ N.Dialer = (*Dialer)(nil)
 
+// Dialer writes a PROXY protocol header carrying the inbound source to TCP connections.
 type Dialer struct {
 	N.Dialer
+	Version uint8
+}
+
+func NewDialer(dialer N.Dialer, version uint8) (*Dialer, error) {
+	if version != 1 && version != 2 {
+		return nil, E.New("unknown proxy protocol version: ", version)
+	}
+	return &Dialer{dialer, version}, nil
 }
 
 func (d *Dialer) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
@@ -26,18 +35,40 @@ func (d *Dialer) DialContext(ctx context.Context, network string, destination M.
 		if err != nil {
 			return nil, err
 		}
-		var source M.Socksaddr
+		var (
+			source    M.Socksaddr
+			authority string
+		)
 		metadata := adapter.ContextFrom(ctx)
 		if metadata != nil {
 			source = metadata.Source
+			authority = metadata.Domain
 		}
 		if !source.IsValid() {
 			source = M.SocksaddrFromNet(conn.LocalAddr())
 		}
-		if destination.Addr.Is6() {
+		if destination.IsFqdn() {
+			if authority == "" {
+				authority = destination.Fqdn
+			}
+			destination = M.SocksaddrFromNet(conn.RemoteAddr())
+		}
+		source, destination = source.Unwrap(), destination.Unwrap()
+		if source.Addr.Is4() != destination.Addr.Is4() {
 			source = M.SocksaddrFrom(netip.AddrFrom16(source.Addr.As16()), source.Port)
+			destination = M.SocksaddrFrom(netip.AddrFrom16(destination.Addr.As16()), destination.Port)
+		}
+		h := proxyproto.HeaderProxyFromAddrs(d.Version, source.TCPAddr(), destination.TCPAddr())
+		if d.Version == 2 && authority != "" {
+			err = h.SetTLVs([]proxyproto.TLV{{
+				Type:  proxyproto.PP2_TYPE_AUTHORITY,
+				Value: []byte(authority),
+			}})
+			if err != nil {
+				conn.Close()
+				return nil, E.Cause(err, "set proxy protocol TLVs")
+			}
 		}
-		h := proxyproto.HeaderProxyFromAddrs(1, source.TCPAddr(), destination.TCPAddr())
 		_, err = h.WriteTo(conn)
 		if err != nil {
 			conn.Close()
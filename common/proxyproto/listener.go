@@ -4,11 +4,13 @@ import (
 	std_bufio "bufio"
 	"net"
 
+	"github.com/sagernet/sing-box/adapter"
 	"github.com/sagernet/sing/common/buf"
 	"github.com/sagernet/sing/common/bufio"
 	M "github.com/sagernet/sing/common/metadata"
 
 	"github.com/pires/go-proxyproto"
+	"github.com/pires/go-proxyproto/tlvparse"
 )
 
 type Listener struct {
@@ -35,14 +37,64 @@ func (l *Listener) Accept() (net.Conn, error) {
 		conn = bufio.NewCachedConn(conn, cache)
 	}
 	if header != nil {
-		return &bufio.AddrConn{Conn: conn, Metadata: M.Metadata{
-			Source:      M.SocksaddrFromNet(header.SourceAddr).Unwrap(),
-			Destination: M.SocksaddrFromNet(header.DestinationAddr).Unwrap(),
-		}}, nil
+		return &Conn{
+			AddrConn: bufio.AddrConn{Conn: conn, Metadata: M.Metadata{
+				Source:      M.SocksaddrFromNet(header.SourceAddr).Unwrap(),
+				Destination: M.SocksaddrFromNet(header.DestinationAddr).Unwrap(),
+			}},
+			Info: parseInfo(header),
+		}, nil
 	}
 	return conn, nil
 }
 
+// Conn is a connection accepted with a PROXY protocol header.
+type Conn struct {
+	bufio.AddrConn
+	// Info is nil if the header has no known TLVs.
+	Info *adapter.ProxyProtocolInfo
+}
+
+func (c *Conn) Upstream() any {
+	return c.Conn
+}
+
+func parseInfo(header *proxyproto.Header) *adapter.ProxyProtocolInfo {
+	tlvs, err := header.TLVs()
+	if err != nil || len(tlvs) == 0 {
+		return nil
+	}
+	var (
+		info   adapter.ProxyProtocolInfo
+		loaded bool
+	)
+	for _, tlv := range tlvs {
+		switch tlv.Type {
+		case proxyproto.PP2_TYPE_ALPN:
+			info.ALPN = string(tlv.Value)
+			loaded = true
+		case proxyproto.PP2_TYPE_AUTHORITY:
+			info.Authority = string(tlv.Value)
+			loaded = true
+		case proxyproto.PP2_TYPE_UNIQUE_ID:
+			info.UniqueID = tlv.Value
+			loaded = true
+		}
+	}
+	if ssl, isSSL := tlvparse.FindSSL(tlvs); isSSL {
+		info.SSL = ssl.ClientSSL()
+		info.SSLVerified = ssl.Verified()
+		info.SSLVersion, _ = ssl.SSLVersion()
+		info.SSLCipher, _ = ssl.SSLCipher()
+		info.SSLClientCN, _ = ssl.ClientCN()
+		loaded = true
+	}
+	if !loaded {
+		return nil
+	}
+	return &info
+}
+
 var _ net.Error = (*Error)(nil)
 
 type Error struct {
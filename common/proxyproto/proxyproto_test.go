@@ -0,0 +1,42 @@
+package proxyproto
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/sagernet/sing-box/adapter"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProxyProtocolV2(t *testing.T) {
+	t.Parallel()
+	tcpListener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	listener := &Listener{Listener: tcpListener}
+	defer listener.Close()
+	dialer, err := NewDialer(N.SystemDialer, 2)
+	require.NoError(t, err)
+	ctx, metadata := adapter.AppendContext(context.Background())
+	metadata.Source = M.ParseSocksaddr("10.0.0.1:12345")
+	metadata.Domain = "example.com"
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		conn, _ := listener.Accept()
+		accepted <- conn
+	}()
+	conn, err := dialer.DialContext(ctx, N.NetworkTCP, M.SocksaddrFromNet(tcpListener.Addr()))
+	require.NoError(t, err)
+	defer conn.Close()
+	serverConn := <-accepted
+	require.NotNil(t, serverConn)
+	defer serverConn.Close()
+	require.Equal(t, "10.0.0.1:12345", serverConn.RemoteAddr().String())
+	proxyConn, isProxyConn := serverConn.(*Conn)
+	require.True(t, isProxyConn)
+	require.NotNil(t, proxyConn.Info)
+	require.Equal(t, "example.com", proxyConn.Info.Authority)
+}
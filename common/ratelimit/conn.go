@@ -0,0 +1,177 @@
+package ratelimit
+
+import (
+	"context"
+	"net"
+
+	"github.com/sagernet/sing/common/buf"
+	"github.com/sagernet/sing/common/bufio"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+)
+
+// NewConn limits reads and writes of conn, conn is returned as is if both limiters are nil.
+func NewConn(conn net.Conn, readLimiter *Limiter, writeLimiter *Limiter) net.Conn {
+	if readLimiter == nil && writeLimiter == nil {
+		return conn
+	}
+	ctx, cancel := context.WithCancelCause(context.Background())
+	return &Conn{bufio.NewExtendedConn(conn), readLimiter, writeLimiter, ctx, cancel}
+}
+
+// Conn waits for its limiters until it is closed.
+type Conn struct {
+	N.ExtendedConn
+	readLimiter  *Limiter
+	writeLimiter *Limiter
+	ctx          context.Context
+	cancel       context.CancelCauseFunc
+}
+
+func (c *Conn) Read(p []byte) (n int, err error) {
+	n, err = c.ExtendedConn.Read(p)
+	if n > 0 && c.readLimiter != nil {
+		waitErr := c.readLimiter.Wait(c.ctx, n)
+		if err == nil {
+			err = waitErr
+		}
+	}
+	return
+}
+
+func (c *Conn) ReadBuffer(buffer *buf.Buffer) error {
+	err := c.ExtendedConn.ReadBuffer(buffer)
+	if err == nil && c.readLimiter != nil {
+		err = c.readLimiter.Wait(c.ctx, buffer.Len())
+	}
+	return err
+}
+
+func (c *Conn) Write(p []byte) (n int, err error) {
+	if c.writeLimiter != nil {
+		err = c.writeLimiter.Wait(c.ctx, len(p))
+		if err != nil {
+			return
+		}
+	}
+	return c.ExtendedConn.Write(p)
+}
+
+func (c *Conn) WriteBuffer(buffer *buf.Buffer) error {
+	if c.writeLimiter != nil {
+		err := c.writeLimiter.Wait(c.ctx, buffer.Len())
+		if err != nil {
+			return err
+		}
+	}
+	return c.ExtendedConn.WriteBuffer(buffer)
+}
+
+func (c *Conn) Close() error {
+	c.cancel(net.ErrClosed)
+	return c.ExtendedConn.Close()
+}
+
+func (c *Conn) ReaderReplaceable() bool {
+	return c.readLimiter == nil
+}
+
+func (c *Conn) WriterReplaceable() bool {
+	return c.writeLimiter == nil
+}
+
+func (c *Conn) Upstream() any {
+	return c.ExtendedConn
+}
+
+// NewPacketConn limits reads and writes of conn, conn is returned as is if both limiters are nil.
+func NewPacketConn(conn N.PacketConn, readLimiter *Limiter, writeLimiter *Limiter) N.PacketConn {
+	if readLimiter == nil && writeLimiter == nil {
+		return conn
+	}
+	return newPacketConn(conn, readLimiter, writeLimiter)
+}
+
+func newPacketConn(conn N.PacketConn, readLimiter *Limiter, writeLimiter *Limiter) *PacketConn {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	return &PacketConn{conn, readLimiter, writeLimiter, ctx, cancel}
+}
+
+// PacketConn waits for its limiters until it is closed.
+type PacketConn struct {
+	N.PacketConn
+	readLimiter  *Limiter
+	writeLimiter *Limiter
+	ctx          context.Context
+	cancel       context.CancelCauseFunc
+}
+
+func (c *PacketConn) ReadPacket(buffer *buf.Buffer) (destination M.Socksaddr, err error) {
+	destination, err = c.PacketConn.ReadPacket(buffer)
+	if err == nil && c.readLimiter != nil {
+		err = c.readLimiter.Wait(c.ctx, buffer.Len())
+	}
+	return
+}
+
+func (c *PacketConn) WritePacket(buffer *buf.Buffer, destination M.Socksaddr) error {
+	if c.writeLimiter != nil {
+		err := c.writeLimiter.Wait(c.ctx, buffer.Len())
+		if err != nil {
+			buffer.Release()
+			return err
+		}
+	}
+	return c.PacketConn.WritePacket(buffer, destination)
+}
+
+func (c *PacketConn) Close() error {
+	c.cancel(net.ErrClosed)
+	return c.PacketConn.Close()
+}
+
+func (c *PacketConn) ReaderReplaceable() bool {
+	return c.readLimiter == nil
+}
+
+func (c *PacketConn) WriterReplaceable() bool {
+	return c.writeLimiter == nil
+}
+
+func (c *PacketConn) Upstream() any {
+	return c.PacketConn
+}
+
+// NewNetPacketConn limits reads and writes of conn, conn is returned as is if both limiters are nil.
+func NewNetPacketConn(conn net.PacketConn, readLimiter *Limiter, writeLimiter *Limiter) net.PacketConn {
+	if readLimiter == nil && writeLimiter == nil {
+		return conn
+	}
+	return &NetPacketConn{newPacketConn(bufio.NewPacketConn(conn), readLimiter, writeLimiter), conn}
+}
+
+type NetPacketConn struct {
+	*PacketConn
+	netConn net.PacketConn
+}
+
+func (c *NetPacketConn) ReadFrom(p []byte) (n int, addr net.Addr, err error) {
+	n, addr, err = c.netConn.ReadFrom(p)
+	if n > 0 && c.readLimiter != nil {
+		waitErr := c.readLimiter.Wait(c.ctx, n)
+		if err == nil {
+			err = waitErr
+		}
+	}
+	return
+}
+
+func (c *NetPacketConn) WriteTo(p []byte, addr net.Addr) (n int, err error) {
+	if c.writeLimiter != nil {
+		err = c.writeLimiter.Wait(c.ctx, len(p))
+		if err != nil {
+			return
+		}
+	}
+	return c.netConn.WriteTo(p, addr)
+}
@@ -0,0 +1,70 @@
+package ratelimit
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+const minBurst = 64 * 1024
+
+// Limiter is a token bucket in bytes, it may be shared by multiple connections.
+type Limiter struct {
+	limiter   *rate.Limiter
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewLimiter creates a limiter of bytesPerSecond, burst defaults to one second of traffic.
+// A nil limiter is returned if bytesPerSecond is zero.
+func NewLimiter(bytesPerSecond uint64, burst uint64) *Limiter {
+	if bytesPerSecond == 0 {
+		return nil
+	}
+	if burst == 0 {
+		burst = bytesPerSecond
+	}
+	if burst < minBurst {
+		burst = minBurst
+	}
+	return &Limiter{
+		limiter: rate.NewLimiter(rate.Limit(bytesPerSecond), int(burst)),
+		done:    make(chan struct{}),
+	}
+}
+
+// Wait blocks until n bytes are allowed, the context is canceled or the limiter is closed.
+func (l *Limiter) Wait(ctx context.Context, n int) error {
+	burst := l.limiter.Burst()
+	for n > 0 {
+		chunk := min(n, burst)
+		reservation := l.limiter.ReserveN(time.Now(), chunk)
+		if delay := reservation.Delay(); delay > 0 {
+			timer := time.NewTimer(delay)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				reservation.Cancel()
+				return context.Cause(ctx)
+			case <-l.done:
+				timer.Stop()
+				reservation.Cancel()
+				return net.ErrClosed
+			}
+		}
+		n -= chunk
+	}
+	return nil
+}
+
+// Close wakes up and fails all waiting connections.
+func (l *Limiter) Close() error {
+	l.closeOnce.Do(func() {
+		close(l.done)
+	})
+	return nil
+}
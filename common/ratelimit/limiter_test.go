@@ -0,0 +1,75 @@
+package ratelimit
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/option"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimiter(t *testing.T) {
+	t.Parallel()
+	require.Nil(t, NewLimiter(0, 0))
+	limiter := NewLimiter(minBurst, 0)
+	start := time.Now()
+	require.NoError(t, limiter.Wait(context.Background(), minBurst))
+	require.Less(t, time.Since(start), 100*time.Millisecond)
+	require.NoError(t, limiter.Wait(context.Background(), minBurst/4))
+	require.GreaterOrEqual(t, time.Since(start), 200*time.Millisecond)
+}
+
+func TestLimiterWaitInterrupted(t *testing.T) {
+	t.Parallel()
+	limiter := NewLimiter(minBurst, 0)
+	require.NoError(t, limiter.Wait(context.Background(), minBurst))
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	require.ErrorIs(t, limiter.Wait(ctx, 10*minBurst), context.DeadlineExceeded)
+	require.Less(t, time.Since(start), time.Second)
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		limiter.Close()
+	}()
+	require.ErrorIs(t, limiter.Wait(context.Background(), 10*minBurst), net.ErrClosed)
+	require.Less(t, time.Since(start), time.Second)
+}
+
+func TestConnCloseInterruptsWait(t *testing.T) {
+	t.Parallel()
+	serverConn, clientConn := net.Pipe()
+	defer serverConn.Close()
+	go io.Copy(io.Discard, serverConn)
+	conn := NewConn(clientConn, nil, NewLimiter(minBurst, 0))
+	_, err := conn.Write(make([]byte, minBurst))
+	require.NoError(t, err)
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		conn.Close()
+	}()
+	start := time.Now()
+	_, err = conn.Write(make([]byte, 10*minBurst))
+	require.ErrorIs(t, err, net.ErrClosed)
+	require.Less(t, time.Since(start), time.Second)
+}
+
+func TestLimitersPassthrough(t *testing.T) {
+	t.Parallel()
+	limiters := NewLimiters(option.RateLimitOptions{})
+	require.True(t, limiters.IsEmpty())
+	conn, _ := net.Pipe()
+	defer conn.Close()
+	require.Equal(t, conn, limiters.Conn(conn))
+	limiters = NewLimiters(option.RateLimitOptions{UpMbps: 1})
+	require.NotNil(t, limiters.Upload)
+	require.Nil(t, limiters.Download)
+	limitedConn := limiters.Conn(conn).(*Conn)
+	require.False(t, limitedConn.ReaderReplaceable())
+	require.True(t, limitedConn.WriterReplaceable())
+}
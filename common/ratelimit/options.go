@@ -0,0 +1,42 @@
+package ratelimit
+
+import (
+	"net"
+
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	N "github.com/sagernet/sing/common/network"
+)
+
+// Limiters is a pair of limiters of the upload and download directions.
+type Limiters struct {
+	Upload   *Limiter
+	Download *Limiter
+}
+
+// NewLimiters creates limiters from options, the burst is shared by both directions.
+// Limiters of zero speed are nil.
+func NewLimiters(options option.RateLimitOptions) Limiters {
+	return Limiters{
+		Upload:   NewLimiter(uint64(options.UpMbps)*125000, uint64(options.Burst)),
+		Download: NewLimiter(uint64(options.DownMbps)*125000, uint64(options.Burst)),
+	}
+}
+
+func (l Limiters) IsEmpty() bool {
+	return l.Upload == nil && l.Download == nil
+}
+
+// Conn limits a connection accepted by an inbound, where reads are uploads.
+func (l Limiters) Conn(conn net.Conn) net.Conn {
+	return NewConn(conn, l.Upload, l.Download)
+}
+
+func (l Limiters) PacketConn(conn N.PacketConn) N.PacketConn {
+	return NewPacketConn(conn, l.Upload, l.Download)
+}
+
+// Close fails connections waiting for the limiters.
+func (l Limiters) Close() error {
+	return common.Close(common.PtrOrNil(l.Upload), common.PtrOrNil(l.Download))
+}
@@ -0,0 +1,129 @@
+package rotate
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+type Options struct {
+	Path       string
+	MaxSize    int64
+	MaxAge     time.Duration
+	MaxBackups int
+}
+
+// Writer is an append-only file writer that moves the current file to
+// <path>.1, <path>.2, ... once it grows past MaxSize or gets older than MaxAge.
+type Writer struct {
+	options  Options
+	access   sync.Mutex
+	file     *os.File
+	size     int64
+	openTime time.Time
+}
+
+func NewWriter(options Options) (*Writer, error) {
+	if options.Path == "" {
+		return nil, E.New("missing path")
+	}
+	writer := &Writer{options: options}
+	err := writer.open()
+	if err != nil {
+		return nil, err
+	}
+	return writer, nil
+}
+
+func (w *Writer) open() error {
+	err := os.MkdirAll(filepath.Dir(w.options.Path), 0o755)
+	if err != nil {
+		return E.Cause(err, "create parent directory")
+	}
+	file, err := os.OpenFile(w.options.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return E.Cause(err, "open ", w.options.Path)
+	}
+	fileInfo, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	w.file = file
+	w.size = fileInfo.Size()
+	w.openTime = fileInfo.ModTime()
+	if w.size == 0 {
+		w.openTime = time.Now()
+	}
+	return nil
+}
+
+func (w *Writer) Write(p []byte) (n int, err error) {
+	w.access.Lock()
+	defer w.access.Unlock()
+	if w.file == nil {
+		return 0, os.ErrClosed
+	}
+	if w.needRotate(int64(len(p))) {
+		err = w.rotate()
+		if err != nil {
+			return
+		}
+	}
+	n, err = w.file.Write(p)
+	w.size += int64(n)
+	return
+}
+
+func (w *Writer) needRotate(writeSize int64) bool {
+	if w.size == 0 {
+		return false
+	}
+	if w.options.MaxSize > 0 && w.size+writeSize > w.options.MaxSize {
+		return true
+	}
+	if w.options.MaxAge > 0 && time.Since(w.openTime) > w.options.MaxAge {
+		return true
+	}
+	return false
+}
+
+func (w *Writer) backupPath(index int) string {
+	return w.options.Path + "." + strconv.Itoa(index)
+}
+
+func (w *Writer) rotate() error {
+	err := w.file.Close()
+	w.file = nil
+	if err != nil {
+		return err
+	}
+	if w.options.MaxBackups > 0 {
+		os.Remove(w.backupPath(w.options.MaxBackups))
+		for i := w.options.MaxBackups - 1; i > 0; i-- {
+			os.Rename(w.backupPath(i), w.backupPath(i+1))
+		}
+		err = os.Rename(w.options.Path, w.backupPath(1))
+	} else {
+		err = os.Remove(w.options.Path)
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return E.Cause(err, "rotate ", w.options.Path)
+	}
+	return w.open()
+}
+
+func (w *Writer) Close() error {
+	w.access.Lock()
+	defer w.access.Unlock()
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
@@ -0,0 +1,38 @@
+package rotate_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sagernet/sing-box/common/rotate"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriterRotate(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	writer, err := rotate.NewWriter(rotate.Options{
+		Path:       path,
+		MaxSize:    8,
+		MaxBackups: 2,
+	})
+	require.NoError(t, err)
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		_, err = writer.Write([]byte(line))
+		require.NoError(t, err)
+	}
+	require.NoError(t, writer.Close())
+	for path, content := range map[string]string{
+		path:        "fourth\n",
+		path + ".1": "third\n",
+		path + ".2": "second\n",
+	} {
+		fileContent, err := os.ReadFile(path)
+		require.NoError(t, err)
+		require.Equal(t, content, string(fileContent))
+	}
+	_, err = os.Stat(path + ".3")
+	require.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,33 @@
+package sniff
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"os"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+)
+
+// RDP sniffs the X.224 connection request carrying an RDP negotiation request.
+func RDP(ctx context.Context, reader io.Reader) (*adapter.InboundContext, error) {
+	var header [19]byte
+	_, err := io.ReadFull(reader, header[:])
+	if err != nil {
+		return nil, os.ErrInvalid
+	}
+	// TPKT version 3, reserved 0, length 19
+	if header[0] != 0x03 || header[1] != 0x00 || binary.BigEndian.Uint16(header[2:4]) != 19 {
+		return nil, os.ErrInvalid
+	}
+	// COTP length 14, connection request
+	if header[4] != 14 || header[5] != 0xE0 {
+		return nil, os.ErrInvalid
+	}
+	// RDP negotiation request of length 8
+	if header[11] != 0x01 || binary.LittleEndian.Uint16(header[13:15]) != 8 {
+		return nil, os.ErrInvalid
+	}
+	return &adapter.InboundContext{Protocol: C.ProtocolRDP}, nil
+}
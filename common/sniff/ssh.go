@@ -0,0 +1,23 @@
+package sniff
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+)
+
+func SSH(ctx context.Context, reader io.Reader) (*adapter.InboundContext, error) {
+	scanner := bufio.NewScanner(reader)
+	if !scanner.Scan() {
+		return nil, os.ErrInvalid
+	}
+	if !strings.HasPrefix(scanner.Text(), "SSH-2.0-") {
+		return nil, os.ErrInvalid
+	}
+	return &adapter.InboundContext{Protocol: C.ProtocolSSH}, nil
+}
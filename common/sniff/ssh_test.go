@@ -0,0 +1,31 @@
+package sniff_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/sagernet/sing-box/common/sniff"
+	C "github.com/sagernet/sing-box/constant"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSniffSSH(t *testing.T) {
+	t.Parallel()
+	metadata, err := sniff.SSH(context.Background(), bytes.NewReader([]byte("SSH-2.0-OpenSSH_9.6\r\n")))
+	require.NoError(t, err)
+	require.Equal(t, C.ProtocolSSH, metadata.Protocol)
+	_, err = sniff.SSH(context.Background(), bytes.NewReader([]byte("GET / HTTP/1.1\r\n")))
+	require.Error(t, err)
+}
+
+func TestSniffRDP(t *testing.T) {
+	t.Parallel()
+	packet := []byte{0x03, 0x00, 0x00, 0x13, 0x0e, 0xe0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01, 0x00, 0x08, 0x00, 0x03, 0x00, 0x00, 0x00}
+	metadata, err := sniff.RDP(context.Background(), bytes.NewReader(packet))
+	require.NoError(t, err)
+	require.Equal(t, C.ProtocolRDP, metadata.Protocol)
+	_, err = sniff.RDP(context.Background(), bytes.NewReader(packet[:10]))
+	require.Error(t, err)
+}
@@ -29,6 +29,9 @@ func NewClient(ctx context.Context, serverAddress string, options option.Outboun
 	if !options.Enabled {
 		return nil, nil
 	}
+	if options.Fronting != nil && options.Fronting.Enabled {
+		return NewFrontingClient(ctx, serverAddress, options)
+	}
 	if options.ECH != nil && options.ECH.Enabled {
 		return NewECHClient(ctx, serverAddress, options)
 	} else if options.Reality != nil && options.Reality.Enabled {
@@ -0,0 +1,220 @@
+package tls
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"math/rand"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/atomic"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/ntp"
+	aTLS "github.com/sagernet/sing/common/tls"
+)
+
+var _ ConfigCompat = (*FrontingClientConfig)(nil)
+
+// WithFrontingHost is implemented by client configs that carry a separate
+// HTTP Host / :authority for the V2Ray transports.
+type WithFrontingHost interface {
+	FrontingHost() string
+}
+
+// FrontingHost returns the Host that V2Ray transports should send for config,
+// or an empty string if fronting is not enabled.
+func FrontingHost(config Config) string {
+	if config == nil {
+		return ""
+	}
+	if hostConfig, loaded := config.(WithFrontingHost); loaded {
+		return hostConfig.FrontingHost()
+	}
+	return ""
+}
+
+type FrontingClientConfig struct {
+	config           Config
+	frontDomains     []string
+	randomRotation   bool
+	index            *atomic.Uint32
+	verifyServerName string
+	rootCAs          *x509.CertPool
+	timeFunc         func() time.Time
+	host             string
+}
+
+func NewFrontingClient(ctx context.Context, serverAddress string, options option.OutboundTLSOptions) (Config, error) {
+	frontingOptions := common.PtrValueOrDefault(options.Fronting)
+	options.Fronting = nil
+	if options.Reality != nil && options.Reality.Enabled {
+		return nil, E.New("fronting is unsupported with reality")
+	}
+	if options.DisableSNI {
+		return nil, E.New("fronting and disable_sni are mutually exclusive")
+	}
+	var randomRotation bool
+	switch frontingOptions.Rotation {
+	case "", C.FrontingRotationRoundRobin:
+	case C.FrontingRotationRandom:
+		randomRotation = true
+	default:
+		return nil, E.New("unknown fronting rotation: ", frontingOptions.Rotation)
+	}
+	config := &FrontingClientConfig{
+		frontDomains:     frontingOptions.FrontDomain,
+		randomRotation:   randomRotation,
+		index:            new(atomic.Uint32),
+		verifyServerName: frontingOptions.VerifyServerName,
+		timeFunc:         ntp.TimeFuncFromContext(ctx),
+		host:             frontingOptions.Host,
+	}
+	if config.host == "" {
+		config.host = options.ServerName
+	}
+	if options.Insecure {
+		config.verifyServerName = ""
+	} else if config.verifyServerName != "" {
+		var certificate []byte
+		if len(options.Certificate) > 0 {
+			certificate = []byte(strings.Join(options.Certificate, "\n"))
+		} else if options.CertificatePath != "" {
+			content, err := os.ReadFile(options.CertificatePath)
+			if err != nil {
+				return nil, E.Cause(err, "read certificate")
+			}
+			certificate = content
+		}
+		if len(certificate) > 0 {
+			config.rootCAs = x509.NewCertPool()
+			if !config.rootCAs.AppendCertsFromPEM(certificate) {
+				return nil, E.New("failed to parse certificate:\n\n", certificate)
+			}
+		}
+		// the certificate is verified against verify_server_name after the handshake
+		options.Insecure = true
+	}
+	if options.ServerName == "" && len(config.frontDomains) > 0 {
+		options.ServerName = config.frontDomains[0]
+	}
+	innerConfig, err := NewClient(ctx, serverAddress, options)
+	if err != nil {
+		return nil, err
+	}
+	config.config = innerConfig
+	return config, nil
+}
+
+func (c *FrontingClientConfig) ServerName() string {
+	return c.config.ServerName()
+}
+
+func (c *FrontingClientConfig) SetServerName(serverName string) {
+	c.config.SetServerName(serverName)
+}
+
+func (c *FrontingClientConfig) NextProtos() []string {
+	return c.config.NextProtos()
+}
+
+func (c *FrontingClientConfig) SetNextProtos(nextProto []string) {
+	c.config.SetNextProtos(nextProto)
+}
+
+func (c *FrontingClientConfig) FrontingHost() string {
+	return c.host
+}
+
+func (c *FrontingClientConfig) nextServerName() string {
+	switch domainLen := len(c.frontDomains); domainLen {
+	case 0:
+		return ""
+	case 1:
+		return c.frontDomains[0]
+	default:
+		if c.randomRotation {
+			return c.frontDomains[rand.Intn(domainLen)]
+		}
+		return c.frontDomains[int(c.index.Add(1)-1)%domainLen]
+	}
+}
+
+func (c *FrontingClientConfig) verifyConnection(state tls.ConnectionState) error {
+	if len(state.PeerCertificates) == 0 {
+		return E.New("fronting: missing peer certificate")
+	}
+	verifyOptions := x509.VerifyOptions{
+		Roots:         c.rootCAs,
+		DNSName:       c.verifyServerName,
+		Intermediates: x509.NewCertPool(),
+	}
+	if c.timeFunc != nil {
+		verifyOptions.CurrentTime = c.timeFunc()
+	}
+	for _, cert := range state.PeerCertificates[1:] {
+		verifyOptions.Intermediates.AddCert(cert)
+	}
+	_, err := state.PeerCertificates[0].Verify(verifyOptions)
+	if err != nil {
+		return E.Cause(err, "fronting: verify certificate for ", c.verifyServerName)
+	}
+	return nil
+}
+
+func (c *FrontingClientConfig) Config() (*STDConfig, error) {
+	stdConfig, err := c.config.Config()
+	if err != nil {
+		return nil, err
+	}
+	stdConfig = stdConfig.Clone()
+	if serverName := c.nextServerName(); serverName != "" {
+		stdConfig.ServerName = serverName
+	}
+	if c.verifyServerName != "" {
+		stdConfig.InsecureSkipVerify = true
+		stdConfig.VerifyConnection = c.verifyConnection
+	}
+	return stdConfig, nil
+}
+
+func (c *FrontingClientConfig) Client(conn net.Conn) (Conn, error) {
+	return c.ClientHandshake(context.Background(), conn)
+}
+
+func (c *FrontingClientConfig) ClientHandshake(ctx context.Context, conn net.Conn) (aTLS.Conn, error) {
+	config := c.config.Clone()
+	if serverName := c.nextServerName(); serverName != "" {
+		config.SetServerName(serverName)
+	}
+	tlsConn, err := aTLS.ClientHandshake(ctx, conn, config)
+	if err != nil {
+		return nil, err
+	}
+	if c.verifyServerName != "" {
+		err = c.verifyConnection(tlsConn.ConnectionState())
+		if err != nil {
+			tlsConn.Close()
+			return nil, err
+		}
+	}
+	return tlsConn, nil
+}
+
+func (c *FrontingClientConfig) Clone() Config {
+	return &FrontingClientConfig{
+		config:           c.config.Clone(),
+		frontDomains:     c.frontDomains,
+		randomRotation:   c.randomRotation,
+		index:            c.index,
+		verifyServerName: c.verifyServerName,
+		rootCAs:          c.rootCAs,
+		timeFunc:         c.timeFunc,
+		host:             c.host,
+	}
+}
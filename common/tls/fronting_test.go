@@ -0,0 +1,58 @@
+package tls_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sagernet/sing-box/common/tls"
+	"github.com/sagernet/sing-box/option"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFrontingRotation(t *testing.T) {
+	t.Parallel()
+	config, err := tls.NewClient(context.Background(), "203.0.113.1", option.OutboundTLSOptions{
+		Enabled:    true,
+		ServerName: "hidden.example.org",
+		Fronting: &option.OutboundFrontingOptions{
+			Enabled:          true,
+			FrontDomain:      []string{"a.example.com", "b.example.com"},
+			VerifyServerName: "example.com",
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "hidden.example.org", tls.FrontingHost(config))
+	for _, serverName := range []string{"a.example.com", "b.example.com", "a.example.com"} {
+		stdConfig, err := config.Clone().Config()
+		require.NoError(t, err)
+		require.Equal(t, serverName, stdConfig.ServerName)
+		require.True(t, stdConfig.InsecureSkipVerify)
+		require.NotNil(t, stdConfig.VerifyConnection)
+	}
+}
+
+func TestFrontingHost(t *testing.T) {
+	t.Parallel()
+	config, err := tls.NewClient(context.Background(), "203.0.113.1", option.OutboundTLSOptions{
+		Enabled:    true,
+		ServerName: "hidden.example.org",
+		Fronting: &option.OutboundFrontingOptions{
+			Enabled:     true,
+			FrontDomain: []string{"front.example.com"},
+			Host:        "origin.example.org",
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "origin.example.org", tls.FrontingHost(config))
+	stdConfig, err := config.Config()
+	require.NoError(t, err)
+	require.Equal(t, "front.example.com", stdConfig.ServerName)
+	require.False(t, stdConfig.InsecureSkipVerify)
+	_, err = tls.NewClient(context.Background(), "203.0.113.1", option.OutboundTLSOptions{
+		Enabled:  true,
+		Fronting: &option.OutboundFrontingOptions{Enabled: true, Rotation: "unknown"},
+		Insecure: true,
+	})
+	require.Error(t, err)
+}
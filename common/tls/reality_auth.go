@@ -0,0 +1,218 @@
+//go:build with_reality_server
+
+package tls
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/binary"
+	"math"
+	"net"
+	"time"
+
+	"github.com/sagernet/reality"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing/common/buf"
+
+	"golang.org/x/crypto/cryptobyte"
+	"golang.org/x/crypto/curve25519"
+	"golang.org/x/crypto/hkdf"
+)
+
+const (
+	realityExtensionServerName        = 0
+	realityExtensionSupportedVersions = 43
+	realityExtensionKeyShare          = 51
+	realityGroupX25519                = 29
+)
+
+// realityClientHello is the part of a ClientHello needed to authenticate REALITY clients.
+type realityClientHello struct {
+	raw        []byte
+	random     []byte
+	sessionID  []byte
+	serverName string
+	tls13      bool
+	keyShares  [][]byte
+}
+
+// readRealityClientHello reads TLS records from conn into buffer until the ClientHello is complete,
+// and returns the handshake message reassembled from the record fragments.
+func readRealityClientHello(conn net.Conn, buffer *buf.Buffer) []byte {
+	err := conn.SetReadDeadline(time.Now().Add(C.TCPTimeout))
+	if err != nil {
+		return nil
+	}
+	defer conn.SetReadDeadline(time.Time{})
+	for !buffer.IsFull() {
+		_, err = buffer.ReadOnceFrom(conn)
+		if err != nil {
+			return nil
+		}
+		message, complete, valid := realityHandshakeMessage(buffer.Bytes())
+		if !valid {
+			return nil
+		}
+		if complete {
+			return message
+		}
+	}
+	return nil
+}
+
+// realityHandshakeMessage joins the fragments of the first handshake message in the TLS records of content,
+// valid is false if content is not a handshake.
+func realityHandshakeMessage(content []byte) (message []byte, complete bool, valid bool) {
+	records := cryptobyte.String(content)
+	for {
+		if len(message) >= 4 {
+			messageLength := 4 + (int(message[1])<<16 | int(message[2])<<8 | int(message[3]))
+			if len(message) >= messageLength {
+				return message[:messageLength], true, true
+			}
+		}
+		var (
+			recordType    uint8
+			recordVersion uint16
+			fragment      cryptobyte.String
+		)
+		if !records.ReadUint8(&recordType) {
+			return nil, false, true
+		}
+		if recordType != 22 {
+			return nil, false, false
+		}
+		if !records.ReadUint16(&recordVersion) || !records.ReadUint16LengthPrefixed(&fragment) {
+			return nil, false, true
+		}
+		message = append(message, fragment...)
+	}
+}
+
+// parseRealityClientHello parses the ClientHello handshake message.
+func parseRealityClientHello(message []byte) (*realityClientHello, bool) {
+	fragment := cryptobyte.String(message)
+	hello := new(realityClientHello)
+	var (
+		messageType  uint8
+		body         cryptobyte.String
+		version      uint16
+		cipherSuites cryptobyte.String
+		compression  cryptobyte.String
+		extensions   cryptobyte.String
+	)
+	if !fragment.ReadUint8(&messageType) || messageType != 1 || !fragment.ReadUint24LengthPrefixed(&body) {
+		return nil, false
+	}
+	hello.raw = message[:4+len(body)]
+	if !body.ReadUint16(&version) || !body.ReadBytes(&hello.random, 32) ||
+		!body.ReadUint8LengthPrefixed((*cryptobyte.String)(&hello.sessionID)) ||
+		!body.ReadUint16LengthPrefixed(&cipherSuites) || !body.ReadUint8LengthPrefixed(&compression) ||
+		!body.ReadUint16LengthPrefixed(&extensions) {
+		return nil, false
+	}
+	for !extensions.Empty() {
+		var (
+			extensionType uint16
+			extensionData cryptobyte.String
+		)
+		if !extensions.ReadUint16(&extensionType) || !extensions.ReadUint16LengthPrefixed(&extensionData) {
+			return nil, false
+		}
+		switch extensionType {
+		case realityExtensionServerName:
+			var nameList cryptobyte.String
+			if !extensionData.ReadUint16LengthPrefixed(&nameList) {
+				return nil, false
+			}
+			for !nameList.Empty() {
+				var (
+					nameType uint8
+					name     cryptobyte.String
+				)
+				if !nameList.ReadUint8(&nameType) || !nameList.ReadUint16LengthPrefixed(&name) {
+					return nil, false
+				}
+				if nameType == 0 {
+					hello.serverName = string(name)
+				}
+			}
+		case realityExtensionSupportedVersions:
+			var versions cryptobyte.String
+			if !extensionData.ReadUint8LengthPrefixed(&versions) {
+				return nil, false
+			}
+			for !versions.Empty() {
+				var supportedVersion uint16
+				if !versions.ReadUint16(&supportedVersion) {
+					return nil, false
+				}
+				if supportedVersion == 0x0304 {
+					hello.tls13 = true
+				}
+			}
+		case realityExtensionKeyShare:
+			var keyShares cryptobyte.String
+			if !extensionData.ReadUint16LengthPrefixed(&keyShares) {
+				return nil, false
+			}
+			for !keyShares.Empty() {
+				var (
+					group uint16
+					key   cryptobyte.String
+				)
+				if !keyShares.ReadUint16(&group) || !keyShares.ReadUint16LengthPrefixed(&key) {
+					return nil, false
+				}
+				if group == realityGroupX25519 && len(key) == 32 {
+					hello.keyShares = append(hello.keyShares, key)
+				}
+			}
+		}
+	}
+	return hello, true
+}
+
+// realityAuthenticate checks the client the same way as the REALITY server,
+// so that clients failing authentication can be sent to the fallback site
+// instead of the handshake target.
+func realityAuthenticate(config *reality.Config, hello *realityClientHello) bool {
+	if !hello.tls13 || len(hello.sessionID) != 32 || len(hello.keyShares) == 0 || !config.ServerNames[hello.serverName] {
+		return false
+	}
+	authKey, err := curve25519.X25519(config.PrivateKey, hello.keyShares[0])
+	if err != nil {
+		return false
+	}
+	_, err = hkdf.New(sha256.New, authKey, hello.random[:20], []byte("REALITY")).Read(authKey)
+	if err != nil {
+		return false
+	}
+	block, err := aes.NewCipher(authKey)
+	if err != nil {
+		return false
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return false
+	}
+	// the session ID is authenticated with itself zeroed in the raw message
+	raw := make([]byte, len(hello.raw))
+	copy(raw, hello.raw)
+	sessionIDOffset := 4 + 2 + 32 + 1
+	clear(raw[sessionIDOffset : sessionIDOffset+32])
+	plainText, err := aead.Open(nil, hello.random[20:], hello.sessionID, raw)
+	if err != nil {
+		return false
+	}
+	if config.MaxTimeDiff != 0 {
+		clientTime := time.Unix(int64(binary.BigEndian.Uint32(plainText[4:])), 0)
+		if math.Abs(float64(time.Since(clientTime).Milliseconds())) > float64(config.MaxTimeDiff.Milliseconds()) {
+			return false
+		}
+	}
+	var shortID [8]byte
+	copy(shortID[:], plainText[8:])
+	return config.ShortIds[shortID]
+}
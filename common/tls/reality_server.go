@@ -15,6 +15,9 @@ import (
 	"github.com/sagernet/sing-box/common/dialer"
 	"github.com/sagernet/sing-box/log"
 	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/buf"
+	"github.com/sagernet/sing/common/bufio"
 	"github.com/sagernet/sing/common/debug"
 	E "github.com/sagernet/sing/common/exceptions"
 	M "github.com/sagernet/sing/common/metadata"
@@ -25,7 +28,17 @@ import (
 var _ ServerConfigCompat = (*RealityServerConfig)(nil)
 
 type RealityServerConfig struct {
+	config              *reality.Config
+	users               map[[8]byte]string
+	targets             []*realityTarget
+	fallbackDialer      N.Dialer
+	fallbackDestination M.Socksaddr
+}
+
+// realityTarget is an additional handshake target selected by the server name of the client.
+type realityTarget struct {
 	config *reality.Config
+	users  map[[8]byte]string
 }
 
 func NewRealityServer(ctx context.Context, logger log.Logger, options option.InboundTLSOptions) (*RealityServerConfig, error) {
@@ -76,10 +89,58 @@ func NewRealityServer(ctx context.Context, logger log.Logger, options option.Inb
 
 	tlsConfig.SessionTicketsDisabled = true
 	tlsConfig.Type = N.NetworkTCP
-	tlsConfig.Dest = options.Reality.Handshake.ServerOptions.Build().String()
 
-	tlsConfig.ServerNames = map[string]bool{options.ServerName: true}
-	privateKey, err := base64.RawURLEncoding.DecodeString(options.Reality.PrivateKey)
+	if debug.Enabled {
+		tlsConfig.Show = true
+	}
+
+	router := adapter.RouterFromContext(ctx)
+	defaultTarget, err := newRealityTarget(router, &tlsConfig, option.InboundRealityTargetOptions{
+		ServerName:        []string{options.ServerName},
+		Handshake:         options.Reality.Handshake,
+		PrivateKey:        options.Reality.PrivateKey,
+		ShortID:           options.Reality.ShortID,
+		Users:             options.Reality.Users,
+		MaxTimeDifference: options.Reality.MaxTimeDifference,
+	})
+	if err != nil {
+		return nil, err
+	}
+	config := &RealityServerConfig{
+		config: defaultTarget.config,
+		users:  defaultTarget.users,
+	}
+	for i, targetOptions := range options.Reality.Targets {
+		if len(targetOptions.ServerName) == 0 {
+			return nil, E.New("missing server_name for targets[", i, "]")
+		}
+		target, err := newRealityTarget(router, &tlsConfig, targetOptions)
+		if err != nil {
+			return nil, E.Cause(err, "targets[", i, "]")
+		}
+		config.targets = append(config.targets, target)
+	}
+	if options.Reality.Fallback != nil {
+		config.fallbackDestination = options.Reality.Fallback.ServerOptions.Build()
+		if !config.fallbackDestination.IsValid() {
+			return nil, E.New("invalid fallback server address: ", config.fallbackDestination)
+		}
+		config.fallbackDialer, err = dialer.New(router, options.Reality.Fallback.DialerOptions)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return config, nil
+}
+
+func newRealityTarget(router adapter.Router, baseConfig *reality.Config, options option.InboundRealityTargetOptions) (*realityTarget, error) {
+	tlsConfig := baseConfig.Clone()
+	tlsConfig.Dest = options.Handshake.ServerOptions.Build().String()
+	tlsConfig.ServerNames = make(map[string]bool)
+	for _, serverName := range options.ServerName {
+		tlsConfig.ServerNames[serverName] = true
+	}
+	privateKey, err := base64.RawURLEncoding.DecodeString(options.PrivateKey)
 	if err != nil {
 		return nil, E.Cause(err, "decode private key")
 	}
@@ -87,34 +148,49 @@ func NewRealityServer(ctx context.Context, logger log.Logger, options option.Inb
 		return nil, E.New("invalid private key")
 	}
 	tlsConfig.PrivateKey = privateKey
-	tlsConfig.MaxTimeDiff = time.Duration(options.Reality.MaxTimeDifference)
+	tlsConfig.MaxTimeDiff = time.Duration(options.MaxTimeDifference)
 
 	tlsConfig.ShortIds = make(map[[8]byte]bool)
-	for i, shortIDString := range options.Reality.ShortID {
-		var shortID [8]byte
-		decodedLen, err := hex.Decode(shortID[:], []byte(shortIDString))
+	for i, shortIDString := range options.ShortID {
+		shortID, err := parseRealityShortID(shortIDString)
+		if err != nil {
+			return nil, E.Cause(err, "short_id[", i, "]")
+		}
+		tlsConfig.ShortIds[shortID] = true
+	}
+	users := make(map[[8]byte]string)
+	for i, user := range options.Users {
+		shortID, err := parseRealityShortID(user.ShortID)
 		if err != nil {
-			return nil, E.Cause(err, "decode short_id[", i, "]: ", shortIDString)
+			return nil, E.Cause(err, "users[", i, "]")
 		}
-		if decodedLen > 8 {
-			return nil, E.New("invalid short_id[", i, "]: ", shortIDString)
+		if _, loaded := users[shortID]; loaded {
+			return nil, E.New("users[", i, "]: duplicate short_id: ", user.ShortID)
 		}
+		users[shortID] = user.Name
 		tlsConfig.ShortIds[shortID] = true
 	}
 
-	handshakeDialer, err := dialer.New(adapter.RouterFromContext(ctx), options.Reality.Handshake.DialerOptions)
+	handshakeDialer, err := dialer.New(router, options.Handshake.DialerOptions)
 	if err != nil {
 		return nil, err
 	}
 	tlsConfig.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
 		return handshakeDialer.DialContext(ctx, network, M.ParseSocksaddr(addr))
 	}
+	return &realityTarget{tlsConfig, users}, nil
+}
 
-	if debug.Enabled {
-		tlsConfig.Show = true
+func parseRealityShortID(shortIDString string) ([8]byte, error) {
+	var shortID [8]byte
+	decodedLen, err := hex.Decode(shortID[:], []byte(shortIDString))
+	if err != nil {
+		return shortID, E.Cause(err, "decode short_id: ", shortIDString)
 	}
-
-	return &RealityServerConfig{&tlsConfig}, nil
+	if decodedLen > 8 {
+		return shortID, E.New("invalid short_id: ", shortIDString)
+	}
+	return shortID, nil
 }
 
 func (c *RealityServerConfig) ServerName() string {
@@ -131,6 +207,9 @@ func (c *RealityServerConfig) NextProtos() []string {
 
 func (c *RealityServerConfig) SetNextProtos(nextProto []string) {
 	c.config.NextProtos = nextProto
+	for _, target := range c.targets {
+		target.config.NextProtos = nextProto
+	}
 }
 
 func (c *RealityServerConfig) Config() (*tls.Config, error) {
@@ -154,16 +233,78 @@ func (c *RealityServerConfig) Server(conn net.Conn) (Conn, error) {
 }
 
 func (c *RealityServerConfig) ServerHandshake(ctx context.Context, conn net.Conn) (Conn, error) {
-	tlsConn, err := reality.Server(ctx, conn, c.config)
+	config, users := c.config, c.users
+	if len(c.targets) > 0 || c.fallbackDialer != nil {
+		buffer := buf.NewPacket()
+		message := readRealityClientHello(conn, buffer)
+		if !buffer.IsEmpty() {
+			conn = bufio.NewCachedConn(conn, buffer)
+		} else {
+			buffer.Release()
+		}
+		hello, parsed := parseRealityClientHello(message)
+		var serverName string
+		if parsed {
+			serverName = hello.serverName
+		}
+		if !config.ServerNames[serverName] {
+			target := common.Find(c.targets, func(it *realityTarget) bool {
+				return it.config.ServerNames[serverName]
+			})
+			if target != nil {
+				config, users = target.config, target.users
+			} else if c.fallbackDialer != nil {
+				return c.fallback(ctx, conn)
+			}
+		}
+		if c.fallbackDialer != nil {
+			if !parsed || !realityAuthenticate(config, hello) {
+				return c.fallback(ctx, conn)
+			}
+		}
+	}
+	tlsConn, err := reality.Server(ctx, conn, config)
 	if err != nil {
 		return nil, err
 	}
-	return &realityConnWrapper{Conn: tlsConn}, nil
+	return &realityConnWrapper{Conn: tlsConn, user: users[tlsConn.ClientShortId]}, nil
+}
+
+// fallback forwards the connection to the fallback server, and returns a closed error after it is done
+// since there is no connection left to use.
+func (c *RealityServerConfig) fallback(ctx context.Context, conn net.Conn) (Conn, error) {
+	err := c.fallbackConnection(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	return nil, E.Cause(net.ErrClosed, "REALITY: processed fallback connection to ", c.fallbackDestination)
+}
+
+// fallbackConnection forwards connections matching no target or failing authentication to the fallback server.
+func (c *RealityServerConfig) fallbackConnection(ctx context.Context, conn net.Conn) error {
+	// the handshake timeout does not apply to the forwarded connection
+	ctx = context.WithoutCancel(ctx)
+	remoteConn, err := c.fallbackDialer.DialContext(ctx, N.NetworkTCP, c.fallbackDestination)
+	if err != nil {
+		conn.Close()
+		return E.Cause(err, "REALITY: dial fallback")
+	}
+	err = bufio.CopyConn(ctx, conn, remoteConn)
+	if err != nil && !E.IsClosedOrCanceled(err) {
+		return E.Cause(err, "REALITY: fallback to ", c.fallbackDestination)
+	}
+	return nil
 }
 
 func (c *RealityServerConfig) Clone() Config {
 	return &RealityServerConfig{
 		config: c.config.Clone(),
+		users:  c.users,
+		targets: common.Map(c.targets, func(it *realityTarget) *realityTarget {
+			return &realityTarget{it.config.Clone(), it.users}
+		}),
+		fallbackDialer:      c.fallbackDialer,
+		fallbackDestination: c.fallbackDestination,
 	}
 }
 
@@ -171,6 +312,11 @@ var _ Conn = (*realityConnWrapper)(nil)
 
 type realityConnWrapper struct {
 	*reality.Conn
+	user string
+}
+
+func (c *realityConnWrapper) User() string {
+	return c.user
 }
 
 func (c *realityConnWrapper) ConnectionState() ConnectionState {
@@ -0,0 +1,218 @@
+//go:build with_reality_server && with_utls
+
+package tls_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	sTLS "github.com/sagernet/sing-box/common/tls"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	M "github.com/sagernet/sing/common/metadata"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/curve25519"
+)
+
+func newRealityKeyPair(t *testing.T) (privateKey string, publicKey string) {
+	var key [32]byte
+	_, err := rand.Read(key[:])
+	require.NoError(t, err)
+	public, err := curve25519.X25519(key[:], curve25519.Basepoint)
+	require.NoError(t, err)
+	return base64.RawURLEncoding.EncodeToString(key[:]), base64.RawURLEncoding.EncodeToString(public)
+}
+
+// startHandshakeServer starts a TLS 1.3 server to borrow handshakes from.
+func startHandshakeServer(t *testing.T) M.Socksaddr {
+	certificate, err := sTLS.GenerateCertificate(time.Now, "example.com")
+	require.NoError(t, err)
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{*certificate},
+		MinVersion:   tls.VersionTLS13,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				io.Copy(io.Discard, conn)
+			}()
+		}
+	}()
+	return M.SocksaddrFromNet(listener.Addr())
+}
+
+// startFallbackServer counts connections forwarded to the fallback site.
+func startFallbackServer(t *testing.T) (M.Socksaddr, chan struct{}) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+	accepted := make(chan struct{}, 8)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			accepted <- struct{}{}
+			conn.Close()
+		}
+	}()
+	return M.SocksaddrFromNet(listener.Addr()), accepted
+}
+
+func requireFallback(t *testing.T, accepted chan struct{}) {
+	select {
+	case <-accepted:
+	case <-time.After(5 * time.Second):
+		t.Fatal("connection not forwarded to fallback")
+	}
+}
+
+// fragmentConn splits the first TLS record written, the ClientHello, into records of fragmentSize bytes.
+type fragmentConn struct {
+	net.Conn
+	fragmentSize int
+	fragmented   bool
+}
+
+func (c *fragmentConn) Write(b []byte) (int, error) {
+	if c.fragmented || len(b) < 5 || b[0] != 22 {
+		return c.Conn.Write(b)
+	}
+	c.fragmented = true
+	recordLength := 5 + int(binary.BigEndian.Uint16(b[3:5]))
+	for payload := b[5:recordLength]; len(payload) > 0; {
+		fragment := payload[:min(c.fragmentSize, len(payload))]
+		payload = payload[len(fragment):]
+		record := append([]byte{b[0], b[1], b[2], 0, 0}, fragment...)
+		binary.BigEndian.PutUint16(record[3:5], uint16(len(fragment)))
+		_, err := c.Conn.Write(record)
+		if err != nil {
+			return 0, err
+		}
+	}
+	if recordLength < len(b) {
+		_, err := c.Conn.Write(b[recordLength:])
+		if err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+func realityHandshake(t *testing.T, server sTLS.ServerConfig, serverName string, publicKey string, shortID string) (string, error) {
+	return realityHandshakeFragmented(t, server, serverName, publicKey, shortID, 0)
+}
+
+func realityHandshakeFragmented(t *testing.T, server sTLS.ServerConfig, serverName string, publicKey string, shortID string, fragmentSize int) (string, error) {
+	client, err := sTLS.NewClient(context.Background(), serverName, option.OutboundTLSOptions{
+		Enabled:    true,
+		ServerName: serverName,
+		UTLS: &option.OutboundUTLSOptions{
+			Enabled:     true,
+			Fingerprint: "chrome",
+		},
+		Reality: &option.OutboundRealityOptions{
+			Enabled:   true,
+			PublicKey: publicKey,
+			ShortID:   shortID,
+		},
+	})
+	require.NoError(t, err)
+	serverConn, clientConn := net.Pipe()
+	serverDone := make(chan string, 1)
+	go func() {
+		conn, sErr := sTLS.ServerHandshake(context.Background(), serverConn, server)
+		if sErr != nil {
+			serverConn.Close()
+			serverDone <- ""
+			return
+		}
+		serverDone <- sTLS.UserFromConn(conn)
+		conn.Close()
+	}()
+	var conn net.Conn = clientConn
+	if fragmentSize > 0 {
+		conn = &fragmentConn{Conn: clientConn, fragmentSize: fragmentSize}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err = sTLS.ClientHandshake(ctx, conn, client)
+	clientConn.Close()
+	return <-serverDone, err
+}
+
+func TestRealityServer(t *testing.T) {
+	t.Parallel()
+	handshake := startHandshakeServer(t)
+	fallback, fallbackAccepted := startFallbackServer(t)
+	privateKey, publicKey := newRealityKeyPair(t)
+	targetPrivateKey, targetPublicKey := newRealityKeyPair(t)
+	handshakeOptions := option.InboundRealityHandshakeOptions{
+		ServerOptions: option.ServerOptions{Server: handshake.AddrString(), ServerPort: handshake.Port},
+	}
+	server, err := sTLS.NewServer(context.Background(), log.NewNOPFactory().Logger(), option.InboundTLSOptions{
+		Enabled:    true,
+		ServerName: "a.example.com",
+		Reality: &option.InboundRealityOptions{
+			Enabled:    true,
+			Handshake:  handshakeOptions,
+			PrivateKey: privateKey,
+			ShortID:    []string{"0123"},
+			Users:      []option.InboundRealityUser{{Name: "alice", ShortID: "aaaa"}},
+			Targets: []option.InboundRealityTargetOptions{{
+				ServerName: []string{"b.example.com"},
+				Handshake:  handshakeOptions,
+				PrivateKey: targetPrivateKey,
+				Users:      []option.InboundRealityUser{{Name: "bob", ShortID: "bbbb"}},
+			}},
+			Fallback: &option.InboundRealityHandshakeOptions{
+				ServerOptions: option.ServerOptions{Server: fallback.AddrString(), ServerPort: fallback.Port},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	user, err := realityHandshake(t, server, "a.example.com", publicKey, "0123")
+	require.NoError(t, err)
+	require.Empty(t, user)
+	user, err = realityHandshake(t, server, "a.example.com", publicKey, "aaaa")
+	require.NoError(t, err)
+	require.Equal(t, "alice", user)
+	user, err = realityHandshake(t, server, "b.example.com", targetPublicKey, "bbbb")
+	require.NoError(t, err)
+	require.Equal(t, "bob", user)
+	// ClientHello split over several records
+	user, err = realityHandshakeFragmented(t, server, "a.example.com", publicKey, "aaaa", 100)
+	require.NoError(t, err)
+	require.Equal(t, "alice", user)
+	require.Empty(t, fallbackAccepted)
+
+	// short IDs are bound to their target
+	_, err = realityHandshake(t, server, "b.example.com", targetPublicKey, "aaaa")
+	require.Error(t, err)
+	requireFallback(t, fallbackAccepted)
+	// wrong key
+	_, err = realityHandshake(t, server, "a.example.com", targetPublicKey, "0123")
+	require.Error(t, err)
+	requireFallback(t, fallbackAccepted)
+	// unknown server name
+	_, err = realityHandshake(t, server, "c.example.com", publicKey, "0123")
+	require.Error(t, err)
+	requireFallback(t, fallbackAccepted)
+}
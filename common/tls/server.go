@@ -9,6 +9,7 @@ import (
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/log"
 	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
 	aTLS "github.com/sagernet/sing/common/tls"
 )
 
@@ -40,3 +41,17 @@ func ServerHandshake(ctx context.Context, conn net.Conn, config ServerConfig) (C
 	}
 	return tlsConn, nil
 }
+
+// UserConn is implemented by server connections that identify the user during the handshake,
+// such as Reality connections with short IDs bound to users.
+type UserConn interface {
+	User() string
+}
+
+func UserFromConn(conn net.Conn) string {
+	userConn, isUserConn := common.Cast[UserConn](conn)
+	if !isUserConn {
+		return ""
+	}
+	return userConn.User()
+}
@@ -0,0 +1,105 @@
+package udpobfs
+
+import (
+	"context"
+	"net"
+
+	"github.com/sagernet/sing/common/buf"
+	"github.com/sagernet/sing/common/bufio"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+)
+
+var _ N.NetPacketConn = (*PacketConn)(nil)
+
+// PacketConn obfuscates packets written to and deobfuscates packets read from the upstream,
+// invalid packets are dropped.
+type PacketConn struct {
+	N.NetPacketConn
+	obfuscator *Obfuscator
+}
+
+func NewPacketConn(conn net.PacketConn, obfuscator *Obfuscator) *PacketConn {
+	return &PacketConn{bufio.NewPacketConn(conn), obfuscator}
+}
+
+func (c *PacketConn) ReadFrom(p []byte) (n int, addr net.Addr, err error) {
+	buffer := buf.NewPacket()
+	defer buffer.Release()
+	for {
+		buffer.Reset()
+		n, addr, err = c.NetPacketConn.ReadFrom(buffer.FreeBytes())
+		if err != nil {
+			return
+		}
+		payload, decodeErr := c.obfuscator.Decode(buffer.To(n))
+		if decodeErr != nil {
+			continue
+		}
+		return copy(p, payload), addr, nil
+	}
+}
+
+func (c *PacketConn) WriteTo(p []byte, addr net.Addr) (n int, err error) {
+	_, err = c.NetPacketConn.WriteTo(c.obfuscator.Encode(p), addr)
+	if err != nil {
+		return
+	}
+	return len(p), nil
+}
+
+func (c *PacketConn) ReadPacket(buffer *buf.Buffer) (destination M.Socksaddr, err error) {
+	for {
+		destination, err = c.NetPacketConn.ReadPacket(buffer)
+		if err != nil {
+			return
+		}
+		packet := buffer.Bytes()
+		payload, decodeErr := c.obfuscator.Decode(packet)
+		if decodeErr != nil {
+			buffer.Truncate(0)
+			continue
+		}
+		buffer.Advance(cap(packet) - cap(payload))
+		buffer.Truncate(len(payload))
+		return destination, nil
+	}
+}
+
+func (c *PacketConn) WritePacket(buffer *buf.Buffer, destination M.Socksaddr) error {
+	defer buffer.Release()
+	_, err := c.NetPacketConn.WriteTo(c.obfuscator.Encode(buffer.Bytes()), destination.UDPAddr())
+	return err
+}
+
+func (c *PacketConn) Upstream() any {
+	return c.NetPacketConn
+}
+
+var _ N.Dialer = (*Dialer)(nil)
+
+// Dialer obfuscates UDP connections of QUIC clients.
+type Dialer struct {
+	N.Dialer
+	obfuscator *Obfuscator
+}
+
+func NewDialer(dialer N.Dialer, obfuscator *Obfuscator) *Dialer {
+	return &Dialer{dialer, obfuscator}
+}
+
+func (d *Dialer) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
+	conn, err := d.Dialer.DialContext(ctx, network, destination)
+	if err != nil || N.NetworkName(network) != N.NetworkUDP {
+		return conn, err
+	}
+	return bufio.NewBindPacketConn(NewPacketConn(bufio.NewUnbindPacketConn(conn), d.obfuscator), conn.RemoteAddr()), nil
+}
+
+func (d *Dialer) ListenPacket(ctx context.Context, destination M.Socksaddr) (net.PacketConn, error) {
+	conn, err := d.Dialer.ListenPacket(ctx, destination)
+	if err != nil {
+		return nil, err
+	}
+	return NewPacketConn(conn, d.obfuscator), nil
+}
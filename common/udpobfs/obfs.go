@@ -0,0 +1,193 @@
+package udpobfs
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	mRand "math/rand"
+	"sync/atomic"
+
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+const (
+	HeaderSRTP        = "srtp"
+	HeaderUTP         = "utp"
+	HeaderWechatVideo = "wechat-video"
+	HeaderDTLS        = "dtls"
+	HeaderWireGuard   = "wireguard"
+)
+
+const (
+	salamanderSaltLength = 8
+	paddingLengthSize    = 2
+	// maxOverhead keeps QUIC packets of the initial 1280 bytes within a 1500 bytes MTU
+	// over IPv6, since QUIC sets DF and is not aware of the obfuscation.
+	maxOverhead = 1500 - 40 - 8 - 1280
+)
+
+var errInvalidPacket = E.New("invalid obfuscated packet")
+
+// Obfuscator encodes packets with the enabled layers: padding, salamander and fake header,
+// and decodes them in the reverse order.
+type Obfuscator struct {
+	password   []byte
+	maxPadding int
+	header     string
+	headerSize int
+	sequence   atomic.Uint32
+}
+
+func NewObfuscator(options option.UDPObfsOptions) (*Obfuscator, error) {
+	obfuscator := &Obfuscator{
+		password:   []byte(options.Password),
+		maxPadding: options.MaxPadding,
+		header:     options.Header,
+	}
+	if options.MaxPadding < 0 {
+		return nil, E.New("invalid max_padding: ", options.MaxPadding)
+	}
+	switch options.Header {
+	case "":
+	case HeaderSRTP, HeaderUTP, HeaderWireGuard:
+		obfuscator.headerSize = 4
+	case HeaderWechatVideo, HeaderDTLS:
+		obfuscator.headerSize = 13
+	default:
+		return nil, E.New("unknown udp obfs header: ", options.Header)
+	}
+	if len(obfuscator.password) == 0 && obfuscator.maxPadding == 0 && obfuscator.headerSize == 0 {
+		return nil, E.New("udp obfs requires password, max_padding or header")
+	}
+	if overhead := obfuscator.Overhead(); overhead > maxOverhead {
+		return nil, E.New("max_padding must be at most ", maxOverhead-(overhead-obfuscator.maxPadding), " with the enabled layers")
+	}
+	obfuscator.sequence.Store(mRand.Uint32())
+	return obfuscator, nil
+}
+
+// Overhead returns the max length added to a packet.
+func (o *Obfuscator) Overhead() int {
+	overhead := o.headerSize
+	if len(o.password) > 0 {
+		overhead += salamanderSaltLength
+	}
+	if o.maxPadding > 0 {
+		overhead += paddingLengthSize + o.maxPadding
+	}
+	return overhead
+}
+
+// Encode returns the obfuscated packet of payload in a new slice.
+func (o *Obfuscator) Encode(payload []byte) []byte {
+	var paddingLength int
+	if o.maxPadding > 0 {
+		paddingLength = mRand.Intn(o.maxPadding + 1)
+	}
+	packet := make([]byte, o.Overhead()-o.maxPadding+paddingLength+len(payload))
+	o.writeHeader(packet)
+	body := packet[o.headerSize:]
+	var salt []byte
+	if len(o.password) > 0 {
+		salt = body[:salamanderSaltLength]
+		rand.Read(salt)
+		body = body[salamanderSaltLength:]
+	}
+	if o.maxPadding > 0 {
+		binary.BigEndian.PutUint16(body, uint16(len(payload)))
+		copy(body[paddingLengthSize:], payload)
+		rand.Read(body[paddingLengthSize+len(payload):])
+	} else {
+		copy(body, payload)
+	}
+	if salt != nil {
+		o.xor(salt, body)
+	}
+	return packet
+}
+
+// Decode returns the payload of packet, packet is modified in place.
+func (o *Obfuscator) Decode(packet []byte) ([]byte, error) {
+	if len(packet) < o.headerSize || !o.checkHeader(packet) {
+		return nil, errInvalidPacket
+	}
+	body := packet[o.headerSize:]
+	if len(o.password) > 0 {
+		if len(body) < salamanderSaltLength {
+			return nil, errInvalidPacket
+		}
+		salt := body[:salamanderSaltLength]
+		body = body[salamanderSaltLength:]
+		o.xor(salt, body)
+	}
+	if o.maxPadding > 0 {
+		if len(body) < paddingLengthSize {
+			return nil, errInvalidPacket
+		}
+		length := int(binary.BigEndian.Uint16(body))
+		body = body[paddingLengthSize:]
+		if length > len(body) {
+			return nil, errInvalidPacket
+		}
+		body = body[:length]
+	}
+	return body, nil
+}
+
+func (o *Obfuscator) xor(salt []byte, data []byte) {
+	key := blake2b.Sum256(append(append([]byte(nil), o.password...), salt...))
+	for i := range data {
+		data[i] ^= key[i%blake2b.Size256]
+	}
+}
+
+func (o *Obfuscator) writeHeader(packet []byte) {
+	header := packet[:o.headerSize]
+	switch o.header {
+	case HeaderSRTP:
+		header[0] = 0x80
+		header[1] = 0x60
+		binary.BigEndian.PutUint16(header[2:], uint16(o.sequence.Add(1)))
+	case HeaderUTP:
+		rand.Read(header[:2])
+		header[2] = 0x01
+		header[3] = 0x00
+	case HeaderWireGuard:
+		header[0] = 0x04
+		header[1], header[2], header[3] = 0, 0, 0
+	case HeaderWechatVideo:
+		header[0] = 0xa1
+		header[1] = 0x08
+		binary.BigEndian.PutUint32(header[2:], o.sequence.Add(1))
+		copy(header[6:], []byte{0x00, 0x10, 0x11, 0x18, 0x30, 0x22, 0x30})
+	case HeaderDTLS:
+		header[0] = 0x17
+		header[1] = 0xfe
+		header[2] = 0xfd
+		// epoch
+		header[3], header[4] = 0, 1
+		sequence := o.sequence.Add(1)
+		header[5], header[6] = 0, 0
+		binary.BigEndian.PutUint32(header[7:], sequence)
+		binary.BigEndian.PutUint16(header[11:], uint16(len(packet)-o.headerSize))
+	}
+}
+
+func (o *Obfuscator) checkHeader(packet []byte) bool {
+	switch o.header {
+	case HeaderSRTP:
+		return packet[0] == 0x80
+	case HeaderUTP:
+		return packet[2] == 0x01
+	case HeaderWireGuard:
+		return packet[0] == 0x04
+	case HeaderWechatVideo:
+		return packet[0] == 0xa1 && packet[1] == 0x08
+	case HeaderDTLS:
+		return packet[0] == 0x17 && packet[1] == 0xfe && packet[2] == 0xfd
+	default:
+		return true
+	}
+}
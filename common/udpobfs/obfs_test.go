@@ -0,0 +1,78 @@
+package udpobfs
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/option"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestObfuscator(t *testing.T) {
+	t.Parallel()
+	for _, options := range []option.UDPObfsOptions{
+		{Password: "password"},
+		{MaxPadding: 64},
+		{Header: HeaderSRTP},
+		{Header: HeaderDTLS},
+		{Password: "password", MaxPadding: 64, Header: HeaderWechatVideo},
+	} {
+		obfuscator, err := NewObfuscator(options)
+		require.NoError(t, err)
+		payload := []byte("hello quic")
+		packet := obfuscator.Encode(payload)
+		require.LessOrEqual(t, len(packet), len(payload)+obfuscator.Overhead())
+		if options.Password != "" {
+			require.NotContains(t, string(packet), string(payload))
+		}
+		decoded, err := obfuscator.Decode(packet)
+		require.NoError(t, err, options)
+		require.Equal(t, payload, decoded)
+	}
+	_, err := NewObfuscator(option.UDPObfsOptions{})
+	require.Error(t, err)
+	_, err = NewObfuscator(option.UDPObfsOptions{Header: "unknown"})
+	require.Error(t, err)
+}
+
+func TestObfuscatorOverheadFitsMTU(t *testing.T) {
+	t.Parallel()
+	options := option.UDPObfsOptions{Password: "password", MaxPadding: 149, Header: HeaderDTLS}
+	obfuscator, err := NewObfuscator(options)
+	require.NoError(t, err)
+	require.LessOrEqual(t, 1280+obfuscator.Overhead(), 1500-40-8)
+	options.MaxPadding++
+	_, err = NewObfuscator(options)
+	require.Error(t, err)
+	_, err = NewObfuscator(option.UDPObfsOptions{MaxPadding: 170})
+	require.NoError(t, err)
+	_, err = NewObfuscator(option.UDPObfsOptions{MaxPadding: 171})
+	require.Error(t, err)
+}
+
+func TestPacketConnDropsInvalidPackets(t *testing.T) {
+	t.Parallel()
+	obfuscator, err := NewObfuscator(option.UDPObfsOptions{Header: HeaderDTLS})
+	require.NoError(t, err)
+	serverConn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := NewPacketConn(serverConn, obfuscator)
+	defer server.Close()
+	clientConn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	client := NewPacketConn(clientConn, obfuscator)
+	defer client.Close()
+
+	_, err = clientConn.WriteTo([]byte("plain"), serverConn.LocalAddr())
+	require.NoError(t, err)
+	_, err = client.WriteTo([]byte("obfuscated"), serverConn.LocalAddr())
+	require.NoError(t, err)
+
+	buffer := make([]byte, 64)
+	require.NoError(t, server.SetReadDeadline(time.Now().Add(5*time.Second)))
+	n, _, err := server.ReadFrom(buffer)
+	require.NoError(t, err)
+	require.Equal(t, "obfuscated", string(buffer[:n]))
+}
@@ -48,17 +48,18 @@ func (s *HistoryStorage) DeleteURLTestHistory(tag string) {
 	s.access.Lock()
 	delete(s.delayHistory, tag)
 	s.access.Unlock()
-	s.notifyUpdated()
+	s.NotifyUpdated()
 }
 
 func (s *HistoryStorage) StoreURLTestHistory(tag string, history *History) {
 	s.access.Lock()
 	s.delayHistory[tag] = history
 	s.access.Unlock()
-	s.notifyUpdated()
+	s.NotifyUpdated()
 }
 
-func (s *HistoryStorage) notifyUpdated() {
+// NotifyUpdated triggers the update hook, also used for group changes that are not stored in the history.
+func (s *HistoryStorage) NotifyUpdated() {
 	updateHook := s.updateHook
 	if updateHook != nil {
 		updateHook.Update(1)
@@ -0,0 +1,6 @@
+package constant
+
+const (
+	FrontingRotationRoundRobin = "round_robin"
+	FrontingRotationRandom     = "random"
+)
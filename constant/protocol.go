@@ -6,4 +6,6 @@ const (
 	ProtocolQUIC = "quic"
 	ProtocolDNS  = "dns"
 	ProtocolSTUN = "stun"
+	ProtocolSSH  = "ssh"
+	ProtocolRDP  = "rdp"
 )
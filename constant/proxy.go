@@ -23,6 +23,7 @@ const (
 	TypeVLESS         = "vless"
 	TypeTUIC          = "tuic"
 	TypeHysteria2     = "hysteria2"
+	TypeDemux         = "demux"
 	TypeCustom        = "custom"
 	TypeXray          = "xray"
 	TypeInvalidConfig = "invalid"
@@ -31,6 +32,7 @@ const (
 const (
 	TypeSelector = "selector"
 	TypeURLTest  = "urltest"
+	TypeChain    = "chain"
 )
 
 func ProxyDisplayName(proxyType string) string {
@@ -75,6 +77,8 @@ func ProxyDisplayName(proxyType string) string {
 		return "Selector"
 	case TypeURLTest:
 		return "URLTest"
+	case TypeChain:
+		return "Relay"
 	default:
 		return "Unknown"
 	}
@@ -17,4 +17,6 @@ const (
 	StopTimeout                = 5 * time.Second
 	FatalStopTimeout           = 10 * time.Second
 	FakeIPMetadataSaveInterval = 10 * time.Second
+	UserUsageSaveInterval      = time.Minute
+	WireGuardHandshakeTimeout  = 30 * time.Second
 )
@@ -0,0 +1,97 @@
+package adminapi
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+var _ adapter.Service = (*Server)(nil)
+
+type Server struct {
+	ctx        context.Context
+	router     adapter.Router
+	logger     log.Logger
+	httpServer *http.Server
+}
+
+func NewServer(ctx context.Context, router adapter.Router, logger log.Logger, options option.AdminAPIOptions) (*Server, error) {
+	if options.Listen == "" {
+		return nil, E.New("missing listen address")
+	}
+	server := &Server{
+		ctx:    ctx,
+		router: router,
+		logger: logger,
+	}
+	chiRouter := chi.NewRouter()
+	chiRouter.Group(func(r chi.Router) {
+		r.Use(authentication(options.Secret))
+		r.Mount("/inbounds", inboundRouter(router))
+	})
+	server.httpServer = &http.Server{
+		Addr:    options.Listen,
+		Handler: chiRouter,
+	}
+	return server, nil
+}
+
+func (s *Server) Start() error {
+	listener, err := net.Listen("tcp", s.httpServer.Addr)
+	if err != nil {
+		return E.Cause(err, "admin api listen error")
+	}
+	s.logger.Info("admin api listening at ", listener.Addr())
+	go func() {
+		err = s.httpServer.Serve(listener)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.logger.Error("admin api serve error: ", err)
+		}
+	}()
+	return nil
+}
+
+func (s *Server) Close() error {
+	return common.Close(common.PtrOrNil(s.httpServer))
+}
+
+func authentication(serverSecret string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if serverSecret != "" {
+				bearer, token, found := strings.Cut(r.Header.Get("Authorization"), " ")
+				if bearer != "Bearer" || !found || subtle.ConstantTimeCompare([]byte(token), []byte(serverSecret)) != 1 {
+					render.Status(r, http.StatusUnauthorized)
+					render.JSON(w, r, newError("Unauthorized"))
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+type httpError struct {
+	Message string `json:"message"`
+}
+
+func newError(message string) *httpError {
+	return &httpError{Message: message}
+}
+
+func renderError(w http.ResponseWriter, r *http.Request, status int, err error) {
+	render.Status(r, status)
+	render.JSON(w, r, newError(err.Error()))
+}
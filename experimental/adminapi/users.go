@@ -0,0 +1,154 @@
+package adminapi
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/sagernet/sing-box/adapter"
+	E "github.com/sagernet/sing/common/exceptions"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+func inboundRouter(router adapter.Router) http.Handler {
+	r := chi.NewRouter()
+	r.Get("/", listInbounds(router))
+	r.Route("/{tag}/users", func(r chi.Router) {
+		r.Get("/", listUsers(router))
+		r.Post("/", addUsers(router))
+		r.Delete("/", removeUsers(router))
+		r.Delete("/{name}", removeUser(router))
+	})
+	r.Get("/{tag}/relay", relayStatus(router))
+	return r
+}
+
+type inboundInfo struct {
+	Tag   string `json:"tag"`
+	Type  string `json:"type"`
+	Users int    `json:"users"`
+}
+
+func listInbounds(router adapter.Router) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		inbounds := make([]inboundInfo, 0)
+		for _, inbound := range router.Inbounds() {
+			userManager, isUserManager := inbound.(adapter.UserManager)
+			if !isUserManager {
+				continue
+			}
+			inbounds = append(inbounds, inboundInfo{
+				Tag:   inbound.Tag(),
+				Type:  inbound.Type(),
+				Users: len(userManager.Users()),
+			})
+		}
+		render.JSON(w, r, render.M{
+			"inbounds": inbounds,
+		})
+	}
+}
+
+func loadUserManager(router adapter.Router, w http.ResponseWriter, r *http.Request) (adapter.UserManager, bool) {
+	tag := chi.URLParam(r, "tag")
+	inbound, loaded := router.Inbound(tag)
+	if !loaded {
+		renderError(w, r, http.StatusNotFound, E.New("inbound not found: ", tag))
+		return nil, false
+	}
+	userManager, isUserManager := inbound.(adapter.UserManager)
+	if !isUserManager {
+		renderError(w, r, http.StatusBadRequest, E.New("inbound/", inbound.Type(), "[", tag, "] does not support user management"))
+		return nil, false
+	}
+	return userManager, true
+}
+
+func listUsers(router adapter.Router) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userManager, loaded := loadUserManager(router, w, r)
+		if !loaded {
+			return
+		}
+		render.JSON(w, r, render.M{
+			"users": userManager.Users(),
+		})
+	}
+}
+
+func addUsers(router adapter.Router) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userManager, loaded := loadUserManager(router, w, r)
+		if !loaded {
+			return
+		}
+		content, err := io.ReadAll(r.Body)
+		if err != nil {
+			renderError(w, r, http.StatusBadRequest, err)
+			return
+		}
+		err = userManager.AddUsers(content)
+		if err != nil {
+			renderError(w, r, http.StatusBadRequest, err)
+			return
+		}
+		render.NoContent(w, r)
+	}
+}
+
+func removeUsers(router adapter.Router) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userManager, loaded := loadUserManager(router, w, r)
+		if !loaded {
+			return
+		}
+		var request struct {
+			Names   []string `json:"names"`
+			Indexes []int    `json:"indexes"`
+		}
+		err := render.DecodeJSON(r.Body, &request)
+		if err != nil {
+			renderError(w, r, http.StatusBadRequest, err)
+			return
+		}
+		err = userManager.RemoveUsers(request.Names, request.Indexes)
+		if err != nil {
+			renderError(w, r, http.StatusNotFound, err)
+			return
+		}
+		render.NoContent(w, r)
+	}
+}
+
+func removeUser(router adapter.Router) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userManager, loaded := loadUserManager(router, w, r)
+		if !loaded {
+			return
+		}
+		err := userManager.RemoveUsers([]string{chi.URLParam(r, "name")}, nil)
+		if err != nil {
+			renderError(w, r, http.StatusNotFound, err)
+			return
+		}
+		render.NoContent(w, r)
+	}
+}
+
+func relayStatus(router adapter.Router) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userManager, loaded := loadUserManager(router, w, r)
+		if !loaded {
+			return
+		}
+		relayManager, isRelayManager := userManager.(adapter.RelayManager)
+		if !isRelayManager {
+			renderError(w, r, http.StatusBadRequest, E.New("inbound/", userManager.Type(), "[", userManager.Tag(), "] is not a relay"))
+			return
+		}
+		render.JSON(w, r, render.M{
+			"destinations": relayManager.RelayStatus(),
+		})
+	}
+}
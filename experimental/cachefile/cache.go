@@ -23,12 +23,14 @@ var (
 	bucketExpand   = []byte("group_expand")
 	bucketMode     = []byte("clash_mode")
 	bucketRuleSet  = []byte("rule_set")
+	bucketUsage    = []byte("user_usage")
 
 	bucketNameList = []string{
 		string(bucketSelected),
 		string(bucketExpand),
 		string(bucketMode),
 		string(bucketRuleSet),
+		string(bucketUsage),
 		string(bucketRDRC),
 	}
 
@@ -313,3 +315,40 @@ func (c *CacheFile) SaveRuleSet(tag string, set *adapter.SavedRuleSet) error {
 		return bucket.Put([]byte(tag), setBinary)
 	})
 }
+
+func userUsageKey(inbound string, user string) []byte {
+	return []byte(inbound + "\x00" + user)
+}
+
+func (c *CacheFile) LoadUserUsage(inbound string, user string) *adapter.SavedUserUsage {
+	var usage adapter.SavedUserUsage
+	err := c.DB.View(func(t *bbolt.Tx) error {
+		bucket := c.bucket(t, bucketUsage)
+		if bucket == nil {
+			return os.ErrNotExist
+		}
+		usageBinary := bucket.Get(userUsageKey(inbound, user))
+		if len(usageBinary) == 0 {
+			return os.ErrInvalid
+		}
+		return usage.UnmarshalBinary(usageBinary)
+	})
+	if err != nil {
+		return nil
+	}
+	return &usage
+}
+
+func (c *CacheFile) SaveUserUsage(inbound string, user string, usage *adapter.SavedUserUsage) error {
+	return c.DB.Batch(func(t *bbolt.Tx) error {
+		bucket, err := c.createBucket(t, bucketUsage)
+		if err != nil {
+			return err
+		}
+		usageBinary, err := usage.MarshalBinary()
+		if err != nil {
+			return err
+		}
+		return bucket.Put(userUsageKey(inbound, user), usageBinary)
+	})
+}
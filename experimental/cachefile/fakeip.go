@@ -179,6 +179,70 @@ func (c *CacheFile) FakeIPLoadDomain(domain string, isIPv6 bool) (netip.Addr, bo
 	return address, address.IsValid()
 }
 
+func (c *CacheFile) FakeIPLoadAll() (map[netip.Addr]string, error) {
+	mappings := make(map[netip.Addr]string)
+	err := c.DB.View(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(bucketFakeIP)
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(key, value []byte) error {
+			// the bucket also holds the metadata
+			if len(key) != 4 && len(key) != 16 {
+				return nil
+			}
+			mappings[M.AddrFromIP(key)] = string(value)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	c.saveFakeIPAccess.RLock()
+	for address, domain := range c.saveDomain {
+		mappings[address] = domain
+	}
+	c.saveFakeIPAccess.RUnlock()
+	return mappings, nil
+}
+
+func (c *CacheFile) FakeIPDelete(address netip.Addr) error {
+	c.saveFakeIPAccess.Lock()
+	if domain, loaded := c.saveDomain[address]; loaded {
+		delete(c.saveDomain, address)
+		if address.Is4() {
+			delete(c.saveAddress4, domain)
+		} else {
+			delete(c.saveAddress6, domain)
+		}
+	}
+	c.saveFakeIPAccess.Unlock()
+	return c.DB.Batch(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(bucketFakeIP)
+		if bucket == nil {
+			return nil
+		}
+		domain := bucket.Get(address.AsSlice())
+		if domain == nil {
+			return nil
+		}
+		domain = append([]byte(nil), domain...)
+		err := bucket.Delete(address.AsSlice())
+		if err != nil {
+			return err
+		}
+		if address.Is4() {
+			bucket = tx.Bucket(bucketFakeIPDomain4)
+		} else {
+			bucket = tx.Bucket(bucketFakeIPDomain6)
+		}
+		if bucket == nil || M.AddrFromIP(bucket.Get(domain)) != address {
+			return nil
+		}
+		return bucket.Delete(domain)
+	})
+}
+
 func (c *CacheFile) FakeIPReset() error {
 	return c.DB.Batch(func(tx *bbolt.Tx) error {
 		err := tx.DeleteBucket(bucketFakeIP)
@@ -13,22 +13,23 @@ func configRouter(server *Server, logFactory log.Factory) http.Handler {
 	r := chi.NewRouter()
 	r.Get("/", getConfigs(server, logFactory))
 	r.Put("/", updateConfigs)
-	r.Patch("/", patchConfigs(server))
+	r.Patch("/", patchConfigs(server, logFactory))
 	return r
 }
 
 type configSchema struct {
-	Port        int            `json:"port"`
-	SocksPort   int            `json:"socks-port"`
-	RedirPort   int            `json:"redir-port"`
-	TProxyPort  int            `json:"tproxy-port"`
-	MixedPort   int            `json:"mixed-port"`
-	AllowLan    bool           `json:"allow-lan"`
-	BindAddress string         `json:"bind-address"`
-	Mode        string         `json:"mode"`
-	LogLevel    string         `json:"log-level"`
-	IPv6        bool           `json:"ipv6"`
-	Tun         map[string]any `json:"tun"`
+	Port        int               `json:"port"`
+	SocksPort   int               `json:"socks-port"`
+	RedirPort   int               `json:"redir-port"`
+	TProxyPort  int               `json:"tproxy-port"`
+	MixedPort   int               `json:"mixed-port"`
+	AllowLan    bool              `json:"allow-lan"`
+	BindAddress string            `json:"bind-address"`
+	Mode        string            `json:"mode"`
+	LogLevel    string            `json:"log-level"`
+	LogLevels   map[string]string `json:"log-levels,omitempty"`
+	IPv6        bool              `json:"ipv6"`
+	Tun         map[string]any    `json:"tun"`
 }
 
 func getConfigs(server *Server, logFactory log.Factory) func(w http.ResponseWriter, r *http.Request) {
@@ -39,15 +40,21 @@ func getConfigs(server *Server, logFactory log.Factory) func(w http.ResponseWrit
 		} else if logLevel < log.LevelError {
 			logLevel = log.LevelError
 		}
+		tagLevels := logFactory.TagLevels()
+		logLevels := make(map[string]string, len(tagLevels))
+		for tag, level := range tagLevels {
+			logLevels[tag] = log.FormatLevel(level)
+		}
 		render.JSON(w, r, &configSchema{
 			Mode:        server.mode,
 			BindAddress: "*",
 			LogLevel:    log.FormatLevel(logLevel),
+			LogLevels:   logLevels,
 		})
 	}
 }
 
-func patchConfigs(server *Server) func(w http.ResponseWriter, r *http.Request) {
+func patchConfigs(server *Server, logFactory log.Factory) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var newConfig configSchema
 		err := render.DecodeJSON(r.Body, &newConfig)
@@ -56,13 +63,52 @@ func patchConfigs(server *Server) func(w http.ResponseWriter, r *http.Request) {
 			render.JSON(w, r, ErrBadRequest)
 			return
 		}
+		var logLevel log.Level
+		if newConfig.LogLevel != "" {
+			logLevel, err = parseClashLogLevel(newConfig.LogLevel)
+			if err != nil {
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, newError(err.Error()))
+				return
+			}
+		}
+		// an empty level removes the override of the tag
+		tagLevels := make(map[string]log.Level)
+		for tag, levelText := range newConfig.LogLevels {
+			if levelText == "" {
+				continue
+			}
+			tagLevels[tag], err = parseClashLogLevel(levelText)
+			if err != nil {
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, newError(err.Error()))
+				return
+			}
+		}
 		if newConfig.Mode != "" {
 			server.SetMode(newConfig.Mode)
 		}
+		if newConfig.LogLevel != "" {
+			logFactory.SetLevel(logLevel)
+		}
+		for tag, levelText := range newConfig.LogLevels {
+			if levelText == "" {
+				logFactory.RemoveTagLevel(tag)
+			} else {
+				logFactory.SetTagLevel(tag, tagLevels[tag])
+			}
+		}
 		render.NoContent(w, r)
 	}
 }
 
+func parseClashLogLevel(level string) (log.Level, error) {
+	if level == "silent" {
+		return log.LevelPanic, nil
+	}
+	return log.ParseLevel(level)
+}
+
 func updateConfigs(w http.ResponseWriter, r *http.Request) {
 	render.NoContent(w, r)
 }
@@ -19,6 +19,8 @@ import (
 func connectionRouter(router adapter.Router, trafficManager *trafficontrol.Manager) http.Handler {
 	r := chi.NewRouter()
 	r.Get("/", getConnections(trafficManager))
+	r.Get("/history", getConnectionHistory(trafficManager))
+	r.Get("/statistics/{kind}", getConnectionStatistics(trafficManager))
 	r.Delete("/", closeAllConnections(router, trafficManager))
 	r.Delete("/{id}", closeConnection(trafficManager))
 	return r
@@ -98,3 +100,51 @@ func closeAllConnections(router adapter.Router, trafficManager *trafficontrol.Ma
 		render.NoContent(w, r)
 	}
 }
+
+func parseLimit(r *http.Request) (int, error) {
+	limitStr := r.URL.Query().Get("limit")
+	if limitStr == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(limitStr)
+}
+
+func getConnectionHistory(trafficManager *trafficontrol.Manager) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		limit, err := parseLimit(r)
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, ErrBadRequest)
+			return
+		}
+		render.JSON(w, r, render.M{
+			"connections": trafficManager.History(limit),
+		})
+	}
+}
+
+func getConnectionStatistics(trafficManager *trafficontrol.Manager) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		kind := chi.URLParam(r, "kind")
+		switch kind {
+		case trafficontrol.StatisticsOutbound, trafficontrol.StatisticsInbound, trafficontrol.StatisticsRule, trafficontrol.StatisticsHost:
+		default:
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, ErrNotFound)
+			return
+		}
+		limit, err := parseLimit(r)
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, ErrBadRequest)
+			return
+		}
+		statistics := trafficManager.Statistics(kind)
+		if limit > 0 && limit < len(statistics) {
+			statistics = statistics[:limit]
+		}
+		render.JSON(w, r, render.M{
+			"statistics": statistics,
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package clashapi
+
+import (
+	"net/http"
+	"net/netip"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing/common"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+func fakeipRouter(router adapter.Router) http.Handler {
+	r := chi.NewRouter()
+	r.Get("/", getFakeIPMappings(router))
+	r.Get("/{address}", getFakeIPMapping(router))
+	r.Delete("/{address}", deleteFakeIPMapping(router))
+	return r
+}
+
+type fakeIPMapping struct {
+	Address     netip.Addr `json:"address"`
+	Domain      string     `json:"domain"`
+	Pool        string     `json:"pool,omitempty"`
+	Connections int        `json:"connections"`
+}
+
+func newFakeIPMapping(mapping adapter.FakeIPMapping) fakeIPMapping {
+	return fakeIPMapping{
+		Address:     mapping.Address,
+		Domain:      mapping.Domain,
+		Pool:        mapping.Pool,
+		Connections: mapping.Connections,
+	}
+}
+
+func loadFakeIPStore(router adapter.Router, w http.ResponseWriter, r *http.Request) (adapter.FakeIPStore, bool) {
+	store := router.FakeIPStore()
+	if store == nil {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, newError("FakeIP not enabled"))
+		return nil, false
+	}
+	return store, true
+}
+
+func getFakeIPMappings(router adapter.Router) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		store, loaded := loadFakeIPStore(router, w, r)
+		if !loaded {
+			return
+		}
+		mappings, err := store.Mappings()
+		if err != nil {
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, newError(err.Error()))
+			return
+		}
+		query := r.URL.Query()
+		if domain := query.Get("domain"); domain != "" {
+			mappings = common.Filter(mappings, func(it adapter.FakeIPMapping) bool {
+				return it.Domain == domain
+			})
+		}
+		if query.Has("pool") {
+			pool := query.Get("pool")
+			mappings = common.Filter(mappings, func(it adapter.FakeIPMapping) bool {
+				return it.Pool == pool
+			})
+		}
+		render.JSON(w, r, render.M{
+			"mappings": common.Map(mappings, newFakeIPMapping),
+		})
+	}
+}
+
+func parseFakeIPAddress(store adapter.FakeIPStore, w http.ResponseWriter, r *http.Request) (netip.Addr, bool) {
+	address, err := netip.ParseAddr(getEscapeParam(r, "address"))
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, newError(err.Error()))
+		return netip.Addr{}, false
+	}
+	if !store.Contains(address) {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, ErrNotFound)
+		return netip.Addr{}, false
+	}
+	return address, true
+}
+
+func getFakeIPMapping(router adapter.Router) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		store, loaded := loadFakeIPStore(router, w, r)
+		if !loaded {
+			return
+		}
+		address, loaded := parseFakeIPAddress(store, w, r)
+		if !loaded {
+			return
+		}
+		mapping, loaded := store.Mapping(address)
+		if !loaded {
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, ErrNotFound)
+			return
+		}
+		render.JSON(w, r, newFakeIPMapping(mapping))
+	}
+}
+
+func deleteFakeIPMapping(router adapter.Router) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		store, loaded := loadFakeIPStore(router, w, r)
+		if !loaded {
+			return
+		}
+		address, loaded := parseFakeIPAddress(store, w, r)
+		if !loaded {
+			return
+		}
+		err := store.Delete(address)
+		if err != nil {
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, newError(err.Error()))
+			return
+		}
+		render.NoContent(w, r)
+	}
+}
@@ -0,0 +1,98 @@
+package clashapi
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common/json"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+func outboundRouter(router adapter.Router) http.Handler {
+	r := chi.NewRouter()
+	r.Post("/", addOutbound(router))
+	r.Route("/{name}", func(r chi.Router) {
+		r.Use(parseProxyName)
+		r.Put("/", replaceOutbound(router))
+		r.Delete("/", removeOutbound(router))
+	})
+	return r
+}
+
+func addOutbound(router adapter.Router) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		options, err := decodeOutbound(r)
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, newError(err.Error()))
+			return
+		}
+		err = router.AddOutbound(options)
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, newError(err.Error()))
+			return
+		}
+		render.NoContent(w, r)
+	}
+}
+
+func replaceOutbound(router adapter.Router) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := r.Context().Value(CtxKeyProxyName).(string)
+		if _, loaded := router.Outbound(name); !loaded {
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, ErrNotFound)
+			return
+		}
+		options, err := decodeOutbound(r)
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, newError(err.Error()))
+			return
+		}
+		if options.Tag != "" && options.Tag != name {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, newError("Tag mismatch"))
+			return
+		}
+		options.Tag = name
+		err = router.ReplaceOutbound(options)
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, newError(err.Error()))
+			return
+		}
+		render.NoContent(w, r)
+	}
+}
+
+func removeOutbound(router adapter.Router) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := r.Context().Value(CtxKeyProxyName).(string)
+		if _, loaded := router.Outbound(name); !loaded {
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, ErrNotFound)
+			return
+		}
+		err := router.RemoveOutbound(name)
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, newError(err.Error()))
+			return
+		}
+		render.NoContent(w, r)
+	}
+}
+
+func decodeOutbound(r *http.Request) (option.Outbound, error) {
+	content, err := io.ReadAll(r.Body)
+	if err != nil {
+		return option.Outbound{}, err
+	}
+	return json.UnmarshalExtended[option.Outbound](content)
+}
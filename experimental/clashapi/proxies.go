@@ -30,6 +30,7 @@ func proxyRouter(server *Server, router adapter.Router) http.Handler {
 		r.Get("/", getProxy(server))
 		r.Get("/delay", getProxyDelay(server))
 		r.Put("/", updateProxy)
+		r.Post("/newnym", newProxyIdentity)
 	})
 	return r
 }
@@ -46,7 +47,7 @@ func findProxyByName(router adapter.Router) func(next http.Handler) http.Handler
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			name := r.Context().Value(CtxKeyProxyName).(string)
-			proxy, exist := router.Outbound(name)
+			proxy, exist := lookupProxy(router, name)
 			if !exist {
 				render.Status(r, http.StatusNotFound)
 				render.JSON(w, r, ErrNotFound)
@@ -58,6 +59,25 @@ func findProxyByName(router adapter.Router) func(next http.Handler) http.Handler
 	}
 }
 
+// lookupProxy finds the outbound with the tag, including auto members of selectors.
+func lookupProxy(router adapter.Router, name string) (adapter.Outbound, bool) {
+	if proxy, loaded := router.Outbound(name); loaded {
+		return proxy, true
+	}
+	if !strings.HasSuffix(name, outbound.AutoTagSuffix) {
+		return nil, false
+	}
+	proxy, loaded := router.Outbound(strings.TrimSuffix(name, outbound.AutoTagSuffix))
+	if !loaded {
+		return nil, false
+	}
+	selector, isSelector := proxy.(*outbound.Selector)
+	if !isSelector {
+		return nil, false
+	}
+	return selector.Auto()
+}
+
 func proxyInfo(server *Server, detour adapter.Outbound) *badjson.JSONObject {
 	var info badjson.JSONObject
 	var clashType string
@@ -80,9 +100,37 @@ func proxyInfo(server *Server, detour adapter.Outbound) *badjson.JSONObject {
 		info.Put("now", group.Now())
 		info.Put("all", group.All())
 	}
+	if selector, isSelector := detour.(*outbound.Selector); isSelector {
+		info.Put("tcp_now", selector.NowNetwork(N.NetworkTCP))
+		info.Put("udp_now", selector.NowNetwork(N.NetworkUDP))
+	}
+	if peerOutbound, isPeerOutbound := detour.(adapter.PeerOutbound); isPeerOutbound {
+		info.Put("peers", common.Map(peerOutbound.PeerStatus(), newPeerInfo))
+	}
 	return &info
 }
 
+type peerInfo struct {
+	PublicKey     string     `json:"public_key"`
+	Endpoint      string     `json:"endpoint,omitempty"`
+	LastHandshake *time.Time `json:"last_handshake,omitempty"`
+	Upload        uint64     `json:"upload"`
+	Download      uint64     `json:"download"`
+}
+
+func newPeerInfo(status adapter.PeerStatus) peerInfo {
+	info := peerInfo{
+		PublicKey: status.PublicKey,
+		Endpoint:  status.Endpoint,
+		Upload:    status.Upload,
+		Download:  status.Download,
+	}
+	if !status.LastHandshake.IsZero() {
+		info.LastHandshake = &status.LastHandshake
+	}
+	return info
+}
+
 func getProxies(server *Server, router adapter.Router) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var proxyMap badjson.JSONObject
@@ -129,6 +177,11 @@ func getProxies(server *Server, router adapter.Router) func(w http.ResponseWrite
 				tag = detour.Tag()
 			}
 			proxyMap.Put(tag, proxyInfo(server, detour))
+			if selector, isSelector := detour.(*outbound.Selector); isSelector {
+				if autoOutbound, loaded := selector.Auto(); loaded {
+					proxyMap.Put(autoOutbound.Tag(), proxyInfo(server, autoOutbound))
+				}
+			}
 		}
 		var responseMap badjson.JSONObject
 		responseMap.Put("proxies", &proxyMap)
@@ -145,7 +198,17 @@ func getProxies(server *Server, router adapter.Router) func(w http.ResponseWrite
 func getProxy(server *Server) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		proxy := r.Context().Value(CtxKeyProxy).(adapter.Outbound)
-		response, err := proxyInfo(server, proxy).MarshalJSON()
+		info := proxyInfo(server, proxy)
+		if circuitOutbound, isCircuitOutbound := proxy.(adapter.CircuitOutbound); isCircuitOutbound {
+			circuits, err := circuitOutbound.CircuitStatus()
+			if err != nil {
+				render.Status(r, http.StatusServiceUnavailable)
+				render.JSON(w, r, newError(err.Error()))
+				return
+			}
+			info.Put("circuits", common.Map(circuits, newCircuitInfo))
+		}
+		response, err := info.MarshalJSON()
 		if err != nil {
 			render.Status(r, http.StatusInternalServerError)
 			render.JSON(w, r, newError(err.Error()))
@@ -155,8 +218,46 @@ func getProxy(server *Server) func(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+type circuitInfo struct {
+	ID      string   `json:"id"`
+	Status  string   `json:"status"`
+	Path    []string `json:"path"`
+	Purpose string   `json:"purpose,omitempty"`
+}
+
+func newCircuitInfo(status adapter.CircuitStatus) circuitInfo {
+	info := circuitInfo{
+		ID:      status.ID,
+		Status:  status.Status,
+		Path:    status.Path,
+		Purpose: status.Purpose,
+	}
+	if info.Path == nil {
+		info.Path = []string{}
+	}
+	return info
+}
+
+func newProxyIdentity(w http.ResponseWriter, r *http.Request) {
+	proxy := r.Context().Value(CtxKeyProxy).(adapter.Outbound)
+	circuitOutbound, ok := proxy.(adapter.CircuitOutbound)
+	if !ok {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, newError("Must be a Tor outbound"))
+		return
+	}
+	err := circuitOutbound.NewIdentity()
+	if err != nil {
+		render.Status(r, http.StatusServiceUnavailable)
+		render.JSON(w, r, newError(err.Error()))
+		return
+	}
+	render.NoContent(w, r)
+}
+
 type UpdateProxyRequest struct {
-	Name string `json:"name"`
+	Name    string `json:"name"`
+	Network string `json:"network,omitempty"`
 }
 
 func updateProxy(w http.ResponseWriter, r *http.Request) {
@@ -175,6 +276,21 @@ func updateProxy(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if req.Network != "" {
+		if req.Network != N.NetworkTCP && req.Network != N.NetworkUDP {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, newError("Selector update error: unknown network"))
+			return
+		}
+		if !selector.SelectNetworkOutbound(req.Network, req.Name) {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, newError("Selector update error: not found"))
+			return
+		}
+		render.NoContent(w, r)
+		return
+	}
+
 	if !selector.SelectOutbound(req.Name) {
 		render.Status(r, http.StatusBadRequest)
 		render.JSON(w, r, newError("Selector update error: not found"))
@@ -7,10 +7,13 @@ import (
 	"net"
 	"net/http"
 	"os"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/rotate"
 	"github.com/sagernet/sing-box/common/urltest"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/experimental"
@@ -42,6 +45,7 @@ type Server struct {
 	ctx            context.Context
 	router         adapter.Router
 	logger         log.Logger
+	logFactory     log.ObservableFactory
 	httpServer     *http.Server
 	trafficManager *trafficontrol.Manager
 	urlTestHistory *urltest.HistoryStorage
@@ -56,12 +60,31 @@ type Server struct {
 }
 
 func NewServer(ctx context.Context, router adapter.Router, logFactory log.ObservableFactory, options option.ClashAPIOptions) (adapter.ClashServer, error) {
-	trafficManager := trafficontrol.NewManager()
+	logger := logFactory.NewLogger("clash-api")
+	var managerOptions trafficontrol.ManagerOptions
+	if historyOptions := options.ConnectionHistory; historyOptions != nil {
+		managerOptions.Logger = logger
+		managerOptions.HistorySize = historyOptions.Size
+		if historyOptions.Path != "" {
+			historyExporter, err := rotate.NewWriter(rotate.Options{
+				Path:       filemanager.BasePath(ctx, os.ExpandEnv(historyOptions.Path)),
+				MaxSize:    int64(historyOptions.MaxSize),
+				MaxAge:     time.Duration(historyOptions.MaxAge),
+				MaxBackups: historyOptions.MaxBackups,
+			})
+			if err != nil {
+				return nil, E.Cause(err, "create connection history exporter")
+			}
+			managerOptions.HistoryExporter = historyExporter
+		}
+	}
+	trafficManager := trafficontrol.NewManager(managerOptions)
 	chiRouter := chi.NewRouter()
 	server := &Server{
-		ctx:    ctx,
-		router: router,
-		logger: logFactory.NewLogger("clash-api"),
+		ctx:        ctx,
+		router:     router,
+		logger:     logger,
+		logFactory: logFactory,
 		httpServer: &http.Server{
 			Addr:    options.ExternalController,
 			Handler: chiRouter,
@@ -104,13 +127,15 @@ func NewServer(ctx context.Context, router adapter.Router, logFactory log.Observ
 		r.Get("/version", version)
 		r.Mount("/configs", configRouter(server, logFactory))
 		r.Mount("/proxies", proxyRouter(server, router))
+		r.Mount("/outbounds", outboundRouter(router))
 		r.Mount("/rules", ruleRouter(router))
 		r.Mount("/connections", connectionRouter(router, trafficManager))
 		r.Mount("/providers/proxies", proxyProviderRouter())
 		r.Mount("/providers/rules", ruleProviderRouter())
 		r.Mount("/script", scriptRouter())
 		r.Mount("/profile", profileRouter())
-		r.Mount("/cache", cacheRouter(ctx))
+		r.Mount("/cache", cacheRouter(ctx, router))
+		r.Mount("/fakeip", fakeipRouter(router))
 		r.Mount("/dns", dnsRouter(router))
 
 		server.setupMetaAPI(r)
@@ -213,6 +238,10 @@ func (s *Server) HistoryStorage() *urltest.HistoryStorage {
 	return s.urlTestHistory
 }
 
+func (s *Server) LogFactory() log.ObservableFactory {
+	return s.logFactory
+}
+
 func (s *Server) TrafficManager() *trafficontrol.Manager {
 	return s.trafficManager
 }
@@ -260,6 +289,7 @@ func castMetadata(metadata adapter.InboundContext) trafficontrol.Metadata {
 	return trafficontrol.Metadata{
 		NetWork:     metadata.Network,
 		Type:        inbound,
+		Inbound:     metadata.Inbound,
 		SrcIP:       metadata.Source.Addr,
 		DstIP:       metadata.Destination.Addr,
 		SrcPort:     F.ToString(metadata.Source.Port),
@@ -373,15 +403,10 @@ type Log struct {
 
 func getLogs(logFactory log.ObservableFactory) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
-		levelText := r.URL.Query().Get("level")
-		if levelText == "" {
-			levelText = "info"
-		}
-
-		level, ok := log.ParseLevel(levelText)
-		if ok != nil {
+		filter, err := parseLogFilter(r)
+		if err != nil {
 			render.Status(r, http.StatusBadRequest)
-			render.JSON(w, r, ErrBadRequest)
+			render.JSON(w, r, newError(err.Error()))
 			return
 		}
 
@@ -414,7 +439,7 @@ func getLogs(logFactory log.ObservableFactory) func(w http.ResponseWriter, r *ht
 				return
 			case logEntry = <-subscription:
 			}
-			if logEntry.Level > level {
+			if !filter.Match(logEntry) {
 				continue
 			}
 			buf.Reset()
@@ -439,6 +464,42 @@ func getLogs(logFactory log.ObservableFactory) func(w http.ResponseWriter, r *ht
 	}
 }
 
+func parseLogFilter(r *http.Request) (*log.Filter, error) {
+	query := r.URL.Query()
+	levelText := query.Get("level")
+	if levelText == "" {
+		levelText = "info"
+	}
+	level, err := log.ParseLevel(levelText)
+	if err != nil {
+		return nil, err
+	}
+	filter := &log.Filter{
+		Level: level,
+	}
+	for _, tags := range query["tag"] {
+		for _, tag := range strings.Split(tags, ",") {
+			if tag != "" {
+				filter.Tags = append(filter.Tags, tag)
+			}
+		}
+	}
+	if idText := query.Get("id"); idText != "" {
+		id, err := strconv.ParseUint(idText, 10, 32)
+		if err != nil {
+			return nil, E.Cause(err, "parse connection id")
+		}
+		filter.ID = uint32(id)
+	}
+	if expression := query.Get("regex"); expression != "" {
+		filter.Regexp, err = regexp.Compile(expression)
+		if err != nil {
+			return nil, E.Cause(err, "parse regex")
+		}
+	}
+	return filter, nil
+}
+
 func version(w http.ResponseWriter, r *http.Request) {
 	render.JSON(w, r, render.M{"version": "sing-box " + C.Version, "premium": true, "meta": true})
 }
@@ -0,0 +1,173 @@
+package trafficontrol
+
+import (
+	"sort"
+	"time"
+
+	"github.com/sagernet/sing/common/atomic"
+	"github.com/sagernet/sing/common/json"
+)
+
+const (
+	StatisticsOutbound = "outbound"
+	StatisticsInbound  = "inbound"
+	StatisticsRule     = "rule"
+	StatisticsHost     = "host"
+)
+
+type ClosedConnection struct {
+	ID          string    `json:"id"`
+	Metadata    Metadata  `json:"metadata"`
+	Upload      int64     `json:"upload"`
+	Download    int64     `json:"download"`
+	Start       time.Time `json:"start"`
+	End         time.Time `json:"end"`
+	Duration    int64     `json:"duration"`
+	Chain       []string  `json:"chains"`
+	Rule        string    `json:"rule"`
+	RulePayload string    `json:"rulePayload"`
+	Error       string    `json:"error,omitempty"`
+}
+
+type counter struct {
+	upload      atomic.Int64
+	download    atomic.Int64
+	connections atomic.Int64
+}
+
+type Statistics struct {
+	Name        string `json:"name"`
+	Upload      int64  `json:"upload"`
+	Download    int64  `json:"download"`
+	Connections int64  `json:"connections"`
+}
+
+func (m *Manager) counter(kind string, name string) *counter {
+	m.statisticsAccess.Lock()
+	defer m.statisticsAccess.Unlock()
+	counters := m.statistics[kind]
+	if counters == nil {
+		counters = make(map[string]*counter)
+		m.statistics[kind] = counters
+	}
+	c := counters[name]
+	if c == nil {
+		c = new(counter)
+		counters[name] = c
+	}
+	return c
+}
+
+// Statistics returns cumulative byte counters of kind, sorted by total bytes.
+// Counters for hosts are computed from the connection history and live connections.
+func (m *Manager) Statistics(kind string) []Statistics {
+	var statistics []Statistics
+	if kind == StatisticsHost {
+		statistics = m.hostStatistics()
+	} else {
+		m.statisticsAccess.Lock()
+		for name, c := range m.statistics[kind] {
+			statistics = append(statistics, Statistics{
+				Name:        name,
+				Upload:      c.upload.Load(),
+				Download:    c.download.Load(),
+				Connections: c.connections.Load(),
+			})
+		}
+		m.statisticsAccess.Unlock()
+	}
+	sort.Slice(statistics, func(i, j int) bool {
+		return statistics[i].Upload+statistics[i].Download > statistics[j].Upload+statistics[j].Download
+	})
+	return statistics
+}
+
+func (m *Manager) hostStatistics() []Statistics {
+	hostMap := make(map[string]*Statistics)
+	update := func(metadata Metadata, upload int64, download int64) {
+		host := metadata.Host
+		if host == "" {
+			host = metadata.DstIP.String()
+		}
+		item := hostMap[host]
+		if item == nil {
+			item = &Statistics{Name: host}
+			hostMap[host] = item
+		}
+		item.Upload += upload
+		item.Download += download
+		item.Connections++
+	}
+	for _, connection := range m.History(0) {
+		update(connection.Metadata, connection.Upload, connection.Download)
+	}
+	m.connections.Range(func(_ string, value tracker) bool {
+		info := value.info()
+		update(info.Metadata, info.UploadTotal.Load(), info.DownloadTotal.Load())
+		return true
+	})
+	statistics := make([]Statistics, 0, len(hostMap))
+	for _, item := range hostMap {
+		statistics = append(statistics, *item)
+	}
+	return statistics
+}
+
+// History returns closed connections, newest first.
+func (m *Manager) History(limit int) []*ClosedConnection {
+	m.historyAccess.Lock()
+	defer m.historyAccess.Unlock()
+	historyLen := len(m.history)
+	if limit <= 0 || limit > historyLen {
+		limit = historyLen
+	}
+	connections := make([]*ClosedConnection, 0, limit)
+	for i := 0; i < limit; i++ {
+		connections = append(connections, m.history[(m.historyIndex-1-i+historyLen)%historyLen])
+	}
+	return connections
+}
+
+func (m *Manager) archive(info *trackerInfo) {
+	if !info.archived.CompareAndSwap(false, true) {
+		return
+	}
+	if m.historySize <= 0 && m.historyExporter == nil {
+		return
+	}
+	now := time.Now()
+	connection := &ClosedConnection{
+		ID:          info.UUID.String(),
+		Metadata:    info.Metadata,
+		Upload:      info.UploadTotal.Load(),
+		Download:    info.DownloadTotal.Load(),
+		Start:       info.Start,
+		End:         now,
+		Duration:    now.Sub(info.Start).Milliseconds(),
+		Chain:       info.Chain,
+		Rule:        info.Rule,
+		RulePayload: info.RulePayload,
+	}
+	if err := info.err.Load(); err != nil {
+		connection.Error = err.Error()
+	}
+	if m.historySize > 0 {
+		m.historyAccess.Lock()
+		if len(m.history) < m.historySize {
+			m.history = append(m.history, connection)
+		} else {
+			m.history[m.historyIndex] = connection
+		}
+		m.historyIndex = (m.historyIndex + 1) % m.historySize
+		m.historyAccess.Unlock()
+	}
+	if m.historyExporter != nil {
+		content, err := json.Marshal(connection)
+		if err == nil {
+			_, err = m.historyExporter.Write(append(content, '\n'))
+		}
+		if err != nil && m.logger != nil {
+			m.logger.Error("export connection history: ", err)
+		}
+	}
+}
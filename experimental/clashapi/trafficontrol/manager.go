@@ -1,13 +1,23 @@
 package trafficontrol
 
 import (
+	"io"
 	"runtime"
+	"sync"
 	"time"
 
 	"github.com/sagernet/sing-box/experimental/clashapi/compatible"
+	"github.com/sagernet/sing/common"
 	"github.com/sagernet/sing/common/atomic"
+	"github.com/sagernet/sing/common/logger"
 )
 
+type ManagerOptions struct {
+	Logger          logger.Logger
+	HistorySize     int
+	HistoryExporter io.WriteCloser
+}
+
 type Manager struct {
 	uploadTemp    atomic.Int64
 	downloadTemp  atomic.Int64
@@ -21,13 +31,26 @@ type Manager struct {
 	done        chan struct{}
 	// process     *process.Process
 	memory uint64
+
+	logger           logger.Logger
+	statisticsAccess sync.Mutex
+	statistics       map[string]map[string]*counter
+	historyAccess    sync.Mutex
+	history          []*ClosedConnection
+	historyIndex     int
+	historySize      int
+	historyExporter  io.WriteCloser
 }
 
-func NewManager() *Manager {
+func NewManager(options ManagerOptions) *Manager {
 	manager := &Manager{
 		ticker: time.NewTicker(time.Second),
 		done:   make(chan struct{}),
 		// process: &process.Process{Pid: int32(os.Getpid())},
+		logger:          options.Logger,
+		statistics:      make(map[string]map[string]*counter),
+		historySize:     options.HistorySize,
+		historyExporter: options.HistoryExporter,
 	}
 	go manager.handle()
 	return manager
@@ -89,6 +112,13 @@ func (m *Manager) ResetStatistic() {
 	m.downloadTemp.Store(0)
 	m.downloadBlip.Store(0)
 	m.downloadTotal.Store(0)
+	m.statisticsAccess.Lock()
+	m.statistics = make(map[string]map[string]*counter)
+	m.statisticsAccess.Unlock()
+	m.historyAccess.Lock()
+	m.history = nil
+	m.historyIndex = 0
+	m.historyAccess.Unlock()
 }
 
 func (m *Manager) handle() {
@@ -110,7 +140,9 @@ func (m *Manager) handle() {
 func (m *Manager) Close() error {
 	m.ticker.Stop()
 	close(m.done)
-	return nil
+	m.historyAccess.Lock()
+	defer m.historyAccess.Unlock()
+	return common.Close(m.historyExporter)
 }
 
 type Snapshot struct {
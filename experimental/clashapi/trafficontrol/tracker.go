@@ -18,6 +18,7 @@ import (
 type Metadata struct {
 	NetWork     string     `json:"network"`
 	Type        string     `json:"type"`
+	Inbound     string     `json:"inboundName"`
 	SrcIP       netip.Addr `json:"sourceIP"`
 	DstIP       netip.Addr `json:"destinationIP"`
 	SrcPort     string     `json:"sourcePort"`
@@ -31,6 +32,7 @@ type tracker interface {
 	ID() string
 	Close() error
 	Leave()
+	info() *trackerInfo
 }
 
 type trackerInfo struct {
@@ -42,9 +44,20 @@ type trackerInfo struct {
 	Chain         []string      `json:"chains"`
 	Rule          string        `json:"rule"`
 	RulePayload   string        `json:"rulePayload"`
+
+	err      atomic.TypedValue[error]
+	archived atomic.Bool
+}
+
+func (t *trackerInfo) info() *trackerInfo {
+	return t
 }
 
-func (t trackerInfo) MarshalJSON() ([]byte, error) {
+func (t *trackerInfo) SetError(err error) {
+	t.err.Store(err)
+}
+
+func (t *trackerInfo) MarshalJSON() ([]byte, error) {
 	return json.Marshal(map[string]any{
 		"id":          t.UUID.String(),
 		"metadata":    t.Metadata,
@@ -74,6 +87,7 @@ func (tt *tcpTracker) Close() error {
 
 func (tt *tcpTracker) Leave() {
 	tt.manager.Leave(tt)
+	tt.manager.archive(tt.trackerInfo)
 }
 
 func (tt *tcpTracker) Upstream() any {
@@ -88,40 +102,88 @@ func (tt *tcpTracker) WriterReplaceable() bool {
 	return true
 }
 
-func NewTCPTracker(conn net.Conn, manager *Manager, metadata Metadata, router adapter.Router, rule adapter.Rule) *tcpTracker {
-	uuid, _ := uuid.NewV4()
+// memberGroup is an outbound group with members not registered in the router, like the auto member of selectors.
+type memberGroup interface {
+	Outbound(tag string) (adapter.Outbound, bool)
+}
 
+// outboundChain returns the chain from the outbound used for the network to the outbound of the rule,
+// and the tag of the outbound used.
+func outboundChain(router adapter.Router, rule adapter.Rule, network string) ([]string, string) {
 	var chain []string
 	var next string
 	if rule == nil {
-		if defaultOutbound, err := router.DefaultOutbound(N.NetworkTCP); err == nil {
+		if defaultOutbound, err := router.DefaultOutbound(network); err == nil {
 			next = defaultOutbound.Tag()
 		}
 	} else {
 		next = rule.Outbound()
 	}
+	var members memberGroup
 	for {
 		chain = append(chain, next)
-		detour, loaded := router.Outbound(next)
+		var (
+			detour adapter.Outbound
+			loaded bool
+		)
+		if members != nil {
+			detour, loaded = members.Outbound(next)
+		}
+		if !loaded {
+			detour, loaded = router.Outbound(next)
+		}
 		if !loaded {
 			break
 		}
 		group, isGroup := detour.(adapter.OutboundGroup)
 		if !isGroup {
+			if chainOutbound, isChain := detour.(adapter.ChainOutbound); isChain {
+				chain = append(chain, common.Reverse(append([]string(nil), chainOutbound.Hops()...))...)
+			}
 			break
 		}
-		next = group.Now()
+		members, _ = group.(memberGroup)
+		if networkGroup, isNetworkGroup := group.(adapter.NetworkOutboundGroup); isNetworkGroup {
+			next = networkGroup.NowNetwork(network)
+		} else {
+			next = group.Now()
+		}
 	}
+	return common.Reverse(chain), next
+}
+
+func NewTCPTracker(conn net.Conn, manager *Manager, metadata Metadata, router adapter.Router, rule adapter.Rule) *tcpTracker {
+	uuid, _ := uuid.NewV4()
+
+	chain, next := outboundChain(router, rule, N.NetworkTCP)
 
 	upload := new(atomic.Int64)
 	download := new(atomic.Int64)
+	ruleName := "final"
+	if rule != nil {
+		ruleName = rule.String() + " => " + rule.Outbound()
+	}
+	counters := []*counter{
+		manager.counter(StatisticsOutbound, next),
+		manager.counter(StatisticsInbound, metadata.Inbound),
+		manager.counter(StatisticsRule, ruleName),
+	}
+	for _, c := range counters {
+		c.connections.Add(1)
+	}
 
 	t := &tcpTracker{
 		ExtendedConn: bufio.NewCounterConn(conn, []N.CountFunc{func(n int64) {
 			upload.Add(n)
+			for _, c := range counters {
+				c.upload.Add(n)
+			}
 			manager.PushUploaded(n)
 		}}, []N.CountFunc{func(n int64) {
 			download.Add(n)
+			for _, c := range counters {
+				c.download.Add(n)
+			}
 			manager.PushDownloaded(n)
 		}}),
 		manager: manager,
@@ -129,19 +191,13 @@ func NewTCPTracker(conn net.Conn, manager *Manager, metadata Metadata, router ad
 			UUID:          uuid,
 			Start:         time.Now(),
 			Metadata:      metadata,
-			Chain:         common.Reverse(chain),
-			Rule:          "",
+			Chain:         chain,
+			Rule:          ruleName,
 			UploadTotal:   upload,
 			DownloadTotal: download,
 		},
 	}
 
-	if rule != nil {
-		t.trackerInfo.Rule = rule.String() + " => " + rule.Outbound()
-	} else {
-		t.trackerInfo.Rule = "final"
-	}
-
 	manager.Join(t)
 	return t
 }
@@ -163,6 +219,7 @@ func (ut *udpTracker) Close() error {
 
 func (ut *udpTracker) Leave() {
 	ut.manager.Leave(ut)
+	ut.manager.archive(ut.trackerInfo)
 }
 
 func (ut *udpTracker) Upstream() any {
@@ -180,37 +237,35 @@ func (ut *udpTracker) WriterReplaceable() bool {
 func NewUDPTracker(conn N.PacketConn, manager *Manager, metadata Metadata, router adapter.Router, rule adapter.Rule) *udpTracker {
 	uuid, _ := uuid.NewV4()
 
-	var chain []string
-	var next string
-	if rule == nil {
-		if defaultOutbound, err := router.DefaultOutbound(N.NetworkUDP); err == nil {
-			next = defaultOutbound.Tag()
-		}
-	} else {
-		next = rule.Outbound()
-	}
-	for {
-		chain = append(chain, next)
-		detour, loaded := router.Outbound(next)
-		if !loaded {
-			break
-		}
-		group, isGroup := detour.(adapter.OutboundGroup)
-		if !isGroup {
-			break
-		}
-		next = group.Now()
-	}
+	chain, next := outboundChain(router, rule, N.NetworkUDP)
 
 	upload := new(atomic.Int64)
 	download := new(atomic.Int64)
+	ruleName := "final"
+	if rule != nil {
+		ruleName = rule.String() + " => " + rule.Outbound()
+	}
+	counters := []*counter{
+		manager.counter(StatisticsOutbound, next),
+		manager.counter(StatisticsInbound, metadata.Inbound),
+		manager.counter(StatisticsRule, ruleName),
+	}
+	for _, c := range counters {
+		c.connections.Add(1)
+	}
 
 	ut := &udpTracker{
 		PacketConn: bufio.NewCounterPacketConn(conn, []N.CountFunc{func(n int64) {
 			upload.Add(n)
+			for _, c := range counters {
+				c.upload.Add(n)
+			}
 			manager.PushUploaded(n)
 		}}, []N.CountFunc{func(n int64) {
 			download.Add(n)
+			for _, c := range counters {
+				c.download.Add(n)
+			}
 			manager.PushDownloaded(n)
 		}}),
 		manager: manager,
@@ -218,19 +273,13 @@ func NewUDPTracker(conn N.PacketConn, manager *Manager, metadata Metadata, route
 			UUID:          uuid,
 			Start:         time.Now(),
 			Metadata:      metadata,
-			Chain:         common.Reverse(chain),
-			Rule:          "",
+			Chain:         chain,
+			Rule:          ruleName,
 			UploadTotal:   upload,
 			DownloadTotal: download,
 		},
 	}
 
-	if rule != nil {
-		ut.trackerInfo.Rule = rule.String() + " => " + rule.Outbound()
-	} else {
-		ut.trackerInfo.Rule = "final"
-	}
-
 	manager.Join(ut)
 	return ut
 }
@@ -0,0 +1,108 @@
+package trafficontrol
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/urltest"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing-box/outbound"
+	"github.com/sagernet/sing/common/bufio"
+	"github.com/sagernet/sing/service"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testRouter routes everything to the default outbound, other router methods are not implemented.
+type testRouter struct {
+	adapter.Router
+	outbounds       map[string]adapter.Outbound
+	defaultOutbound string
+}
+
+func (r *testRouter) Outbound(tag string) (adapter.Outbound, bool) {
+	detour, loaded := r.outbounds[tag]
+	return detour, loaded
+}
+
+func (r *testRouter) DefaultOutbound(network string) (adapter.Outbound, error) {
+	return r.outbounds[r.defaultOutbound], nil
+}
+
+func startTestSelector(t *testing.T, history *urltest.HistoryStorage, options option.SelectorOutboundOptions) *testRouter {
+	logger := log.NewNOPFactory().Logger()
+	router := &testRouter{outbounds: make(map[string]adapter.Outbound), defaultOutbound: "select"}
+	for _, tag := range []string{"a", "b", "c"} {
+		router.outbounds[tag] = outbound.NewBlock(logger, tag)
+	}
+	selector, err := outbound.NewSelector(service.ContextWithPtr(context.Background(), history), router, logger, "select", options)
+	require.NoError(t, err)
+	router.outbounds["select"] = selector
+	require.NoError(t, selector.Start())
+	require.NoError(t, selector.PostStart())
+	t.Cleanup(func() {
+		selector.Close()
+	})
+	return router
+}
+
+func outboundStatistics(manager *Manager) map[string]int64 {
+	return statistics(manager, StatisticsOutbound)
+}
+
+func statistics(manager *Manager, kind string) map[string]int64 {
+	connections := make(map[string]int64)
+	for _, item := range manager.Statistics(kind) {
+		connections[item.Name] = item.Connections
+	}
+	return connections
+}
+
+func TestTrackerChainNetworkMember(t *testing.T) {
+	t.Parallel()
+	router := startTestSelector(t, urltest.NewHistoryStorage(), option.SelectorOutboundOptions{
+		Outbounds:   []string{"a", "b", "c"},
+		TCPOutbound: "b",
+		UDPOutbound: "c",
+	})
+	manager := NewManager(ManagerOptions{})
+	defer manager.Close()
+	tcpConn, _ := net.Pipe()
+	defer tcpConn.Close()
+	tcpTracker := NewTCPTracker(tcpConn, manager, Metadata{Type: "mixed/in", Inbound: "in"}, router, nil)
+	require.Equal(t, []string{"b", "select"}, tcpTracker.Chain)
+	udpConn, _ := net.Pipe()
+	defer udpConn.Close()
+	udpTracker := NewUDPTracker(bufio.NewUnbindPacketConn(udpConn), manager, Metadata{Type: "mixed/in", Inbound: "in"}, router, nil)
+	require.Equal(t, []string{"c", "select"}, udpTracker.Chain)
+	require.Equal(t, map[string]int64{"b": 1, "c": 1}, outboundStatistics(manager))
+	require.Equal(t, map[string]int64{"in": 2}, statistics(manager, StatisticsInbound))
+}
+
+func TestTrackerChainAutoMember(t *testing.T) {
+	t.Parallel()
+	history := urltest.NewHistoryStorage()
+	history.StoreURLTestHistory("a", &urltest.History{Time: time.Now(), Delay: 200})
+	history.StoreURLTestHistory("b", &urltest.History{Time: time.Now(), Delay: 100})
+	router := startTestSelector(t, history, option.SelectorOutboundOptions{
+		Outbounds: []string{"a", "b"},
+		Auto:      true,
+	})
+	selector := router.outbounds["select"].(*outbound.Selector)
+	autoOutbound, loaded := selector.Auto()
+	require.True(t, loaded)
+	require.Eventually(t, func() bool {
+		return autoOutbound.(adapter.OutboundGroup).Now() == "b"
+	}, 5*time.Second, 10*time.Millisecond)
+	manager := NewManager(ManagerOptions{})
+	defer manager.Close()
+	conn, _ := net.Pipe()
+	defer conn.Close()
+	tracker := NewTCPTracker(conn, manager, Metadata{}, router, nil)
+	require.Equal(t, []string{"b", "select/auto", "select"}, tracker.Chain)
+	require.Equal(t, map[string]int64{"b": 1}, outboundStatistics(manager))
+}
@@ -16,4 +16,11 @@ const (
 	CommandSetSystemProxyEnabled
 
 	CommandGroupInfoOnly//hiddify
+	CommandTrafficStatistics
+	CommandConnectionHistory
+	CommandSetLogLevel
+	CommandFilteredLog
+	CommandAddOutbound
+	CommandReplaceOutbound
+	CommandRemoveOutbound
 )
@@ -20,6 +20,7 @@ type CommandClient struct {
 type CommandClientOptions struct {
 	Command        int32
 	StatusInterval int64
+	LogFilter      *LogFilter
 }
 
 type CommandClientHandler interface {
@@ -85,6 +86,17 @@ func (c *CommandClient) Connect() error {
 	case CommandLog:
 		c.handler.Connected()
 		go c.handleLogConn(conn)
+	case CommandFilteredLog:
+		err = writeLogFilter(conn, c.options.LogFilter)
+		if err != nil {
+			return E.Cause(err, "write log filter")
+		}
+		err = readError(conn)
+		if err != nil {
+			return err
+		}
+		c.handler.Connected()
+		go c.handleLogConn(conn)
 	case CommandStatus:
 		err = binary.Write(conn, binary.BigEndian, c.options.StatusInterval)
 		if err != nil {
@@ -183,7 +183,8 @@ func writeGroups(writer io.Writer, boxService *BoxService, onlyGroupitems bool)
 		var group OutboundGroup
 		group.Tag = iGroup.Tag()
 		group.Type = iGroup.Type()
-		_, group.Selectable = iGroup.(*outbound.Selector)
+		selector, isSelector := iGroup.(*outbound.Selector)
+		group.Selectable = isSelector
 		group.Selected = iGroup.Now()
 		if cacheFile != nil {
 			if isExpand, loaded := cacheFile.LoadGroupExpand(group.Tag); loaded {
@@ -192,7 +193,15 @@ func writeGroups(writer io.Writer, boxService *BoxService, onlyGroupitems bool)
 		}
 
 		for _, itemTag := range iGroup.All() {
-			itemOutbound, isLoaded := boxService.instance.Router().Outbound(itemTag)
+			var (
+				itemOutbound adapter.Outbound
+				isLoaded     bool
+			)
+			if isSelector {
+				itemOutbound, isLoaded = selector.Outbound(itemTag)
+			} else {
+				itemOutbound, isLoaded = boxService.instance.Router().Outbound(itemTag)
+			}
 			if !isLoaded {
 				continue
 			}
@@ -0,0 +1,186 @@
+package libbox
+
+import (
+	"encoding/binary"
+	"net"
+	"regexp"
+	"strings"
+
+	"github.com/sagernet/sing-box/log"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/rw"
+)
+
+type LogFilter struct {
+	// Level defaults to info.
+	Level string
+	// Tags are comma-separated logger tags, e.g. "dns,outbound/vless".
+	Tags         string
+	ConnectionID int64
+	Regex        string
+}
+
+func (s *CommandServer) logFactory() (log.ObservableFactory, error) {
+	service := s.service
+	if service == nil {
+		return nil, E.New("service not started")
+	}
+	logFactory, isObservable := service.instance.LogFactory().(log.ObservableFactory)
+	if !isObservable {
+		return nil, E.New("log is not observable")
+	}
+	return logFactory, nil
+}
+
+func (c *CommandClient) SetLogLevel(tag string, level string) error {
+	conn, err := c.directConnect()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	err = binary.Write(conn, binary.BigEndian, uint8(CommandSetLogLevel))
+	if err != nil {
+		return err
+	}
+	err = rw.WriteVString(conn, tag)
+	if err != nil {
+		return err
+	}
+	err = rw.WriteVString(conn, level)
+	if err != nil {
+		return err
+	}
+	return readError(conn)
+}
+
+// handleSetLogLevel sets the global level if tag is empty,
+// an empty level removes the override of tag.
+func (s *CommandServer) handleSetLogLevel(conn net.Conn) error {
+	tag, err := rw.ReadVString(conn)
+	if err != nil {
+		return err
+	}
+	levelText, err := rw.ReadVString(conn)
+	if err != nil {
+		return err
+	}
+	logFactory, err := s.logFactory()
+	if err != nil {
+		return writeError(conn, err)
+	}
+	if levelText == "" {
+		if tag == "" {
+			return writeError(conn, E.New("missing log level"))
+		}
+		logFactory.RemoveTagLevel(tag)
+		return writeError(conn, nil)
+	}
+	level, err := log.ParseLevel(levelText)
+	if err != nil {
+		return writeError(conn, err)
+	}
+	if tag == "" {
+		logFactory.SetLevel(level)
+	} else {
+		logFactory.SetTagLevel(tag, level)
+	}
+	return writeError(conn, nil)
+}
+
+func writeLogFilter(conn net.Conn, filter *LogFilter) error {
+	if filter == nil {
+		filter = new(LogFilter)
+	}
+	err := rw.WriteVString(conn, filter.Level)
+	if err != nil {
+		return err
+	}
+	err = rw.WriteVString(conn, filter.Tags)
+	if err != nil {
+		return err
+	}
+	err = binary.Write(conn, binary.BigEndian, uint32(filter.ConnectionID))
+	if err != nil {
+		return err
+	}
+	return rw.WriteVString(conn, filter.Regex)
+}
+
+func readLogFilter(conn net.Conn) (*log.Filter, error) {
+	levelText, err := rw.ReadVString(conn)
+	if err != nil {
+		return nil, err
+	}
+	tags, err := rw.ReadVString(conn)
+	if err != nil {
+		return nil, err
+	}
+	var id uint32
+	err = binary.Read(conn, binary.BigEndian, &id)
+	if err != nil {
+		return nil, err
+	}
+	expression, err := rw.ReadVString(conn)
+	if err != nil {
+		return nil, err
+	}
+	filter := &log.Filter{
+		Level: log.LevelInfo,
+		ID:    id,
+	}
+	if levelText != "" {
+		filter.Level, err = log.ParseLevel(levelText)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, tag := range strings.Split(tags, ",") {
+		if tag != "" {
+			filter.Tags = append(filter.Tags, tag)
+		}
+	}
+	if expression != "" {
+		filter.Regexp, err = regexp.Compile(expression)
+		if err != nil {
+			return nil, E.Cause(err, "parse regex")
+		}
+	}
+	return filter, nil
+}
+
+func (s *CommandServer) handleFilteredLogConn(conn net.Conn) error {
+	filter, err := readLogFilter(conn)
+	if err != nil {
+		return writeError(conn, err)
+	}
+	logFactory, err := s.logFactory()
+	if err != nil {
+		return writeError(conn, err)
+	}
+	subscription, done, err := logFactory.Subscribe()
+	if err != nil {
+		return writeError(conn, err)
+	}
+	defer logFactory.UnSubscribe(subscription)
+	err = writeError(conn, nil)
+	if err != nil {
+		return err
+	}
+	ctx := connKeepAlive(conn)
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case entry := <-subscription:
+			if !filter.Match(entry) {
+				continue
+			}
+			err = writeLog(conn, []byte(strings.ToUpper(log.FormatLevel(entry.Level))+" "+entry.Message))
+			if err != nil {
+				return err
+			}
+		case <-done:
+			return nil
+		}
+	}
+}
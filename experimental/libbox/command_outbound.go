@@ -0,0 +1,101 @@
+package libbox
+
+import (
+	"encoding/binary"
+	"net"
+
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/json"
+	"github.com/sagernet/sing/common/rw"
+)
+
+// AddOutbound creates an outbound from its JSON configuration.
+func (c *CommandClient) AddOutbound(content string) error {
+	return c.writeOutboundCommand(CommandAddOutbound, content)
+}
+
+// ReplaceOutbound replaces the outbound with the same tag from its JSON configuration.
+func (c *CommandClient) ReplaceOutbound(content string) error {
+	return c.writeOutboundCommand(CommandReplaceOutbound, content)
+}
+
+func (c *CommandClient) RemoveOutbound(tag string) error {
+	return c.writeOutboundCommand(CommandRemoveOutbound, tag)
+}
+
+func (c *CommandClient) writeOutboundCommand(command int32, content string) error {
+	conn, err := c.directConnect()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	err = binary.Write(conn, binary.BigEndian, uint8(command))
+	if err != nil {
+		return err
+	}
+	err = rw.WriteVString(conn, content)
+	if err != nil {
+		return err
+	}
+	return readError(conn)
+}
+
+func (s *CommandServer) handleAddOutbound(conn net.Conn) error {
+	content, err := rw.ReadVString(conn)
+	if err != nil {
+		return err
+	}
+	service := s.service
+	if service == nil {
+		return writeError(conn, E.New("service not ready"))
+	}
+	options, err := json.UnmarshalExtended[option.Outbound]([]byte(content))
+	if err != nil {
+		return writeError(conn, E.Cause(err, "decode outbound"))
+	}
+	err = service.instance.Router().AddOutbound(options)
+	if err != nil {
+		return writeError(conn, err)
+	}
+	s.notifyURLTestUpdate()
+	return writeError(conn, nil)
+}
+
+func (s *CommandServer) handleReplaceOutbound(conn net.Conn) error {
+	content, err := rw.ReadVString(conn)
+	if err != nil {
+		return err
+	}
+	service := s.service
+	if service == nil {
+		return writeError(conn, E.New("service not ready"))
+	}
+	options, err := json.UnmarshalExtended[option.Outbound]([]byte(content))
+	if err != nil {
+		return writeError(conn, E.Cause(err, "decode outbound"))
+	}
+	err = service.instance.Router().ReplaceOutbound(options)
+	if err != nil {
+		return writeError(conn, err)
+	}
+	s.notifyURLTestUpdate()
+	return writeError(conn, nil)
+}
+
+func (s *CommandServer) handleRemoveOutbound(conn net.Conn) error {
+	tag, err := rw.ReadVString(conn)
+	if err != nil {
+		return err
+	}
+	service := s.service
+	if service == nil {
+		return writeError(conn, E.New("service not ready"))
+	}
+	err = service.instance.Router().RemoveOutbound(tag)
+	if err != nil {
+		return writeError(conn, err)
+	}
+	s.notifyURLTestUpdate()
+	return writeError(conn, nil)
+}
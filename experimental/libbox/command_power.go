@@ -8,7 +8,9 @@ import (
 	"github.com/sagernet/sing/common/rw"
 )
 
-func (c *CommandClient) ServiceReload() error {
+// ServiceReload applies the configuration to the running service, only changed components are restarted.
+// The service is rebuilt by the platform if configContent is empty or can only be applied by a restart.
+func (c *CommandClient) ServiceReload(configContent string) error {
 	conn, err := c.directConnect()
 	if err != nil {
 		return err
@@ -18,6 +20,10 @@ func (c *CommandClient) ServiceReload() error {
 	if err != nil {
 		return err
 	}
+	err = rw.WriteVString(conn, configContent)
+	if err != nil {
+		return err
+	}
 	var hasError bool
 	err = binary.Read(conn, binary.BigEndian, &hasError)
 	if err != nil {
@@ -34,8 +40,22 @@ func (c *CommandClient) ServiceReload() error {
 }
 
 func (s *CommandServer) handleServiceReload(conn net.Conn) error {
-	rErr := s.handler.ServiceReload()
-	err := binary.Write(conn, binary.BigEndian, rErr != nil)
+	configContent, err := rw.ReadVString(conn)
+	if err != nil {
+		return err
+	}
+	var (
+		reloaded bool
+		rErr     error
+	)
+	service := s.service
+	if service != nil && configContent != "" {
+		reloaded, rErr = service.Reload(configContent)
+	}
+	if rErr == nil && !reloaded {
+		rErr = s.handler.ServiceReload()
+	}
+	err = binary.Write(conn, binary.BigEndian, rErr != nil)
 	if err != nil {
 		return err
 	}
@@ -188,6 +188,20 @@ func (s *CommandServer) handleConnection(conn net.Conn) error {
 		return s.handleGetSystemProxyStatus(conn)
 	case CommandSetSystemProxyEnabled:
 		return s.handleSetSystemProxyEnabled(conn)
+	case CommandTrafficStatistics:
+		return s.handleTrafficStatistics(conn)
+	case CommandConnectionHistory:
+		return s.handleConnectionHistory(conn)
+	case CommandSetLogLevel:
+		return s.handleSetLogLevel(conn)
+	case CommandFilteredLog:
+		return s.handleFilteredLogConn(conn)
+	case CommandAddOutbound:
+		return s.handleAddOutbound(conn)
+	case CommandReplaceOutbound:
+		return s.handleReplaceOutbound(conn)
+	case CommandRemoveOutbound:
+		return s.handleRemoveOutbound(conn)
 	default:
 		return E.New("unknown command: ", command)
 	}
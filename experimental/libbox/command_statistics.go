@@ -0,0 +1,272 @@
+package libbox
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+
+	"github.com/sagernet/sing-box/experimental/clashapi"
+	"github.com/sagernet/sing-box/experimental/clashapi/trafficontrol"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/rw"
+)
+
+const (
+	TrafficStatisticsOutbound = trafficontrol.StatisticsOutbound
+	TrafficStatisticsInbound  = trafficontrol.StatisticsInbound
+	TrafficStatisticsRule     = trafficontrol.StatisticsRule
+	TrafficStatisticsHost     = trafficontrol.StatisticsHost
+)
+
+type TrafficStatistics struct {
+	Name        string
+	Upload      int64
+	Download    int64
+	Connections int64
+}
+
+type TrafficStatisticsIterator interface {
+	Next() *TrafficStatistics
+	HasNext() bool
+}
+
+type ClosedConnection struct {
+	ID          string
+	Network     string
+	Inbound     string
+	Source      string
+	Destination string
+	Host        string
+	Chain       string
+	Rule        string
+	Upload      int64
+	Download    int64
+	StartTime   int64
+	Duration    int64
+	Error       string
+}
+
+type ClosedConnectionIterator interface {
+	Next() *ClosedConnection
+	HasNext() bool
+}
+
+func (s *CommandServer) trafficManager() (*trafficontrol.Manager, error) {
+	service := s.service
+	if service == nil {
+		return nil, E.New("service not started")
+	}
+	clashServer := service.instance.Router().ClashServer()
+	if clashServer == nil {
+		return nil, E.New("clash api not enabled")
+	}
+	return clashServer.(*clashapi.Server).TrafficManager(), nil
+}
+
+func (c *CommandClient) GetTrafficStatistics(kind string, limit int32) (TrafficStatisticsIterator, error) {
+	conn, err := c.directConnect()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	err = binary.Write(conn, binary.BigEndian, uint8(CommandTrafficStatistics))
+	if err != nil {
+		return nil, err
+	}
+	err = rw.WriteVString(conn, kind)
+	if err != nil {
+		return nil, err
+	}
+	err = binary.Write(conn, binary.BigEndian, limit)
+	if err != nil {
+		return nil, err
+	}
+	err = readError(conn)
+	if err != nil {
+		return nil, err
+	}
+	return readTrafficStatistics(conn)
+}
+
+func (s *CommandServer) handleTrafficStatistics(conn net.Conn) error {
+	kind, err := rw.ReadVString(conn)
+	if err != nil {
+		return err
+	}
+	var limit int32
+	err = binary.Read(conn, binary.BigEndian, &limit)
+	if err != nil {
+		return err
+	}
+	trafficManager, err := s.trafficManager()
+	if err != nil {
+		return writeError(conn, err)
+	}
+	switch kind {
+	case TrafficStatisticsOutbound, TrafficStatisticsInbound, TrafficStatisticsRule, TrafficStatisticsHost:
+	default:
+		return writeError(conn, E.New("unknown statistics kind: ", kind))
+	}
+	statistics := trafficManager.Statistics(kind)
+	if limit > 0 && int(limit) < len(statistics) {
+		statistics = statistics[:limit]
+	}
+	err = writeError(conn, nil)
+	if err != nil {
+		return err
+	}
+	return writeTrafficStatistics(conn, statistics)
+}
+
+func writeTrafficStatistics(writer io.Writer, statistics []trafficontrol.Statistics) error {
+	err := binary.Write(writer, binary.BigEndian, uint32(len(statistics)))
+	if err != nil {
+		return err
+	}
+	for _, item := range statistics {
+		err = rw.WriteVString(writer, item.Name)
+		if err != nil {
+			return err
+		}
+		err = binary.Write(writer, binary.BigEndian, []int64{item.Upload, item.Download, item.Connections})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readTrafficStatistics(reader io.Reader) (TrafficStatisticsIterator, error) {
+	var statisticsLen uint32
+	err := binary.Read(reader, binary.BigEndian, &statisticsLen)
+	if err != nil {
+		return nil, err
+	}
+	statistics := make([]*TrafficStatistics, 0, statisticsLen)
+	for i := 0; i < int(statisticsLen); i++ {
+		var item TrafficStatistics
+		item.Name, err = rw.ReadVString(reader)
+		if err != nil {
+			return nil, err
+		}
+		counters := make([]int64, 3)
+		err = binary.Read(reader, binary.BigEndian, counters)
+		if err != nil {
+			return nil, err
+		}
+		item.Upload, item.Download, item.Connections = counters[0], counters[1], counters[2]
+		statistics = append(statistics, &item)
+	}
+	return newIterator(statistics), nil
+}
+
+func (c *CommandClient) GetConnectionHistory(limit int32) (ClosedConnectionIterator, error) {
+	conn, err := c.directConnect()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	err = binary.Write(conn, binary.BigEndian, uint8(CommandConnectionHistory))
+	if err != nil {
+		return nil, err
+	}
+	err = binary.Write(conn, binary.BigEndian, limit)
+	if err != nil {
+		return nil, err
+	}
+	err = readError(conn)
+	if err != nil {
+		return nil, err
+	}
+	return readConnectionHistory(conn)
+}
+
+func (s *CommandServer) handleConnectionHistory(conn net.Conn) error {
+	var limit int32
+	err := binary.Read(conn, binary.BigEndian, &limit)
+	if err != nil {
+		return err
+	}
+	trafficManager, err := s.trafficManager()
+	if err != nil {
+		return writeError(conn, err)
+	}
+	history := trafficManager.History(int(limit))
+	err = writeError(conn, nil)
+	if err != nil {
+		return err
+	}
+	return writeConnectionHistory(conn, history)
+}
+
+func writeConnectionHistory(writer io.Writer, history []*trafficontrol.ClosedConnection) error {
+	err := binary.Write(writer, binary.BigEndian, uint32(len(history)))
+	if err != nil {
+		return err
+	}
+	for _, connection := range history {
+		for _, value := range []string{
+			connection.ID,
+			connection.Metadata.NetWork,
+			connection.Metadata.Type,
+			net.JoinHostPort(connection.Metadata.SrcIP.String(), connection.Metadata.SrcPort),
+			net.JoinHostPort(connection.Metadata.DstIP.String(), connection.Metadata.DstPort),
+			connection.Metadata.Host,
+			strings.Join(connection.Chain, " <- "),
+			connection.Rule,
+			connection.Error,
+		} {
+			err = rw.WriteVString(writer, value)
+			if err != nil {
+				return err
+			}
+		}
+		err = binary.Write(writer, binary.BigEndian, []int64{
+			connection.Upload,
+			connection.Download,
+			connection.Start.UnixMilli(),
+			connection.Duration,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readConnectionHistory(reader io.Reader) (ClosedConnectionIterator, error) {
+	var historyLen uint32
+	err := binary.Read(reader, binary.BigEndian, &historyLen)
+	if err != nil {
+		return nil, err
+	}
+	history := make([]*ClosedConnection, 0, historyLen)
+	for i := 0; i < int(historyLen); i++ {
+		var connection ClosedConnection
+		for _, value := range []*string{
+			&connection.ID,
+			&connection.Network,
+			&connection.Inbound,
+			&connection.Source,
+			&connection.Destination,
+			&connection.Host,
+			&connection.Chain,
+			&connection.Rule,
+			&connection.Error,
+		} {
+			*value, err = rw.ReadVString(reader)
+			if err != nil {
+				return nil, err
+			}
+		}
+		counters := make([]int64, 4)
+		err = binary.Read(reader, binary.BigEndian, counters)
+		if err != nil {
+			return nil, err
+		}
+		connection.Upload, connection.Download, connection.StartTime, connection.Duration = counters[0], counters[1], counters[2], counters[3]
+		history = append(history, &connection)
+	}
+	return newIterator(history), nil
+}
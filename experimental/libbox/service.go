@@ -90,6 +90,23 @@ func (s *BoxService) Close() error {
 	return s.instance.Close()
 }
 
+// Reload applies the configuration by replacing only changed components of the running service,
+// false is returned if the configuration can only be applied by restarting the service.
+func (s *BoxService) Reload(configContent string) (bool, error) {
+	options, err := parseConfig(configContent)
+	if err != nil {
+		return false, err
+	}
+	plan, err := s.instance.Reload(options)
+	if plan != nil && plan.NeedRestart() {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
 func (s *BoxService) NeedWIFIState() bool {
 	return s.instance.Router().NeedWIFIState()
 }
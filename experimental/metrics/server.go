@@ -0,0 +1,236 @@
+package metrics
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net"
+	"net/http"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/urltest"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/atomic"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/service"
+)
+
+var _ adapter.MetricsServer = (*Server)(nil)
+
+type Server struct {
+	ctx         context.Context
+	router      adapter.Router
+	logger      log.Logger
+	httpServer  *http.Server
+	secret      string
+	ruleSetTags []string
+	startTime   time.Time
+
+	access   sync.Mutex
+	counters map[string]map[string]*trafficCounter
+
+	dnsQueries   atomic.Int64
+	dnsCacheHits atomic.Int64
+	dnsFailures  atomic.Int64
+}
+
+func NewServer(ctx context.Context, router adapter.Router, logger log.Logger, options option.MetricsOptions, ruleSetTags []string) (*Server, error) {
+	if options.Listen == "" {
+		return nil, E.New("missing listen address")
+	}
+	path := options.Path
+	if path == "" {
+		path = "/metrics"
+	}
+	server := &Server{
+		ctx:         ctx,
+		router:      router,
+		logger:      logger,
+		secret:      options.Secret,
+		ruleSetTags: ruleSetTags,
+		startTime:   time.Now(),
+		counters:    make(map[string]map[string]*trafficCounter),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(path, server.serveMetrics)
+	server.httpServer = &http.Server{
+		Addr:    options.Listen,
+		Handler: mux,
+	}
+	return server, nil
+}
+
+func (s *Server) Start() error {
+	listener, err := net.Listen("tcp", s.httpServer.Addr)
+	if err != nil {
+		return E.Cause(err, "metrics listen error")
+	}
+	s.logger.Info("metrics listening at ", listener.Addr())
+	go func() {
+		err = s.httpServer.Serve(listener)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.logger.Error("metrics serve error: ", err)
+		}
+	}()
+	return nil
+}
+
+func (s *Server) Close() error {
+	return common.Close(common.PtrOrNil(s.httpServer))
+}
+
+func (s *Server) serveMetrics(w http.ResponseWriter, r *http.Request) {
+	if s.secret != "" && subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+s.secret)) != 1 {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	var buffer writer
+	s.writeInfo(&buffer)
+	s.writeTraffic(&buffer)
+	s.writeDNS(&buffer)
+	s.writeURLTest(&buffer)
+	s.writeRuleSet(&buffer)
+	s.writeRuntime(&buffer)
+	buffer.end()
+	w.Header().Set("Content-Type", contentType)
+	_, _ = w.Write(buffer.Bytes())
+}
+
+func (s *Server) writeInfo(w *writer) {
+	w.family("sing_box_build", "info", "Build information.")
+	w.sample("sing_box_build_info", 1, "version", C.Version, "go_version", runtime.Version())
+	w.family("sing_box_start_time_seconds", "gauge", "Start time since unix epoch in seconds.")
+	w.sample("sing_box_start_time_seconds", float64(s.startTime.UnixNano())/1e9)
+}
+
+func (s *Server) writeTraffic(w *writer) {
+	s.access.Lock()
+	defer s.access.Unlock()
+	for _, kind := range []string{kindInbound, kindOutbound, kindUser} {
+		counters := s.counters[kind]
+		names := sortedKeys(counters)
+		prefix := "sing_box_" + kind + "_"
+		families := []struct {
+			name       string
+			metricType string
+			help       string
+			load       func(counter *trafficCounter) int64
+		}{
+			{"upload_bytes", "counter", "Bytes uploaded.", func(counter *trafficCounter) int64 { return counter.upload.Load() }},
+			{"download_bytes", "counter", "Bytes downloaded.", func(counter *trafficCounter) int64 { return counter.download.Load() }},
+			{"connections", "counter", "Connections routed.", func(counter *trafficCounter) int64 { return counter.connections.Load() }},
+			{"active_connections", "gauge", "Connections currently open.", func(counter *trafficCounter) int64 { return counter.activeConnections.Load() }},
+			{"failed_connections", "counter", "Connections failed to establish.", func(counter *trafficCounter) int64 { return counter.failedConnections.Load() }},
+		}
+		for _, family := range families {
+			name := prefix + family.name
+			w.family(name, family.metricType, family.help)
+			sampleName := name
+			if family.metricType == "counter" {
+				sampleName += "_total"
+			}
+			for _, tag := range names {
+				w.sample(sampleName, float64(family.load(counters[tag])), kind, tag)
+			}
+		}
+	}
+}
+
+func (s *Server) writeDNS(w *writer) {
+	w.family("sing_box_dns_queries", "counter", "DNS queries.")
+	w.sample("sing_box_dns_queries_total", float64(s.dnsQueries.Load()))
+	w.family("sing_box_dns_cache_hits", "counter", "DNS queries answered from cache.")
+	w.sample("sing_box_dns_cache_hits_total", float64(s.dnsCacheHits.Load()))
+	w.family("sing_box_dns_failures", "counter", "DNS queries failed.")
+	w.sample("sing_box_dns_failures_total", float64(s.dnsFailures.Load()))
+}
+
+func (s *Server) historyStorage() *urltest.HistoryStorage {
+	if historyStorage := service.PtrFromContext[urltest.HistoryStorage](s.ctx); historyStorage != nil {
+		return historyStorage
+	}
+	if clashServer := s.router.ClashServer(); clashServer != nil {
+		return clashServer.HistoryStorage()
+	}
+	return nil
+}
+
+func (s *Server) writeURLTest(w *writer) {
+	historyStorage := s.historyStorage()
+	if historyStorage == nil {
+		return
+	}
+	w.family("sing_box_urltest_delay_milliseconds", "gauge", "Latest URL test delay.")
+	var timestamps []float64
+	var tags []string
+	for _, outbound := range s.router.Outbounds() {
+		history := historyStorage.LoadURLTestHistory(outbound.Tag())
+		if history == nil {
+			continue
+		}
+		w.sample("sing_box_urltest_delay_milliseconds", float64(history.Delay), "outbound", outbound.Tag())
+		tags = append(tags, outbound.Tag())
+		timestamps = append(timestamps, float64(history.Time.Unix()))
+	}
+	w.family("sing_box_urltest_timestamp_seconds", "gauge", "Time of the latest URL test since unix epoch in seconds.")
+	for i, tag := range tags {
+		w.sample("sing_box_urltest_timestamp_seconds", timestamps[i], "outbound", tag)
+	}
+}
+
+func (s *Server) writeRuleSet(w *writer) {
+	var ruleSets []adapter.UpdatableRuleSet
+	var tags []string
+	for _, tag := range s.ruleSetTags {
+		ruleSet, loaded := s.router.RuleSet(tag)
+		if !loaded {
+			continue
+		}
+		updatableRuleSet, isUpdatable := ruleSet.(adapter.UpdatableRuleSet)
+		if !isUpdatable {
+			continue
+		}
+		ruleSets = append(ruleSets, updatableRuleSet)
+		tags = append(tags, tag)
+	}
+	if len(ruleSets) == 0 {
+		return
+	}
+	w.family("sing_box_rule_set_last_updated_timestamp_seconds", "gauge", "Time of the last successful rule-set update since unix epoch in seconds.")
+	for i, ruleSet := range ruleSets {
+		var timestamp float64
+		if lastUpdated := ruleSet.LastUpdated(); !lastUpdated.IsZero() {
+			timestamp = float64(lastUpdated.Unix())
+		}
+		w.sample("sing_box_rule_set_last_updated_timestamp_seconds", timestamp, "rule_set", tags[i])
+	}
+	w.family("sing_box_rule_set_update_success", "gauge", "Whether the last rule-set update succeeded.")
+	for i, ruleSet := range ruleSets {
+		var success float64
+		if ruleSet.LastUpdateError() == nil {
+			success = 1
+		}
+		w.sample("sing_box_rule_set_update_success", success, "rule_set", tags[i])
+	}
+}
+
+func (s *Server) writeRuntime(w *writer) {
+	var memStats runtime.MemStats
+	runtime.ReadMemStats(&memStats)
+	w.family("go_goroutines", "gauge", "Number of goroutines that currently exist.")
+	w.sample("go_goroutines", float64(runtime.NumGoroutine()))
+	w.family("go_memstats_heap_inuse_bytes", "gauge", "Number of heap bytes that are in use.")
+	w.sample("go_memstats_heap_inuse_bytes", float64(memStats.HeapInuse))
+	w.family("go_memstats_stack_inuse_bytes", "gauge", "Number of bytes in use by the stack allocator.")
+	w.sample("go_memstats_stack_inuse_bytes", float64(memStats.StackInuse))
+	w.family("go_memstats_sys_bytes", "gauge", "Number of bytes obtained from system.")
+	w.sample("go_memstats_sys_bytes", float64(memStats.Sys))
+	w.family("go_gc_cycles", "counter", "Number of completed GC cycles.")
+	w.sample("go_gc_cycles_total", float64(memStats.NumGC))
+}
@@ -0,0 +1,126 @@
+package metrics
+
+import (
+	"context"
+	"net"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing/common/atomic"
+	"github.com/sagernet/sing/common/bufio"
+	E "github.com/sagernet/sing/common/exceptions"
+	N "github.com/sagernet/sing/common/network"
+)
+
+const (
+	kindInbound  = "inbound"
+	kindOutbound = "outbound"
+	kindUser     = "user"
+)
+
+type trafficCounter struct {
+	upload            atomic.Int64
+	download          atomic.Int64
+	connections       atomic.Int64
+	activeConnections atomic.Int64
+	failedConnections atomic.Int64
+}
+
+var _ adapter.Tracker = (*tracker)(nil)
+
+type tracker struct {
+	counters []*trafficCounter
+	left     atomic.Bool
+}
+
+func (t *tracker) Leave() {
+	if !t.left.CompareAndSwap(false, true) {
+		return
+	}
+	for _, counter := range t.counters {
+		counter.activeConnections.Add(-1)
+	}
+}
+
+func (t *tracker) SetError(err error) {
+	if E.IsClosedOrCanceled(err) {
+		return
+	}
+	for _, counter := range t.counters {
+		counter.failedConnections.Add(1)
+	}
+}
+
+func (s *Server) loadOrCreateCounter(kind string, name string) *trafficCounter {
+	counters := s.counters[kind]
+	if counters == nil {
+		counters = make(map[string]*trafficCounter)
+		s.counters[kind] = counters
+	}
+	counter := counters[name]
+	if counter == nil {
+		counter = new(trafficCounter)
+		counters[name] = counter
+	}
+	return counter
+}
+
+// finalOutbound resolves outbound groups to the outbound actually in use.
+func (s *Server) finalOutbound(outbound adapter.Outbound) string {
+	for {
+		group, isGroup := outbound.(adapter.OutboundGroup)
+		if !isGroup {
+			return outbound.Tag()
+		}
+		next, loaded := s.router.Outbound(group.Now())
+		if !loaded {
+			return group.Now()
+		}
+		outbound = next
+	}
+}
+
+func (s *Server) newTracker(metadata adapter.InboundContext, outbound adapter.Outbound) (*tracker, []*atomic.Int64, []*atomic.Int64) {
+	names := [][2]string{
+		{kindInbound, metadata.Inbound},
+		{kindOutbound, s.finalOutbound(outbound)},
+		{kindUser, metadata.User},
+	}
+	t := new(tracker)
+	var readCounters, writeCounters []*atomic.Int64
+	s.access.Lock()
+	for _, name := range names {
+		if name[1] == "" {
+			continue
+		}
+		counter := s.loadOrCreateCounter(name[0], name[1])
+		t.counters = append(t.counters, counter)
+		readCounters = append(readCounters, &counter.upload)
+		writeCounters = append(writeCounters, &counter.download)
+	}
+	s.access.Unlock()
+	for _, counter := range t.counters {
+		counter.connections.Add(1)
+		counter.activeConnections.Add(1)
+	}
+	return t, readCounters, writeCounters
+}
+
+func (s *Server) RoutedConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext, outbound adapter.Outbound) (net.Conn, adapter.Tracker) {
+	t, readCounters, writeCounters := s.newTracker(metadata, outbound)
+	return bufio.NewInt64CounterConn(conn, readCounters, writeCounters), t
+}
+
+func (s *Server) RoutedPacketConnection(ctx context.Context, conn N.PacketConn, metadata adapter.InboundContext, outbound adapter.Outbound) (N.PacketConn, adapter.Tracker) {
+	t, readCounters, writeCounters := s.newTracker(metadata, outbound)
+	return bufio.NewInt64CounterPacketConn(conn, readCounters, writeCounters), t
+}
+
+func (s *Server) DNSQuery(cached bool, err error) {
+	s.dnsQueries.Add(1)
+	if cached {
+		s.dnsCacheHits.Add(1)
+	}
+	if err != nil {
+		s.dnsFailures.Add(1)
+	}
+}
@@ -112,6 +112,31 @@ func (s *StatsService) RoutedPacketConnection(inbound string, outbound string, u
 	return bufio.NewInt64CounterPacketConn(conn, readCounter, writeCounter)
 }
 
+// RelayedConnection counts traffic of a relay inbound to the upstream server of a destination.
+func (s *StatsService) RelayedConnection(inbound string, destination string, server string, conn net.Conn) net.Conn {
+	if !s.inbounds[inbound] {
+		return conn
+	}
+	name := "relay>>>" + inbound + ">>>" + destination + ">>>" + server + ">>>traffic>>>"
+	s.access.Lock()
+	readCounter := s.loadOrCreateCounter(name + "uplink")
+	writeCounter := s.loadOrCreateCounter(name + "downlink")
+	s.access.Unlock()
+	return bufio.NewInt64CounterConn(conn, []*atomic.Int64{readCounter}, []*atomic.Int64{writeCounter})
+}
+
+func (s *StatsService) RelayedPacketConnection(inbound string, destination string, server string, conn N.PacketConn) N.PacketConn {
+	if !s.inbounds[inbound] {
+		return conn
+	}
+	name := "relay>>>" + inbound + ">>>" + destination + ">>>" + server + ">>>traffic>>>"
+	s.access.Lock()
+	readCounter := s.loadOrCreateCounter(name + "uplink")
+	writeCounter := s.loadOrCreateCounter(name + "downlink")
+	s.access.Unlock()
+	return bufio.NewInt64CounterPacketConn(conn, []*atomic.Int64{readCounter}, []*atomic.Int64{writeCounter})
+}
+
 func (s *StatsService) GetStats(ctx context.Context, request *GetStatsRequest) (*GetStatsResponse, error) {
 	s.access.Lock()
 	counter, loaded := s.counters[request.Name]
@@ -48,6 +48,10 @@ func New(ctx context.Context, router adapter.Router, logger log.ContextLogger, o
 		return NewTUIC(ctx, router, logger, options.Tag, options.TUICOptions)
 	case C.TypeHysteria2:
 		return NewHysteria2(ctx, router, logger, options.Tag, options.Hysteria2Options)
+	case C.TypeWireGuard:
+		return NewWireGuard(ctx, router, logger, options.Tag, options.WireGuardOptions)
+	case C.TypeDemux:
+		return NewDemux(ctx, router, logger, options.Tag, options.DemuxOptions)
 	
 	default:
 		return nil, E.New("unknown inbound type: ", options.Type)
@@ -5,10 +5,12 @@ import (
 	"net"
 
 	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/proxyproto"
 	"github.com/sagernet/sing-box/common/settings"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/log"
 	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing-box/transport/sip003"
 	"github.com/sagernet/sing/common"
 	"github.com/sagernet/sing/common/atomic"
 	E "github.com/sagernet/sing/common/exceptions"
@@ -30,6 +32,7 @@ type myInboundAdapter struct {
 	packetHandler    adapter.PacketHandler
 	oobPacketHandler adapter.OOBPacketHandler
 	packetUpstream   any
+	tcpPlugin        sip003.ServerPlugin
 
 	// http mixed
 
@@ -164,6 +167,9 @@ func (a *myInboundAdapter) createMetadata(conn net.Conn, metadata adapter.Inboun
 	if tcpConn, isTCP := common.Cast[*net.TCPConn](conn); isTCP {
 		metadata.OriginDestination = M.SocksaddrFromNet(tcpConn.LocalAddr()).Unwrap()
 	}
+	if proxyConn, isProxyConn := common.Cast[*proxyproto.Conn](conn); isProxyConn {
+		metadata.ProxyProtocol = proxyConn.Info
+	}
 	return metadata
 }
 
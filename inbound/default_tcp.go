@@ -15,8 +15,22 @@ import (
 )
 
 func (a *myInboundAdapter) ListenTCP() (net.Listener, error) {
-	var err error
 	bindAddr := M.SocksaddrFrom(a.listenOptions.Listen.Build(), a.listenOptions.ListenPort)
+	var (
+		tcpListener net.Listener
+		err         error
+	)
+	if a.tcpPlugin != nil {
+		tcpListener, err = a.tcpPlugin.Listen(a.ctx, bindAddr, a.listenTCP)
+	} else {
+		tcpListener, err = a.listenTCP(bindAddr)
+	}
+	a.tcpListener = tcpListener
+	return tcpListener, err
+}
+
+func (a *myInboundAdapter) listenTCP(bindAddr M.Socksaddr) (net.Listener, error) {
+	var err error
 	var tcpListener net.Listener
 	var listenConfig net.ListenConfig
 	// TODO: Add an option to customize the keep alive period
@@ -41,12 +55,9 @@ func (a *myInboundAdapter) ListenTCP() (net.Listener, error) {
 	}
 	//Hiddify
 	if a.listenOptions.ProxyProtocol || a.listenOptions.ProxyProtocolAcceptNoHeader {
-		a.logger.Warn("Proxy Protocol is deprecated, see https://sing-box.sagernet.org/deprecated")
-		// return nil, E.New("Proxy Protocol is deprecated and removed in sing-box 1.6.0")
 		tcpListener = &proxyproto.Listener{Listener: tcpListener, AcceptNoHeader: a.listenOptions.ProxyProtocolAcceptNoHeader}
 	}
 	//Hiddify
-	a.tcpListener = tcpListener
 	return tcpListener, err
 }
 
@@ -0,0 +1,309 @@
+package inbound
+
+import (
+	std_bufio "bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/sniff"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/buf"
+	"github.com/sagernet/sing/common/bufio"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+)
+
+var _ adapter.InjectableInbound = (*Demux)(nil)
+
+// Demux shares one listener between inbounds by peeking the first packet of connections.
+type Demux struct {
+	myInboundAdapter
+	inboundRouter adapter.Router
+	rules         []demuxRule
+	fallback      *demuxTarget
+}
+
+type demuxRule struct {
+	protocol   []string
+	serverName []string
+	alpn       []string
+	host       []string
+	path       []string
+	target     demuxTarget
+}
+
+type demuxTarget struct {
+	inboundTag  string
+	inbound     adapter.InjectableInbound
+	destination M.Socksaddr
+}
+
+// demuxMetadata is what is known about a connection after peeking.
+type demuxMetadata struct {
+	protocol   string
+	serverName string
+	alpn       []string
+	host       string
+	path       string
+}
+
+func NewDemux(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.DemuxInboundOptions) (*Demux, error) {
+	inbound := &Demux{
+		myInboundAdapter: myInboundAdapter{
+			protocol:      C.TypeDemux,
+			network:       []string{N.NetworkTCP},
+			ctx:           ctx,
+			router:        router,
+			logger:        logger,
+			tag:           tag,
+			listenOptions: options.ListenOptions,
+		},
+		inboundRouter: router,
+	}
+	if len(options.Rules) == 0 && options.Fallback == nil {
+		return nil, E.New("missing rules")
+	}
+	for i, ruleOptions := range options.Rules {
+		target, err := newDemuxTarget(ruleOptions.DemuxTarget)
+		if err != nil {
+			return nil, E.Cause(err, "rules[", i, "]")
+		}
+		inbound.rules = append(inbound.rules, demuxRule{
+			protocol:   ruleOptions.Protocol,
+			serverName: ruleOptions.ServerName,
+			alpn:       ruleOptions.ALPN,
+			host:       ruleOptions.Host,
+			path:       ruleOptions.Path,
+			target:     target,
+		})
+	}
+	if options.Fallback != nil {
+		target, err := newDemuxTarget(*options.Fallback)
+		if err != nil {
+			return nil, E.Cause(err, "fallback")
+		}
+		inbound.fallback = &target
+	}
+	inbound.connHandler = inbound
+	return inbound, nil
+}
+
+func newDemuxTarget(options option.DemuxTarget) (demuxTarget, error) {
+	target := demuxTarget{
+		inboundTag: options.Inbound,
+	}
+	if options.Server != "" {
+		target.destination = options.ServerOptions.Build()
+		if !target.destination.IsValid() {
+			return demuxTarget{}, E.New("invalid server address: ", target.destination)
+		}
+	}
+	if (target.inboundTag == "") == (options.Server == "") {
+		return demuxTarget{}, E.New("either inbound or server must be set")
+	}
+	return target, nil
+}
+
+func (d *Demux) Start() error {
+	for i := range d.rules {
+		err := d.resolveTarget(&d.rules[i].target)
+		if err != nil {
+			return err
+		}
+	}
+	if d.fallback != nil {
+		err := d.resolveTarget(d.fallback)
+		if err != nil {
+			return err
+		}
+	}
+	return d.myInboundAdapter.Start()
+}
+
+func (d *Demux) resolveTarget(target *demuxTarget) error {
+	if target.inboundTag == "" {
+		return nil
+	}
+	if target.inboundTag == d.tag {
+		return E.New("demux to itself: ", d.tag)
+	}
+	inbound, loaded := d.inboundRouter.Inbound(target.inboundTag)
+	if !loaded {
+		return E.New("inbound not found: ", target.inboundTag)
+	}
+	injectable, isInjectable := inbound.(adapter.InjectableInbound)
+	if !isInjectable {
+		return E.New("inbound is not injectable: ", target.inboundTag)
+	}
+	if !common.Contains(injectable.Network(), N.NetworkTCP) {
+		return E.New("inbound does not support TCP: ", target.inboundTag)
+	}
+	target.inbound = injectable
+	return nil
+}
+
+func (d *Demux) NewConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext) error {
+	var demuxMetadata demuxMetadata
+	buffer := buf.NewPacket()
+	_, err := sniff.PeekStream(ctx, conn, buffer, time.Duration(d.listenOptions.SniffTimeout),
+		demuxMetadata.sniffTLS,
+		demuxMetadata.sniffHTTP,
+		demuxMetadata.sniffStream(sniff.SSH),
+		demuxMetadata.sniffStream(sniff.RDP),
+	)
+	if err != nil {
+		d.logger.TraceContext(ctx, "sniffed no protocol: ", err)
+	}
+	if !buffer.IsEmpty() {
+		conn = bufio.NewCachedConn(conn, buffer)
+	} else {
+		buffer.Release()
+	}
+	target := d.fallback
+	for i := range d.rules {
+		if d.rules[i].match(&demuxMetadata) {
+			target = &d.rules[i].target
+			break
+		}
+	}
+	if target == nil {
+		return E.New("no matching rule for ", demuxMetadata)
+	}
+	if target.inbound == nil {
+		d.logger.InfoContext(ctx, "fallback connection to ", target.destination)
+		metadata.Destination = target.destination
+		return d.router.RouteConnection(ctx, conn, metadata)
+	}
+	d.logger.DebugContext(ctx, "demux ", demuxMetadata, " to ", target.inboundTag)
+	metadata.LastInbound = metadata.Inbound
+	metadata.Inbound = target.inboundTag
+	metadata.InboundType = target.inbound.Type()
+	metadata.InboundDetour = ""
+	return target.inbound.NewConnection(contextWithDemux(ctx), conn, metadata)
+}
+
+type demuxKey struct{}
+
+// contextWithDemux marks connections handed over by a demux inbound,
+// inbounds with a V2Ray transport serve them through the transport.
+func contextWithDemux(ctx context.Context) context.Context {
+	return context.WithValue(ctx, demuxKey{}, true)
+}
+
+func isDemuxContext(ctx context.Context) bool {
+	return ctx.Value(demuxKey{}) != nil
+}
+
+func (d *Demux) NewPacketConnection(ctx context.Context, conn N.PacketConn, metadata adapter.InboundContext) error {
+	return E.New("demux: UDP unsupported")
+}
+
+func (m *demuxMetadata) sniffTLS(ctx context.Context, reader io.Reader) (*adapter.InboundContext, error) {
+	var clientHello *tls.ClientHelloInfo
+	err := tls.Server(bufio.NewReadOnlyConn(reader), &tls.Config{
+		GetConfigForClient: func(argHello *tls.ClientHelloInfo) (*tls.Config, error) {
+			clientHello = argHello
+			return nil, nil
+		},
+	}).HandshakeContext(ctx)
+	if clientHello == nil {
+		return nil, err
+	}
+	m.protocol = C.ProtocolTLS
+	m.serverName = clientHello.ServerName
+	m.alpn = clientHello.SupportedProtos
+	return &adapter.InboundContext{Protocol: C.ProtocolTLS, Domain: clientHello.ServerName}, nil
+}
+
+func (m *demuxMetadata) sniffHTTP(ctx context.Context, reader io.Reader) (*adapter.InboundContext, error) {
+	request, err := http.ReadRequest(std_bufio.NewReader(reader))
+	if err != nil {
+		return nil, err
+	}
+	m.protocol = C.ProtocolHTTP
+	m.host = M.ParseSocksaddr(request.Host).AddrString()
+	m.path = request.URL.Path
+	return &adapter.InboundContext{Protocol: C.ProtocolHTTP, Domain: m.host}, nil
+}
+
+// sniffStream records the protocol found by a sniffer without further metadata.
+func (m *demuxMetadata) sniffStream(sniffer sniff.StreamSniffer) sniff.StreamSniffer {
+	return func(ctx context.Context, reader io.Reader) (*adapter.InboundContext, error) {
+		metadata, err := sniffer(ctx, reader)
+		if metadata != nil {
+			m.protocol = metadata.Protocol
+		}
+		return metadata, err
+	}
+}
+
+func (m demuxMetadata) String() string {
+	var description bytes.Buffer
+	if m.protocol == "" {
+		description.WriteString("unknown protocol")
+	} else {
+		description.WriteString(m.protocol)
+	}
+	if m.serverName != "" {
+		description.WriteString(" sni=" + m.serverName)
+	}
+	if len(m.alpn) > 0 {
+		description.WriteString(" alpn=" + strings.Join(m.alpn, ","))
+	}
+	if m.host != "" {
+		description.WriteString(" host=" + m.host)
+	}
+	if m.path != "" {
+		description.WriteString(" path=" + m.path)
+	}
+	return description.String()
+}
+
+func (r *demuxRule) match(metadata *demuxMetadata) bool {
+	if len(r.protocol) > 0 && !common.Contains(r.protocol, metadata.protocol) {
+		return false
+	}
+	if len(r.serverName) > 0 && !common.Any(r.serverName, func(it string) bool {
+		return matchDemuxDomain(it, metadata.serverName)
+	}) {
+		return false
+	}
+	if len(r.alpn) > 0 && !common.Any(r.alpn, func(it string) bool {
+		return common.Contains(metadata.alpn, it)
+	}) {
+		return false
+	}
+	if len(r.host) > 0 && !common.Any(r.host, func(it string) bool {
+		return matchDemuxDomain(it, metadata.host)
+	}) {
+		return false
+	}
+	if len(r.path) > 0 && !common.Any(r.path, func(it string) bool {
+		return metadata.path != "" && strings.HasPrefix(metadata.path, it)
+	}) {
+		return false
+	}
+	return true
+}
+
+// matchDemuxDomain matches domain against pattern, a "*." prefix in pattern matches any subdomain.
+func matchDemuxDomain(pattern string, domain string) bool {
+	if domain == "" {
+		return false
+	}
+	if strings.HasPrefix(pattern, "*.") {
+		return strings.HasSuffix(strings.ToLower(domain), strings.ToLower(pattern[1:]))
+	}
+	return strings.EqualFold(pattern, domain)
+}
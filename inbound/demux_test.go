@@ -0,0 +1,62 @@
+package inbound
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"testing"
+
+	"github.com/sagernet/sing-box/common/sniff"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDemuxRule(t *testing.T) {
+	t.Parallel()
+	var metadata demuxMetadata
+	_, err := metadata.sniffHTTP(context.Background(), bytes.NewReader([]byte("GET /ws/path HTTP/1.1\r\nHost: www.example.com:443\r\n\r\n")))
+	require.NoError(t, err)
+	require.Equal(t, "www.example.com", metadata.host)
+	require.Equal(t, "/ws/path", metadata.path)
+
+	require.True(t, (&demuxRule{protocol: []string{"http"}, path: []string{"/ws"}}).match(&metadata))
+	require.True(t, (&demuxRule{host: []string{"*.example.com"}}).match(&metadata))
+	require.False(t, (&demuxRule{host: []string{"example.com"}}).match(&metadata))
+	require.False(t, (&demuxRule{path: []string{"/grpc"}}).match(&metadata))
+	require.False(t, (&demuxRule{serverName: []string{"www.example.com"}}).match(&metadata))
+
+	metadata = demuxMetadata{protocol: "tls", serverName: "a.example.com", alpn: []string{"h2", "http/1.1"}}
+	require.True(t, (&demuxRule{serverName: []string{"a.example.com"}, alpn: []string{"h2"}}).match(&metadata))
+	require.False(t, (&demuxRule{alpn: []string{"h3"}}).match(&metadata))
+	require.True(t, (&demuxRule{}).match(&demuxMetadata{}))
+}
+
+func TestDemuxSniffSSH(t *testing.T) {
+	t.Parallel()
+	var metadata demuxMetadata
+	_, err := metadata.sniffStream(sniff.SSH)(context.Background(), bytes.NewReader([]byte("SSH-2.0-OpenSSH_9.6\r\n")))
+	require.NoError(t, err)
+	require.True(t, (&demuxRule{protocol: []string{"ssh"}}).match(&metadata))
+}
+
+func TestInjectListener(t *testing.T) {
+	t.Parallel()
+	listener := newInjectListener(nil)
+	serverConn, clientConn := net.Pipe()
+	defer clientConn.Close()
+	require.NoError(t, listener.Inject(serverConn))
+	conn, err := listener.Accept()
+	require.NoError(t, err)
+	require.Equal(t, serverConn, conn)
+
+	serverConn, clientConn = net.Pipe()
+	require.NoError(t, listener.Inject(serverConn))
+	require.NoError(t, listener.Close())
+	_, err = clientConn.Read(make([]byte, 1))
+	require.Error(t, err)
+	require.ErrorIs(t, listener.Inject(serverConn), net.ErrClosed)
+	_, err = listener.Accept()
+	require.ErrorIs(t, err, net.ErrClosed)
+	require.False(t, isDemuxContext(context.Background()))
+	require.True(t, isDemuxContext(contextWithDemux(context.Background())))
+}
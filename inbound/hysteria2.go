@@ -8,28 +8,42 @@ import (
 	"net/http"
 	"net/http/httputil"
 	"net/url"
+	"sync"
 	"time"
 
 	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/porthopping"
+	"github.com/sagernet/sing-box/common/ratelimit"
 	"github.com/sagernet/sing-box/common/tls"
+	"github.com/sagernet/sing-box/common/udpobfs"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/log"
 	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing-box/transport/hysteria2"
 	"github.com/sagernet/sing-quic/hysteria"
-	"github.com/sagernet/sing-quic/hysteria2"
 	"github.com/sagernet/sing/common"
 	"github.com/sagernet/sing/common/auth"
 	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
 	N "github.com/sagernet/sing/common/network"
 )
 
-var _ adapter.Inbound = (*Hysteria2)(nil)
+var (
+	_ adapter.Inbound     = (*Hysteria2)(nil)
+	_ adapter.UserManager = (*Hysteria2)(nil)
+)
 
 type Hysteria2 struct {
 	myInboundAdapter
-	tlsConfig    tls.ServerConfig
-	service      *hysteria2.Service[int]
-	userNameList []string
+	tlsConfig tls.ServerConfig
+	service   *hysteria2.Service[int]
+	users     *userManager[option.Hysteria2User]
+	ports     []uint16
+	hopConn   *porthopping.ServerConn
+	udpObfs   *udpobfs.Obfuscator
+
+	limiterAccess sync.RWMutex
+	limiters      map[int]ratelimit.Limiters
 }
 
 func NewHysteria2(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.Hysteria2InboundOptions) (*Hysteria2, error) {
@@ -53,6 +67,24 @@ func NewHysteria2(ctx context.Context, router adapter.Router, logger log.Context
 			return nil, E.New("unknown obfs type: ", options.Obfs.Type)
 		}
 	}
+	var ports []uint16
+	if len(options.ListenPorts) > 0 {
+		ports, err = porthopping.ParseListenPorts(options.ListenPorts)
+		if err != nil {
+			return nil, E.Cause(err, "parse listen_ports")
+		}
+	}
+	switch options.CongestionControl {
+	case "":
+	case hysteria2.CongestionControlBBR, hysteria2.CongestionControlNewReno:
+		options.IgnoreClientBandwidth = true
+	case hysteria2.CongestionControlBrutal:
+		if options.IgnoreClientBandwidth {
+			return nil, E.New("brutal congestion control conflicts with ignore_client_bandwidth")
+		}
+	default:
+		return nil, E.New("unsupported congestion control for hysteria2: ", options.CongestionControl)
+	}
 	var masqueradeHandler http.Handler
 	if options.Masquerade != "" {
 		masqueradeURL, err := url.Parse(options.Masquerade)
@@ -87,6 +119,14 @@ func NewHysteria2(ctx context.Context, router adapter.Router, logger log.Context
 			listenOptions: options.ListenOptions,
 		},
 		tlsConfig: tlsConfig,
+		ports:     ports,
+		limiters:  make(map[int]ratelimit.Limiters),
+	}
+	if options.UDPObfs != nil {
+		inbound.udpObfs, err = udpobfs.NewObfuscator(*options.UDPObfs)
+		if err != nil {
+			return nil, err
+		}
 	}
 	var udpTimeout time.Duration
 	if options.UDPTimeout != 0 {
@@ -98,6 +138,7 @@ func NewHysteria2(ctx context.Context, router adapter.Router, logger log.Context
 		Context:               ctx,
 		Logger:                logger,
 		BrutalDebug:           options.BrutalDebug,
+		CongestionControl:     options.CongestionControl,
 		SendBPS:               uint64(options.UpMbps * hysteria.MbpsToBps),
 		ReceiveBPS:            uint64(options.DownMbps * hysteria.MbpsToBps),
 		SalamanderPassword:    salamanderPassword,
@@ -110,17 +151,23 @@ func NewHysteria2(ctx context.Context, router adapter.Router, logger log.Context
 	if err != nil {
 		return nil, err
 	}
-	userList := make([]int, 0, len(options.Users))
-	userNameList := make([]string, 0, len(options.Users))
-	userPasswordList := make([]string, 0, len(options.Users))
-	for index, user := range options.Users {
-		userList = append(userList, index)
-		userNameList = append(userNameList, user.Name)
-		userPasswordList = append(userPasswordList, user.Password)
+	inbound.users, err = newUserManager(options.Users, func(it option.Hysteria2User) string {
+		return it.Name
+	}, func(indexes []int, users []option.Hysteria2User) error {
+		service.UpdateUsers(indexes, common.Map(users, func(it option.Hysteria2User) string {
+			return it.Password
+		}))
+		inbound.updateLimiters(indexes, users)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	inbound.users.limiter, err = newUserLimiter(ctx, logger, tag, options.UserLimits)
+	if err != nil {
+		return nil, err
 	}
-	service.UpdateUsers(userList, userPasswordList)
 	inbound.service = service
-	inbound.userNameList = userNameList
 	return inbound, nil
 }
 
@@ -129,12 +176,20 @@ func (h *Hysteria2) newConnection(ctx context.Context, conn net.Conn, metadata a
 	metadata = h.createMetadata(conn, metadata)
 	h.logger.InfoContext(ctx, "inbound connection from ", metadata.Source)
 	userID, _ := auth.UserFromContext[int](ctx)
-	if userName := h.userNameList[userID]; userName != "" {
+	if userName, _ := h.users.Name(userID); userName != "" {
 		metadata.User = userName
 		h.logger.InfoContext(ctx, "[", userName, "] inbound connection to ", metadata.Destination)
 	} else {
 		h.logger.InfoContext(ctx, "inbound connection to ", metadata.Destination)
 	}
+	conn, done, err := h.users.NewConnection(userID, conn, metadata.Source)
+	if err != nil {
+		return err
+	}
+	defer done()
+	if limiters, loaded := h.loadLimiters(userID); loaded {
+		conn = limiters.Conn(conn)
+	}
 	return h.router.RouteConnection(ctx, conn, metadata)
 }
 
@@ -143,12 +198,20 @@ func (h *Hysteria2) newPacketConnection(ctx context.Context, conn N.PacketConn,
 	metadata = h.createPacketMetadata(conn, metadata)
 	h.logger.InfoContext(ctx, "inbound packet connection from ", metadata.Source)
 	userID, _ := auth.UserFromContext[int](ctx)
-	if userName := h.userNameList[userID]; userName != "" {
+	if userName, _ := h.users.Name(userID); userName != "" {
 		metadata.User = userName
 		h.logger.InfoContext(ctx, "[", userName, "] inbound packet connection to ", metadata.Destination)
 	} else {
 		h.logger.InfoContext(ctx, "inbound packet connection to ", metadata.Destination)
 	}
+	conn, done, err := h.users.NewPacketConnection(userID, conn, metadata.Source)
+	if err != nil {
+		return err
+	}
+	defer done()
+	if limiters, loaded := h.loadLimiters(userID); loaded {
+		conn = limiters.PacketConn(conn)
+	}
 	return h.router.RoutePacketConnection(ctx, conn, metadata)
 }
 
@@ -159,17 +222,95 @@ func (h *Hysteria2) Start() error {
 			return err
 		}
 	}
+	if len(h.ports) > 0 {
+		hopConn, err := h.listenPorts()
+		if err != nil {
+			return err
+		}
+		h.hopConn = hopConn
+		return h.service.Start(h.obfsPacketConn(hopConn))
+	}
 	packetConn, err := h.myInboundAdapter.ListenUDP()
 	if err != nil {
 		return err
 	}
-	return h.service.Start(packetConn)
+	return h.service.Start(h.obfsPacketConn(packetConn))
+}
+
+func (h *Hysteria2) obfsPacketConn(conn net.PacketConn) net.PacketConn {
+	if h.udpObfs == nil {
+		return conn
+	}
+	return udpobfs.NewPacketConn(conn, h.udpObfs)
+}
+
+// listenPorts listens on listen_port and every port of listen_ports for port hopping clients.
+func (h *Hysteria2) listenPorts() (*porthopping.ServerConn, error) {
+	listenAddr := h.listenOptions.Listen.Build()
+	ports := h.ports
+	if h.listenOptions.ListenPort != 0 && !common.Contains(ports, h.listenOptions.ListenPort) {
+		ports = append([]uint16{h.listenOptions.ListenPort}, ports...)
+	}
+	var conns []net.PacketConn
+	for _, port := range ports {
+		conn, err := h.listenUDP(M.SocksaddrFrom(listenAddr, port))
+		if err != nil {
+			for _, conn := range conns {
+				conn.Close()
+			}
+			return nil, err
+		}
+		conns = append(conns, conn)
+	}
+	h.logger.Info("udp server started at ", conns[0].LocalAddr(), " with ", len(conns), " ports")
+	return porthopping.NewServerConn(conns), nil
+}
+
+// updateLimiters keeps the limiters of existing users, so their traffic stays limited across updates.
+func (h *Hysteria2) updateLimiters(indexes []int, users []option.Hysteria2User) {
+	h.limiterAccess.Lock()
+	defer h.limiterAccess.Unlock()
+	limiters := make(map[int]ratelimit.Limiters, len(indexes))
+	for i, index := range indexes {
+		userLimiters, loaded := h.limiters[index]
+		if !loaded {
+			userLimiters = ratelimit.NewLimiters(option.RateLimitOptions{
+				UpMbps:   users[i].UpMbps,
+				DownMbps: users[i].DownMbps,
+			})
+		}
+		if !userLimiters.IsEmpty() {
+			limiters[index] = userLimiters
+		}
+	}
+	h.limiters = limiters
+}
+
+func (h *Hysteria2) loadLimiters(index int) (ratelimit.Limiters, bool) {
+	h.limiterAccess.RLock()
+	defer h.limiterAccess.RUnlock()
+	limiters, loaded := h.limiters[index]
+	return limiters, loaded
 }
 
 func (h *Hysteria2) Close() error {
 	return common.Close(
 		&h.myInboundAdapter,
+		h.users,
+		common.PtrOrNil(h.hopConn),
 		h.tlsConfig,
 		common.PtrOrNil(h.service),
 	)
 }
+
+func (h *Hysteria2) Users() []any {
+	return h.users.ListAny()
+}
+
+func (h *Hysteria2) AddUsers(content []byte) error {
+	return h.users.AddJSON(content)
+}
+
+func (h *Hysteria2) RemoveUsers(names []string, indexes []int) error {
+	return h.users.Remove(names, indexes)
+}
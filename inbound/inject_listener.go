@@ -0,0 +1,88 @@
+package inbound
+
+import (
+	"net"
+	"sync"
+)
+
+const injectQueueSize = 64
+
+// injectListener accepts connections injected by other inbounds,
+// so that they can be served by a V2Ray transport.
+type injectListener struct {
+	addr      net.Addr
+	conns     chan net.Conn
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newInjectListener(addr net.Addr) *injectListener {
+	if addr == nil {
+		addr = &net.TCPAddr{}
+	}
+	return &injectListener{
+		addr:  addr,
+		conns: make(chan net.Conn, injectQueueSize),
+		done:  make(chan struct{}),
+	}
+}
+
+func (l *injectListener) Inject(conn net.Conn) error {
+	select {
+	case <-l.done:
+		return net.ErrClosed
+	default:
+	}
+	select {
+	case l.conns <- conn:
+	case <-l.done:
+		return net.ErrClosed
+	}
+	select {
+	case <-l.done:
+		l.drain()
+	default:
+	}
+	return nil
+}
+
+// drain closes queued connections which will never be accepted.
+func (l *injectListener) drain() {
+	for {
+		select {
+		case conn := <-l.conns:
+			conn.Close()
+		default:
+			return
+		}
+	}
+}
+
+func (l *injectListener) Accept() (net.Conn, error) {
+	select {
+	case <-l.done:
+		return nil, net.ErrClosed
+	default:
+	}
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.done:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *injectListener) Close() error {
+	if l == nil {
+		return nil
+	}
+	l.closeOnce.Do(func() {
+		close(l.done)
+	})
+	l.drain()
+	return nil
+}
+
+func (l *injectListener) Addr() net.Addr {
+	return l.addr
+}
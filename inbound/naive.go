@@ -13,11 +13,13 @@ import (
 
 	"github.com/sagernet/sing-box/adapter"
 	"github.com/sagernet/sing-box/common/tls"
+	"github.com/sagernet/sing-box/common/udpobfs"
 	"github.com/sagernet/sing-box/common/uot"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/log"
 	"github.com/sagernet/sing-box/option"
 	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/atomic"
 	"github.com/sagernet/sing/common/auth"
 	"github.com/sagernet/sing/common/buf"
 	E "github.com/sagernet/sing/common/exceptions"
@@ -27,14 +29,19 @@ import (
 	sHttp "github.com/sagernet/sing/protocol/http"
 )
 
-var _ adapter.Inbound = (*Naive)(nil)
+var (
+	_ adapter.Inbound     = (*Naive)(nil)
+	_ adapter.UserManager = (*Naive)(nil)
+)
 
 type Naive struct {
 	myInboundAdapter
-	authenticator *auth.Authenticator
+	users         *userManager[auth.User]
+	authenticator atomic.TypedValue[*naiveAuthenticator]
 	tlsConfig     tls.ServerConfig
 	httpServer    *http.Server
 	h3Server      any
+	udpObfs       *udpobfs.Obfuscator
 }
 
 func NewNaive(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.NaiveInboundOptions) (*Naive, error) {
@@ -48,16 +55,45 @@ func NewNaive(ctx context.Context, router adapter.Router, logger log.ContextLogg
 			tag:           tag,
 			listenOptions: options.ListenOptions,
 		},
-		authenticator: auth.NewAuthenticator(options.Users),
 	}
 	if common.Contains(inbound.network, N.NetworkUDP) {
 		if options.TLS == nil || !options.TLS.Enabled {
 			return nil, E.New("TLS is required for QUIC server")
 		}
+		if options.UDPObfs != nil {
+			var err error
+			inbound.udpObfs, err = udpobfs.NewObfuscator(*options.UDPObfs)
+			if err != nil {
+				return nil, err
+			}
+		}
 	}
 	if len(options.Users) == 0 {
 		return nil, E.New("missing users")
 	}
+	var err error
+	inbound.users, err = newUserManager(options.Users, func(it auth.User) string {
+		return it.Username
+	}, func(indexes []int, users []auth.User) error {
+		authenticator := &naiveAuthenticator{
+			Authenticator: auth.NewAuthenticator(users),
+			indexes:       make(map[string]int),
+		}
+		for i, user := range users {
+			if _, loaded := authenticator.indexes[user.Username]; !loaded {
+				authenticator.indexes[user.Username] = indexes[i]
+			}
+		}
+		inbound.authenticator.Store(authenticator)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	inbound.users.limiter, err = newUserLimiter(ctx, logger, tag, options.UserLimits)
+	if err != nil {
+		return nil, err
+	}
 	if options.TLS != nil {
 		tlsConfig, err := tls.NewServer(ctx, logger, common.PtrValueOrDefault(options.TLS))
 		if err != nil {
@@ -121,6 +157,7 @@ func (n *Naive) Start() error {
 func (n *Naive) Close() error {
 	return common.Close(
 		&n.myInboundAdapter,
+		n.users,
 		common.PtrOrNil(n.httpServer),
 		n.h3Server,
 		n.tlsConfig,
@@ -139,8 +176,9 @@ func (n *Naive) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
 		return
 	}
 	userName, password, authOk := sHttp.ParseBasicAuth(request.Header.Get("Proxy-Authorization"))
+	authenticator := n.authenticator.Load()
 	if authOk {
-		authOk = n.authenticator.Verify(userName, password)
+		authOk = authenticator.Authenticator != nil && authenticator.Verify(userName, password)
 	}
 	if !authOk {
 		rejectHTTP(writer, http.StatusProxyAuthRequired)
@@ -164,13 +202,21 @@ func (n *Naive) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
 			n.badRequest(ctx, request, E.New("hijack failed"))
 			return
 		}
-		n.newConnection(ctx, &naiveH1Conn{Conn: conn}, userName, source, destination)
+		n.newConnection(ctx, &naiveH1Conn{Conn: conn}, authenticator.indexes[userName], userName, source, destination)
 	} else {
-		n.newConnection(ctx, &naiveH2Conn{reader: request.Body, writer: writer, flusher: writer.(http.Flusher)}, userName, source, destination)
+		n.newConnection(ctx, &naiveH2Conn{reader: request.Body, writer: writer, flusher: writer.(http.Flusher)}, authenticator.indexes[userName], userName, source, destination)
 	}
 }
 
-func (n *Naive) newConnection(ctx context.Context, conn net.Conn, userName string, source, destination M.Socksaddr) {
+func (n *Naive) newConnection(ctx context.Context, conn net.Conn, userIndex int, userName string, source, destination M.Socksaddr) {
+	limitedConn, done, err := n.users.NewConnection(userIndex, conn, source)
+	if err != nil {
+		conn.Close()
+		n.NewError(ctx, E.Cause(err, "process connection from ", source))
+		return
+	}
+	defer done()
+	conn = limitedConn
 	if userName != "" {
 		n.logger.InfoContext(ctx, "[", userName, "] inbound connection from ", source)
 		n.logger.InfoContext(ctx, "[", userName, "] inbound connection to ", destination)
@@ -189,6 +235,23 @@ func (n *Naive) newConnection(ctx context.Context, conn net.Conn, userName strin
 	}
 }
 
+func (n *Naive) Users() []any {
+	return n.users.ListAny()
+}
+
+func (n *Naive) AddUsers(content []byte) error {
+	return n.users.AddJSON(content)
+}
+
+func (n *Naive) RemoveUsers(names []string, indexes []int) error {
+	return n.users.Remove(names, indexes)
+}
+
+type naiveAuthenticator struct {
+	*auth.Authenticator
+	indexes map[string]int
+}
+
 func (n *Naive) badRequest(ctx context.Context, request *http.Request, err error) {
 	n.NewError(ctx, E.Cause(err, "process connection from ", request.RemoteAddr))
 }
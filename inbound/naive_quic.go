@@ -5,6 +5,7 @@ package inbound
 import (
 	"github.com/sagernet/quic-go"
 	"github.com/sagernet/quic-go/http3"
+	"github.com/sagernet/sing-box/common/udpobfs"
 	"github.com/sagernet/sing-quic"
 	E "github.com/sagernet/sing/common/exceptions"
 )
@@ -19,6 +20,9 @@ func (n *Naive) configureHTTP3Listener() error {
 	if err != nil {
 		return err
 	}
+	if n.udpObfs != nil {
+		udpConn = udpobfs.NewPacketConn(udpConn, n.udpObfs)
+	}
 
 	quicListener, err := qtls.ListenEarly(udpConn, n.tlsConfig, &quic.Config{
 		MaxIncomingStreams: 1 << 60,
@@ -0,0 +1,217 @@
+package inbound
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+)
+
+// relayBalancer selects upstream servers of relay destinations and checks their health.
+type relayBalancer struct {
+	ctx          context.Context
+	logger       log.ContextLogger
+	interval     time.Duration
+	timeout      time.Duration
+	access       sync.RWMutex
+	indexes      []int
+	destinations map[int]*relayDestination
+	close        chan struct{}
+}
+
+type relayDestination struct {
+	name    string
+	servers []*relayServer
+	next    atomic.Uint32
+}
+
+type relayServer struct {
+	address     M.Socksaddr
+	healthy     atomic.Bool
+	connections atomic.Int32
+}
+
+func newRelayBalancer(ctx context.Context, logger log.ContextLogger, options *option.ShadowsocksRelayHealthCheck) *relayBalancer {
+	balancer := &relayBalancer{
+		ctx:          ctx,
+		logger:       logger,
+		destinations: make(map[int]*relayDestination),
+		close:        make(chan struct{}),
+	}
+	if options != nil {
+		balancer.interval = time.Duration(options.Interval)
+		if balancer.interval == 0 {
+			balancer.interval = time.Minute
+		}
+		balancer.timeout = time.Duration(options.Timeout)
+		if balancer.timeout == 0 {
+			balancer.timeout = C.TCPTimeout
+		}
+	}
+	return balancer
+}
+
+// relayServerAddresses returns the server and the servers of destination.
+func relayServerAddresses(destination option.ShadowsocksDestination) ([]M.Socksaddr, error) {
+	var addresses []M.Socksaddr
+	if destination.Server != "" {
+		addresses = append(addresses, destination.ServerOptions.Build())
+	}
+	for _, server := range destination.Servers {
+		addresses = append(addresses, server.Build())
+	}
+	if len(addresses) == 0 {
+		return nil, E.New("missing server for destination ", destination.Name)
+	}
+	for _, address := range addresses {
+		if !address.IsValid() {
+			return nil, E.New("invalid server address for destination ", destination.Name, ": ", address)
+		}
+	}
+	return addresses, nil
+}
+
+// Update replaces the destinations, the health of servers of kept destinations is preserved.
+func (b *relayBalancer) Update(indexes []int, destinations []option.ShadowsocksDestination) error {
+	newDestinations := make(map[int]*relayDestination, len(indexes))
+	for i, index := range indexes {
+		addresses, err := relayServerAddresses(destinations[i])
+		if err != nil {
+			return err
+		}
+		b.access.RLock()
+		oldDestination := b.destinations[index]
+		b.access.RUnlock()
+		if oldDestination != nil {
+			newDestinations[index] = oldDestination
+			continue
+		}
+		destination := &relayDestination{
+			name: destinations[i].Name,
+		}
+		for _, address := range addresses {
+			server := &relayServer{address: address}
+			server.healthy.Store(true)
+			destination.servers = append(destination.servers, server)
+		}
+		newDestinations[index] = destination
+	}
+	b.access.Lock()
+	b.indexes = indexes
+	b.destinations = newDestinations
+	b.access.Unlock()
+	return nil
+}
+
+// Select returns the next healthy server of the destination in round-robin order,
+// or the next server if none is healthy.
+func (b *relayBalancer) Select(index int) (*relayDestination, *relayServer, bool) {
+	b.access.RLock()
+	destination, loaded := b.destinations[index]
+	b.access.RUnlock()
+	if !loaded {
+		return nil, nil, false
+	}
+	serverCount := uint32(len(destination.servers))
+	start := destination.next.Add(1)
+	for i := uint32(0); i < serverCount; i++ {
+		server := destination.servers[(start+i)%serverCount]
+		if server.healthy.Load() {
+			return destination, server, true
+		}
+	}
+	return destination, destination.servers[start%serverCount], true
+}
+
+func (b *relayBalancer) Start() {
+	if b.interval > 0 {
+		go b.loopCheck()
+	}
+}
+
+func (b *relayBalancer) loopCheck() {
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+	b.checkAll()
+	for {
+		select {
+		case <-ticker.C:
+			b.checkAll()
+		case <-b.close:
+			return
+		}
+	}
+}
+
+func (b *relayBalancer) checkAll() {
+	b.access.RLock()
+	var servers []*relayServer
+	for _, destination := range b.destinations {
+		servers = append(servers, destination.servers...)
+	}
+	b.access.RUnlock()
+	var group sync.WaitGroup
+	for _, server := range servers {
+		group.Add(1)
+		go func(server *relayServer) {
+			defer group.Done()
+			b.check(server)
+		}(server)
+	}
+	group.Wait()
+}
+
+func (b *relayBalancer) check(server *relayServer) {
+	ctx, cancel := context.WithTimeout(b.ctx, b.timeout)
+	defer cancel()
+	conn, err := N.SystemDialer.DialContext(ctx, N.NetworkTCP, server.address)
+	if err == nil {
+		conn.Close()
+	}
+	healthy := err == nil
+	if server.healthy.Swap(healthy) != healthy {
+		if healthy {
+			b.logger.Info("relay server ", server.address, " is healthy")
+		} else {
+			b.logger.Warn("relay server ", server.address, " is unhealthy: ", err)
+		}
+	}
+}
+
+func (b *relayBalancer) Status() []adapter.RelayDestinationStatus {
+	b.access.RLock()
+	defer b.access.RUnlock()
+	statusList := make([]adapter.RelayDestinationStatus, 0, len(b.indexes))
+	for _, index := range b.indexes {
+		destination := b.destinations[index]
+		status := adapter.RelayDestinationStatus{
+			Name: destination.name,
+		}
+		for _, server := range destination.servers {
+			status.Servers = append(status.Servers, adapter.RelayServerStatus{
+				Server:      server.address.String(),
+				Healthy:     server.healthy.Load(),
+				Connections: server.connections.Load(),
+			})
+		}
+		statusList = append(statusList, status)
+	}
+	return statusList
+}
+
+func (b *relayBalancer) Close() error {
+	select {
+	case <-b.close:
+	default:
+		close(b.close)
+	}
+	return nil
+}
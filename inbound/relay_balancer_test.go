@@ -0,0 +1,43 @@
+package inbound
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRelayBalancer(t *testing.T) {
+	t.Parallel()
+	balancer := newRelayBalancer(context.Background(), log.NewNOPFactory().Logger(), nil)
+	err := balancer.Update([]int{0}, []option.ShadowsocksDestination{{
+		Name:          "test",
+		ServerOptions: option.ServerOptions{Server: "127.0.0.1", ServerPort: 1},
+		Servers: []option.ServerOptions{
+			{Server: "127.0.0.1", ServerPort: 2},
+			{Server: "127.0.0.1", ServerPort: 3},
+		},
+	}})
+	require.NoError(t, err)
+	selected := make(map[uint16]int)
+	for i := 0; i < 6; i++ {
+		_, server, loaded := balancer.Select(0)
+		require.True(t, loaded)
+		selected[server.address.Port]++
+	}
+	require.Equal(t, map[uint16]int{1: 2, 2: 2, 3: 2}, selected)
+	destination, _, _ := balancer.Select(0)
+	destination.servers[0].healthy.Store(false)
+	destination.servers[1].healthy.Store(false)
+	for i := 0; i < 3; i++ {
+		_, server, _ := balancer.Select(0)
+		require.Equal(t, uint16(3), server.address.Port)
+	}
+	_, _, loaded := balancer.Select(1)
+	require.False(t, loaded)
+	err = balancer.Update([]int{1}, []option.ShadowsocksDestination{{Name: "empty"}})
+	require.Error(t, err)
+}
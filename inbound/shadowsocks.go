@@ -12,6 +12,7 @@ import (
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/log"
 	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing-box/transport/sip003"
 	"github.com/sagernet/sing-shadowsocks"
 	"github.com/sagernet/sing-shadowsocks/shadowaead"
 	"github.com/sagernet/sing-shadowsocks/shadowaead_2022"
@@ -65,6 +66,12 @@ func newShadowsocks(ctx context.Context, router adapter.Router, logger log.Conte
 	if err != nil {
 		return nil, err
 	}
+	if options.Plugin != "" || options.PluginPath != "" {
+		inbound.tcpPlugin, err = sip003.CreateServerPlugin(ctx, logger, options.Plugin, options.PluginPath, options.PluginOptions)
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	var udpTimeout time.Duration
 	if options.UDPTimeout != 0 {
@@ -12,6 +12,7 @@ import (
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/log"
 	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing-box/transport/sip003"
 	"github.com/sagernet/sing-shadowsocks"
 	"github.com/sagernet/sing-shadowsocks/shadowaead"
 	"github.com/sagernet/sing-shadowsocks/shadowaead_2022"
@@ -19,7 +20,6 @@ import (
 	"github.com/sagernet/sing/common/auth"
 	"github.com/sagernet/sing/common/buf"
 	E "github.com/sagernet/sing/common/exceptions"
-	F "github.com/sagernet/sing/common/format"
 	N "github.com/sagernet/sing/common/network"
 	"github.com/sagernet/sing/common/ntp"
 )
@@ -27,12 +27,13 @@ import (
 var (
 	_ adapter.Inbound           = (*ShadowsocksMulti)(nil)
 	_ adapter.InjectableInbound = (*ShadowsocksMulti)(nil)
+	_ adapter.UserManager       = (*ShadowsocksMulti)(nil)
 )
 
 type ShadowsocksMulti struct {
 	myInboundAdapter
 	service shadowsocks.MultiService[int]
-	users   []option.ShadowsocksUser
+	users   *userManager[option.ShadowsocksUser]
 }
 
 func newShadowsocksMulti(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.ShadowsocksInboundOptions) (*ShadowsocksMulti, error) {
@@ -54,6 +55,12 @@ func newShadowsocksMulti(ctx context.Context, router adapter.Router, logger log.
 	if err != nil {
 		return nil, err
 	}
+	if options.Plugin != "" || options.PluginPath != "" {
+		inbound.tcpPlugin, err = sip003.CreateServerPlugin(ctx, logger, options.Plugin, options.PluginPath, options.PluginOptions)
+		if err != nil {
+			return nil, err
+		}
+	}
 	var udpTimeout time.Duration
 	if options.UDPTimeout != 0 {
 		udpTimeout = time.Duration(options.UDPTimeout)
@@ -80,20 +87,32 @@ func newShadowsocksMulti(ctx context.Context, router adapter.Router, logger log.
 	if err != nil {
 		return nil, err
 	}
-	err = service.UpdateUsersWithPasswords(common.MapIndexed(options.Users, func(index int, user option.ShadowsocksUser) int {
-		return index
-	}), common.Map(options.Users, func(user option.ShadowsocksUser) string {
-		return user.Password
-	}))
+	inbound.users, err = newUserManager(options.Users, func(it option.ShadowsocksUser) string {
+		return it.Name
+	}, func(indexes []int, users []option.ShadowsocksUser) error {
+		return service.UpdateUsersWithPasswords(indexes, common.Map(users, func(it option.ShadowsocksUser) string {
+			return it.Password
+		}))
+	})
+	if err != nil {
+		return nil, err
+	}
+	inbound.users.limiter, err = newUserLimiter(ctx, logger, tag, options.UserLimits)
 	if err != nil {
 		return nil, err
 	}
 	inbound.service = service
 	inbound.packetUpstream = service
-	inbound.users = options.Users
 	return inbound, err
 }
 
+func (h *ShadowsocksMulti) Close() error {
+	return common.Close(
+		&h.myInboundAdapter,
+		h.users,
+	)
+}
+
 func (h *ShadowsocksMulti) NewConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext) error {
 	return h.service.NewConnection(adapter.WithContext(log.ContextWithNewID(ctx), &metadata), conn, adapter.UpstreamMetadata(metadata))
 }
@@ -111,12 +130,13 @@ func (h *ShadowsocksMulti) newConnection(ctx context.Context, conn net.Conn, met
 	if !loaded {
 		return os.ErrInvalid
 	}
-	user := h.users[userIndex].Name
-	if user == "" {
-		user = F.ToString(userIndex)
-	} else {
-		metadata.User = user
+	userName, user := h.users.Name(userIndex)
+	metadata.User = userName
+	conn, done, err := h.users.NewConnection(userIndex, conn, metadata.Source)
+	if err != nil {
+		return err
 	}
+	defer done()
 	h.logger.InfoContext(ctx, "[", user, "] inbound connection to ", metadata.Destination)
 	return h.router.RouteConnection(ctx, conn, metadata)
 }
@@ -126,14 +146,27 @@ func (h *ShadowsocksMulti) newPacketConnection(ctx context.Context, conn N.Packe
 	if !loaded {
 		return os.ErrInvalid
 	}
-	user := h.users[userIndex].Name
-	if user == "" {
-		user = F.ToString(userIndex)
-	} else {
-		metadata.User = user
+	userName, user := h.users.Name(userIndex)
+	metadata.User = userName
+	conn, done, err := h.users.NewPacketConnection(userIndex, conn, metadata.Source)
+	if err != nil {
+		return err
 	}
+	defer done()
 	ctx = log.ContextWithNewID(ctx)
 	h.logger.InfoContext(ctx, "[", user, "] inbound packet connection from ", metadata.Source)
 	h.logger.InfoContext(ctx, "[", user, "] inbound packet connection to ", metadata.Destination)
 	return h.router.RoutePacketConnection(ctx, conn, metadata)
 }
+
+func (h *ShadowsocksMulti) Users() []any {
+	return h.users.ListAny()
+}
+
+func (h *ShadowsocksMulti) AddUsers(content []byte) error {
+	return h.users.AddJSON(content)
+}
+
+func (h *ShadowsocksMulti) RemoveUsers(names []string, indexes []int) error {
+	return h.users.Remove(names, indexes)
+}
@@ -12,23 +12,27 @@ import (
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/log"
 	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing-box/transport/sip003"
 	"github.com/sagernet/sing-shadowsocks/shadowaead_2022"
 	"github.com/sagernet/sing/common"
 	"github.com/sagernet/sing/common/auth"
 	"github.com/sagernet/sing/common/buf"
-	F "github.com/sagernet/sing/common/format"
+	M "github.com/sagernet/sing/common/metadata"
 	N "github.com/sagernet/sing/common/network"
 )
 
 var (
 	_ adapter.Inbound           = (*ShadowsocksRelay)(nil)
 	_ adapter.InjectableInbound = (*ShadowsocksRelay)(nil)
+	_ adapter.RelayManager      = (*ShadowsocksRelay)(nil)
 )
 
 type ShadowsocksRelay struct {
 	myInboundAdapter
-	service      *shadowaead_2022.RelayService[int]
-	destinations []option.ShadowsocksDestination
+	inboundRouter adapter.Router
+	service       *shadowaead_2022.RelayService[int]
+	users         *userManager[option.ShadowsocksDestination]
+	balancer      *relayBalancer
 }
 
 func newShadowsocksRelay(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.ShadowsocksInboundOptions) (*ShadowsocksRelay, error) {
@@ -42,7 +46,8 @@ func newShadowsocksRelay(ctx context.Context, router adapter.Router, logger log.
 			tag:           tag,
 			listenOptions: options.ListenOptions,
 		},
-		destinations: options.Destinations,
+		inboundRouter: router,
+		balancer:      newRelayBalancer(ctx, logger, options.HealthCheck),
 	}
 	inbound.connHandler = inbound
 	inbound.packetHandler = inbound
@@ -51,6 +56,12 @@ func newShadowsocksRelay(ctx context.Context, router adapter.Router, logger log.
 	if err != nil {
 		return nil, err
 	}
+	if options.Plugin != "" || options.PluginPath != "" {
+		inbound.tcpPlugin, err = sip003.CreateServerPlugin(ctx, logger, options.Plugin, options.PluginPath, options.PluginOptions)
+		if err != nil {
+			return nil, err
+		}
+	}
 	var udpTimeout time.Duration
 	if options.UDPTimeout != 0 {
 		udpTimeout = time.Duration(options.UDPTimeout)
@@ -66,11 +77,25 @@ func newShadowsocksRelay(ctx context.Context, router adapter.Router, logger log.
 	if err != nil {
 		return nil, err
 	}
-	err = service.UpdateUsersWithPasswords(common.MapIndexed(options.Destinations, func(index int, user option.ShadowsocksDestination) int {
-		return index
-	}), common.Map(options.Destinations, func(user option.ShadowsocksDestination) string {
-		return user.Password
-	}), common.Map(options.Destinations, option.ShadowsocksDestination.Build))
+	inbound.users, err = newUserManager(options.Destinations, func(it option.ShadowsocksDestination) string {
+		return it.Name
+	}, func(indexes []int, destinations []option.ShadowsocksDestination) error {
+		var serverAddresses []M.Socksaddr
+		for _, destination := range destinations {
+			addresses, err := relayServerAddresses(destination)
+			if err != nil {
+				return err
+			}
+			serverAddresses = append(serverAddresses, addresses[0])
+		}
+		err := service.UpdateUsersWithPasswords(indexes, common.Map(destinations, func(it option.ShadowsocksDestination) string {
+			return it.Password
+		}), serverAddresses)
+		if err != nil {
+			return err
+		}
+		return inbound.balancer.Update(indexes, destinations)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -79,6 +104,19 @@ func newShadowsocksRelay(ctx context.Context, router adapter.Router, logger log.
 	return inbound, err
 }
 
+func (h *ShadowsocksRelay) Start() error {
+	h.balancer.Start()
+	return h.myInboundAdapter.Start()
+}
+
+func (h *ShadowsocksRelay) Close() error {
+	return common.Close(
+		&h.myInboundAdapter,
+		h.balancer,
+		h.users,
+	)
+}
+
 func (h *ShadowsocksRelay) NewConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext) error {
 	return h.service.NewConnection(adapter.WithContext(log.ContextWithNewID(ctx), &metadata), conn, adapter.UpstreamMetadata(metadata))
 }
@@ -96,11 +134,22 @@ func (h *ShadowsocksRelay) newConnection(ctx context.Context, conn net.Conn, met
 	if !loaded {
 		return os.ErrInvalid
 	}
-	destination := h.destinations[destinationIndex].Name
-	if destination == "" {
-		destination = F.ToString(destinationIndex)
-	} else {
-		metadata.User = destination
+	destinationName, destination := h.users.Name(destinationIndex)
+	metadata.User = destinationName
+	_, server, loaded := h.balancer.Select(destinationIndex)
+	if !loaded {
+		return os.ErrInvalid
+	}
+	metadata.Destination = server.address
+	conn, done, err := h.users.NewConnection(destinationIndex, conn, metadata.Source)
+	if err != nil {
+		return err
+	}
+	defer done()
+	server.connections.Add(1)
+	defer server.connections.Add(-1)
+	if statsService := h.statsService(); statsService != nil {
+		conn = statsService.RelayedConnection(h.tag, destination, server.address.String(), conn)
 	}
 	h.logger.InfoContext(ctx, "[", destination, "] inbound connection to ", metadata.Destination)
 	return h.router.RouteConnection(ctx, conn, metadata)
@@ -111,14 +160,49 @@ func (h *ShadowsocksRelay) newPacketConnection(ctx context.Context, conn N.Packe
 	if !loaded {
 		return os.ErrInvalid
 	}
-	destination := h.destinations[destinationIndex].Name
-	if destination == "" {
-		destination = F.ToString(destinationIndex)
-	} else {
-		metadata.User = destination
+	destinationName, destination := h.users.Name(destinationIndex)
+	metadata.User = destinationName
+	_, server, loaded := h.balancer.Select(destinationIndex)
+	if !loaded {
+		return os.ErrInvalid
+	}
+	metadata.Destination = server.address
+	conn, done, err := h.users.NewPacketConnection(destinationIndex, conn, metadata.Source)
+	if err != nil {
+		return err
+	}
+	defer done()
+	server.connections.Add(1)
+	defer server.connections.Add(-1)
+	if statsService := h.statsService(); statsService != nil {
+		conn = statsService.RelayedPacketConnection(h.tag, destination, server.address.String(), conn)
 	}
 	ctx = log.ContextWithNewID(ctx)
 	h.logger.InfoContext(ctx, "[", destination, "] inbound packet connection from ", metadata.Source)
 	h.logger.InfoContext(ctx, "[", destination, "] inbound packet connection to ", metadata.Destination)
 	return h.router.RoutePacketConnection(ctx, conn, metadata)
 }
+
+func (h *ShadowsocksRelay) statsService() adapter.V2RayStatsService {
+	v2rayServer := h.inboundRouter.V2RayServer()
+	if v2rayServer == nil {
+		return nil
+	}
+	return v2rayServer.StatsService()
+}
+
+func (h *ShadowsocksRelay) Users() []any {
+	return h.users.ListAny()
+}
+
+func (h *ShadowsocksRelay) AddUsers(content []byte) error {
+	return h.users.AddJSON(content)
+}
+
+func (h *ShadowsocksRelay) RemoveUsers(names []string, indexes []int) error {
+	return h.users.Remove(names, indexes)
+}
+
+func (h *ShadowsocksRelay) RelayStatus() []adapter.RelayDestinationStatus {
+	return h.balancer.Status()
+}
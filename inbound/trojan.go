@@ -16,7 +16,6 @@ import (
 	"github.com/sagernet/sing/common"
 	"github.com/sagernet/sing/common/auth"
 	E "github.com/sagernet/sing/common/exceptions"
-	F "github.com/sagernet/sing/common/format"
 	M "github.com/sagernet/sing/common/metadata"
 	N "github.com/sagernet/sing/common/network"
 )
@@ -24,16 +23,18 @@ import (
 var (
 	_ adapter.Inbound           = (*Trojan)(nil)
 	_ adapter.InjectableInbound = (*Trojan)(nil)
+	_ adapter.UserManager       = (*Trojan)(nil)
 )
 
 type Trojan struct {
 	myInboundAdapter
 	service                  *trojan.Service[int]
-	users                    []option.TrojanUser
+	users                    *userManager[option.TrojanUser]
 	tlsConfig                tls.ServerConfig
 	fallbackAddr             M.Socksaddr
 	fallbackAddrTLSNextProto map[string]M.Socksaddr
 	transport                adapter.V2RayServerTransport
+	injected                 *injectListener
 }
 
 func NewTrojan(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.TrojanInboundOptions) (*Trojan, error) {
@@ -47,7 +48,6 @@ func NewTrojan(ctx context.Context, router adapter.Router, logger log.ContextLog
 			tag:           tag,
 			listenOptions: options.ListenOptions,
 		},
-		users: options.Users,
 	}
 	if options.TLS != nil {
 		tlsConfig, err := tls.NewServer(ctx, logger, common.PtrValueOrDefault(options.TLS))
@@ -81,11 +81,18 @@ func NewTrojan(ctx context.Context, router adapter.Router, logger log.ContextLog
 		fallbackHandler = adapter.NewUpstreamContextHandler(inbound.fallbackConnection, nil, nil)
 	}
 	service := trojan.NewService[int](adapter.NewUpstreamContextHandler(inbound.newConnection, inbound.newPacketConnection, inbound), fallbackHandler)
-	err := service.UpdateUsers(common.MapIndexed(options.Users, func(index int, it option.TrojanUser) int {
-		return index
-	}), common.Map(options.Users, func(it option.TrojanUser) string {
-		return it.Password
-	}))
+	var err error
+	inbound.users, err = newUserManager(options.Users, func(it option.TrojanUser) string {
+		return it.Name
+	}, func(indexes []int, users []option.TrojanUser) error {
+		return service.UpdateUsers(indexes, common.Map(users, func(it option.TrojanUser) string {
+			return it.Password
+		}))
+	})
+	if err != nil {
+		return nil, err
+	}
+	inbound.users.limiter, err = newUserLimiter(ctx, logger, tag, options.UserLimits)
 	if err != nil {
 		return nil, err
 	}
@@ -94,6 +101,9 @@ func NewTrojan(ctx context.Context, router adapter.Router, logger log.ContextLog
 		if err != nil {
 			return nil, E.Cause(err, "create server transport: ", options.Transport.Type)
 		}
+		if common.Contains(inbound.transport.Network(), N.NetworkTCP) {
+			inbound.injected = newInjectListener(nil)
+		}
 	}
 	inbound.router, err = mux.NewRouterWithOptions(inbound.router, logger, common.PtrValueOrDefault(options.Multiplex))
 	if err != nil {
@@ -126,6 +136,14 @@ func (h *Trojan) Start() error {
 			}
 		}()
 	}
+	if h.injected != nil {
+		go func() {
+			sErr := h.transport.Serve(h.injected)
+			if sErr != nil && !E.IsClosed(sErr) {
+				h.logger.Error("transport serve error: ", sErr)
+			}
+		}()
+	}
 	if common.Contains(h.transport.Network(), N.NetworkUDP) {
 		udpConn, err := h.myInboundAdapter.ListenUDP()
 		if err != nil {
@@ -144,8 +162,10 @@ func (h *Trojan) Start() error {
 func (h *Trojan) Close() error {
 	return common.Close(
 		&h.myInboundAdapter,
+		h.users,
 		h.tlsConfig,
 		h.transport,
+		h.injected,
 	)
 }
 
@@ -155,12 +175,19 @@ func (h *Trojan) newTransportConnection(ctx context.Context, conn net.Conn, meta
 }
 
 func (h *Trojan) NewConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext) error {
+	if h.transport != nil && isDemuxContext(ctx) {
+		if h.injected == nil {
+			return E.New("inject: TCP unsupported by transport")
+		}
+		return h.injected.Inject(conn)
+	}
 	var err error
 	if h.tlsConfig != nil && h.transport == nil {
 		conn, err = tls.ServerHandshake(ctx, conn, h.tlsConfig)
 		if err != nil {
 			return err
 		}
+		metadata.User = tls.UserFromConn(conn)
 	}
 	return h.service.NewConnection(adapter.WithContext(ctx, &metadata), conn, adapter.UpstreamMetadata(metadata))
 }
@@ -174,12 +201,13 @@ func (h *Trojan) newConnection(ctx context.Context, conn net.Conn, metadata adap
 	if !loaded {
 		return os.ErrInvalid
 	}
-	user := h.users[userIndex].Name
-	if user == "" {
-		user = F.ToString(userIndex)
-	} else {
-		metadata.User = user
+	userName, user := h.users.NameOr(userIndex, metadata.User)
+	metadata.User = userName
+	conn, done, err := h.users.NewConnection(userIndex, conn, metadata.Source)
+	if err != nil {
+		return err
 	}
+	defer done()
 	h.logger.InfoContext(ctx, "[", user, "] inbound connection to ", metadata.Destination)
 	return h.router.RouteConnection(ctx, conn, metadata)
 }
@@ -212,16 +240,29 @@ func (h *Trojan) newPacketConnection(ctx context.Context, conn N.PacketConn, met
 	if !loaded {
 		return os.ErrInvalid
 	}
-	user := h.users[userIndex].Name
-	if user == "" {
-		user = F.ToString(userIndex)
-	} else {
-		metadata.User = user
+	userName, user := h.users.NameOr(userIndex, metadata.User)
+	metadata.User = userName
+	conn, done, err := h.users.NewPacketConnection(userIndex, conn, metadata.Source)
+	if err != nil {
+		return err
 	}
+	defer done()
 	h.logger.InfoContext(ctx, "[", user, "] inbound packet connection to ", metadata.Destination)
 	return h.router.RoutePacketConnection(ctx, conn, metadata)
 }
 
+func (h *Trojan) Users() []any {
+	return h.users.ListAny()
+}
+
+func (h *Trojan) AddUsers(content []byte) error {
+	return h.users.AddJSON(content)
+}
+
+func (h *Trojan) RemoveUsers(names []string, indexes []int) error {
+	return h.users.Remove(names, indexes)
+}
+
 var _ adapter.V2RayServerTransportHandler = (*trojanTransportHandler)(nil)
 
 type trojanTransportHandler Trojan
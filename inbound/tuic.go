@@ -9,6 +9,7 @@ import (
 
 	"github.com/sagernet/sing-box/adapter"
 	"github.com/sagernet/sing-box/common/tls"
+	"github.com/sagernet/sing-box/common/udpobfs"
 	"github.com/sagernet/sing-box/common/uot"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/log"
@@ -22,13 +23,17 @@ import (
 	"github.com/gofrs/uuid/v5"
 )
 
-var _ adapter.Inbound = (*TUIC)(nil)
+var (
+	_ adapter.Inbound     = (*TUIC)(nil)
+	_ adapter.UserManager = (*TUIC)(nil)
+)
 
 type TUIC struct {
 	myInboundAdapter
-	tlsConfig    tls.ServerConfig
-	server       *tuic.Service[int]
-	userNameList []string
+	tlsConfig tls.ServerConfig
+	server    *tuic.Service[int]
+	users     *userManager[option.TUICUser]
+	udpObfs   *udpobfs.Obfuscator
 }
 
 func NewTUIC(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.TUICInboundOptions) (*TUIC, error) {
@@ -52,6 +57,12 @@ func NewTUIC(ctx context.Context, router adapter.Router, logger log.ContextLogge
 		},
 		tlsConfig: tlsConfig,
 	}
+	if options.UDPObfs != nil {
+		inbound.udpObfs, err = udpobfs.NewObfuscator(*options.UDPObfs)
+		if err != nil {
+			return nil, err
+		}
+	}
 	var udpTimeout time.Duration
 	if options.UDPTimeout != 0 {
 		udpTimeout = time.Duration(options.UDPTimeout)
@@ -72,26 +83,33 @@ func NewTUIC(ctx context.Context, router adapter.Router, logger log.ContextLogge
 	if err != nil {
 		return nil, err
 	}
-	var userList []int
-	var userNameList []string
-	var userUUIDList [][16]byte
-	var userPasswordList []string
-	for index, user := range options.Users {
-		if user.UUID == "" {
-			return nil, E.New("missing uuid for user ", index)
+	inbound.users, err = newUserManager(options.Users, func(it option.TUICUser) string {
+		return it.Name
+	}, func(indexes []int, users []option.TUICUser) error {
+		var userUUIDList [][16]byte
+		var userPasswordList []string
+		for index, user := range users {
+			if user.UUID == "" {
+				return E.New("missing uuid for user ", index)
+			}
+			userUUID, err := uuid.FromString(user.UUID)
+			if err != nil {
+				return E.Cause(err, "invalid uuid for user ", index)
+			}
+			userUUIDList = append(userUUIDList, userUUID)
+			userPasswordList = append(userPasswordList, user.Password)
 		}
-		userUUID, err := uuid.FromString(user.UUID)
-		if err != nil {
-			return nil, E.Cause(err, "invalid uuid for user ", index)
-		}
-		userList = append(userList, index)
-		userNameList = append(userNameList, user.Name)
-		userUUIDList = append(userUUIDList, userUUID)
-		userPasswordList = append(userPasswordList, user.Password)
+		service.UpdateUsers(indexes, userUUIDList, userPasswordList)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	inbound.users.limiter, err = newUserLimiter(ctx, logger, tag, options.UserLimits)
+	if err != nil {
+		return nil, err
 	}
-	service.UpdateUsers(userList, userUUIDList, userPasswordList)
 	inbound.server = service
-	inbound.userNameList = userNameList
 	return inbound, nil
 }
 
@@ -100,12 +118,17 @@ func (h *TUIC) newConnection(ctx context.Context, conn net.Conn, metadata adapte
 	metadata = h.createMetadata(conn, metadata)
 	h.logger.InfoContext(ctx, "inbound connection from ", metadata.Source)
 	userID, _ := auth.UserFromContext[int](ctx)
-	if userName := h.userNameList[userID]; userName != "" {
+	if userName, _ := h.users.Name(userID); userName != "" {
 		metadata.User = userName
 		h.logger.InfoContext(ctx, "[", userName, "] inbound connection to ", metadata.Destination)
 	} else {
 		h.logger.InfoContext(ctx, "inbound connection to ", metadata.Destination)
 	}
+	conn, done, err := h.users.NewConnection(userID, conn, metadata.Source)
+	if err != nil {
+		return err
+	}
+	defer done()
 	return h.router.RouteConnection(ctx, conn, metadata)
 }
 
@@ -114,12 +137,17 @@ func (h *TUIC) newPacketConnection(ctx context.Context, conn N.PacketConn, metad
 	metadata = h.createPacketMetadata(conn, metadata)
 	h.logger.InfoContext(ctx, "inbound packet connection from ", metadata.Source)
 	userID, _ := auth.UserFromContext[int](ctx)
-	if userName := h.userNameList[userID]; userName != "" {
+	if userName, _ := h.users.Name(userID); userName != "" {
 		metadata.User = userName
 		h.logger.InfoContext(ctx, "[", userName, "] inbound packet connection to ", metadata.Destination)
 	} else {
 		h.logger.InfoContext(ctx, "inbound packet connection to ", metadata.Destination)
 	}
+	conn, done, err := h.users.NewPacketConnection(userID, conn, metadata.Source)
+	if err != nil {
+		return err
+	}
+	defer done()
 	return h.router.RoutePacketConnection(ctx, conn, metadata)
 }
 
@@ -134,13 +162,29 @@ func (h *TUIC) Start() error {
 	if err != nil {
 		return err
 	}
+	if h.udpObfs != nil {
+		packetConn = udpobfs.NewPacketConn(packetConn, h.udpObfs)
+	}
 	return h.server.Start(packetConn)
 }
 
 func (h *TUIC) Close() error {
 	return common.Close(
 		&h.myInboundAdapter,
+		h.users,
 		h.tlsConfig,
 		common.PtrOrNil(h.server),
 	)
 }
+
+func (h *TUIC) Users() []any {
+	return h.users.ListAny()
+}
+
+func (h *TUIC) AddUsers(content []byte) error {
+	return h.users.AddJSON(content)
+}
+
+func (h *TUIC) RemoveUsers(names []string, indexes []int) error {
+	return h.users.Remove(names, indexes)
+}
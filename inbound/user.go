@@ -0,0 +1,266 @@
+package inbound
+
+import (
+	"io"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	F "github.com/sagernet/sing/common/format"
+	"github.com/sagernet/sing/common/json"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+)
+
+// userManager keeps the users of a multi-user inbound under stable indexes,
+// so that users can be added or removed while connections are running.
+type userManager[T any] struct {
+	access      sync.RWMutex
+	users       map[int]T
+	indexes     []int
+	nextIndex   int
+	connections map[int]map[*io.Closer]struct{}
+	userName    func(user T) string
+	update      func(indexes []int, users []T) error
+	limiter     *userLimiter
+}
+
+func newUserManager[T any](users []T, userName func(user T) string, update func(indexes []int, users []T) error) (*userManager[T], error) {
+	manager := &userManager[T]{
+		users:       make(map[int]T),
+		connections: make(map[int]map[*io.Closer]struct{}),
+		userName:    userName,
+		update:      update,
+	}
+	for _, user := range users {
+		manager.users[manager.nextIndex] = user
+		manager.indexes = append(manager.indexes, manager.nextIndex)
+		manager.nextIndex++
+	}
+	err := manager.updateLocked()
+	if err != nil {
+		return nil, err
+	}
+	return manager, nil
+}
+
+func (m *userManager[T]) updateLocked() error {
+	return m.update(m.indexes, common.Map(m.indexes, func(index int) T {
+		return m.users[index]
+	}))
+}
+
+func (m *userManager[T]) Load(index int) (T, bool) {
+	m.access.RLock()
+	defer m.access.RUnlock()
+	user, loaded := m.users[index]
+	return user, loaded
+}
+
+// Name returns the name of the user, or an empty string if the user has none.
+// The index is returned for display instead.
+func (m *userManager[T]) Name(index int) (name string, display string) {
+	user, loaded := m.Load(index)
+	if loaded {
+		name = m.userName(user)
+	}
+	if name == "" {
+		display = F.ToString(index)
+	} else {
+		display = name
+	}
+	return
+}
+
+// NameOr is like Name, but fallback is used if the user has no name,
+// e.g. the user identified by the Reality short ID.
+func (m *userManager[T]) NameOr(index int, fallback string) (name string, display string) {
+	name, display = m.Name(index)
+	if name == "" && fallback != "" {
+		name, display = fallback, fallback
+	}
+	return
+}
+
+func (m *userManager[T]) List() []T {
+	m.access.RLock()
+	defer m.access.RUnlock()
+	return common.Map(m.indexes, func(index int) T {
+		return m.users[index]
+	})
+}
+
+func (m *userManager[T]) ListAny() []any {
+	return common.Map(m.List(), func(it T) any {
+		return it
+	})
+}
+
+// AddJSON adds users from a JSON array.
+func (m *userManager[T]) AddJSON(content []byte) error {
+	var users []T
+	err := json.Unmarshal(content, &users)
+	if err != nil {
+		return E.Cause(err, "decode users")
+	}
+	return m.Add(users)
+}
+
+// Add adds users, managed users are required to have unique names.
+func (m *userManager[T]) Add(users []T) error {
+	m.access.Lock()
+	defer m.access.Unlock()
+	names := make(map[string]bool)
+	for _, index := range m.indexes {
+		names[m.userName(m.users[index])] = true
+	}
+	for i, user := range users {
+		name := m.userName(user)
+		if name == "" {
+			return E.New("missing name for user ", i)
+		}
+		if names[name] {
+			return E.New("duplicate user: ", name)
+		}
+		names[name] = true
+	}
+	originIndexes := m.indexes
+	originNextIndex := m.nextIndex
+	for _, user := range users {
+		m.users[m.nextIndex] = user
+		m.indexes = append(m.indexes, m.nextIndex)
+		m.nextIndex++
+	}
+	err := m.updateLocked()
+	if err != nil {
+		for index := originNextIndex; index < m.nextIndex; index++ {
+			delete(m.users, index)
+		}
+		m.indexes = originIndexes
+		m.nextIndex = originNextIndex
+		return err
+	}
+	return nil
+}
+
+// Remove removes users by name or by index and closes their connections.
+// Names and indexes are looked up separately, so users without a name
+// can be removed by index without colliding with named users.
+func (m *userManager[T]) Remove(names []string, indexes []int) error {
+	m.access.Lock()
+	nameMap := make(map[string]bool)
+	for _, name := range names {
+		nameMap[name] = true
+	}
+	indexMap := make(map[int]bool)
+	for _, index := range indexes {
+		indexMap[index] = true
+	}
+	var (
+		newIndexes     []int
+		removedIndexes []int
+	)
+	for _, index := range m.indexes {
+		name := m.userName(m.users[index])
+		if (name != "" && nameMap[name]) || indexMap[index] {
+			delete(nameMap, name)
+			delete(indexMap, index)
+			removedIndexes = append(removedIndexes, index)
+		} else {
+			newIndexes = append(newIndexes, index)
+		}
+	}
+	if len(nameMap) > 0 || len(indexMap) > 0 {
+		m.access.Unlock()
+		var missingUsers []string
+		for name := range nameMap {
+			missingUsers = append(missingUsers, name)
+		}
+		for index := range indexMap {
+			missingUsers = append(missingUsers, F.ToString("#", index))
+		}
+		return E.New("users not found: ", strings.Join(missingUsers, ", "))
+	}
+	originIndexes := m.indexes
+	m.indexes = newIndexes
+	err := m.updateLocked()
+	if err != nil {
+		m.indexes = originIndexes
+		m.access.Unlock()
+		return err
+	}
+	var connections []io.Closer
+	for _, index := range removedIndexes {
+		delete(m.users, index)
+		for conn := range m.connections[index] {
+			connections = append(connections, *conn)
+		}
+		delete(m.connections, index)
+	}
+	m.access.Unlock()
+	for _, conn := range connections {
+		conn.Close()
+	}
+	return nil
+}
+
+// Track registers a connection of the user until the returned function is called.
+// Connections of a user removed meanwhile are closed immediately.
+func (m *userManager[T]) Track(index int, conn io.Closer) func() {
+	m.access.Lock()
+	if _, loaded := m.users[index]; !loaded {
+		m.access.Unlock()
+		conn.Close()
+		return func() {}
+	}
+	connections := m.connections[index]
+	if connections == nil {
+		connections = make(map[*io.Closer]struct{})
+		m.connections[index] = connections
+	}
+	entry := &conn
+	connections[entry] = struct{}{}
+	m.access.Unlock()
+	return func() {
+		m.access.Lock()
+		delete(m.connections[index], entry)
+		if len(m.connections[index]) == 0 {
+			delete(m.connections, index)
+		}
+		m.access.Unlock()
+	}
+}
+
+// NewConnection applies the limits of the user to conn and tracks it,
+// the returned function must be called when the connection is closed.
+func (m *userManager[T]) NewConnection(index int, conn net.Conn, source M.Socksaddr) (net.Conn, func(), error) {
+	name, _ := m.Name(index)
+	conn, release, err := m.limiter.NewConnection(name, index, conn, source)
+	if err != nil {
+		return nil, nil, err
+	}
+	untrack := m.Track(index, conn)
+	return conn, func() {
+		untrack()
+		release()
+	}, nil
+}
+
+func (m *userManager[T]) NewPacketConnection(index int, conn N.PacketConn, source M.Socksaddr) (N.PacketConn, func(), error) {
+	name, _ := m.Name(index)
+	conn, release, err := m.limiter.NewPacketConnection(name, index, conn, source)
+	if err != nil {
+		return nil, nil, err
+	}
+	untrack := m.Track(index, conn)
+	return conn, func() {
+		untrack()
+		release()
+	}, nil
+}
+
+func (m *userManager[T]) Close() error {
+	return m.limiter.Close()
+}
@@ -0,0 +1,414 @@
+package inbound
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/ratelimit"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common/bufio"
+	E "github.com/sagernet/sing/common/exceptions"
+	F "github.com/sagernet/sing/common/format"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+	"github.com/sagernet/sing/service"
+)
+
+// userLimit is the parsed form of option.UserLimitOptions.
+type userLimit struct {
+	quota          int64
+	quotaResetDay  int
+	expire         time.Time
+	maxConnections int
+	maxIPs         int
+	rateLimit      option.RateLimitOptions
+}
+
+// userLimiter enforces quotas, expiry and speed limits of users of an inbound.
+type userLimiter struct {
+	ctx          context.Context
+	logger       log.ContextLogger
+	tag          string
+	cacheFile    adapter.CacheFile
+	limits       map[string]*userLimit
+	defaultLimit *userLimit
+	saveInterval time.Duration
+	access       sync.Mutex
+	states       map[userKey]*userLimitState
+	saveOnce     sync.Once
+	done         chan struct{}
+	closeOnce    sync.Once
+}
+
+// userKey identifies a user, users without a name are identified by their index.
+type userKey struct {
+	name  string
+	index int
+}
+
+func newUserKey(name string, index int) userKey {
+	if name != "" {
+		index = -1
+	}
+	return userKey{name, index}
+}
+
+// String returns the name of the user, or the index for users without a name.
+func (k userKey) String() string {
+	if k.name != "" {
+		return k.name
+	}
+	return F.ToString(k.index)
+}
+
+type userLimitState struct {
+	key           userKey
+	limit         *userLimit
+	periodStart   time.Time
+	upload        atomic.Int64
+	download      atomic.Int64
+	connections   int
+	ips           map[netip.Addr]int
+	closers       map[*net.Conn]struct{}
+	packetClosers map[*N.PacketConn]struct{}
+	rateLimiters  ratelimit.Limiters
+	exceeded      atomic.Bool
+	expireTimer   *time.Timer
+}
+
+func newUserLimiter(ctx context.Context, logger log.ContextLogger, tag string, options []option.UserLimitOptions) (*userLimiter, error) {
+	if len(options) == 0 {
+		return nil, nil
+	}
+	limiter := &userLimiter{
+		ctx:          ctx,
+		logger:       logger,
+		tag:          tag,
+		limits:       make(map[string]*userLimit),
+		saveInterval: C.UserUsageSaveInterval,
+		states:       make(map[userKey]*userLimitState),
+		done:         make(chan struct{}),
+	}
+	for i, limitOptions := range options {
+		limit := &userLimit{
+			quota:          int64(limitOptions.Quota),
+			quotaResetDay:  limitOptions.QuotaResetDay,
+			maxConnections: limitOptions.MaxConnections,
+			maxIPs:         limitOptions.MaxIPs,
+			rateLimit:      limitOptions.RateLimitOptions,
+		}
+		if limit.quotaResetDay == 0 {
+			limit.quotaResetDay = 1
+		} else if limit.quotaResetDay < 1 || limit.quotaResetDay > 28 {
+			return nil, E.New("user_limits[", i, "]: quota_reset_day must be between 1 and 28")
+		}
+		if limitOptions.Expire != "" {
+			expire, err := time.Parse(time.RFC3339, limitOptions.Expire)
+			if err != nil {
+				return nil, E.Cause(err, "user_limits[", i, "]: parse expire")
+			}
+			limit.expire = expire
+		}
+		if len(limitOptions.Name) == 0 {
+			if limiter.defaultLimit != nil {
+				return nil, E.New("user_limits[", i, "]: duplicate default limit")
+			}
+			limiter.defaultLimit = limit
+			continue
+		}
+		for _, name := range limitOptions.Name {
+			if _, loaded := limiter.limits[name]; loaded {
+				return nil, E.New("user_limits[", i, "]: duplicate limit for user ", name)
+			}
+			limiter.limits[name] = limit
+		}
+	}
+	return limiter, nil
+}
+
+func quotaPeriodStart(now time.Time, resetDay int) time.Time {
+	periodStart := time.Date(now.Year(), now.Month(), resetDay, 0, 0, 0, 0, now.Location())
+	if periodStart.After(now) {
+		periodStart = periodStart.AddDate(0, -1, 0)
+	}
+	return periodStart
+}
+
+func (l *userLimiter) loadState(key userKey) *userLimitState {
+	state, loaded := l.states[key]
+	if loaded {
+		return state
+	}
+	var limit *userLimit
+	if key.name != "" {
+		limit = l.limits[key.name]
+	}
+	if limit == nil {
+		limit = l.defaultLimit
+	}
+	if limit == nil {
+		return nil
+	}
+	state = &userLimitState{
+		key:           key,
+		limit:         limit,
+		periodStart:   quotaPeriodStart(time.Now(), limit.quotaResetDay),
+		ips:           make(map[netip.Addr]int),
+		closers:       make(map[*net.Conn]struct{}),
+		packetClosers: make(map[*N.PacketConn]struct{}),
+		rateLimiters:  ratelimit.NewLimiters(limit.rateLimit),
+	}
+	// users without a name have no stable identity across restarts, so their usage is not saved
+	if limit.quota > 0 && key.name != "" {
+		if l.cacheFile == nil {
+			l.cacheFile = service.FromContext[adapter.CacheFile](l.ctx)
+		}
+		if l.cacheFile != nil {
+			savedUsage := l.cacheFile.LoadUserUsage(l.tag, key.name)
+			if savedUsage != nil && savedUsage.PeriodStart.Equal(state.periodStart) {
+				state.upload.Store(savedUsage.Upload)
+				state.download.Store(savedUsage.Download)
+			}
+			l.saveOnce.Do(func() {
+				go l.loopSaveUsage()
+			})
+		}
+	}
+	if !limit.expire.IsZero() {
+		state.expireTimer = time.AfterFunc(time.Until(limit.expire), func() {
+			l.logger.Info("user ", key, " expired")
+			l.closeAll(state)
+		})
+	}
+	l.states[key] = state
+	return state
+}
+
+// checkLocked resets the quota at the start of a new period and checks
+// whether the user is allowed to open a connection from source.
+func (l *userLimiter) checkLocked(state *userLimitState, source netip.Addr) error {
+	limit := state.limit
+	now := time.Now()
+	if !limit.expire.IsZero() && !now.Before(limit.expire) {
+		return E.New("user ", state.key, " expired")
+	}
+	if limit.quota > 0 {
+		periodStart := quotaPeriodStart(now, limit.quotaResetDay)
+		if periodStart.After(state.periodStart) {
+			state.periodStart = periodStart
+			state.upload.Store(0)
+			state.download.Store(0)
+			state.exceeded.Store(false)
+		}
+		if state.upload.Load()+state.download.Load() >= limit.quota {
+			return E.New("user ", state.key, " exceeded traffic quota")
+		}
+	}
+	if limit.maxConnections > 0 && state.connections >= limit.maxConnections {
+		return E.New("user ", state.key, " exceeded max connections")
+	}
+	if limit.maxIPs > 0 && state.ips[source] == 0 && len(state.ips) >= limit.maxIPs {
+		return E.New("user ", state.key, " exceeded max IPs")
+	}
+	return nil
+}
+
+func (l *userLimiter) countFuncs(state *userLimitState) (readCounters []N.CountFunc, writeCounters []N.CountFunc) {
+	if state.limit.quota == 0 {
+		return
+	}
+	checkQuota := func() {
+		if state.upload.Load()+state.download.Load() >= state.limit.quota && state.exceeded.CompareAndSwap(false, true) {
+			l.logger.Info("user ", state.key, " exceeded traffic quota")
+			go l.closeAll(state)
+		}
+	}
+	readCounters = []N.CountFunc{func(n int64) {
+		state.upload.Add(n)
+		checkQuota()
+	}}
+	writeCounters = []N.CountFunc{func(n int64) {
+		state.download.Add(n)
+		checkQuota()
+	}}
+	return
+}
+
+func (l *userLimiter) acquire(key userKey, source M.Socksaddr) (*userLimitState, error) {
+	l.access.Lock()
+	defer l.access.Unlock()
+	state := l.loadState(key)
+	if state == nil {
+		return nil, nil
+	}
+	sourceAddr := source.Unwrap().Addr
+	err := l.checkLocked(state, sourceAddr)
+	if err != nil {
+		return nil, err
+	}
+	state.connections++
+	state.ips[sourceAddr]++
+	return state, nil
+}
+
+func (l *userLimiter) release(state *userLimitState, source M.Socksaddr) {
+	l.access.Lock()
+	state.connections--
+	sourceAddr := source.Unwrap().Addr
+	state.ips[sourceAddr]--
+	if state.ips[sourceAddr] <= 0 {
+		delete(state.ips, sourceAddr)
+	}
+	l.access.Unlock()
+	l.saveUsage(state)
+}
+
+// NewConnection checks the limits of the user and wraps conn with its quota counter and speed limit,
+// users without a name are identified by index. The returned function must be called when the connection is closed.
+func (l *userLimiter) NewConnection(name string, index int, conn net.Conn, source M.Socksaddr) (net.Conn, func(), error) {
+	if l == nil {
+		return conn, func() {}, nil
+	}
+	state, err := l.acquire(newUserKey(name, index), source)
+	if err != nil {
+		return nil, nil, err
+	}
+	if state == nil {
+		return conn, func() {}, nil
+	}
+	readCounters, writeCounters := l.countFuncs(state)
+	if len(readCounters) > 0 {
+		conn = bufio.NewCounterConn(conn, readCounters, writeCounters)
+	}
+	conn = state.rateLimiters.Conn(conn)
+	entry := &conn
+	l.access.Lock()
+	state.closers[entry] = struct{}{}
+	l.access.Unlock()
+	return conn, func() {
+		l.access.Lock()
+		delete(state.closers, entry)
+		l.access.Unlock()
+		l.release(state, source)
+	}, nil
+}
+
+func (l *userLimiter) NewPacketConnection(name string, index int, conn N.PacketConn, source M.Socksaddr) (N.PacketConn, func(), error) {
+	if l == nil {
+		return conn, func() {}, nil
+	}
+	state, err := l.acquire(newUserKey(name, index), source)
+	if err != nil {
+		return nil, nil, err
+	}
+	if state == nil {
+		return conn, func() {}, nil
+	}
+	readCounters, writeCounters := l.countFuncs(state)
+	if len(readCounters) > 0 {
+		conn = bufio.NewCounterPacketConn(conn, readCounters, writeCounters)
+	}
+	conn = state.rateLimiters.PacketConn(conn)
+	entry := &conn
+	l.access.Lock()
+	state.packetClosers[entry] = struct{}{}
+	l.access.Unlock()
+	return conn, func() {
+		l.access.Lock()
+		delete(state.packetClosers, entry)
+		l.access.Unlock()
+		l.release(state, source)
+	}, nil
+}
+
+func (l *userLimiter) closeAll(state *userLimitState) {
+	l.access.Lock()
+	var (
+		conns       []net.Conn
+		packetConns []N.PacketConn
+	)
+	for conn := range state.closers {
+		conns = append(conns, *conn)
+	}
+	for conn := range state.packetClosers {
+		packetConns = append(packetConns, *conn)
+	}
+	l.access.Unlock()
+	for _, conn := range conns {
+		conn.Close()
+	}
+	for _, conn := range packetConns {
+		conn.Close()
+	}
+}
+
+func (l *userLimiter) saveUsage(state *userLimitState) {
+	if state.limit.quota == 0 || state.key.name == "" || l.cacheFile == nil {
+		return
+	}
+	l.access.Lock()
+	usage := &adapter.SavedUserUsage{
+		PeriodStart: state.periodStart,
+		Upload:      state.upload.Load(),
+		Download:    state.download.Load(),
+	}
+	l.access.Unlock()
+	err := l.cacheFile.SaveUserUsage(l.tag, state.key.name, usage)
+	if err != nil {
+		l.logger.Error(E.Cause(err, "save usage of user ", state.key))
+	}
+}
+
+// loopSaveUsage saves usage of active users periodically,
+// so that traffic of long-lived connections is not lost on crashes.
+func (l *userLimiter) loopSaveUsage() {
+	ticker := time.NewTicker(l.saveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-l.done:
+			return
+		}
+		l.access.Lock()
+		var states []*userLimitState
+		for _, state := range l.states {
+			if state.connections > 0 {
+				states = append(states, state)
+			}
+		}
+		l.access.Unlock()
+		for _, state := range states {
+			l.saveUsage(state)
+		}
+	}
+}
+
+func (l *userLimiter) Close() error {
+	if l == nil {
+		return nil
+	}
+	l.closeOnce.Do(func() {
+		close(l.done)
+	})
+	l.access.Lock()
+	states := make([]*userLimitState, 0, len(l.states))
+	for _, state := range l.states {
+		states = append(states, state)
+		if state.expireTimer != nil {
+			state.expireTimer.Stop()
+		}
+	}
+	l.access.Unlock()
+	for _, state := range states {
+		l.saveUsage(state)
+	}
+	return nil
+}
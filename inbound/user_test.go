@@ -0,0 +1,156 @@
+package inbound
+
+import (
+	"context"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	M "github.com/sagernet/sing/common/metadata"
+	"github.com/sagernet/sing/service"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testCloser struct {
+	closed bool
+}
+
+func (c *testCloser) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestUserManager(t *testing.T) {
+	t.Parallel()
+	var (
+		updatedIndexes []int
+		updatedUsers   []option.TrojanUser
+	)
+	manager, err := newUserManager([]option.TrojanUser{{Password: "anonymous"}, {Name: "a", Password: "a"}}, func(it option.TrojanUser) string {
+		return it.Name
+	}, func(indexes []int, users []option.TrojanUser) error {
+		updatedIndexes = indexes
+		updatedUsers = users
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []int{0, 1}, updatedIndexes)
+
+	require.Error(t, manager.AddJSON([]byte(`[{"password":"b"}]`)))
+	require.Error(t, manager.AddJSON([]byte(`[{"name":"a","password":"b"}]`)))
+	require.NoError(t, manager.AddJSON([]byte(`[{"name":"b","password":"b"}]`)))
+	require.Equal(t, []int{0, 1, 2}, updatedIndexes)
+	require.Equal(t, "b", updatedUsers[2].Password)
+
+	conn := new(testCloser)
+	done := manager.Track(1, conn)
+	require.Error(t, manager.Remove([]string{"c"}, nil))
+	require.NoError(t, manager.Remove([]string{"a"}, nil))
+	require.True(t, conn.closed)
+	done()
+	require.Equal(t, []int{0, 2}, updatedIndexes)
+
+	name, display := manager.Name(2)
+	require.Equal(t, "b", name)
+	require.Equal(t, "b", display)
+	name, display = manager.Name(0)
+	require.Empty(t, name)
+	require.Equal(t, "0", display)
+
+	conn = new(testCloser)
+	manager.Track(1, conn)()
+	require.True(t, conn.closed)
+
+	require.Error(t, manager.Remove([]string{"0"}, nil))
+	require.NoError(t, manager.Remove(nil, []int{0}))
+	require.Equal(t, []int{2}, updatedIndexes)
+	require.Error(t, manager.Remove(nil, []int{0}))
+
+	require.NoError(t, manager.AddJSON([]byte(`[{"name":"2","password":"c"}]`)))
+	require.Equal(t, []int{2, 3}, updatedIndexes)
+	require.NoError(t, manager.Remove([]string{"2"}, nil))
+	require.Equal(t, []int{2}, updatedIndexes)
+	require.Equal(t, "b", updatedUsers[0].Password)
+}
+
+func TestUserLimiter(t *testing.T) {
+	t.Parallel()
+	limiter, err := newUserLimiter(context.Background(), log.NewNOPFactory().Logger(), "test", []option.UserLimitOptions{
+		{Name: []string{"a"}, MaxConnections: 1},
+		{MaxIPs: 1, Expire: "2000-01-01T00:00:00Z"},
+	})
+	require.NoError(t, err)
+	source := M.ParseSocksaddr("127.0.0.1:1000")
+	conn, done, err := limiter.NewConnection("a", 0, new(net.TCPConn), source)
+	require.NoError(t, err)
+	require.NotNil(t, conn)
+	_, _, err = limiter.NewConnection("a", 0, new(net.TCPConn), source)
+	require.Error(t, err)
+	done()
+	_, done, err = limiter.NewConnection("a", 0, new(net.TCPConn), source)
+	require.NoError(t, err)
+	done()
+	_, _, err = limiter.NewConnection("b", 1, new(net.TCPConn), source)
+	require.ErrorContains(t, err, "expired")
+	// users without a name get the default limit too
+	_, _, err = limiter.NewConnection("", 2, new(net.TCPConn), source)
+	require.ErrorContains(t, err, "user 2 expired")
+
+	require.Equal(t, time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC), quotaPeriodStart(time.Date(2024, 2, 14, 12, 0, 0, 0, time.UTC), 15))
+	require.Equal(t, time.Date(2024, 2, 15, 0, 0, 0, 0, time.UTC), quotaPeriodStart(time.Date(2024, 2, 15, 0, 0, 0, 0, time.UTC), 15))
+}
+
+// testUsageCache keeps saved usage in memory, other cache file methods are not implemented.
+type testUsageCache struct {
+	adapter.CacheFile
+	access sync.Mutex
+	usage  map[string]adapter.SavedUserUsage
+}
+
+func (c *testUsageCache) LoadUserUsage(inbound string, user string) *adapter.SavedUserUsage {
+	c.access.Lock()
+	defer c.access.Unlock()
+	usage, loaded := c.usage[inbound+"/"+user]
+	if !loaded {
+		return nil
+	}
+	return &usage
+}
+
+func (c *testUsageCache) SaveUserUsage(inbound string, user string, usage *adapter.SavedUserUsage) error {
+	c.access.Lock()
+	defer c.access.Unlock()
+	c.usage[inbound+"/"+user] = *usage
+	return nil
+}
+
+func TestUserLimiterSaveUsage(t *testing.T) {
+	t.Parallel()
+	cacheFile := &testUsageCache{usage: make(map[string]adapter.SavedUserUsage)}
+	ctx := service.ContextWith[adapter.CacheFile](context.Background(), cacheFile)
+	limiter, err := newUserLimiter(ctx, log.NewNOPFactory().Logger(), "test", []option.UserLimitOptions{
+		{Quota: 1 << 30},
+	})
+	require.NoError(t, err)
+	limiter.saveInterval = 10 * time.Millisecond
+	defer limiter.Close()
+	serverConn, clientConn := net.Pipe()
+	defer serverConn.Close()
+	go io.Copy(io.Discard, serverConn)
+	conn, done, err := limiter.NewConnection("a", 0, clientConn, M.ParseSocksaddr("127.0.0.1:1000"))
+	require.NoError(t, err)
+	defer done()
+	_, err = conn.Write(make([]byte, 1024))
+	require.NoError(t, err)
+	// usage of the open connection is saved without waiting for it to be closed
+	require.Eventually(t, func() bool {
+		usage := cacheFile.LoadUserUsage("test", "a")
+		return usage != nil && usage.Download == 1024
+	}, 5*time.Second, 10*time.Millisecond)
+}
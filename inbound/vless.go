@@ -19,7 +19,6 @@ import (
 	"github.com/sagernet/sing/common"
 	"github.com/sagernet/sing/common/auth"
 	E "github.com/sagernet/sing/common/exceptions"
-	F "github.com/sagernet/sing/common/format"
 	M "github.com/sagernet/sing/common/metadata"
 	N "github.com/sagernet/sing/common/network"
 )
@@ -27,15 +26,17 @@ import (
 var (
 	_ adapter.Inbound           = (*VLESS)(nil)
 	_ adapter.InjectableInbound = (*VLESS)(nil)
+	_ adapter.UserManager       = (*VLESS)(nil)
 )
 
 type VLESS struct {
 	myInboundAdapter
 	ctx       context.Context
-	users     []option.VLESSUser
+	users     *userManager[option.VLESSUser]
 	service   *vless.Service[int]
 	tlsConfig tls.ServerConfig
 	transport adapter.V2RayServerTransport
+	injected  *injectListener
 }
 
 func NewVLESS(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.VLESSInboundOptions) (*VLESS, error) {
@@ -49,8 +50,7 @@ func NewVLESS(ctx context.Context, router adapter.Router, logger log.ContextLogg
 			tag:           tag,
 			listenOptions: options.ListenOptions,
 		},
-		ctx:   ctx,
-		users: options.Users,
+		ctx: ctx,
 	}
 	var err error
 	inbound.router, err = mux.NewRouterWithOptions(inbound.router, logger, common.PtrValueOrDefault(options.Multiplex))
@@ -58,13 +58,23 @@ func NewVLESS(ctx context.Context, router adapter.Router, logger log.ContextLogg
 		return nil, err
 	}
 	service := vless.NewService[int](logger, adapter.NewUpstreamContextHandler(inbound.newConnection, inbound.newPacketConnection, inbound))
-	service.UpdateUsers(common.MapIndexed(inbound.users, func(index int, _ option.VLESSUser) int {
-		return index
-	}), common.Map(inbound.users, func(it option.VLESSUser) string {
-		return it.UUID
-	}), common.Map(inbound.users, func(it option.VLESSUser) string {
-		return it.Flow
-	}))
+	inbound.users, err = newUserManager(options.Users, func(it option.VLESSUser) string {
+		return it.Name
+	}, func(indexes []int, users []option.VLESSUser) error {
+		service.UpdateUsers(indexes, common.Map(users, func(it option.VLESSUser) string {
+			return it.UUID
+		}), common.Map(users, func(it option.VLESSUser) string {
+			return it.Flow
+		}))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	inbound.users.limiter, err = newUserLimiter(ctx, logger, tag, options.UserLimits)
+	if err != nil {
+		return nil, err
+	}
 	inbound.service = service
 	if options.TLS != nil {
 		inbound.tlsConfig, err = tls.NewServer(ctx, logger, common.PtrValueOrDefault(options.TLS))
@@ -77,6 +87,9 @@ func NewVLESS(ctx context.Context, router adapter.Router, logger log.ContextLogg
 		if err != nil {
 			return nil, E.Cause(err, "create server transport: ", options.Transport.Type)
 		}
+		if common.Contains(inbound.transport.Network(), N.NetworkTCP) {
+			inbound.injected = newInjectListener(nil)
+		}
 	}
 	inbound.connHandler = inbound
 	return inbound, nil
@@ -105,6 +118,14 @@ func (h *VLESS) Start() error {
 			}
 		}()
 	}
+	if h.injected != nil {
+		go func() {
+			sErr := h.transport.Serve(h.injected)
+			if sErr != nil && !E.IsClosed(sErr) {
+				h.logger.Error("transport serve error: ", sErr)
+			}
+		}()
+	}
 	if common.Contains(h.transport.Network(), N.NetworkUDP) {
 		udpConn, err := h.myInboundAdapter.ListenUDP()
 		if err != nil {
@@ -124,8 +145,10 @@ func (h *VLESS) Close() error {
 	return common.Close(
 		h.service,
 		&h.myInboundAdapter,
+		h.users,
 		h.tlsConfig,
 		h.transport,
+		h.injected,
 	)
 }
 
@@ -135,12 +158,19 @@ func (h *VLESS) newTransportConnection(ctx context.Context, conn net.Conn, metad
 }
 
 func (h *VLESS) NewConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext) error {
+	if h.transport != nil && isDemuxContext(ctx) {
+		if h.injected == nil {
+			return E.New("inject: TCP unsupported by transport")
+		}
+		return h.injected.Inject(conn)
+	}
 	var err error
 	if h.tlsConfig != nil && h.transport == nil {
 		conn, err = tls.ServerHandshake(ctx, conn, h.tlsConfig)
 		if err != nil {
 			return err
 		}
+		metadata.User = tls.UserFromConn(conn)
 	}
 	return h.service.NewConnection(adapter.WithContext(log.ContextWithNewID(ctx), &metadata), conn, adapter.UpstreamMetadata(metadata))
 }
@@ -154,12 +184,13 @@ func (h *VLESS) newConnection(ctx context.Context, conn net.Conn, metadata adapt
 	if !loaded {
 		return os.ErrInvalid
 	}
-	user := h.users[userIndex].Name
-	if user == "" {
-		user = F.ToString(userIndex)
-	} else {
-		metadata.User = user
+	userName, user := h.users.NameOr(userIndex, metadata.User)
+	metadata.User = userName
+	conn, done, err := h.users.NewConnection(userIndex, conn, metadata.Source)
+	if err != nil {
+		return err
 	}
+	defer done()
 	h.logger.InfoContext(ctx, "[", user, "] inbound connection to ", metadata.Destination)
 	return h.router.RouteConnection(ctx, conn, metadata)
 }
@@ -169,12 +200,8 @@ func (h *VLESS) newPacketConnection(ctx context.Context, conn N.PacketConn, meta
 	if !loaded {
 		return os.ErrInvalid
 	}
-	user := h.users[userIndex].Name
-	if user == "" {
-		user = F.ToString(userIndex)
-	} else {
-		metadata.User = user
-	}
+	userName, user := h.users.NameOr(userIndex, metadata.User)
+	metadata.User = userName
 	if metadata.Destination.Fqdn == packetaddr.SeqPacketMagicAddress {
 		metadata.Destination = M.Socksaddr{}
 		conn = packetaddr.NewConn(conn.(vmess.PacketConn), metadata.Destination)
@@ -182,9 +209,26 @@ func (h *VLESS) newPacketConnection(ctx context.Context, conn N.PacketConn, meta
 	} else {
 		h.logger.InfoContext(ctx, "[", user, "] inbound packet connection to ", metadata.Destination)
 	}
+	conn, done, err := h.users.NewPacketConnection(userIndex, conn, metadata.Source)
+	if err != nil {
+		return err
+	}
+	defer done()
 	return h.router.RoutePacketConnection(ctx, conn, metadata)
 }
 
+func (h *VLESS) Users() []any {
+	return h.users.ListAny()
+}
+
+func (h *VLESS) AddUsers(content []byte) error {
+	return h.users.AddJSON(content)
+}
+
+func (h *VLESS) RemoveUsers(names []string, indexes []int) error {
+	return h.users.Remove(names, indexes)
+}
+
 var _ adapter.V2RayServerTransportHandler = (*vlessTransportHandler)(nil)
 
 type vlessTransportHandler VLESS
@@ -18,7 +18,6 @@ import (
 	"github.com/sagernet/sing/common"
 	"github.com/sagernet/sing/common/auth"
 	E "github.com/sagernet/sing/common/exceptions"
-	F "github.com/sagernet/sing/common/format"
 	M "github.com/sagernet/sing/common/metadata"
 	N "github.com/sagernet/sing/common/network"
 	"github.com/sagernet/sing/common/ntp"
@@ -27,15 +26,17 @@ import (
 var (
 	_ adapter.Inbound           = (*VMess)(nil)
 	_ adapter.InjectableInbound = (*VMess)(nil)
+	_ adapter.UserManager       = (*VMess)(nil)
 )
 
 type VMess struct {
 	myInboundAdapter
 	ctx       context.Context
 	service   *vmess.Service[int]
-	users     []option.VMessUser
+	users     *userManager[option.VMessUser]
 	tlsConfig tls.ServerConfig
 	transport adapter.V2RayServerTransport
+	injected  *injectListener
 }
 
 func NewVMess(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.VMessInboundOptions) (*VMess, error) {
@@ -49,8 +50,7 @@ func NewVMess(ctx context.Context, router adapter.Router, logger log.ContextLogg
 			tag:           tag,
 			listenOptions: options.ListenOptions,
 		},
-		ctx:   ctx,
-		users: options.Users,
+		ctx: ctx,
 	}
 	var err error
 	inbound.router, err = mux.NewRouterWithOptions(inbound.router, logger, common.PtrValueOrDefault(options.Multiplex))
@@ -66,13 +66,19 @@ func NewVMess(ctx context.Context, router adapter.Router, logger log.ContextLogg
 	}
 	service := vmess.NewService[int](adapter.NewUpstreamContextHandler(inbound.newConnection, inbound.newPacketConnection, inbound), serviceOptions...)
 	inbound.service = service
-	err = service.UpdateUsers(common.MapIndexed(options.Users, func(index int, it option.VMessUser) int {
-		return index
-	}), common.Map(options.Users, func(it option.VMessUser) string {
-		return it.UUID
-	}), common.Map(options.Users, func(it option.VMessUser) int {
-		return it.AlterId
-	}))
+	inbound.users, err = newUserManager(options.Users, func(it option.VMessUser) string {
+		return it.Name
+	}, func(indexes []int, users []option.VMessUser) error {
+		return service.UpdateUsers(indexes, common.Map(users, func(it option.VMessUser) string {
+			return it.UUID
+		}), common.Map(users, func(it option.VMessUser) int {
+			return it.AlterId
+		}))
+	})
+	if err != nil {
+		return nil, err
+	}
+	inbound.users.limiter, err = newUserLimiter(ctx, logger, tag, options.UserLimits)
 	if err != nil {
 		return nil, err
 	}
@@ -87,6 +93,9 @@ func NewVMess(ctx context.Context, router adapter.Router, logger log.ContextLogg
 		if err != nil {
 			return nil, E.Cause(err, "create server transport: ", options.Transport.Type)
 		}
+		if common.Contains(inbound.transport.Network(), N.NetworkTCP) {
+			inbound.injected = newInjectListener(nil)
+		}
 	}
 	inbound.connHandler = inbound
 	return inbound, nil
@@ -115,6 +124,14 @@ func (h *VMess) Start() error {
 			}
 		}()
 	}
+	if h.injected != nil {
+		go func() {
+			sErr := h.transport.Serve(h.injected)
+			if sErr != nil && !E.IsClosed(sErr) {
+				h.logger.Error("transport serve error: ", sErr)
+			}
+		}()
+	}
 	if common.Contains(h.transport.Network(), N.NetworkUDP) {
 		udpConn, err := h.myInboundAdapter.ListenUDP()
 		if err != nil {
@@ -134,8 +151,10 @@ func (h *VMess) Close() error {
 	return common.Close(
 		h.service,
 		&h.myInboundAdapter,
+		h.users,
 		h.tlsConfig,
 		h.transport,
+		h.injected,
 	)
 }
 
@@ -145,12 +164,19 @@ func (h *VMess) newTransportConnection(ctx context.Context, conn net.Conn, metad
 }
 
 func (h *VMess) NewConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext) error {
+	if h.transport != nil && isDemuxContext(ctx) {
+		if h.injected == nil {
+			return E.New("inject: TCP unsupported by transport")
+		}
+		return h.injected.Inject(conn)
+	}
 	var err error
 	if h.tlsConfig != nil && h.transport == nil {
 		conn, err = tls.ServerHandshake(ctx, conn, h.tlsConfig)
 		if err != nil {
 			return err
 		}
+		metadata.User = tls.UserFromConn(conn)
 	}
 	return h.service.NewConnection(adapter.WithContext(log.ContextWithNewID(ctx), &metadata), conn, adapter.UpstreamMetadata(metadata))
 }
@@ -164,12 +190,13 @@ func (h *VMess) newConnection(ctx context.Context, conn net.Conn, metadata adapt
 	if !loaded {
 		return os.ErrInvalid
 	}
-	user := h.users[userIndex].Name
-	if user == "" {
-		user = F.ToString(userIndex)
-	} else {
-		metadata.User = user
+	userName, user := h.users.NameOr(userIndex, metadata.User)
+	metadata.User = userName
+	conn, done, err := h.users.NewConnection(userIndex, conn, metadata.Source)
+	if err != nil {
+		return err
 	}
+	defer done()
 	h.logger.InfoContext(ctx, "[", user, "] inbound connection to ", metadata.Destination)
 	return h.router.RouteConnection(ctx, conn, metadata)
 }
@@ -179,12 +206,8 @@ func (h *VMess) newPacketConnection(ctx context.Context, conn N.PacketConn, meta
 	if !loaded {
 		return os.ErrInvalid
 	}
-	user := h.users[userIndex].Name
-	if user == "" {
-		user = F.ToString(userIndex)
-	} else {
-		metadata.User = user
-	}
+	userName, user := h.users.NameOr(userIndex, metadata.User)
+	metadata.User = userName
 	if metadata.Destination.Fqdn == packetaddr.SeqPacketMagicAddress {
 		metadata.Destination = M.Socksaddr{}
 		conn = packetaddr.NewConn(conn.(vmess.PacketConn), metadata.Destination)
@@ -192,9 +215,26 @@ func (h *VMess) newPacketConnection(ctx context.Context, conn N.PacketConn, meta
 	} else {
 		h.logger.InfoContext(ctx, "[", user, "] inbound packet connection to ", metadata.Destination)
 	}
+	conn, done, err := h.users.NewPacketConnection(userIndex, conn, metadata.Source)
+	if err != nil {
+		return err
+	}
+	defer done()
 	return h.router.RoutePacketConnection(ctx, conn, metadata)
 }
 
+func (h *VMess) Users() []any {
+	return h.users.ListAny()
+}
+
+func (h *VMess) AddUsers(content []byte) error {
+	return h.users.AddJSON(content)
+}
+
+func (h *VMess) RemoveUsers(names []string, indexes []int) error {
+	return h.users.Remove(names, indexes)
+}
+
 var _ adapter.V2RayServerTransportHandler = (*vmessTransportHandler)(nil)
 
 type vmessTransportHandler VMess
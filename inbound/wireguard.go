@@ -0,0 +1,308 @@
+//go:build with_wireguard
+
+package inbound
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing-box/transport/wireguard"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+	"github.com/sagernet/wireguard-go/device"
+)
+
+var (
+	_ adapter.Inbound     = (*WireGuard)(nil)
+	_ adapter.UserManager = (*WireGuard)(nil)
+)
+
+// WireGuard accepts peers on the listen port and routes their traffic,
+// peers are managed as users named by their public keys.
+type WireGuard struct {
+	myInboundAdapter
+	ipcConf    string
+	workers    int
+	udpTimeout time.Duration
+	tunDevice  wireguard.ServerDevice
+	device     *device.Device
+	users      *userManager[option.WireGuardPeer]
+	peerAccess sync.RWMutex
+	peers      []wireGuardPeer
+	amnezia    *wireguard.AmneziaObfuscator
+}
+
+type wireGuardPeer struct {
+	index      int
+	config     wireguard.PeerConfig
+	allowedIPs []netip.Prefix
+}
+
+func NewWireGuard(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.WireGuardInboundOptions) (*WireGuard, error) {
+	inbound := &WireGuard{
+		myInboundAdapter: myInboundAdapter{
+			protocol:      C.TypeWireGuard,
+			network:       []string{N.NetworkUDP},
+			ctx:           ctx,
+			router:        router,
+			logger:        logger,
+			tag:           tag,
+			listenOptions: options.ListenOptions,
+		},
+		workers: options.Workers,
+	}
+	if len(options.LocalAddress) == 0 {
+		return nil, E.New("missing local address")
+	}
+	privateKey, err := base64.StdEncoding.DecodeString(options.PrivateKey)
+	if err != nil {
+		return nil, E.Cause(err, "decode private key")
+	}
+	inbound.ipcConf = "private_key=" + hex.EncodeToString(privateKey)
+	if options.UDPTimeout != 0 {
+		inbound.udpTimeout = time.Duration(options.UDPTimeout)
+	} else {
+		inbound.udpTimeout = C.UDPTimeout
+	}
+	mtu := options.MTU
+	if mtu == 0 {
+		mtu = 1408
+	}
+	tunDevice, err := wireguard.NewStackServerDevice(options.LocalAddress, mtu)
+	if err != nil {
+		return nil, E.Cause(err, "create WireGuard device")
+	}
+	inbound.tunDevice = tunDevice
+	if options.Amnezia != nil {
+		inbound.amnezia, err = wireguard.NewAmneziaObfuscator(*options.Amnezia)
+		if err != nil {
+			return nil, E.Cause(err, "create amnezia obfuscator")
+		}
+	}
+	inbound.users, err = newUserManager(options.Peers, func(it option.WireGuardPeer) string {
+		return it.PublicKey
+	}, inbound.updatePeers)
+	if err != nil {
+		return nil, err
+	}
+	inbound.users.limiter, err = newUserLimiter(ctx, logger, tag, options.UserLimits)
+	if err != nil {
+		return nil, err
+	}
+	return inbound, nil
+}
+
+func (w *WireGuard) Start() error {
+	err := w.tunDevice.SetHandler(w.ctx, (*wireGuardHandler)(w), w.udpTimeout)
+	if err != nil {
+		return err
+	}
+	bind := wireguard.NewClientBind(w.ctx, w, (*wireGuardListener)(w), false, netip.AddrPort{}, [3]uint8{})
+	wgDevice := device.NewDevice(w.tunDevice, bind, &device.Logger{
+		Verbosef: func(format string, args ...interface{}) {
+			w.logger.Debug(fmt.Sprintf(strings.ToLower(format), args...))
+		},
+		Errorf: func(format string, args ...interface{}) {
+			w.logger.Error(fmt.Sprintf(strings.ToLower(format), args...))
+		},
+	}, w.workers)
+	w.peerAccess.Lock()
+	ipcConf := w.ipcConf
+	for _, peer := range w.peers {
+		ipcConf += peer.config.GenerateIpcLines()
+	}
+	err = wgDevice.IpcSet(ipcConf)
+	if err == nil {
+		w.device = wgDevice
+	}
+	w.peerAccess.Unlock()
+	if err != nil {
+		wgDevice.Close()
+		return E.Cause(err, "setup wireguard")
+	}
+	return w.tunDevice.Start()
+}
+
+func (w *WireGuard) Close() error {
+	if w.device != nil {
+		w.device.Close()
+	}
+	return common.Close(
+		w.tunDevice,
+		w.users,
+	)
+}
+
+// updatePeers applies added and removed peers to the running device.
+func (w *WireGuard) updatePeers(indexes []int, users []option.WireGuardPeer) error {
+	peers := make([]wireGuardPeer, 0, len(users))
+	for i, user := range users {
+		config, err := wireguard.ParseServerPeer(user)
+		if err != nil {
+			return E.Cause(err, "parse peer ", user.PublicKey)
+		}
+		peer := wireGuardPeer{
+			index:  indexes[i],
+			config: config,
+		}
+		for _, allowedIP := range config.AllowedIPs {
+			prefix, err := netip.ParsePrefix(allowedIP)
+			if err != nil {
+				return E.Cause(err, "parse allowed_ips of peer ", user.PublicKey)
+			}
+			peer.allowedIPs = append(peer.allowedIPs, prefix.Masked())
+		}
+		peers = append(peers, peer)
+	}
+	w.peerAccess.Lock()
+	defer w.peerAccess.Unlock()
+	if w.device != nil {
+		var ipcLines []string
+		for _, oldPeer := range w.peers {
+			if !common.Any(peers, func(it wireGuardPeer) bool {
+				return it.config.PublicKey == oldPeer.config.PublicKey
+			}) {
+				ipcLines = append(ipcLines, "public_key="+oldPeer.config.PublicKey, "remove=true")
+			}
+		}
+		for _, newPeer := range peers {
+			if !common.Any(w.peers, func(it wireGuardPeer) bool {
+				return it.config.PublicKey == newPeer.config.PublicKey
+			}) {
+				ipcLines = append(ipcLines, strings.Split(strings.TrimPrefix(newPeer.config.GenerateIpcLines(), "\n"), "\n")...)
+			}
+		}
+		if len(ipcLines) > 0 {
+			err := w.device.IpcSet(strings.Join(ipcLines, "\n"))
+			if err != nil {
+				return E.Cause(err, "update peers")
+			}
+		}
+	}
+	w.peers = peers
+	return nil
+}
+
+// lookupPeer returns the index of the peer whose allowed IPs contain the inner source address.
+func (w *WireGuard) lookupPeer(source netip.Addr) (int, bool) {
+	w.peerAccess.RLock()
+	defer w.peerAccess.RUnlock()
+	var (
+		peerIndex int
+		peerBits  = -1
+	)
+	for _, peer := range w.peers {
+		for _, prefix := range peer.allowedIPs {
+			if prefix.Bits() > peerBits && prefix.Contains(source) {
+				peerIndex = peer.index
+				peerBits = prefix.Bits()
+			}
+		}
+	}
+	return peerIndex, peerBits >= 0
+}
+
+func (w *WireGuard) newConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext) error {
+	ctx = log.ContextWithNewID(ctx)
+	metadata = w.createMetadata(conn, metadata)
+	w.logger.InfoContext(ctx, "inbound connection from ", metadata.Source)
+	peerIndex, loaded := w.lookupPeer(metadata.Source.Addr)
+	if !loaded {
+		return E.New("unknown peer for ", metadata.Source)
+	}
+	metadata.User, _ = w.users.Name(peerIndex)
+	w.logger.InfoContext(ctx, "[", metadata.User, "] inbound connection to ", metadata.Destination)
+	conn, done, err := w.users.NewConnection(peerIndex, conn, metadata.Source)
+	if err != nil {
+		return err
+	}
+	defer done()
+	return w.router.RouteConnection(ctx, conn, metadata)
+}
+
+func (w *WireGuard) newPacketConnection(ctx context.Context, conn N.PacketConn, metadata adapter.InboundContext) error {
+	ctx = log.ContextWithNewID(ctx)
+	metadata = w.createPacketMetadata(conn, metadata)
+	w.logger.InfoContext(ctx, "inbound packet connection from ", metadata.Source)
+	peerIndex, loaded := w.lookupPeer(metadata.Source.Addr)
+	if !loaded {
+		return E.New("unknown peer for ", metadata.Source)
+	}
+	metadata.User, _ = w.users.Name(peerIndex)
+	w.logger.InfoContext(ctx, "[", metadata.User, "] inbound packet connection to ", metadata.Destination)
+	conn, done, err := w.users.NewPacketConnection(peerIndex, conn, metadata.Source)
+	if err != nil {
+		return err
+	}
+	defer done()
+	return w.router.RoutePacketConnection(ctx, conn, metadata)
+}
+
+func (w *WireGuard) Users() []any {
+	return w.users.ListAny()
+}
+
+func (w *WireGuard) AddUsers(content []byte) error {
+	return w.users.AddJSON(content)
+}
+
+func (w *WireGuard) RemoveUsers(names []string, indexes []int) error {
+	return w.users.Remove(names, indexes)
+}
+
+type wireGuardHandler WireGuard
+
+func (h *wireGuardHandler) NewConnection(ctx context.Context, conn net.Conn, upstreamMetadata M.Metadata) error {
+	var metadata adapter.InboundContext
+	metadata.Source = upstreamMetadata.Source
+	metadata.Destination = upstreamMetadata.Destination
+	err := (*WireGuard)(h).newConnection(ctx, conn, metadata)
+	if err != nil {
+		h.NewError(ctx, err)
+	}
+	return nil
+}
+
+func (h *wireGuardHandler) NewPacketConnection(ctx context.Context, conn N.PacketConn, upstreamMetadata M.Metadata) error {
+	var metadata adapter.InboundContext
+	metadata.Source = upstreamMetadata.Source
+	metadata.Destination = upstreamMetadata.Destination
+	err := (*WireGuard)(h).newPacketConnection(ctx, conn, metadata)
+	if err != nil {
+		h.NewError(ctx, err)
+	}
+	return nil
+}
+
+// wireGuardListener opens the listen port for the bind, which reopens it after read errors.
+type wireGuardListener WireGuard
+
+func (l *wireGuardListener) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
+	return nil, E.New("WireGuard inbound does not dial")
+}
+
+func (l *wireGuardListener) ListenPacket(ctx context.Context, destination M.Socksaddr) (net.PacketConn, error) {
+	bindAddr := M.SocksaddrFrom(l.listenOptions.Listen.Build(), l.listenOptions.ListenPort)
+	udpConn, err := l.listenUDP(bindAddr)
+	if err != nil {
+		return nil, err
+	}
+	l.logger.Info("udp server started at ", udpConn.LocalAddr())
+	if l.amnezia != nil {
+		return wireguard.NewAmneziaPacketConn(udpConn, l.amnezia), nil
+	}
+	return udpConn, nil
+}
@@ -15,6 +15,9 @@ type Factory interface {
 	Close() error
 	Level() Level
 	SetLevel(level Level)
+	TagLevels() map[string]Level
+	SetTagLevel(tag string, level Level)
+	RemoveTagLevel(tag string)
 	Logger() ContextLogger
 	NewLogger(tag string) ContextLogger
 }
@@ -26,5 +29,7 @@ type ObservableFactory interface {
 
 type Entry struct {
 	Level   Level
+	Tag     string
+	ID      uint32
 	Message string
 }
@@ -0,0 +1,43 @@
+package log
+
+import (
+	"context"
+	"errors"
+)
+
+// Fields are connection-scoped values attached to structured log records.
+type Fields struct {
+	Inbound     string `json:"inbound,omitempty"`
+	InboundType string `json:"inbound_type,omitempty"`
+	Network     string `json:"network,omitempty"`
+	Source      string `json:"source,omitempty"`
+	Destination string `json:"destination,omitempty"`
+	User        string `json:"user,omitempty"`
+	Outbound    string `json:"outbound,omitempty"`
+	Rule        string `json:"rule,omitempty"`
+}
+
+type fieldsKey struct{}
+
+func ContextWithFields(ctx context.Context, fields Fields) context.Context {
+	return context.WithValue(ctx, (*fieldsKey)(nil), fields)
+}
+
+func FieldsFromContext(ctx context.Context) (Fields, bool) {
+	fields, loaded := ctx.Value((*fieldsKey)(nil)).(Fields)
+	return fields, loaded
+}
+
+func errorChain(args []any) []string {
+	var chain []string
+	for _, arg := range args {
+		err, isErr := arg.(error)
+		if !isErr {
+			continue
+		}
+		for ; err != nil; err = errors.Unwrap(err) {
+			chain = append(chain, err.Error())
+		}
+	}
+	return chain
+}
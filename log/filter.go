@@ -0,0 +1,75 @@
+package log
+
+import (
+	"regexp"
+	"strings"
+)
+
+// MatchTag reports whether the logger tag falls under pattern.
+// A pattern matches the tag itself and its sub-tags, so "outbound" matches
+// "outbound/vless[proxy]" and "outbound/vless" matches "outbound/vless[proxy]".
+func MatchTag(tag string, pattern string) bool {
+	if !strings.HasPrefix(tag, pattern) {
+		return false
+	}
+	if len(tag) == len(pattern) {
+		return true
+	}
+	switch tag[len(pattern)] {
+	case '/', '[':
+		return true
+	default:
+		return false
+	}
+}
+
+func tagLevel(levels map[string]Level, tag string) (Level, bool) {
+	var (
+		level   Level
+		pattern string
+		loaded  bool
+	)
+	// the most specific pattern wins
+	for itemPattern, itemLevel := range levels {
+		if (loaded && len(itemPattern) <= len(pattern)) || !MatchTag(tag, itemPattern) {
+			continue
+		}
+		level = itemLevel
+		pattern = itemPattern
+		loaded = true
+	}
+	return level, loaded
+}
+
+// Filter selects entries of a log subscription.
+type Filter struct {
+	Level  Level
+	Tags   []string
+	ID     uint32
+	Regexp *regexp.Regexp
+}
+
+func (f *Filter) Match(entry Entry) bool {
+	if entry.Level > f.Level {
+		return false
+	}
+	if len(f.Tags) > 0 {
+		var tagMatched bool
+		for _, pattern := range f.Tags {
+			if MatchTag(entry.Tag, pattern) {
+				tagMatched = true
+				break
+			}
+		}
+		if !tagMatched {
+			return false
+		}
+	}
+	if f.ID != 0 && entry.ID != f.ID {
+		return false
+	}
+	if f.Regexp != nil && !f.Regexp.MatchString(entry.Message) {
+		return false
+	}
+	return true
+}
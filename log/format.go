@@ -18,6 +18,7 @@ type Formatter struct {
 	FullTimestamp    bool
 	TimestampFormat  string
 	DisableLineBreak bool
+	JSON             bool
 }
 
 func (f Formatter) Format(ctx context.Context, level Level, tag string, message string, timestamp time.Time) string {
@@ -0,0 +1,48 @@
+package log
+
+import (
+	"context"
+	"time"
+
+	"github.com/sagernet/sing/common/json"
+)
+
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+type jsonRecord struct {
+	Time     string `json:"time"`
+	Level    string `json:"level"`
+	Tag      string `json:"tag,omitempty"`
+	ID       uint32 `json:"id,omitempty"`
+	Duration int64  `json:"duration,omitempty"`
+	Fields
+	Message string   `json:"message"`
+	Error   []string `json:"error,omitempty"`
+}
+
+// FormatJSON formats a record as a single line of JSON.
+// Connection fields are read from the log ID and Fields attached to ctx.
+func (f Formatter) FormatJSON(ctx context.Context, level Level, tag string, message string, args []any, timestamp time.Time) string {
+	record := jsonRecord{
+		Time:    timestamp.Format(time.RFC3339Nano),
+		Level:   FormatLevel(level),
+		Tag:     tag,
+		Message: message,
+		Error:   errorChain(args),
+	}
+	if ctx != nil {
+		if id, loaded := IDFromContext(ctx); loaded {
+			record.ID = id.ID
+			record.Duration = time.Since(id.CreatedAt).Milliseconds()
+		}
+		record.Fields, _ = FieldsFromContext(ctx)
+	}
+	content, err := json.Marshal(record)
+	if err != nil {
+		return message + "\n"
+	}
+	return string(content) + "\n"
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 	"time"
 
+	"github.com/sagernet/sing-box/common/rotate"
 	"github.com/sagernet/sing-box/option"
 	E "github.com/sagernet/sing/common/exceptions"
 )
@@ -42,14 +43,23 @@ func New(options Options) (Factory, error) {
 	default:
 		logFilePath = logOptions.Output
 	}
+	var jsonFormat bool
+	switch logOptions.Format {
+	case "", FormatText:
+	case FormatJSON:
+		jsonFormat = true
+	default:
+		return nil, E.New("unknown log format: ", logOptions.Format)
+	}
 	logFormatter := Formatter{
 		BaseTime:         options.BaseTime,
 		DisableColors:    logOptions.DisableColor || logFilePath != "",
 		DisableTimestamp: !logOptions.Timestamp && logFilePath != "",
 		FullTimestamp:    logOptions.Timestamp,
 		TimestampFormat:  "-0700 2006-01-02 15:04:05",
+		JSON:             jsonFormat,
 	}
-	factory := NewDefaultFactory(
+	factory := newDefaultFactory(
 		options.Context,
 		logFormatter,
 		logWriter,
@@ -57,6 +67,16 @@ func New(options Options) (Factory, error) {
 		options.PlatformWriter,
 		options.Observable,
 	)
+	if rotation := logOptions.Rotation; rotation != nil {
+		if logFilePath == "" {
+			return nil, E.New("log rotation requires a file output")
+		}
+		factory.rotation = &rotate.Options{
+			MaxSize:    int64(rotation.MaxSize),
+			MaxAge:     time.Duration(rotation.MaxAge),
+			MaxBackups: rotation.MaxBackups,
+		}
+	}
 	if logOptions.Level != "" {
 		logLevel, err := ParseLevel(logOptions.Level)
 		if err != nil {
@@ -66,5 +86,12 @@ func New(options Options) (Factory, error) {
 	} else {
 		factory.SetLevel(LevelTrace)
 	}
+	for tag, levelString := range logOptions.Levels {
+		tagLevel, err := ParseLevel(levelString)
+		if err != nil {
+			return nil, E.Cause(err, "parse log level for ", tag)
+		}
+		factory.SetTagLevel(tag, tagLevel)
+	}
 	return factory, nil
 }
@@ -30,6 +30,16 @@ func (f *nopFactory) Level() Level {
 func (f *nopFactory) SetLevel(level Level) {
 }
 
+func (f *nopFactory) TagLevels() map[string]Level {
+	return nil
+}
+
+func (f *nopFactory) SetTagLevel(tag string, level Level) {
+}
+
+func (f *nopFactory) RemoveTagLevel(tag string) {
+}
+
 func (f *nopFactory) Logger() ContextLogger {
 	return f
 }
@@ -4,8 +4,11 @@ import (
 	"context"
 	"io"
 	"os"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/sagernet/sing-box/common/rotate"
 	"github.com/sagernet/sing/common"
 	F "github.com/sagernet/sing/common/format"
 	"github.com/sagernet/sing/common/observable"
@@ -21,9 +24,13 @@ type defaultFactory struct {
 	writer            io.Writer
 	file              *os.File
 	filePath          string
+	rotation          *rotate.Options
+	rotateWriter      *rotate.Writer
 	platformWriter    PlatformWriter
 	needObservable    bool
 	level             Level
+	tagLevelAccess    sync.Mutex
+	tagLevels         atomic.Pointer[tagLevels]
 	subscriber        *observable.Subscriber[Entry]
 	observer          *observable.Observer[Entry]
 }
@@ -36,6 +43,17 @@ func NewDefaultFactory(
 	platformWriter PlatformWriter,
 	needObservable bool,
 ) ObservableFactory {
+	return newDefaultFactory(ctx, formatter, writer, filePath, platformWriter, needObservable)
+}
+
+func newDefaultFactory(
+	ctx context.Context,
+	formatter Formatter,
+	writer io.Writer,
+	filePath string,
+	platformWriter PlatformWriter,
+	needObservable bool,
+) *defaultFactory {
 	factory := &defaultFactory{
 		ctx:       ctx,
 		formatter: formatter,
@@ -60,7 +78,16 @@ func NewDefaultFactory(
 }
 
 func (f *defaultFactory) Start() error {
-	if f.filePath != "" {
+	if f.filePath != "" && f.rotation != nil {
+		rotation := *f.rotation
+		rotation.Path = filemanager.BasePath(f.ctx, f.filePath)
+		rotateWriter, err := rotate.NewWriter(rotation)
+		if err != nil {
+			return err
+		}
+		f.writer = rotateWriter
+		f.rotateWriter = rotateWriter
+	} else if f.filePath != "" {
 		logFile, err := filemanager.OpenFile(f.ctx, f.filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
 		if err != nil {
 			return err
@@ -74,6 +101,7 @@ func (f *defaultFactory) Start() error {
 func (f *defaultFactory) Close() error {
 	return common.Close(
 		common.PtrOrNil(f.file),
+		common.PtrOrNil(f.rotateWriter),
 		f.subscriber,
 	)
 }
@@ -86,12 +114,51 @@ func (f *defaultFactory) SetLevel(level Level) {
 	f.level = level
 }
 
+// tagLevels is an immutable snapshot of the tag levels, replaced as a whole on updates
+// so that loggers can cache the level resolved for their tag.
+type tagLevels struct {
+	levels map[string]Level
+}
+
+// resolvedLevel is the level of a logger tag resolved from a tag level snapshot.
+type resolvedLevel struct {
+	tagLevels *tagLevels
+	level     Level
+	loaded    bool
+}
+
+func (f *defaultFactory) TagLevels() map[string]Level {
+	levels := make(map[string]Level)
+	if snapshot := f.tagLevels.Load(); snapshot != nil {
+		for tag, level := range snapshot.levels {
+			levels[tag] = level
+		}
+	}
+	return levels
+}
+
+func (f *defaultFactory) SetTagLevel(tag string, level Level) {
+	f.tagLevelAccess.Lock()
+	defer f.tagLevelAccess.Unlock()
+	levels := f.TagLevels()
+	levels[tag] = level
+	f.tagLevels.Store(&tagLevels{levels})
+}
+
+func (f *defaultFactory) RemoveTagLevel(tag string) {
+	f.tagLevelAccess.Lock()
+	defer f.tagLevelAccess.Unlock()
+	levels := f.TagLevels()
+	delete(levels, tag)
+	f.tagLevels.Store(&tagLevels{levels})
+}
+
 func (f *defaultFactory) Logger() ContextLogger {
 	return f.NewLogger("")
 }
 
 func (f *defaultFactory) NewLogger(tag string) ContextLogger {
-	return &observableLogger{f, tag}
+	return &observableLogger{defaultFactory: f, tag: tag}
 }
 
 func (f *defaultFactory) Subscribe() (subscription observable.Subscription[Entry], done <-chan struct{}, err error) {
@@ -106,16 +173,48 @@ var _ ContextLogger = (*observableLogger)(nil)
 
 type observableLogger struct {
 	*defaultFactory
-	tag string
+	tag      string
+	resolved atomic.Pointer[resolvedLevel]
+}
+
+// currentLevel returns the level of the logger tag, which is only resolved again after the tag levels change.
+func (l *observableLogger) currentLevel() Level {
+	snapshot := l.tagLevels.Load()
+	resolved := l.resolved.Load()
+	if resolved == nil || resolved.tagLevels != snapshot {
+		resolved = &resolvedLevel{tagLevels: snapshot}
+		if snapshot != nil {
+			resolved.level, resolved.loaded = tagLevel(snapshot.levels, l.tag)
+		}
+		l.resolved.Store(resolved)
+	}
+	if resolved.loaded {
+		return resolved.level
+	}
+	return l.defaultFactory.level
 }
 
 func (l *observableLogger) Log(ctx context.Context, level Level, args []any) {
 	level = OverrideLevelFromContext(level, ctx)
-	if level > l.level {
+	if level > l.currentLevel() {
 		return
 	}
 	nowTime := time.Now()
-	if l.needObservable {
+	if l.formatter.JSON {
+		messageRaw := F.ToString(args...)
+		message := l.formatter.FormatJSON(ctx, level, l.tag, messageRaw, args, nowTime)
+		if level == LevelPanic {
+			panic(message)
+		}
+		l.writer.Write([]byte(message))
+		if level == LevelFatal {
+			os.Exit(1)
+		}
+		if l.needObservable {
+			_, messageSimple := l.formatter.FormatWithSimple(ctx, level, l.tag, messageRaw, nowTime)
+			l.emit(ctx, level, messageSimple)
+		}
+	} else if l.needObservable {
 		message, messageSimple := l.formatter.FormatWithSimple(ctx, level, l.tag, F.ToString(args...), nowTime)
 		if level == LevelPanic {
 			panic(message)
@@ -124,7 +223,7 @@ func (l *observableLogger) Log(ctx context.Context, level Level, args []any) {
 		if level == LevelFatal {
 			os.Exit(1)
 		}
-		l.subscriber.Emit(Entry{level, messageSimple})
+		l.emit(ctx, level, messageSimple)
 	} else {
 		message := l.formatter.Format(ctx, level, l.tag, F.ToString(args...), nowTime)
 		if level == LevelPanic {
@@ -140,6 +239,20 @@ func (l *observableLogger) Log(ctx context.Context, level Level, args []any) {
 	}
 }
 
+func (l *observableLogger) emit(ctx context.Context, level Level, message string) {
+	entry := Entry{
+		Level:   level,
+		Tag:     l.tag,
+		Message: message,
+	}
+	if ctx != nil {
+		if id, loaded := IDFromContext(ctx); loaded {
+			entry.ID = id.ID
+		}
+	}
+	l.subscriber.Emit(entry)
+}
+
 func (l *observableLogger) Trace(args ...any) {
 	l.TraceContext(context.Background(), args...)
 }
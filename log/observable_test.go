@@ -0,0 +1,29 @@
+package log
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestObservableTagLevel(t *testing.T) {
+	t.Parallel()
+	factory := newDefaultFactory(context.Background(), Formatter{}, nil, "", nil, false)
+	factory.SetLevel(LevelInfo)
+	logger := factory.NewLogger("outbound/direct").(*observableLogger)
+	require.Equal(t, LevelInfo, logger.currentLevel())
+
+	factory.SetTagLevel("outbound", LevelDebug)
+	require.Equal(t, LevelDebug, logger.currentLevel())
+	factory.SetTagLevel("outbound/direct", LevelError)
+	require.Equal(t, LevelError, logger.currentLevel())
+	require.Equal(t, map[string]Level{"outbound": LevelDebug, "outbound/direct": LevelError}, factory.TagLevels())
+
+	factory.RemoveTagLevel("outbound/direct")
+	require.Equal(t, LevelDebug, logger.currentLevel())
+	factory.RemoveTagLevel("outbound")
+	require.Equal(t, LevelInfo, logger.currentLevel())
+	factory.SetLevel(LevelWarn)
+	require.Equal(t, LevelWarn, logger.currentLevel())
+}
@@ -33,9 +33,12 @@ func (o *Options) UnmarshalJSON(content []byte) error {
 }
 
 type LogOptions struct {
-	Disabled     bool   `json:"disabled,omitempty"`
-	Level        string `json:"level,omitempty"`
-	Output       string `json:"output,omitempty"`
-	Timestamp    bool   `json:"timestamp,omitempty"`
-	DisableColor bool   `json:"-"`
+	Disabled     bool              `json:"disabled,omitempty"`
+	Level        string            `json:"level,omitempty"`
+	Levels       map[string]string `json:"levels,omitempty"`
+	Output       string            `json:"output,omitempty"`
+	Timestamp    bool              `json:"timestamp,omitempty"`
+	Format       string            `json:"format,omitempty"`
+	Rotation     *RotationOptions  `json:"rotation,omitempty"`
+	DisableColor bool              `json:"-"`
 }
@@ -0,0 +1,21 @@
+package option
+
+type DemuxInboundOptions struct {
+	ListenOptions
+	Rules    []DemuxRule  `json:"rules,omitempty"`
+	Fallback *DemuxTarget `json:"fallback,omitempty"`
+}
+
+type DemuxRule struct {
+	Protocol   Listable[string] `json:"protocol,omitempty"`
+	ServerName Listable[string] `json:"server_name,omitempty"`
+	ALPN       Listable[string] `json:"alpn,omitempty"`
+	Host       Listable[string] `json:"host,omitempty"`
+	Path       Listable[string] `json:"path,omitempty"`
+	DemuxTarget
+}
+
+type DemuxTarget struct {
+	Inbound string `json:"inbound,omitempty"`
+	ServerOptions
+}
@@ -32,7 +32,14 @@ type DNSClientOptions struct {
 }
 
 type DNSFakeIPOptions struct {
-	Enabled    bool          `json:"enabled,omitempty"`
+	Enabled    bool                   `json:"enabled,omitempty"`
+	Inet4Range *netip.Prefix          `json:"inet4_range,omitempty"`
+	Inet6Range *netip.Prefix          `json:"inet6_range,omitempty"`
+	Pools      []DNSFakeIPPoolOptions `json:"pools,omitempty"`
+}
+
+type DNSFakeIPPoolOptions struct {
+	Tag        string        `json:"tag"`
 	Inet4Range *netip.Prefix `json:"inet4_range,omitempty"`
 	Inet6Range *netip.Prefix `json:"inet6_range,omitempty"`
 }
@@ -4,6 +4,8 @@ type ExperimentalOptions struct {
 	CacheFile *CacheFileOptions `json:"cache_file,omitempty"`
 	ClashAPI  *ClashAPIOptions  `json:"clash_api,omitempty"`
 	V2RayAPI  *V2RayAPIOptions  `json:"v2ray_api,omitempty"`
+	Metrics   *MetricsOptions   `json:"metrics,omitempty"`
+	AdminAPI  *AdminAPIOptions  `json:"admin_api,omitempty"`
 	Debug     *DebugOptions     `json:"debug,omitempty"`
 }
 
@@ -25,6 +27,8 @@ type ClashAPIOptions struct {
 	DefaultMode              string   `json:"default_mode,omitempty"`
 	ModeList                 []string `json:"-"`
 
+	ConnectionHistory *ConnectionHistoryOptions `json:"connection_history,omitempty"`
+
 	// Deprecated: migrated to global cache file
 	CacheFile string `json:"cache_file,omitempty"`
 	// Deprecated: migrated to global cache file
@@ -37,6 +41,23 @@ type ClashAPIOptions struct {
 	StoreFakeIP bool `json:"store_fakeip,omitempty"`
 }
 
+type ConnectionHistoryOptions struct {
+	Size int    `json:"size,omitempty"`
+	Path string `json:"path,omitempty"`
+	RotationOptions
+}
+
+type AdminAPIOptions struct {
+	Listen string `json:"listen,omitempty"`
+	Secret string `json:"secret,omitempty"`
+}
+
+type MetricsOptions struct {
+	Listen string `json:"listen,omitempty"`
+	Path   string `json:"path,omitempty"`
+	Secret string `json:"secret,omitempty"`
+}
+
 type V2RayAPIOptions struct {
 	Listen string                    `json:"listen,omitempty"`
 	Stats  *V2RayStatsServiceOptions `json:"stats,omitempty"`
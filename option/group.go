@@ -3,6 +3,14 @@ package option
 type SelectorOutboundOptions struct {
 	Outbounds                 []string `json:"outbounds"`
 	Default                   string   `json:"default,omitempty"`
+	TCPOutbound               string   `json:"tcp_outbound,omitempty"`
+	UDPOutbound               string   `json:"udp_outbound,omitempty"`
+	Auto                      bool     `json:"auto,omitempty"`
+	Follow                    bool     `json:"follow,omitempty"`
+	URL                       string   `json:"url,omitempty"`
+	Interval                  Duration `json:"interval,omitempty"`
+	Tolerance                 uint16   `json:"tolerance,omitempty"`
+	IdleTimeout               Duration `json:"idle_timeout,omitempty"`
 	InterruptExistConnections bool     `json:"interrupt_exist_connections,omitempty"`
 }
 
@@ -14,3 +22,7 @@ type URLTestOutboundOptions struct {
 	IdleTimeout               Duration `json:"idle_timeout,omitempty"`
 	InterruptExistConnections bool     `json:"interrupt_exist_connections,omitempty"`
 }
+
+type ChainOutboundOptions struct {
+	Outbounds []string `json:"outbounds"`
+}
@@ -2,14 +2,17 @@ package option
 
 type Hysteria2InboundOptions struct {
 	ListenOptions
-	UpMbps                int             `json:"up_mbps,omitempty"`
-	DownMbps              int             `json:"down_mbps,omitempty"`
-	Obfs                  *Hysteria2Obfs  `json:"obfs,omitempty"`
-	Users                 []Hysteria2User `json:"users,omitempty"`
-	IgnoreClientBandwidth bool            `json:"ignore_client_bandwidth,omitempty"`
+	ListenPorts           Listable[string] `json:"listen_ports,omitempty"`
+	UpMbps                int              `json:"up_mbps,omitempty"`
+	DownMbps              int              `json:"down_mbps,omitempty"`
+	Obfs                  *Hysteria2Obfs   `json:"obfs,omitempty"`
+	Users                 []Hysteria2User  `json:"users,omitempty"`
+	IgnoreClientBandwidth bool             `json:"ignore_client_bandwidth,omitempty"`
+	CongestionControl     string           `json:"congestion_control,omitempty"`
 	InboundTLSOptionsContainer
-	Masquerade  string `json:"masquerade,omitempty"`
-	BrutalDebug bool   `json:"brutal_debug,omitempty"`
+	Masquerade  string          `json:"masquerade,omitempty"`
+	BrutalDebug bool            `json:"brutal_debug,omitempty"`
+	UDPObfs     *UDPObfsOptions `json:"udp_obfs,omitempty"`
 }
 
 type Hysteria2Obfs struct {
@@ -20,17 +23,23 @@ type Hysteria2Obfs struct {
 type Hysteria2User struct {
 	Name     string `json:"name,omitempty"`
 	Password string `json:"password,omitempty"`
+	UpMbps   int    `json:"up_mbps,omitempty"`
+	DownMbps int    `json:"down_mbps,omitempty"`
 }
 
 type Hysteria2OutboundOptions struct {
 	DialerOptions
 	ServerOptions
-	UpMbps   int            `json:"up_mbps,omitempty"`
-	DownMbps int            `json:"down_mbps,omitempty"`
-	Obfs     *Hysteria2Obfs `json:"obfs,omitempty"`
-	Password string         `json:"password,omitempty"`
-	Network  NetworkList    `json:"network,omitempty"`
+	ServerPorts       Listable[string] `json:"server_ports,omitempty"`
+	HopInterval       Duration         `json:"hop_interval,omitempty"`
+	CongestionControl string           `json:"congestion_control,omitempty"`
+	UpMbps            int              `json:"up_mbps,omitempty"`
+	DownMbps          int              `json:"down_mbps,omitempty"`
+	Obfs              *Hysteria2Obfs   `json:"obfs,omitempty"`
+	Password          string           `json:"password,omitempty"`
+	Network           NetworkList      `json:"network,omitempty"`
 	OutboundTLSOptionsContainer
 	BrutalDebug bool              `json:"brutal_debug,omitempty"`
+	UDPObfs     *UDPObfsOptions   `json:"udp_obfs,omitempty"`
 	TurnRelay   *TurnRelayOptions `json:"turn_relay,omitempty"`
 }
@@ -27,6 +27,7 @@ type _Outbound struct {
 	Hysteria2Options    Hysteria2OutboundOptions    `json:"-"`
 	SelectorOptions     SelectorOutboundOptions     `json:"-"`
 	URLTestOptions      URLTestOutboundOptions      `json:"-"`
+	ChainOptions        ChainOutboundOptions        `json:"-"`
 	XrayOptions         XrayOutboundOptions         `json:"-"`
 	CustomOptions       map[string]interface{}      `json:"-"`
 }
@@ -72,6 +73,8 @@ func (h *Outbound) RawOptions() (any, error) {
 		rawOptionsPtr = &h.SelectorOptions
 	case C.TypeURLTest:
 		rawOptionsPtr = &h.URLTestOptions
+	case C.TypeChain:
+		rawOptionsPtr = &h.ChainOptions
 	case C.TypeCustom:
 		rawOptionsPtr = &h.CustomOptions
 	case C.TypeXray:
@@ -130,6 +133,8 @@ type DialerOptions struct {
 	FallbackDelay       Duration            `json:"fallback_delay,omitempty"`
 	IsWireGuardListener bool                `json:"-"`
 	TLSFragment         *TLSFragmentOptions `json:"tls_fragment,omitempty"` // hiddify
+	RateLimit           *RateLimitOptions   `json:"rate_limit,omitempty"`
+	ProxyProtocol       uint8               `json:"proxy_protocol,omitempty"`
 
 	WsTunnelOptions WsTunnelOptions `json:"ws_tunnel,omitempty"`
 }
@@ -0,0 +1,7 @@
+package option
+
+type RateLimitOptions struct {
+	UpMbps   int         `json:"up_mbps,omitempty"`
+	DownMbps int         `json:"down_mbps,omitempty"`
+	Burst    MemoryBytes `json:"burst,omitempty"`
+}
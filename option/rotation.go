@@ -0,0 +1,7 @@
+package option
+
+type RotationOptions struct {
+	MaxSize    MemoryBytes `json:"max_size,omitempty"`
+	MaxAge     Duration    `json:"max_age,omitempty"`
+	MaxBackups int         `json:"max_backups,omitempty"`
+}
@@ -2,12 +2,16 @@ package option
 
 type ShadowsocksInboundOptions struct {
 	ListenOptions
-	Network      NetworkList              `json:"network,omitempty"`
-	Method       string                   `json:"method"`
-	Password     string                   `json:"password,omitempty"`
-	Users        []ShadowsocksUser        `json:"users,omitempty"`
-	Destinations []ShadowsocksDestination `json:"destinations,omitempty"`
-	Multiplex    *InboundMultiplexOptions `json:"multiplex,omitempty"`
+	Network       NetworkList                  `json:"network,omitempty"`
+	Method        string                       `json:"method"`
+	Password      string                       `json:"password,omitempty"`
+	Plugin        string                       `json:"plugin,omitempty"`
+	PluginPath    string                       `json:"plugin_path,omitempty"`
+	PluginOptions string                       `json:"plugin_opts,omitempty"`
+	Users         []ShadowsocksUser            `json:"users,omitempty"`
+	Destinations  []ShadowsocksDestination     `json:"destinations,omitempty"`
+	HealthCheck   *ShadowsocksRelayHealthCheck `json:"health_check,omitempty"`
+	Multiplex     *InboundMultiplexOptions     `json:"multiplex,omitempty"`
 }
 
 type ShadowsocksUser struct {
@@ -19,6 +23,12 @@ type ShadowsocksDestination struct {
 	Name     string `json:"name"`
 	Password string `json:"password"`
 	ServerOptions
+	Servers []ServerOptions `json:"servers,omitempty"`
+}
+
+type ShadowsocksRelayHealthCheck struct {
+	Interval Duration `json:"interval,omitempty"`
+	Timeout  Duration `json:"timeout,omitempty"`
 }
 
 type ShadowsocksOutboundOptions struct {
@@ -27,6 +37,7 @@ type ShadowsocksOutboundOptions struct {
 	Method        string                    `json:"method"`
 	Password      string                    `json:"password"`
 	Plugin        string                    `json:"plugin,omitempty"`
+	PluginPath    string                    `json:"plugin_path,omitempty"`
 	PluginOptions string                    `json:"plugin_opts,omitempty"`
 	Network       NetworkList               `json:"network,omitempty"`
 	UDPOverTCP    *UDPOverTCPOptions        `json:"udp_over_tcp,omitempty"`
@@ -2,14 +2,23 @@ package option
 
 type SSHOutboundOptions struct {
 	DialerOptions
+	SSHClientOptions
+	Jump              []SSHClientOptions `json:"jump,omitempty"`
+	Sessions          int                `json:"sessions,omitempty"`
+	KeepAliveInterval Duration           `json:"keepalive_interval,omitempty"`
+	UDPOverTCP        *UDPOverTCPOptions `json:"udp_over_tcp,omitempty"`
+	UDPHelper         string             `json:"udp_helper,omitempty"`
+}
+
+type SSHClientOptions struct {
 	ServerOptions
-	User                 string             `json:"user,omitempty"`
-	Password             string             `json:"password,omitempty"`
-	PrivateKey           Listable[string]   `json:"private_key,omitempty"`
-	PrivateKeyPath       string             `json:"private_key_path,omitempty"`
-	PrivateKeyPassphrase string             `json:"private_key_passphrase,omitempty"`
-	HostKey              Listable[string]   `json:"host_key,omitempty"`
-	HostKeyAlgorithms    Listable[string]   `json:"host_key_algorithms,omitempty"`
-	ClientVersion        string             `json:"client_version,omitempty"`
-	UDPOverTCP           *UDPOverTCPOptions `json:"udp_over_tcp,omitempty"`
+	User                 string           `json:"user,omitempty"`
+	Password             string           `json:"password,omitempty"`
+	PrivateKey           Listable[string] `json:"private_key,omitempty"`
+	PrivateKeyPath       string           `json:"private_key_path,omitempty"`
+	PrivateKeyPassphrase string           `json:"private_key_passphrase,omitempty"`
+	HostKey              Listable[string] `json:"host_key,omitempty"`
+	KnownHostsPath       string           `json:"known_hosts_path,omitempty"`
+	HostKeyAlgorithms    Listable[string] `json:"host_key_algorithms,omitempty"`
+	ClientVersion        string           `json:"client_version,omitempty"`
 }
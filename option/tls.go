@@ -35,20 +35,21 @@ func (o *InboundTLSOptionsContainer) ReplaceInboundTLSOptions(options *InboundTL
 }
 
 type OutboundTLSOptions struct {
-	Enabled         bool                    `json:"enabled,omitempty"`
-	DisableSNI      bool                    `json:"disable_sni,omitempty"`
-	ServerName      string                  `json:"server_name,omitempty"`
-	Insecure        bool                    `json:"insecure,omitempty"`
-	ALPN            Listable[string]        `json:"alpn,omitempty"`
-	MinVersion      string                  `json:"min_version,omitempty"`
-	MaxVersion      string                  `json:"max_version,omitempty"`
-	CipherSuites    Listable[string]        `json:"cipher_suites,omitempty"`
-	Certificate     Listable[string]        `json:"certificate,omitempty"`
-	CertificatePath string                  `json:"certificate_path,omitempty"`
-	ECH             *OutboundECHOptions     `json:"ech,omitempty"`
-	UTLS            *OutboundUTLSOptions    `json:"utls,omitempty"`
-	Reality         *OutboundRealityOptions `json:"reality,omitempty"`
-	TLSTricks       *TLSTricksOptions      `json:"tls_tricks,omitempty"`
+	Enabled         bool                     `json:"enabled,omitempty"`
+	DisableSNI      bool                     `json:"disable_sni,omitempty"`
+	ServerName      string                   `json:"server_name,omitempty"`
+	Insecure        bool                     `json:"insecure,omitempty"`
+	ALPN            Listable[string]         `json:"alpn,omitempty"`
+	MinVersion      string                   `json:"min_version,omitempty"`
+	MaxVersion      string                   `json:"max_version,omitempty"`
+	CipherSuites    Listable[string]         `json:"cipher_suites,omitempty"`
+	Certificate     Listable[string]         `json:"certificate,omitempty"`
+	CertificatePath string                   `json:"certificate_path,omitempty"`
+	ECH             *OutboundECHOptions      `json:"ech,omitempty"`
+	UTLS            *OutboundUTLSOptions     `json:"utls,omitempty"`
+	Reality         *OutboundRealityOptions  `json:"reality,omitempty"`
+	TLSTricks       *TLSTricksOptions        `json:"tls_tricks,omitempty"`
+	Fronting        *OutboundFrontingOptions `json:"fronting,omitempty"`
 }
 
 type OutboundTLSOptionsContainer struct {
@@ -69,13 +70,30 @@ func (o *OutboundTLSOptionsContainer) ReplaceOutboundTLSOptions(options *Outboun
 }
 
 type InboundRealityOptions struct {
-	Enabled           bool                           `json:"enabled,omitempty"`
+	Enabled           bool                            `json:"enabled,omitempty"`
+	Handshake         InboundRealityHandshakeOptions  `json:"handshake,omitempty"`
+	PrivateKey        string                          `json:"private_key,omitempty"`
+	ShortID           Listable[string]                `json:"short_id,omitempty"`
+	Users             []InboundRealityUser            `json:"users,omitempty"`
+	MaxTimeDifference Duration                        `json:"max_time_difference,omitempty"`
+	Targets           []InboundRealityTargetOptions   `json:"targets,omitempty"`
+	Fallback          *InboundRealityHandshakeOptions `json:"fallback,omitempty"`
+}
+
+type InboundRealityTargetOptions struct {
+	ServerName        Listable[string]               `json:"server_name,omitempty"`
 	Handshake         InboundRealityHandshakeOptions `json:"handshake,omitempty"`
 	PrivateKey        string                         `json:"private_key,omitempty"`
 	ShortID           Listable[string]               `json:"short_id,omitempty"`
+	Users             []InboundRealityUser           `json:"users,omitempty"`
 	MaxTimeDifference Duration                       `json:"max_time_difference,omitempty"`
 }
 
+type InboundRealityUser struct {
+	Name    string `json:"name,omitempty"`
+	ShortID string `json:"short_id,omitempty"`
+}
+
 type InboundRealityHandshakeOptions struct {
 	ServerOptions
 	DialerOptions
@@ -107,3 +125,11 @@ type OutboundRealityOptions struct {
 	PublicKey string `json:"public_key,omitempty"`
 	ShortID   string `json:"short_id,omitempty"`
 }
+
+type OutboundFrontingOptions struct {
+	Enabled          bool             `json:"enabled,omitempty"`
+	FrontDomain      Listable[string] `json:"front_domain,omitempty"`
+	Rotation         string           `json:"rotation,omitempty"`
+	VerifyServerName string           `json:"verify_server_name,omitempty"`
+	Host             string           `json:"host,omitempty"`
+}
@@ -2,8 +2,17 @@ package option
 
 type TorOutboundOptions struct {
 	DialerOptions
-	ExecutablePath string            `json:"executable_path,omitempty"`
-	ExtraArgs      []string          `json:"extra_args,omitempty"`
-	DataDirectory  string            `json:"data_directory,omitempty"`
-	Options        map[string]string `json:"torrc,omitempty"`
+	ExecutablePath      string                         `json:"executable_path,omitempty"`
+	ExtraArgs           []string                       `json:"extra_args,omitempty"`
+	DataDirectory       string                         `json:"data_directory,omitempty"`
+	Options             map[string]string              `json:"torrc,omitempty"`
+	Bridges             Listable[string]               `json:"bridges,omitempty"`
+	PluggableTransports []TorPluggableTransportOptions `json:"pluggable_transports,omitempty"`
+	StreamIsolation     Listable[string]               `json:"stream_isolation,omitempty"`
+}
+
+type TorPluggableTransportOptions struct {
+	Transports     Listable[string] `json:"transports,omitempty"`
+	ExecutablePath string           `json:"executable_path,omitempty"`
+	Args           Listable[string] `json:"args,omitempty"`
 }
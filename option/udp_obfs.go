@@ -0,0 +1,7 @@
+package option
+
+type UDPObfsOptions struct {
+	Password   string `json:"password,omitempty"`
+	MaxPadding int    `json:"max_padding,omitempty"`
+	Header     string `json:"header,omitempty"`
+}
@@ -0,0 +1,11 @@
+package option
+
+type UserLimitOptions struct {
+	Name           Listable[string] `json:"name,omitempty"`
+	Quota          MemoryBytes      `json:"quota,omitempty"`
+	QuotaResetDay  int              `json:"quota_reset_day,omitempty"`
+	Expire         string           `json:"expire,omitempty"`
+	MaxConnections int              `json:"max_connections,omitempty"`
+	MaxIPs         int              `json:"max_ips,omitempty"`
+	RateLimitOptions
+}
@@ -81,7 +81,9 @@ type V2RayWebsocketOptions struct {
 	EarlyDataHeaderName string     `json:"early_data_header_name,omitempty"`
 }
 
-type V2RayQUICOptions struct{}
+type V2RayQUICOptions struct {
+	UDPObfs *UDPObfsOptions `json:"udp_obfs,omitempty"`
+}
 
 type V2RayGRPCOptions struct {
 	ServiceName         string   `json:"service_name,omitempty"`
@@ -11,6 +11,8 @@ type WireGuardOutboundOptions struct {
 	PrivateKey      string                 `json:"private_key"`
 	Peers           []WireGuardPeer        `json:"peers,omitempty"`
 	ServerOptions
+	Endpoints        Listable[string]  `json:"endpoints,omitempty"`
+	HandshakeTimeout Duration          `json:"handshake_timeout,omitempty"`
 	PeerPublicKey    string            `json:"peer_public_key"`
 	PreSharedKey     string            `json:"pre_shared_key,omitempty"`
 	Reserved         []uint8           `json:"reserved,omitempty"`
@@ -22,12 +24,36 @@ type WireGuardOutboundOptions struct {
 	FakePacketsSize  string            `json:"fake_packets_size,omitempty"`
 	FakePacketsDelay string            `json:"fake_packets_delay,omitempty"`
 	FakePacketsMode string `json:"fake_packets_mode,omitempty"`
+	Amnezia          *WireGuardAmneziaOptions `json:"amnezia,omitempty"`
 }
 
 type WireGuardPeer struct {
 	ServerOptions
+	Endpoints    Listable[string] `json:"endpoints,omitempty"`
 	PublicKey    string           `json:"public_key,omitempty"`
 	PreSharedKey string           `json:"pre_shared_key,omitempty"`
 	AllowedIPs   Listable[string] `json:"allowed_ips,omitempty"`
 	Reserved     []uint8          `json:"reserved,omitempty"`
 }
+
+type WireGuardInboundOptions struct {
+	ListenOptions
+	LocalAddress Listable[netip.Prefix]   `json:"local_address"`
+	PrivateKey   string                   `json:"private_key"`
+	Peers        []WireGuardPeer          `json:"peers,omitempty"`
+	Workers      int                      `json:"workers,omitempty"`
+	MTU          uint32                   `json:"mtu,omitempty"`
+	Amnezia      *WireGuardAmneziaOptions `json:"amnezia,omitempty"`
+}
+
+type WireGuardAmneziaOptions struct {
+	JunkPacketCount            int    `json:"junk_packet_count,omitempty"`
+	JunkPacketMinSize          int    `json:"junk_packet_min_size,omitempty"`
+	JunkPacketMaxSize          int    `json:"junk_packet_max_size,omitempty"`
+	InitPacketJunkSize         int    `json:"init_packet_junk_size,omitempty"`
+	ResponsePacketJunkSize     int    `json:"response_packet_junk_size,omitempty"`
+	InitPacketMagicHeader      uint32 `json:"init_packet_magic_header,omitempty"`
+	ResponsePacketMagicHeader  uint32 `json:"response_packet_magic_header,omitempty"`
+	UnderloadPacketMagicHeader uint32 `json:"underload_packet_magic_header,omitempty"`
+	TransportPacketMagicHeader uint32 `json:"transport_packet_magic_header,omitempty"`
+}
@@ -59,6 +59,8 @@ func New(ctx context.Context, router adapter.Router, logger log.ContextLogger, t
 		return NewSelector(ctx, router, logger, tag, options.SelectorOptions)
 	case C.TypeURLTest:
 		return NewURLTest(ctx, router, logger, tag, options.URLTestOptions)
+	case C.TypeChain:
+		return NewChain(ctx, router, logger, tag, options.ChainOptions)
 	case C.TypeXray:
 		return NewXray(ctx, router, logger, tag, options.XrayOptions)
 	default:
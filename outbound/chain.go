@@ -0,0 +1,247 @@
+package outbound
+
+import (
+	"context"
+	"net"
+	"sync"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+)
+
+var (
+	_ adapter.Outbound          = (*Chain)(nil)
+	_ adapter.ChainOutbound     = (*Chain)(nil)
+	_ adapter.DependencyUpdater = (*Chain)(nil)
+)
+
+// Chain dials through the outbounds in order, every outbound after the first one
+// is created again with the previous one as detour, so they can still be used alone.
+type Chain struct {
+	myOutboundAdapter
+	ctx    context.Context
+	tags   []string
+	access sync.RWMutex
+	hops   []adapter.Outbound
+}
+
+func NewChain(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.ChainOutboundOptions) (*Chain, error) {
+	outbound := &Chain{
+		myOutboundAdapter: myOutboundAdapter{
+			protocol:     C.TypeChain,
+			network:      []string{N.NetworkTCP, N.NetworkUDP},
+			router:       router,
+			logger:       logger,
+			tag:          tag,
+			dependencies: options.Outbounds,
+		},
+		ctx:  ctx,
+		tags: options.Outbounds,
+	}
+	if len(outbound.tags) < 2 {
+		return nil, E.New("chain requires at least two outbounds")
+	}
+	return outbound, nil
+}
+
+func (s *Chain) Start() error {
+	hops, err := s.createHops()
+	if err != nil {
+		return err
+	}
+	s.hops = hops
+	s.network = hops[len(hops)-1].Network()
+	return nil
+}
+
+func (s *Chain) createHops() ([]adapter.Outbound, error) {
+	optionsStore := OptionsStoreFromContext(s.ctx)
+	if optionsStore == nil {
+		return nil, E.New("missing outbound options store")
+	}
+	detour, loaded := s.router.Outbound(s.tags[0])
+	if !loaded {
+		return nil, E.New("outbound 0 not found: ", s.tags[0])
+	}
+	hops := []adapter.Outbound{detour}
+	for i := 1; i < len(s.tags); i++ {
+		hop, err := s.createHop(optionsStore, i, hops[i-1])
+		if err != nil {
+			closeHops(hops)
+			return nil, err
+		}
+		hops = append(hops, hop)
+	}
+	return hops, nil
+}
+
+func (s *Chain) createHop(optionsStore *OptionsStore, index int, detour adapter.Outbound) (adapter.Outbound, error) {
+	tag := s.tags[index]
+	options, loaded := optionsStore.Load(tag)
+	if !loaded {
+		return nil, E.New("outbound ", index, " not found: ", tag)
+	}
+	rawOptions, err := options.RawOptions()
+	if err != nil {
+		return nil, err
+	}
+	dialerOptionsWrapper, isDialerOptionsWrapper := rawOptions.(option.DialerOptionsWrapper)
+	if !isDialerOptionsWrapper || options.Type == C.TypeDirect {
+		return nil, E.New("outbound ", index, " can not be chained: ", tag)
+	}
+	dialerOptions := dialerOptionsWrapper.TakeDialerOptions()
+	dialerOptions.Detour = detour.Tag()
+	dialerOptionsWrapper.ReplaceDialerOptions(dialerOptions)
+	router := &chainRouter{Router: s.router, detour: detour}
+	hop, err := New(s.ctx, router, s.logger, tag, options)
+	if err != nil {
+		return nil, E.Cause(err, "create outbound ", index, ": ", tag)
+	}
+	if starter, isStarter := hop.(common.Starter); isStarter {
+		err = starter.Start()
+	}
+	if err == nil {
+		if postStarter, isPostStarter := hop.(adapter.PostStarter); isPostStarter {
+			err = postStarter.PostStart()
+		}
+	}
+	if err != nil {
+		common.Close(hop)
+		return nil, E.Cause(err, "initialize outbound ", index, ": ", tag)
+	}
+	return hop, nil
+}
+
+func (s *Chain) Close() error {
+	s.access.Lock()
+	defer s.access.Unlock()
+	return closeHops(s.hops)
+}
+
+func (s *Chain) Hops() []string {
+	return s.tags
+}
+
+func (s *Chain) UpdateDependency(detour adapter.Outbound) {
+	hops, err := s.createHops()
+	if err != nil {
+		s.logger.Error("recreate chain after ", detour.Tag(), " replaced: ", err)
+		return
+	}
+	s.access.Lock()
+	oldHops := s.hops
+	s.hops = hops
+	s.network = hops[len(hops)-1].Network()
+	s.access.Unlock()
+	closeHops(oldHops)
+}
+
+func (s *Chain) lastHop() adapter.Outbound {
+	s.access.RLock()
+	defer s.access.RUnlock()
+	return s.hops[len(s.hops)-1]
+}
+
+func (s *Chain) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
+	return s.lastHop().DialContext(ctx, network, destination)
+}
+
+func (s *Chain) ListenPacket(ctx context.Context, destination M.Socksaddr) (net.PacketConn, error) {
+	return s.lastHop().ListenPacket(ctx, destination)
+}
+
+func (s *Chain) NewConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext) error {
+	return NewConnection(ctx, s, conn, metadata)
+}
+
+func (s *Chain) NewPacketConnection(ctx context.Context, conn N.PacketConn, metadata adapter.InboundContext) error {
+	return NewPacketConnection(ctx, s, conn, metadata)
+}
+
+// closeHops closes the hops created by chain, the first one is owned by the router.
+func closeHops(hops []adapter.Outbound) error {
+	if len(hops) < 2 {
+		return nil
+	}
+	var errors []error
+	for _, hop := range hops[1:] {
+		errors = append(errors, common.Close(hop))
+	}
+	return E.Errors(errors...)
+}
+
+// chainRouter resolves the detour of a hop to the previous hop of the chain.
+type chainRouter struct {
+	adapter.Router
+	detour adapter.Outbound
+}
+
+func (r *chainRouter) Outbound(tag string) (adapter.Outbound, bool) {
+	if tag == r.detour.Tag() {
+		return r.detour, true
+	}
+	return r.Router.Outbound(tag)
+}
+
+type optionsStoreKey struct{}
+
+// ContextWithOptionsStore returns a context carrying the options store,
+// it is kept out of the service registry since the registry may be shared between instances.
+func ContextWithOptionsStore(ctx context.Context, optionsStore *OptionsStore) context.Context {
+	return context.WithValue(ctx, optionsStoreKey{}, optionsStore)
+}
+
+func OptionsStoreFromContext(ctx context.Context) *OptionsStore {
+	optionsStore, _ := ctx.Value(optionsStoreKey{}).(*OptionsStore)
+	return optionsStore
+}
+
+// OptionsStore keeps options of the current outbounds, used by chain to create them again.
+type OptionsStore struct {
+	access  sync.RWMutex
+	options map[string]option.Outbound
+}
+
+func NewOptionsStore() *OptionsStore {
+	return &OptionsStore{
+		options: make(map[string]option.Outbound),
+	}
+}
+
+func (s *OptionsStore) Load(tag string) (option.Outbound, bool) {
+	s.access.RLock()
+	defer s.access.RUnlock()
+	options, loaded := s.options[tag]
+	return options, loaded
+}
+
+func (s *OptionsStore) Store(options option.Outbound) {
+	s.access.Lock()
+	s.options[options.Tag] = options
+	s.access.Unlock()
+}
+
+func (s *OptionsStore) Delete(tag string) {
+	s.access.Lock()
+	delete(s.options, tag)
+	s.access.Unlock()
+}
+
+// Reset replaces all options with the tagged ones of the list.
+func (s *OptionsStore) Reset(outbounds []option.Outbound) {
+	options := make(map[string]option.Outbound, len(outbounds))
+	for _, outboundOptions := range outbounds {
+		if outboundOptions.Tag != "" {
+			options[outboundOptions.Tag] = outboundOptions
+		}
+	}
+	s.access.Lock()
+	s.options = options
+	s.access.Unlock()
+}
@@ -0,0 +1,158 @@
+package outbound
+
+import (
+	"context"
+	"io"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common/bufio"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+	"github.com/sagernet/sing/protocol/socks"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testSocksServer is a SOCKS5 server recording the requested destinations.
+type testSocksServer struct {
+	address      M.Socksaddr
+	access       sync.Mutex
+	destinations []M.Socksaddr
+}
+
+func startTestSocksServer(t *testing.T) *testSocksServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		listener.Close()
+	})
+	server := &testSocksServer{address: M.SocksaddrFromNet(listener.Addr())}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				socks.HandleConnection(context.Background(), conn, nil, server, M.Metadata{})
+			}()
+		}
+	}()
+	return server
+}
+
+func (s *testSocksServer) NewConnection(ctx context.Context, conn net.Conn, metadata M.Metadata) error {
+	s.access.Lock()
+	s.destinations = append(s.destinations, metadata.Destination)
+	s.access.Unlock()
+	remoteConn, err := N.SystemDialer.DialContext(ctx, N.NetworkTCP, metadata.Destination)
+	if err != nil {
+		return err
+	}
+	return bufio.CopyConn(ctx, conn, remoteConn)
+}
+
+func (s *testSocksServer) NewPacketConnection(ctx context.Context, conn N.PacketConn, metadata M.Metadata) error {
+	return io.ErrClosedPipe
+}
+
+func (s *testSocksServer) Destinations() []M.Socksaddr {
+	s.access.Lock()
+	defer s.access.Unlock()
+	return append([]M.Socksaddr(nil), s.destinations...)
+}
+
+func testSocksOptions(tag string, server *testSocksServer) option.Outbound {
+	return option.Outbound{
+		Type: "socks",
+		Tag:  tag,
+		SocksOptions: option.SocksOutboundOptions{
+			ServerOptions: option.ServerOptions{Server: server.address.AddrString(), ServerPort: server.address.Port},
+		},
+	}
+}
+
+func requireChainEcho(t *testing.T, detour adapter.Outbound, destination M.Socksaddr) {
+	conn, err := detour.DialContext(context.Background(), N.NetworkTCP, destination)
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("hello"))
+	require.NoError(t, err)
+	response := make([]byte, 5)
+	_, err = io.ReadFull(conn, response)
+	require.NoError(t, err)
+	require.Equal(t, "hello", string(response))
+}
+
+func TestChain(t *testing.T) {
+	t.Parallel()
+	echoListener := startTestEchoServer(t)
+	echoAddr := M.SocksaddrFromNet(echoListener.Addr())
+	servers := []*testSocksServer{startTestSocksServer(t), startTestSocksServer(t), startTestSocksServer(t)}
+	logger := log.NewNOPFactory().Logger()
+	optionsStore := NewOptionsStore()
+	optionsStore.Reset([]option.Outbound{
+		testSocksOptions("a", servers[0]),
+		testSocksOptions("b", servers[1]),
+		testSocksOptions("c", servers[2]),
+		{Type: "direct", Tag: "direct"},
+	})
+	first, err := New(context.Background(), nil, logger, "a", testSocksOptions("a", servers[0]))
+	require.NoError(t, err)
+	router := newTestRouter(first)
+	ctx := ContextWithOptionsStore(context.Background(), optionsStore)
+
+	chain, err := NewChain(ctx, router, logger, "chain", option.ChainOutboundOptions{Outbounds: []string{"a", "b", "c"}})
+	require.NoError(t, err)
+	require.NoError(t, chain.Start())
+	t.Cleanup(func() {
+		chain.Close()
+	})
+	require.Equal(t, []string{"a", "b", "c"}, chain.Hops())
+	requireChainEcho(t, chain, echoAddr)
+	// every hop connects to the next one
+	require.Equal(t, []M.Socksaddr{servers[1].address}, servers[0].Destinations())
+	require.Equal(t, []M.Socksaddr{servers[2].address}, servers[1].Destinations())
+	require.Equal(t, []M.Socksaddr{echoAddr}, servers[2].Destinations())
+
+	// hops are created again once a dependency is replaced
+	replacement := startTestSocksServer(t)
+	optionsStore.Store(testSocksOptions("b", replacement))
+	oldHops := chain.hops
+	chain.UpdateDependency(first)
+	require.NotEqual(t, oldHops[1], chain.hops[1])
+	requireChainEcho(t, chain, echoAddr)
+	require.Equal(t, []M.Socksaddr{servers[2].address}, replacement.Destinations())
+	require.Len(t, servers[1].Destinations(), 1)
+
+	// the chain is kept if it can't be created again
+	optionsStore.Delete("c")
+	hops := chain.hops
+	chain.UpdateDependency(first)
+	require.Equal(t, hops, chain.hops)
+	requireChainEcho(t, chain, echoAddr)
+
+	twoHops, err := NewChain(ctx, router, logger, "two", option.ChainOutboundOptions{Outbounds: []string{"a", "b"}})
+	require.NoError(t, err)
+	require.NoError(t, twoHops.Start())
+	t.Cleanup(func() {
+		twoHops.Close()
+	})
+	requireChainEcho(t, twoHops, echoAddr)
+	require.Equal(t, []M.Socksaddr{servers[2].address, servers[2].address, echoAddr}, replacement.Destinations())
+
+	_, err = NewChain(ctx, router, logger, "chain", option.ChainOutboundOptions{Outbounds: []string{"a"}})
+	require.Error(t, err)
+	directHop, err := NewChain(ctx, router, logger, "chain", option.ChainOutboundOptions{Outbounds: []string{"a", "direct"}})
+	require.NoError(t, err)
+	require.Error(t, directHop.Start())
+	withoutStore, err := NewChain(context.Background(), router, logger, "chain", option.ChainOutboundOptions{Outbounds: []string{"a", "b"}})
+	require.NoError(t, err)
+	require.Error(t, withoutStore.Start())
+}
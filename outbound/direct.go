@@ -53,9 +53,6 @@ func NewDirect(router adapter.Router, logger log.ContextLogger, tag string, opti
 		dialer:         outboundDialer,
 		loopBack:       newLoopBackDetector(router),
 	}
-	if options.ProxyProtocol != 0 {
-		return nil, E.New("Proxy Protocol is deprecated and removed in sing-box 1.6.0")
-	}
 	if options.OverrideAddress != "" && options.OverridePort != 0 {
 		outbound.overrideOption = 1
 		outbound.overrideDestination = M.ParseSocksaddrHostPort(options.OverrideAddress, options.OverridePort)
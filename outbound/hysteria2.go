@@ -6,15 +6,18 @@ import (
 	"context"
 	"net"
 	"os"
+	"time"
 
 	"github.com/sagernet/sing-box/adapter"
 	"github.com/sagernet/sing-box/common/dialer"
+	"github.com/sagernet/sing-box/common/porthopping"
 	"github.com/sagernet/sing-box/common/tls"
+	"github.com/sagernet/sing-box/common/udpobfs"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/log"
 	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing-box/transport/hysteria2"
 	"github.com/sagernet/sing-quic/hysteria"
-	"github.com/sagernet/sing-quic/hysteria2"
 	"github.com/sagernet/sing/common"
 	"github.com/sagernet/sing/common/bufio"
 	E "github.com/sagernet/sing/common/exceptions"
@@ -57,16 +60,44 @@ func NewHysteria2(ctx context.Context, router adapter.Router, logger log.Context
 			return nil, E.New("unknown obfs type: ", options.Obfs.Type)
 		}
 	}
+	switch options.CongestionControl {
+	case "":
+	case hysteria2.CongestionControlBBR, hysteria2.CongestionControlNewReno:
+		if options.UpMbps != 0 {
+			return nil, E.New(options.CongestionControl, " congestion control conflicts with up_mbps")
+		}
+	case hysteria2.CongestionControlBrutal:
+		if options.UpMbps == 0 {
+			return nil, E.New("brutal congestion control requires up_mbps")
+		}
+	default:
+		return nil, E.New("unsupported congestion control for hysteria2: ", options.CongestionControl)
+	}
 	outboundDialer, err := dialer.New(router, options.DialerOptions)
 	if err != nil {
 		return nil, err
 	}
+	if options.UDPObfs != nil {
+		obfuscator, err := udpobfs.NewObfuscator(*options.UDPObfs)
+		if err != nil {
+			return nil, err
+		}
+		outboundDialer = udpobfs.NewDialer(outboundDialer, obfuscator)
+	}
+	if len(options.ServerPorts) > 0 {
+		ports, err := porthopping.ParsePorts(options.ServerPorts)
+		if err != nil {
+			return nil, E.Cause(err, "parse server_ports")
+		}
+		outboundDialer = &hopDialer{outboundDialer, ports, time.Duration(options.HopInterval)}
+	}
 	networkList := options.Network.Build()
 	client, err := hysteria2.NewClient(hysteria2.ClientOptions{
 		Context:            ctx,
 		Dialer:             outboundDialer,
 		Logger:             logger,
 		BrutalDebug:        options.BrutalDebug,
+		CongestionControl:  options.CongestionControl,
 		ServerAddress:      options.ServerOptions.Build(),
 		SendBPS:            uint64(options.UpMbps * hysteria.MbpsToBps),
 		ReceiveBPS:         uint64(options.DownMbps * hysteria.MbpsToBps),
@@ -131,3 +162,17 @@ func (h *Hysteria2) Close() error {
 	}
 	return h.client.CloseWithError(os.ErrClosed)
 }
+
+// hopDialer dials the UDP connection of the hysteria2 client to random ports of the server.
+type hopDialer struct {
+	N.Dialer
+	ports    []uint16
+	interval time.Duration
+}
+
+func (d *hopDialer) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
+	if N.NetworkName(network) != N.NetworkUDP {
+		return d.Dialer.DialContext(ctx, network, destination)
+	}
+	return porthopping.NewClientConn(ctx, d.Dialer, destination, d.ports, d.interval)
+}
@@ -3,12 +3,14 @@ package outbound
 import (
 	"context"
 	"net"
+	"sync"
 
 	"github.com/sagernet/sing-box/adapter"
 	"github.com/sagernet/sing-box/common/interrupt"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/log"
 	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
 	E "github.com/sagernet/sing/common/exceptions"
 	M "github.com/sagernet/sing/common/metadata"
 	N "github.com/sagernet/sing/common/network"
@@ -16,17 +18,32 @@ import (
 )
 
 var (
-	_ adapter.Outbound      = (*Selector)(nil)
-	_ adapter.OutboundGroup = (*Selector)(nil)
+	_ adapter.Outbound             = (*Selector)(nil)
+	_ adapter.OutboundGroup        = (*Selector)(nil)
+	_ adapter.NetworkOutboundGroup = (*Selector)(nil)
+	_ adapter.DependencyUpdater    = (*Selector)(nil)
 )
 
+// AutoTagSuffix is appended to the selector tag to name its auto member.
+const AutoTagSuffix = "/auto"
+
 type Selector struct {
 	myOutboundAdapter
 	ctx                          context.Context
 	tags                         []string
 	defaultTag                   string
+	tcpTag                       string
+	udpTag                       string
+	auto                         bool
+	autoTag                      string
+	follow                       bool
+	urlTestOptions               option.URLTestOutboundOptions
+	urlTest                      *URLTest
+	access                       sync.RWMutex
 	outbounds                    map[string]adapter.Outbound
 	selected                     adapter.Outbound
+	selectedTCP                  adapter.Outbound
+	selectedUDP                  adapter.Outbound
 	interruptGroup               *interrupt.Group
 	interruptExternalConnections bool
 }
@@ -40,9 +57,22 @@ func NewSelector(ctx context.Context, router adapter.Router, logger log.ContextL
 			tag:          tag,
 			dependencies: options.Outbounds,
 		},
-		ctx:                          ctx,
-		tags:                         options.Outbounds,
-		defaultTag:                   options.Default,
+		ctx:        ctx,
+		tags:       options.Outbounds,
+		defaultTag: options.Default,
+		tcpTag:     options.TCPOutbound,
+		udpTag:     options.UDPOutbound,
+		auto:       options.Auto,
+		autoTag:    tag + AutoTagSuffix,
+		follow:     options.Follow,
+		urlTestOptions: option.URLTestOutboundOptions{
+			Outbounds:                 options.Outbounds,
+			URL:                       options.URL,
+			Interval:                  options.Interval,
+			Tolerance:                 options.Tolerance,
+			IdleTimeout:               options.IdleTimeout,
+			InterruptExistConnections: options.InterruptExistConnections,
+		},
 		outbounds:                    make(map[string]adapter.Outbound),
 		interruptGroup:               interrupt.NewGroup(),
 		interruptExternalConnections: options.InterruptExistConnections,
@@ -50,14 +80,38 @@ func NewSelector(ctx context.Context, router adapter.Router, logger log.ContextL
 	if len(outbound.tags) == 0 {
 		return nil, E.New("missing tags")
 	}
+	if outbound.auto && common.Contains(outbound.tags, outbound.autoTag) {
+		return nil, E.New("auto is conflict with outbound ", outbound.autoTag)
+	}
+	if outbound.defaultTag != "" && !outbound.hasMember(outbound.defaultTag) {
+		return nil, E.New("default outbound not found: ", outbound.defaultTag)
+	}
+	if outbound.tcpTag != "" && !outbound.hasMember(outbound.tcpTag) {
+		return nil, E.New("tcp outbound not found: ", outbound.tcpTag)
+	}
+	if outbound.udpTag != "" && !outbound.hasMember(outbound.udpTag) {
+		return nil, E.New("udp outbound not found: ", outbound.udpTag)
+	}
 	return outbound, nil
 }
 
+func (s *Selector) hasMember(tag string) bool {
+	return common.Contains(s.tags, tag) || s.auto && tag == s.autoTag
+}
+
 func (s *Selector) Network() []string {
-	if s.selected == nil {
+	tcpOutbound, udpOutbound := s.selectedOutbound(N.NetworkTCP), s.selectedOutbound(N.NetworkUDP)
+	if tcpOutbound == nil || udpOutbound == nil {
 		return []string{N.NetworkTCP, N.NetworkUDP}
 	}
-	return s.selected.Network()
+	var networks []string
+	if common.Contains(tcpOutbound.Network(), N.NetworkTCP) {
+		networks = append(networks, N.NetworkTCP)
+	}
+	if common.Contains(udpOutbound.Network(), N.NetworkUDP) {
+		networks = append(networks, N.NetworkUDP)
+	}
+	return networks
 }
 
 func (s *Selector) Start() error {
@@ -69,65 +123,294 @@ func (s *Selector) Start() error {
 		s.outbounds[tag] = detour
 	}
 
-	if s.tag != "" {
-		cacheFile := service.FromContext[adapter.CacheFile](s.ctx)
-		if cacheFile != nil {
-			selected := cacheFile.LoadSelected(s.tag)
-			if selected != "" {
-				detour, loaded := s.outbounds[selected]
-				if loaded {
-					s.selected = detour
-					return nil
-				}
-			}
+	if s.auto || s.follow {
+		urlTest, err := NewURLTest(s.ctx, s.router, s.logger, s.autoTag, s.urlTestOptions)
+		if err != nil {
+			return err
+		}
+		err = urlTest.Start()
+		if err != nil {
+			return err
+		}
+		if s.follow {
+			urlTest.group.updateHook = s.followAvailable
+		}
+		s.urlTest = urlTest
+		if s.auto {
+			s.outbounds[s.autoTag] = urlTest
 		}
 	}
 
-	if s.defaultTag != "" {
-		detour, loaded := s.outbounds[s.defaultTag]
-		if !loaded {
-			return E.New("default outbound not found: ", s.defaultTag)
-		}
+	if s.tcpTag != "" {
+		s.selectedTCP = s.outbounds[s.tcpTag]
+	}
+	if s.udpTag != "" {
+		s.selectedUDP = s.outbounds[s.udpTag]
+	}
+	if detour := s.loadSelected(s.tag + "/" + N.NetworkTCP); detour != nil {
+		s.selectedTCP = detour
+	}
+	if detour := s.loadSelected(s.tag + "/" + N.NetworkUDP); detour != nil {
+		s.selectedUDP = detour
+	}
+
+	if detour := s.loadSelected(s.tag); detour != nil {
 		s.selected = detour
 		return nil
 	}
 
+	if s.defaultTag != "" {
+		s.selected = s.outbounds[s.defaultTag]
+		return nil
+	}
+
+	if s.auto {
+		s.selected = s.urlTest
+		return nil
+	}
+
 	s.selected = s.outbounds[s.tags[0]]
 	return nil
 }
 
+func (s *Selector) PostStart() error {
+	if s.urlTest != nil {
+		return s.urlTest.PostStart()
+	}
+	return nil
+}
+
+func (s *Selector) Close() error {
+	return common.Close(
+		common.PtrOrNil(s.urlTest),
+	)
+}
+
+func (s *Selector) loadSelected(key string) adapter.Outbound {
+	if s.tag == "" {
+		return nil
+	}
+	cacheFile := service.FromContext[adapter.CacheFile](s.ctx)
+	if cacheFile == nil {
+		return nil
+	}
+	selected := cacheFile.LoadSelected(key)
+	if selected == "" {
+		return nil
+	}
+	return s.outbounds[selected]
+}
+
+func (s *Selector) storeSelected(key string, tag string) {
+	if s.tag == "" {
+		return
+	}
+	cacheFile := service.FromContext[adapter.CacheFile](s.ctx)
+	if cacheFile != nil {
+		err := cacheFile.StoreSelected(key, tag)
+		if err != nil {
+			s.logger.Error("store selected: ", err)
+		}
+	}
+}
+
 func (s *Selector) Now() string {
+	s.access.RLock()
+	defer s.access.RUnlock()
 	return s.selected.Tag()
 }
 
+// NowNetwork returns the outbound used for the network, which differs from Now if a per-network member is selected.
+func (s *Selector) NowNetwork(network string) string {
+	return s.selectedOutbound(network).Tag()
+}
+
 func (s *Selector) All() []string {
+	if s.auto {
+		return append([]string{s.autoTag}, s.tags...)
+	}
 	return s.tags
 }
 
+// Auto returns the auto member, which is not registered in the router.
+func (s *Selector) Auto() (adapter.Outbound, bool) {
+	if !s.auto || s.urlTest == nil {
+		return nil, false
+	}
+	return s.urlTest, true
+}
+
+// Outbound returns the member with the tag, including the auto member.
+func (s *Selector) Outbound(tag string) (adapter.Outbound, bool) {
+	s.access.RLock()
+	defer s.access.RUnlock()
+	detour, loaded := s.outbounds[tag]
+	return detour, loaded
+}
+
 func (s *Selector) SelectOutbound(tag string) bool {
+	s.access.Lock()
 	detour, loaded := s.outbounds[tag]
 	if !loaded {
+		s.access.Unlock()
 		return false
 	}
 	if s.selected == detour {
+		s.access.Unlock()
 		return true
 	}
 	s.selected = detour
-	if s.tag != "" {
-		cacheFile := service.FromContext[adapter.CacheFile](s.ctx)
-		if cacheFile != nil {
-			err := cacheFile.StoreSelected(s.tag, tag)
-			if err != nil {
-				s.logger.Error("store selected: ", err)
-			}
+	s.access.Unlock()
+	s.storeSelected(s.tag, tag)
+	s.interruptGroup.Interrupt(s.interruptExternalConnections)
+	return true
+}
+
+// SelectNetworkOutbound selects the member for a single network, an empty tag restores the configured one.
+func (s *Selector) SelectNetworkOutbound(network string, tag string) bool {
+	var (
+		detour    adapter.Outbound
+		loaded    bool
+		configTag string
+	)
+	switch network {
+	case N.NetworkTCP:
+		configTag = s.tcpTag
+	case N.NetworkUDP:
+		configTag = s.udpTag
+	default:
+		return false
+	}
+	s.access.Lock()
+	if tag != "" {
+		detour, loaded = s.outbounds[tag]
+		if !loaded {
+			s.access.Unlock()
+			return false
 		}
+	} else if configTag != "" {
+		detour = s.outbounds[configTag]
 	}
+	selected := &s.selectedTCP
+	if network == N.NetworkUDP {
+		selected = &s.selectedUDP
+	}
+	if *selected == detour {
+		s.access.Unlock()
+		return true
+	}
+	*selected = detour
+	s.access.Unlock()
+	s.storeSelected(s.tag+"/"+network, tag)
 	s.interruptGroup.Interrupt(s.interruptExternalConnections)
 	return true
 }
 
+func (s *Selector) selectedOutbound(network string) adapter.Outbound {
+	s.access.RLock()
+	defer s.access.RUnlock()
+	switch N.NetworkName(network) {
+	case N.NetworkTCP:
+		if s.selectedTCP != nil {
+			return s.selectedTCP
+		}
+	case N.NetworkUDP:
+		if s.selectedUDP != nil {
+			return s.selectedUDP
+		}
+	}
+	return s.selected
+}
+
+// followAvailable switches away from the selected outbounds once health checks report them unavailable.
+func (s *Selector) followAvailable() {
+	s.access.RLock()
+	selected, selectedTCP, selectedUDP := s.selected, s.selectedTCP, s.selectedUDP
+	s.access.RUnlock()
+	var updated bool
+	if detour := s.availableOutbound(selected, N.NetworkTCP); detour != nil {
+		s.logger.Warn("selected outbound ", selected.Tag(), " unavailable, switch to ", detour.Tag())
+		s.SelectOutbound(detour.Tag())
+		updated = true
+	}
+	if detour := s.availableOutbound(selectedTCP, N.NetworkTCP); detour != nil {
+		s.logger.Warn("selected tcp outbound ", selectedTCP.Tag(), " unavailable, switch to ", detour.Tag())
+		s.SelectNetworkOutbound(N.NetworkTCP, detour.Tag())
+		updated = true
+	}
+	if detour := s.availableOutbound(selectedUDP, N.NetworkUDP); detour != nil {
+		s.logger.Warn("selected udp outbound ", selectedUDP.Tag(), " unavailable, switch to ", detour.Tag())
+		s.SelectNetworkOutbound(N.NetworkUDP, detour.Tag())
+		updated = true
+	}
+	if updated {
+		s.urlTest.group.history.NotifyUpdated()
+	}
+}
+
+// availableOutbound returns the outbound to switch to if the selected one is unavailable, or nil to keep it.
+func (s *Selector) availableOutbound(selected adapter.Outbound, network string) adapter.Outbound {
+	if selected == nil || selected == adapter.Outbound(s.urlTest) {
+		return nil
+	}
+	history := s.urlTest.group.history.LoadURLTestHistory(RealTag(selected))
+	if history == nil || history.Delay != TimeoutDelay {
+		return nil
+	}
+	detour, available := s.urlTest.group.Select(network)
+	if !available {
+		return nil
+	}
+	if s.auto {
+		return s.urlTest
+	}
+	return detour
+}
+
+func (s *Selector) touch() {
+	if s.urlTest != nil {
+		s.urlTest.group.Touch()
+	}
+}
+
+func (s *Selector) UpdateDependency(detour adapter.Outbound) {
+	s.access.Lock()
+	oldDetour, loaded := s.outbounds[detour.Tag()]
+	if !loaded {
+		s.access.Unlock()
+		return
+	}
+	outbounds := make(map[string]adapter.Outbound, len(s.outbounds))
+	for tag, it := range s.outbounds {
+		outbounds[tag] = it
+	}
+	outbounds[detour.Tag()] = detour
+	s.outbounds = outbounds
+	var updated bool
+	if s.selected == oldDetour {
+		s.selected = detour
+		updated = true
+	}
+	if s.selectedTCP == oldDetour {
+		s.selectedTCP = detour
+		updated = true
+	}
+	if s.selectedUDP == oldDetour {
+		s.selectedUDP = detour
+		updated = true
+	}
+	s.access.Unlock()
+	if s.urlTest != nil {
+		s.urlTest.UpdateDependency(detour)
+	}
+	if updated {
+		s.interruptGroup.Interrupt(s.interruptExternalConnections)
+	}
+}
+
 func (s *Selector) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
-	conn, err := s.selected.DialContext(ctx, network, destination)
+	s.touch()
+	conn, err := s.selectedOutbound(network).DialContext(ctx, network, destination)
 	if err != nil {
 		return nil, err
 	}
@@ -135,7 +418,8 @@ func (s *Selector) DialContext(ctx context.Context, network string, destination
 }
 
 func (s *Selector) ListenPacket(ctx context.Context, destination M.Socksaddr) (net.PacketConn, error) {
-	conn, err := s.selected.ListenPacket(ctx, destination)
+	s.touch()
+	conn, err := s.selectedOutbound(N.NetworkUDP).ListenPacket(ctx, destination)
 	if err != nil {
 		return nil, err
 	}
@@ -143,13 +427,15 @@ func (s *Selector) ListenPacket(ctx context.Context, destination M.Socksaddr) (n
 }
 
 func (s *Selector) NewConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext) error {
+	s.touch()
 	ctx = interrupt.ContextWithIsExternalConnection(ctx)
-	return s.selected.NewConnection(ctx, conn, metadata)
+	return s.selectedOutbound(N.NetworkTCP).NewConnection(ctx, conn, metadata)
 }
 
 func (s *Selector) NewPacketConnection(ctx context.Context, conn N.PacketConn, metadata adapter.InboundContext) error {
+	s.touch()
 	ctx = interrupt.ContextWithIsExternalConnection(ctx)
-	return s.selected.NewPacketConnection(ctx, conn, metadata)
+	return s.selectedOutbound(N.NetworkUDP).NewPacketConnection(ctx, conn, metadata)
 }
 
 func RealTag(detour adapter.Outbound) string {
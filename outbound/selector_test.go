@@ -0,0 +1,159 @@
+package outbound
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/urltest"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	N "github.com/sagernet/sing/common/network"
+	"github.com/sagernet/sing/service"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testRouter resolves outbounds by tag, other router methods are not implemented.
+type testRouter struct {
+	adapter.Router
+	outbounds map[string]adapter.Outbound
+}
+
+func newTestRouter(outbounds ...adapter.Outbound) *testRouter {
+	router := &testRouter{outbounds: make(map[string]adapter.Outbound)}
+	for _, detour := range outbounds {
+		router.outbounds[detour.Tag()] = detour
+	}
+	return router
+}
+
+func (r *testRouter) Outbound(tag string) (adapter.Outbound, bool) {
+	detour, loaded := r.outbounds[tag]
+	return detour, loaded
+}
+
+func startTestSelector(t *testing.T, history *urltest.HistoryStorage, tag string, options option.SelectorOutboundOptions) *Selector {
+	ctx := service.ContextWithPtr(context.Background(), history)
+	logger := log.NewNOPFactory().Logger()
+	router := newTestRouter(NewBlock(logger, "a"), NewBlock(logger, "b"), NewBlock(logger, "c"))
+	selector, err := NewSelector(ctx, router, logger, tag, options)
+	require.NoError(t, err)
+	require.NoError(t, selector.Start())
+	t.Cleanup(func() {
+		selector.Close()
+	})
+	return selector
+}
+
+func TestSelectorNetworkOutbounds(t *testing.T) {
+	t.Parallel()
+	selector := startTestSelector(t, urltest.NewHistoryStorage(), "select", option.SelectorOutboundOptions{
+		Outbounds:   []string{"a", "b", "c"},
+		TCPOutbound: "b",
+	})
+	require.Equal(t, "a", selector.Now())
+	require.Equal(t, "b", selector.NowNetwork(N.NetworkTCP))
+	require.Equal(t, "a", selector.NowNetwork(N.NetworkUDP))
+
+	require.True(t, selector.SelectNetworkOutbound(N.NetworkUDP, "c"))
+	require.Equal(t, "c", selector.NowNetwork(N.NetworkUDP))
+	require.True(t, selector.SelectOutbound("c"))
+	require.Equal(t, "c", selector.Now())
+	require.Equal(t, "b", selector.NowNetwork(N.NetworkTCP))
+
+	// an empty tag restores the configured member
+	require.True(t, selector.SelectNetworkOutbound(N.NetworkTCP, "a"))
+	require.Equal(t, "a", selector.NowNetwork(N.NetworkTCP))
+	require.True(t, selector.SelectNetworkOutbound(N.NetworkTCP, ""))
+	require.Equal(t, "b", selector.NowNetwork(N.NetworkTCP))
+	require.True(t, selector.SelectNetworkOutbound(N.NetworkUDP, ""))
+	require.Equal(t, "c", selector.NowNetwork(N.NetworkUDP))
+
+	require.False(t, selector.SelectNetworkOutbound(N.NetworkUDP, "d"))
+	require.False(t, selector.SelectNetworkOutbound("icmp", "a"))
+	require.False(t, selector.SelectOutbound("d"))
+
+	_, err := NewSelector(context.Background(), newTestRouter(), log.NewNOPFactory().Logger(), "select", option.SelectorOutboundOptions{
+		Outbounds:   []string{"a"},
+		UDPOutbound: "b",
+	})
+	require.Error(t, err)
+}
+
+func TestSelectorAuto(t *testing.T) {
+	t.Parallel()
+	history := urltest.NewHistoryStorage()
+	selector := startTestSelector(t, history, "select", option.SelectorOutboundOptions{
+		Outbounds:   []string{"a", "b"},
+		UDPOutbound: "select/auto",
+		Auto:        true,
+	})
+	require.Equal(t, []string{"select/auto", "a", "b"}, selector.All())
+	require.Equal(t, "select/auto", selector.Now())
+	require.Equal(t, "select/auto", selector.NowNetwork(N.NetworkUDP))
+	autoOutbound, loaded := selector.Auto()
+	require.True(t, loaded)
+	require.Equal(t, "select/auto", autoOutbound.Tag())
+	member, loaded := selector.Outbound("select/auto")
+	require.True(t, loaded)
+	require.Equal(t, autoOutbound, member)
+
+	// the auto member resolves to the outbound with the lowest delay
+	history.StoreURLTestHistory("a", &urltest.History{Time: time.Now(), Delay: 200})
+	history.StoreURLTestHistory("b", &urltest.History{Time: time.Now(), Delay: 100})
+	detour, available := autoOutbound.(*URLTest).group.Select(N.NetworkTCP)
+	require.True(t, available)
+	require.Equal(t, "b", detour.Tag())
+
+	// auto members of other selectors don't collide
+	other := startTestSelector(t, history, "other", option.SelectorOutboundOptions{
+		Outbounds: []string{"a"},
+		Auto:      true,
+	})
+	otherAuto, _ := other.Auto()
+	require.Equal(t, "other/auto", otherAuto.Tag())
+	require.False(t, other.SelectOutbound("select/auto"))
+
+	withoutAuto := startTestSelector(t, history, "plain", option.SelectorOutboundOptions{
+		Outbounds: []string{"a"},
+	})
+	_, loaded = withoutAuto.Auto()
+	require.False(t, loaded)
+	require.False(t, withoutAuto.SelectOutbound("plain/auto"))
+}
+
+func TestSelectorFollow(t *testing.T) {
+	t.Parallel()
+	history := urltest.NewHistoryStorage()
+	selector := startTestSelector(t, history, "select", option.SelectorOutboundOptions{
+		Outbounds:   []string{"a", "b", "c"},
+		TCPOutbound: "b",
+		UDPOutbound: "c",
+		Follow:      true,
+	})
+	history.StoreURLTestHistory("a", &urltest.History{Time: time.Now(), Delay: TimeoutDelay})
+	history.StoreURLTestHistory("b", &urltest.History{Time: time.Now(), Delay: TimeoutDelay})
+	history.StoreURLTestHistory("c", &urltest.History{Time: time.Now(), Delay: 100})
+	selector.followAvailable()
+	require.Equal(t, "c", selector.Now())
+	require.Equal(t, "c", selector.NowNetwork(N.NetworkTCP))
+	require.Equal(t, "c", selector.NowNetwork(N.NetworkUDP))
+
+	// available outbounds are kept
+	history.StoreURLTestHistory("a", &urltest.History{Time: time.Now(), Delay: 50})
+	selector.followAvailable()
+	require.Equal(t, "c", selector.Now())
+
+	autoSelector := startTestSelector(t, history, "auto-select", option.SelectorOutboundOptions{
+		Outbounds:   []string{"a", "b", "c"},
+		Default:     "b",
+		UDPOutbound: "b",
+		Auto:        true,
+		Follow:      true,
+	})
+	autoSelector.followAvailable()
+	require.Equal(t, "auto-select/auto", autoSelector.Now())
+	require.Equal(t, "auto-select/auto", autoSelector.NowNetwork(N.NetworkUDP))
+}
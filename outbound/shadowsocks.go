@@ -56,8 +56,8 @@ func NewShadowsocks(ctx context.Context, router adapter.Router, logger log.Conte
 		method:     method,
 		serverAddr: options.ServerOptions.Build(),
 	}
-	if options.Plugin != "" {
-		outbound.plugin, err = sip003.CreatePlugin(ctx, options.Plugin, options.PluginOptions, router, outbound.dialer, outbound.serverAddr)
+	if options.Plugin != "" || options.PluginPath != "" {
+		outbound.plugin, err = sip003.CreatePlugin(ctx, options.Plugin, options.PluginPath, options.PluginOptions, router, outbound.dialer, outbound.serverAddr)
 		if err != nil {
 			return nil, err
 		}
@@ -141,7 +141,7 @@ func (h *Shadowsocks) InterfaceUpdated() {
 }
 
 func (h *Shadowsocks) Close() error {
-	return common.Close(common.PtrOrNil(h.multiplexDialer))
+	return common.Close(common.PtrOrNil(h.multiplexDialer), h.plugin)
 }
 
 var _ N.Dialer = (*shadowsocksDialer)(nil)
@@ -10,6 +10,8 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/sagernet/sing-box/adapter"
 	"github.com/sagernet/sing-box/common/dialer"
@@ -21,8 +23,10 @@ import (
 	M "github.com/sagernet/sing/common/metadata"
 	N "github.com/sagernet/sing/common/network"
 	"github.com/sagernet/sing/common/uot"
+	"github.com/sagernet/sing/protocol/socks"
 
 	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
 )
 
 var (
@@ -34,16 +38,25 @@ type SSH struct {
 	myOutboundAdapter
 	ctx               context.Context
 	dialer            N.Dialer
-	serverAddr        M.Socksaddr
-	user              string
-	hostKey           []ssh.PublicKey
-	hostKeyAlgorithms []string
-	clientVersion     string
-	authMethod        []ssh.AuthMethod
-	clientAccess      sync.Mutex
-	clientConn        net.Conn
-	client            *ssh.Client
+	hops              []sshHop
+	keepAliveInterval time.Duration
+	sessions          []*sshSession
+	sessionIndex      atomic.Uint32
 	uotClient         *uot.Client
+	done              chan struct{}
+}
+
+// sshHop is a jump host or the server, connected through the previous hop.
+type sshHop struct {
+	serverAddr M.Socksaddr
+	config     *ssh.ClientConfig
+}
+
+// sshSession is one connection of the session pool, reconnected lazily when closed.
+type sshSession struct {
+	access sync.Mutex
+	conn   net.Conn
+	client *ssh.Client
 }
 
 func NewSSH(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.SSHOutboundOptions) (*SSH, error) {
@@ -62,22 +75,71 @@ func NewSSH(ctx context.Context, router adapter.Router, logger log.ContextLogger
 		},
 		ctx:               ctx,
 		dialer:            outboundDialer,
-		serverAddr:        options.ServerOptions.Build(),
-		user:              options.User,
-		hostKeyAlgorithms: options.HostKeyAlgorithms,
-		clientVersion:     options.ClientVersion,
+		keepAliveInterval: time.Duration(options.KeepAliveInterval),
+		done:              make(chan struct{}),
+	}
+	for i, jumpOptions := range options.Jump {
+		hop, err := newSSHHop(jumpOptions)
+		if err != nil {
+			return nil, E.Cause(err, "jump host ", i)
+		}
+		outbound.hops = append(outbound.hops, hop)
+	}
+	hop, err := newSSHHop(options.SSHClientOptions)
+	if err != nil {
+		return nil, err
+	}
+	outbound.hops = append(outbound.hops, hop)
+	sessions := options.Sessions
+	if sessions == 0 {
+		sessions = 1
+	} else if sessions < 0 {
+		return nil, E.New("invalid sessions: ", sessions)
+	}
+	for i := 0; i < sessions; i++ {
+		outbound.sessions = append(outbound.sessions, &sshSession{})
+	}
+	uotOptions := common.PtrValueOrDefault(options.UDPOverTCP)
+	if uotOptions.Enabled {
+		var uotDialer N.Dialer = outbound
+		if options.UDPHelper != "" {
+			helperAddr := M.ParseSocksaddr(options.UDPHelper)
+			if !helperAddr.IsValid() || helperAddr.Port == 0 {
+				return nil, E.New("invalid udp_helper: ", options.UDPHelper)
+			}
+			uotDialer = socks.NewClient(outbound, helperAddr, socks.Version5, "", "")
+		}
+		outbound.uotClient = &uot.Client{
+			Dialer:  uotDialer,
+			Version: uotOptions.Version,
+		}
+		outbound.network = append(outbound.network, N.NetworkUDP)
+	} else if options.UDPHelper != "" {
+		return nil, E.New("udp_helper requires udp_over_tcp")
+	}
+	return outbound, nil
+}
+
+func newSSHHop(options option.SSHClientOptions) (sshHop, error) {
+	hop := sshHop{
+		serverAddr: options.ServerOptions.Build(),
+	}
+	if hop.serverAddr.Port == 0 {
+		hop.serverAddr.Port = 22
 	}
-	if outbound.serverAddr.Port == 0 {
-		outbound.serverAddr.Port = 22
+	config := &ssh.ClientConfig{
+		User:              options.User,
+		ClientVersion:     options.ClientVersion,
+		HostKeyAlgorithms: options.HostKeyAlgorithms,
 	}
-	if outbound.user == "" {
-		outbound.user = "root"
+	if config.User == "" {
+		config.User = "root"
 	}
-	if outbound.clientVersion == "" {
-		outbound.clientVersion = randomVersion()
+	if config.ClientVersion == "" {
+		config.ClientVersion = randomVersion()
 	}
 	if options.Password != "" {
-		outbound.authMethod = append(outbound.authMethod, ssh.Password(options.Password))
+		config.Auth = append(config.Auth, ssh.Password(options.Password))
 	}
 	if len(options.PrivateKey) > 0 || options.PrivateKeyPath != "" {
 		var privateKey []byte
@@ -87,7 +149,7 @@ func NewSSH(ctx context.Context, router adapter.Router, logger log.ContextLogger
 			var err error
 			privateKey, err = os.ReadFile(os.ExpandEnv(options.PrivateKeyPath))
 			if err != nil {
-				return nil, E.Cause(err, "read private key")
+				return sshHop{}, E.Cause(err, "read private key")
 			}
 		}
 		var signer ssh.Signer
@@ -98,27 +160,44 @@ func NewSSH(ctx context.Context, router adapter.Router, logger log.ContextLogger
 			signer, err = ssh.ParsePrivateKeyWithPassphrase(privateKey, []byte(options.PrivateKeyPassphrase))
 		}
 		if err != nil {
-			return nil, E.Cause(err, "parse private key")
+			return sshHop{}, E.Cause(err, "parse private key")
 		}
-		outbound.authMethod = append(outbound.authMethod, ssh.PublicKeys(signer))
+		config.Auth = append(config.Auth, ssh.PublicKeys(signer))
 	}
-	if len(options.HostKey) > 0 {
-		for _, hostKey := range options.HostKey {
-			key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(hostKey))
-			if err != nil {
-				return nil, E.New("parse host key ", key)
-			}
-			outbound.hostKey = append(outbound.hostKey, key)
+	if options.KnownHostsPath != "" {
+		if len(options.HostKey) > 0 {
+			return sshHop{}, E.New("host_key is conflict with known_hosts_path")
+		}
+		hostKeyCallback, err := knownhosts.New(os.ExpandEnv(options.KnownHostsPath))
+		if err != nil {
+			return sshHop{}, E.Cause(err, "read known hosts")
 		}
+		config.HostKeyCallback = hostKeyCallback
+		hop.config = config
+		return hop, nil
 	}
-	uotOptions := common.PtrValueOrDefault(options.UDPOverTCP)
-	if uotOptions.Enabled {
-		outbound.uotClient = &uot.Client{
-			Dialer:  outbound,
-			Version: uotOptions.Version,
+	var hostKeys []ssh.PublicKey
+	for _, hostKey := range options.HostKey {
+		key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(hostKey))
+		if err != nil {
+			return sshHop{}, E.New("parse host key ", key)
 		}
+		hostKeys = append(hostKeys, key)
 	}
-	return outbound, nil
+	config.HostKeyCallback = func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		if len(hostKeys) == 0 {
+			return nil
+		}
+		serverKey := key.Marshal()
+		for _, hostKey := range hostKeys {
+			if bytes.Equal(serverKey, hostKey.Marshal()) {
+				return nil
+			}
+		}
+		return E.New("host key mismatch, server send ", key.Type(), " ", base64.StdEncoding.EncodeToString(serverKey))
+	}
+	hop.config = config
+	return hop, nil
 }
 
 func randomVersion() string {
@@ -132,76 +211,154 @@ func randomVersion() string {
 }
 
 func (s *SSH) connect() (*ssh.Client, error) {
-	if s.client != nil {
-		return s.client, nil
-	}
+	session := s.sessions[int(s.sessionIndex.Add(1)-1)%len(s.sessions)]
+	return s.connectSession(session)
+}
 
-	s.clientAccess.Lock()
-	defer s.clientAccess.Unlock()
+func (s *SSH) connectSession(session *sshSession) (*ssh.Client, error) {
+	session.access.Lock()
+	defer session.access.Unlock()
 
-	if s.client != nil {
-		return s.client, nil
+	if s.isClosed() {
+		return nil, os.ErrClosed
+	}
+	if session.client != nil {
+		return session.client, nil
 	}
 
-	conn, err := s.dialer.DialContext(s.ctx, N.NetworkTCP, s.serverAddr)
+	conn, err := s.dialer.DialContext(s.ctx, N.NetworkTCP, s.hops[0].serverAddr)
 	if err != nil {
 		return nil, err
 	}
-	config := &ssh.ClientConfig{
-		User:              s.user,
-		Auth:              s.authMethod,
-		ClientVersion:     s.clientVersion,
-		HostKeyAlgorithms: s.hostKeyAlgorithms,
-		HostKeyCallback: func(hostname string, remote net.Addr, key ssh.PublicKey) error {
-			if len(s.hostKey) == 0 {
-				return nil
+	var client *ssh.Client
+	for i, hop := range s.hops {
+		hopConn := conn
+		if i > 0 {
+			hopConn, err = client.Dial(N.NetworkTCP, hop.serverAddr.String())
+			if err != nil {
+				conn.Close()
+				return nil, E.Cause(err, "connect to ", hop.serverAddr, " through jump host")
 			}
-			serverKey := key.Marshal()
-			for _, hostKey := range s.hostKey {
-				if bytes.Equal(serverKey, hostKey.Marshal()) {
-					return nil
-				}
+		}
+		clientConn, chans, reqs, err := ssh.NewClientConn(hopConn, hop.serverAddr.String(), hop.config)
+		if err != nil {
+			conn.Close()
+			if i < len(s.hops)-1 {
+				return nil, E.Cause(err, "connect to jump host ", hop.serverAddr)
 			}
-			return E.New("host key mismatch, server send ", key.Type(), " ", base64.StdEncoding.EncodeToString(serverKey))
-		},
+			return nil, E.Cause(err, "connect to ssh server")
+		}
+		client = ssh.NewClient(clientConn, chans, reqs)
 	}
-	clientConn, chans, reqs, err := ssh.NewClientConn(conn, s.serverAddr.Addr.String(), config)
-	if err != nil {
+
+	// Close does not see a client that is still handshaking, so drop it here
+	if s.isClosed() {
+		client.Close()
 		conn.Close()
-		return nil, E.Cause(err, "connect to ssh server")
+		return nil, os.ErrClosed
 	}
+	session.conn = conn
+	session.client = client
 
-	client := ssh.NewClient(clientConn, chans, reqs)
-
-	s.clientConn = conn
-	s.client = client
-
+	done := make(chan struct{})
 	go func() {
 		client.Wait()
 		conn.Close()
-		s.clientAccess.Lock()
-		s.client = nil
-		s.clientConn = nil
-		s.clientAccess.Unlock()
+		session.access.Lock()
+		if session.client == client {
+			session.client = nil
+			session.conn = nil
+		}
+		session.access.Unlock()
+		close(done)
 	}()
+	if s.keepAliveInterval > 0 {
+		go s.loopKeepAlive(session, client, done)
+	}
 
 	return client, nil
 }
 
+// loopKeepAlive closes the client if a probe is not answered in time and reconnects the session.
+func (s *SSH) loopKeepAlive(session *sshSession, client *ssh.Client, done <-chan struct{}) {
+	ticker := time.NewTicker(s.keepAliveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+		err := sendKeepAlive(client, s.keepAliveInterval)
+		if err == nil {
+			continue
+		}
+		s.logger.Debug("keepalive failed: ", err)
+		client.Close()
+		<-done
+		if s.isClosed() {
+			return
+		}
+		_, err = s.connectSession(session)
+		if err != nil {
+			s.logger.Error("reconnect: ", err)
+		}
+		return
+	}
+}
+
+func sendKeepAlive(client *ssh.Client, timeout time.Duration) error {
+	result := make(chan error, 1)
+	go func() {
+		// any reply, including a failure for the unknown request, proves that the server is alive
+		_, _, err := client.SendRequest("keepalive@openssh.com", true, nil)
+		result <- err
+	}()
+	select {
+	case err := <-result:
+		return err
+	case <-time.After(timeout):
+		return os.ErrDeadlineExceeded
+	}
+}
+
+func (s *SSH) closeSessions() error {
+	var conns []any
+	for _, session := range s.sessions {
+		session.access.Lock()
+		if session.conn != nil {
+			conns = append(conns, session.conn)
+		}
+		session.access.Unlock()
+	}
+	return common.Close(conns...)
+}
+
 func (s *SSH) InterfaceUpdated() {
-	common.Close(s.clientConn)
+	s.closeSessions()
 	return
 }
 
+func (s *SSH) isClosed() bool {
+	select {
+	case <-s.done:
+		return true
+	default:
+		return false
+	}
+}
+
 func (s *SSH) Close() error {
-	return common.Close(s.clientConn)
+	select {
+	case <-s.done:
+		return os.ErrClosed
+	default:
+		close(s.done)
+	}
+	return s.closeSessions()
 }
 
 func (s *SSH) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
-	client, err := s.connect()
-	if err != nil {
-		return nil, err
-	}
 	switch N.NetworkName(network) {
 	case N.NetworkTCP:
 		s.logger.InfoContext(ctx, "outbound connection to ", destination)
@@ -214,12 +371,19 @@ func (s *SSH) DialContext(ctx context.Context, network string, destination M.Soc
 	default:
 		return nil, E.Extend(N.ErrUnknownNetwork, network)
 	}
-
+	client, err := s.connect()
+	if err != nil {
+		return nil, err
+	}
 	return client.Dial(network, destination.String())
 }
 
 func (s *SSH) ListenPacket(ctx context.Context, destination M.Socksaddr) (net.PacketConn, error) {
-	return nil, os.ErrInvalid
+	if s.uotClient == nil {
+		return nil, os.ErrInvalid
+	}
+	s.logger.InfoContext(ctx, "outbound UoT packet connection to ", destination)
+	return s.uotClient.ListenPacket(ctx, destination)
 }
 
 func (s *SSH) NewConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext) error {
@@ -227,5 +391,8 @@ func (s *SSH) NewConnection(ctx context.Context, conn net.Conn, metadata adapter
 }
 
 func (s *SSH) NewPacketConnection(ctx context.Context, conn N.PacketConn, metadata adapter.InboundContext) error {
-	return os.ErrInvalid
+	if s.uotClient == nil {
+		return os.ErrInvalid
+	}
+	return NewPacketConnection(ctx, s, conn, metadata)
 }
@@ -0,0 +1,332 @@
+package outbound
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+	"github.com/sagernet/sing/common/uot"
+	"github.com/sagernet/sing/protocol/socks"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+func TestSSHJumpHost(t *testing.T) {
+	t.Parallel()
+	jumpAddr, jumpKey, _ := startTestSSHServer(t)
+	serverAddr, serverKey, _ := startTestSSHServer(t)
+	echoListener := startTestEchoServer(t)
+	knownHostsPath := filepath.Join(t.TempDir(), "known_hosts")
+	require.NoError(t, os.WriteFile(knownHostsPath, []byte(
+		knownhosts.Line([]string{knownhosts.Normalize(jumpAddr.String())}, jumpKey)+"\n"+
+			knownhosts.Line([]string{knownhosts.Normalize(serverAddr.String())}, serverKey)+"\n",
+	), 0o644))
+	newClientOptions := func(addr M.Socksaddr) option.SSHClientOptions {
+		return option.SSHClientOptions{
+			ServerOptions:  option.ServerOptions{Server: addr.AddrString(), ServerPort: addr.Port},
+			User:           "test",
+			Password:       "test",
+			KnownHostsPath: knownHostsPath,
+		}
+	}
+	outbound, err := NewSSH(context.Background(), nil, log.NewNOPFactory().Logger(), "ssh", option.SSHOutboundOptions{
+		SSHClientOptions: newClientOptions(serverAddr),
+		Jump:             []option.SSHClientOptions{newClientOptions(jumpAddr)},
+		Sessions:         2,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		outbound.Close()
+	})
+	for i := 0; i < 4; i++ {
+		conn, err := outbound.DialContext(context.Background(), N.NetworkTCP, M.SocksaddrFromNet(echoListener.Addr()))
+		require.NoError(t, err)
+		message := []byte("hello " + strconv.Itoa(i))
+		_, err = conn.Write(message)
+		require.NoError(t, err)
+		response := make([]byte, len(message))
+		_, err = io.ReadFull(conn, response)
+		require.NoError(t, err)
+		require.Equal(t, message, response)
+		conn.Close()
+	}
+	for _, session := range outbound.sessions {
+		require.NotNil(t, session.client)
+	}
+
+	otherKnownHosts := filepath.Join(t.TempDir(), "known_hosts")
+	require.NoError(t, os.WriteFile(otherKnownHosts, []byte(
+		knownhosts.Line([]string{knownhosts.Normalize(jumpAddr.String())}, jumpKey)+"\n"+
+			knownhosts.Line([]string{knownhosts.Normalize(serverAddr.String())}, jumpKey)+"\n",
+	), 0o644))
+	serverOptions := newClientOptions(serverAddr)
+	serverOptions.KnownHostsPath = otherKnownHosts
+	mismatchOutbound, err := NewSSH(context.Background(), nil, log.NewNOPFactory().Logger(), "ssh", option.SSHOutboundOptions{
+		SSHClientOptions: serverOptions,
+		Jump:             []option.SSHClientOptions{newClientOptions(jumpAddr)},
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		mismatchOutbound.Close()
+	})
+	_, err = mismatchOutbound.DialContext(context.Background(), N.NetworkTCP, M.SocksaddrFromNet(echoListener.Addr()))
+	require.Error(t, err)
+}
+
+func TestSSHKeepAliveReconnect(t *testing.T) {
+	t.Parallel()
+	serverAddr, _, hang := startTestSSHServer(t)
+	echoListener := startTestEchoServer(t)
+	outbound, err := NewSSH(context.Background(), nil, log.NewNOPFactory().Logger(), "ssh", option.SSHOutboundOptions{
+		SSHClientOptions: option.SSHClientOptions{
+			ServerOptions: option.ServerOptions{Server: serverAddr.AddrString(), ServerPort: serverAddr.Port},
+			User:          "test",
+			Password:      "test",
+		},
+		KeepAliveInterval: option.Duration(100 * time.Millisecond),
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		outbound.Close()
+	})
+	conn, err := outbound.DialContext(context.Background(), N.NetworkTCP, M.SocksaddrFromNet(echoListener.Addr()))
+	require.NoError(t, err)
+	conn.Close()
+	session := outbound.sessions[0]
+	session.access.Lock()
+	client := session.client
+	session.access.Unlock()
+	require.NotNil(t, client)
+
+	// the session is reconnected once the server stops answering keepalives
+	hang.Store(true)
+	require.Eventually(t, func() bool {
+		session.access.Lock()
+		defer session.access.Unlock()
+		return session.client != nil && session.client != client
+	}, 5*time.Second, 50*time.Millisecond)
+	hang.Store(false)
+	conn, err = outbound.DialContext(context.Background(), N.NetworkTCP, M.SocksaddrFromNet(echoListener.Addr()))
+	require.NoError(t, err)
+	conn.Close()
+}
+
+func TestSSHDialAfterClose(t *testing.T) {
+	t.Parallel()
+	serverAddr, _, _ := startTestSSHServer(t)
+	echoListener := startTestEchoServer(t)
+	outbound, err := NewSSH(context.Background(), nil, log.NewNOPFactory().Logger(), "ssh", option.SSHOutboundOptions{
+		SSHClientOptions: option.SSHClientOptions{
+			ServerOptions: option.ServerOptions{Server: serverAddr.AddrString(), ServerPort: serverAddr.Port},
+			User:          "test",
+			Password:      "test",
+		},
+		KeepAliveInterval: option.Duration(100 * time.Millisecond),
+	})
+	require.NoError(t, err)
+	require.NoError(t, outbound.Close())
+	_, err = outbound.DialContext(context.Background(), N.NetworkTCP, M.SocksaddrFromNet(echoListener.Addr()))
+	require.ErrorIs(t, err, os.ErrClosed)
+	for _, session := range outbound.sessions {
+		require.Nil(t, session.client)
+	}
+}
+
+func TestSSHUDPHelper(t *testing.T) {
+	t.Parallel()
+	serverAddr, _, _ := startTestSSHServer(t)
+	helperListener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		helperListener.Close()
+	})
+	go func() {
+		for {
+			conn, err := helperListener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				socks.HandleConnection(context.Background(), conn, nil, (*testUoTHelper)(nil), M.Metadata{})
+			}()
+		}
+	}()
+	outbound, err := NewSSH(context.Background(), nil, log.NewNOPFactory().Logger(), "ssh", option.SSHOutboundOptions{
+		SSHClientOptions: option.SSHClientOptions{
+			ServerOptions: option.ServerOptions{Server: serverAddr.AddrString(), ServerPort: serverAddr.Port},
+			User:          "test",
+			Password:      "test",
+		},
+		UDPOverTCP: &option.UDPOverTCPOptions{Enabled: true, Version: uot.Version},
+		UDPHelper:  helperListener.Addr().String(),
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		outbound.Close()
+	})
+	require.Contains(t, outbound.Network(), N.NetworkUDP)
+	destination := M.ParseSocksaddr("192.0.2.1:53")
+	packetConn, err := outbound.ListenPacket(context.Background(), destination)
+	require.NoError(t, err)
+	defer packetConn.Close()
+	_, err = packetConn.WriteTo([]byte("hello"), destination.UDPAddr())
+	require.NoError(t, err)
+	buffer := make([]byte, 64)
+	n, addr, err := packetConn.ReadFrom(buffer)
+	require.NoError(t, err)
+	require.Equal(t, "hello", string(buffer[:n]))
+	require.Equal(t, destination, M.SocksaddrFromNet(addr))
+
+	_, err = NewSSH(context.Background(), nil, log.NewNOPFactory().Logger(), "ssh", option.SSHOutboundOptions{
+		UDPHelper: helperListener.Addr().String(),
+	})
+	require.Error(t, err)
+}
+
+// testUoTHelper is a SOCKS5 server echoing UDP over TCP packets back to the client.
+type testUoTHelper struct{}
+
+func (h *testUoTHelper) NewConnection(ctx context.Context, conn net.Conn, metadata M.Metadata) error {
+	if metadata.Destination.Fqdn != uot.MagicAddress {
+		return os.ErrInvalid
+	}
+	request, err := uot.ReadRequest(conn)
+	if err != nil {
+		return err
+	}
+	packetConn := uot.NewConn(conn, *request)
+	buffer := make([]byte, 2048)
+	for {
+		n, addr, err := packetConn.ReadFrom(buffer)
+		if err != nil {
+			return err
+		}
+		_, err = packetConn.WriteTo(buffer[:n], addr)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (h *testUoTHelper) NewPacketConnection(ctx context.Context, conn N.PacketConn, metadata M.Metadata) error {
+	return os.ErrInvalid
+}
+
+func startTestEchoServer(t *testing.T) net.Listener {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		listener.Close()
+	})
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go io.Copy(conn, conn)
+		}
+	}()
+	return listener
+}
+
+// startTestSSHServer starts a server accepting password test and forwarding TCP connections,
+// global requests such as keepalives are not answered while hang is set.
+func startTestSSHServer(t *testing.T) (M.Socksaddr, ssh.PublicKey, *atomic.Bool) {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	signer, err := ssh.NewSignerFromKey(privateKey)
+	require.NoError(t, err)
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(conn ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			if conn.User() == "test" && string(password) == "test" {
+				return nil, nil
+			}
+			return nil, os.ErrPermission
+		},
+	}
+	config.AddHostKey(signer)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		listener.Close()
+	})
+	hang := new(atomic.Bool)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveTestSSHConn(conn, config, hang)
+		}
+	}()
+	return M.SocksaddrFromNet(listener.Addr()), signer.PublicKey(), hang
+}
+
+func serveTestSSHConn(conn net.Conn, config *ssh.ServerConfig, hang *atomic.Bool) {
+	serverConn, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	defer serverConn.Close()
+	go func() {
+		for request := range reqs {
+			if request.WantReply && !hang.Load() {
+				request.Reply(false, nil)
+			}
+		}
+	}()
+	for newChannel := range chans {
+		if newChannel.ChannelType() != "direct-tcpip" {
+			newChannel.Reject(ssh.UnknownChannelType, "unsupported")
+			continue
+		}
+		var request struct {
+			Host       string
+			Port       uint32
+			OriginHost string
+			OriginPort uint32
+		}
+		err = ssh.Unmarshal(newChannel.ExtraData(), &request)
+		if err != nil {
+			newChannel.Reject(ssh.ConnectionFailed, err.Error())
+			continue
+		}
+		destination, err := net.Dial("tcp", net.JoinHostPort(request.Host, strconv.Itoa(int(request.Port))))
+		if err != nil {
+			newChannel.Reject(ssh.ConnectionFailed, err.Error())
+			continue
+		}
+		channel, channelReqs, err := newChannel.Accept()
+		if err != nil {
+			destination.Close()
+			continue
+		}
+		go ssh.DiscardRequests(channelReqs)
+		go func() {
+			io.Copy(channel, destination)
+			channel.CloseWrite()
+		}()
+		go func() {
+			io.Copy(destination, channel)
+			destination.Close()
+		}()
+	}
+}
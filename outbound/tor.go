@@ -2,6 +2,8 @@ package outbound
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"net"
 	"os"
 	"path/filepath"
@@ -24,17 +26,27 @@ import (
 	"github.com/cretz/bine/tor"
 )
 
-var _ adapter.Outbound = (*Tor)(nil)
+var _ adapter.CircuitOutbound = (*Tor)(nil)
+
+const (
+	torIsolationInbound     = "inbound"
+	torIsolationUser        = "user"
+	torIsolationSourceIP    = "source_ip"
+	torIsolationDestination = "destination"
+)
 
 type Tor struct {
 	myOutboundAdapter
-	ctx         context.Context
-	proxy       *ProxyListener
-	startConf   *tor.StartConf
-	options     map[string]string
-	events      chan control.Event
-	instance    *tor.Tor
-	socksClient *socks.Client
+	ctx             context.Context
+	proxy           *ProxyListener
+	startConf       *tor.StartConf
+	options         map[string]string
+	bridgeOptions   []*control.KeyVal
+	streamIsolation []string
+	events          chan control.Event
+	instance        *tor.Tor
+	socksAddr       M.Socksaddr
+	socksClient     *socks.Client
 }
 
 func NewTor(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.TorOutboundOptions) (*Tor, error) {
@@ -66,6 +78,17 @@ func NewTor(ctx context.Context, router adapter.Router, logger log.ContextLogger
 		}
 		startConf.TorrcFile = torrcFile
 	}
+	bridgeOptions, err := newTorBridgeOptions(options)
+	if err != nil {
+		return nil, err
+	}
+	for _, isolation := range options.StreamIsolation {
+		switch isolation {
+		case torIsolationInbound, torIsolationUser, torIsolationSourceIP, torIsolationDestination:
+		default:
+			return nil, E.New("unknown stream isolation: ", isolation)
+		}
+	}
 	outboundDialer, err := dialer.New(router, options.DialerOptions)
 	if err != nil {
 		return nil, err
@@ -79,13 +102,56 @@ func NewTor(ctx context.Context, router adapter.Router, logger log.ContextLogger
 			tag:          tag,
 			dependencies: withDialerDependency(options.DialerOptions),
 		},
-		ctx:       ctx,
-		proxy:     NewProxyListener(ctx, logger, outboundDialer),
-		startConf: &startConf,
-		options:   options.Options,
+		ctx:             ctx,
+		proxy:           NewProxyListener(ctx, logger, outboundDialer),
+		startConf:       &startConf,
+		options:         options.Options,
+		bridgeOptions:   bridgeOptions,
+		streamIsolation: options.StreamIsolation,
 	}, nil
 }
 
+func newTorBridgeOptions(options option.TorOutboundOptions) ([]*control.KeyVal, error) {
+	if len(options.Bridges) == 0 {
+		if len(options.PluggableTransports) > 0 {
+			return nil, E.New("pluggable_transports requires bridges")
+		}
+		return nil, nil
+	}
+	var (
+		transports []string
+		keyValues  []*control.KeyVal
+	)
+	for i, transport := range options.PluggableTransports {
+		if len(transport.Transports) == 0 {
+			return nil, E.New("missing transports for pluggable transport ", i)
+		}
+		if transport.ExecutablePath == "" {
+			return nil, E.New("missing executable_path for pluggable transport ", i)
+		}
+		transports = append(transports, transport.Transports...)
+		plugin := strings.Join(append([]string{
+			strings.Join(transport.Transports, ","),
+			"exec",
+			os.ExpandEnv(transport.ExecutablePath),
+		}, transport.Args...), " ")
+		keyValues = append(keyValues, control.NewKeyVal("ClientTransportPlugin", plugin))
+	}
+	keyValues = append(keyValues, control.NewKeyVal("UseBridges", "1"))
+	for _, bridge := range options.Bridges {
+		fields := strings.Fields(bridge)
+		if len(fields) == 0 {
+			return nil, E.New("empty bridge line")
+		}
+		// a bridge line starts with its transport name unless it is a plain bridge address
+		if destination := M.ParseSocksaddr(fields[0]); !destination.IsIP() && !common.Contains(transports, fields[0]) {
+			return nil, E.New("missing pluggable transport for bridge: ", bridge)
+		}
+		keyValues = append(keyValues, control.NewKeyVal("Bridge", strings.Join(fields, " ")))
+	}
+	return keyValues, nil
+}
+
 func (t *Tor) Start() error {
 	err := t.start()
 	if err != nil {
@@ -133,6 +199,12 @@ func (t *Tor) start() error {
 	if err != nil {
 		return err
 	}
+	if len(t.bridgeOptions) > 0 {
+		err = torInstance.Control.SetConf(t.bridgeOptions...)
+		if err != nil {
+			return E.Cause(err, "set bridges")
+		}
+	}
 	if len(t.options) > 0 {
 		for key, value := range t.options {
 			switch key {
@@ -160,7 +232,8 @@ func (t *Tor) start() error {
 	}
 	t.logger.Trace("obtained tor socks5 address ", info[0].Val)
 	// TODO: set password for tor socks5 server if supported
-	t.socksClient = socks.NewClient(N.SystemDialer, M.ParseSocksaddr(info[0].Val), socks.Version5, "", "")
+	t.socksAddr = M.ParseSocksaddr(info[0].Val)
+	t.socksClient = socks.NewClient(N.SystemDialer, t.socksAddr, socks.Version5, "", "")
 	return nil
 }
 
@@ -201,14 +274,95 @@ func (t *Tor) Close() error {
 
 func (t *Tor) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
 	t.logger.InfoContext(ctx, "outbound connection to ", destination)
+	if len(t.streamIsolation) > 0 {
+		if isolationKey := t.isolationKey(ctx, destination); isolationKey != "" {
+			// tor isolates streams with different SOCKS credentials by default
+			return socks.NewClient(N.SystemDialer, t.socksAddr, socks.Version5, isolationKey, "sing-box").DialContext(ctx, network, destination)
+		}
+	}
 	return t.socksClient.DialContext(ctx, network, destination)
 }
 
+func (t *Tor) isolationKey(ctx context.Context, destination M.Socksaddr) string {
+	metadata := adapter.ContextFrom(ctx)
+	if metadata == nil {
+		return ""
+	}
+	var values []string
+	for _, isolation := range t.streamIsolation {
+		switch isolation {
+		case torIsolationInbound:
+			values = append(values, metadata.Inbound)
+		case torIsolationUser:
+			values = append(values, metadata.User)
+		case torIsolationSourceIP:
+			values = append(values, metadata.Source.Addr.String())
+		case torIsolationDestination:
+			values = append(values, destination.String())
+		}
+	}
+	keyHash := sha256.Sum256([]byte(strings.Join(values, "\x00")))
+	return hex.EncodeToString(keyHash[:16])
+}
+
+func (t *Tor) NewIdentity() error {
+	if t.instance == nil {
+		return E.New("tor is not started")
+	}
+	return t.instance.Control.Signal("NEWNYM")
+}
+
+func (t *Tor) CircuitStatus() ([]adapter.CircuitStatus, error) {
+	if t.instance == nil {
+		return nil, E.New("tor is not started")
+	}
+	info, err := t.instance.Control.GetInfo("circuit-status")
+	if err != nil {
+		return nil, err
+	}
+	if len(info) != 1 || info[0].Key != "circuit-status" {
+		return nil, E.New("get circuit status")
+	}
+	return parseTorCircuitStatus(info[0].Val), nil
+}
+
+// parseTorCircuitStatus parses lines like "<id> <status> [<path>] [<key>=<value> ...]".
+func parseTorCircuitStatus(content string) []adapter.CircuitStatus {
+	var circuits []adapter.CircuitStatus
+	for _, line := range strings.Split(content, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		circuit := adapter.CircuitStatus{
+			ID:     fields[0],
+			Status: fields[1],
+		}
+		for i, field := range fields[2:] {
+			key, value, isKeyValue := strings.Cut(field, "=")
+			if !isKeyValue {
+				if i == 0 {
+					circuit.Path = strings.Split(field, ",")
+				}
+				continue
+			}
+			if key == "PURPOSE" {
+				circuit.Purpose = value
+			}
+		}
+		circuits = append(circuits, circuit)
+	}
+	return circuits
+}
+
 func (t *Tor) ListenPacket(ctx context.Context, destination M.Socksaddr) (net.PacketConn, error) {
 	return nil, os.ErrInvalid
 }
 
 func (t *Tor) NewConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext) error {
+	if len(t.streamIsolation) > 0 {
+		ctx = adapter.WithContext(ctx, &metadata)
+	}
 	return NewConnection(ctx, t, conn, metadata)
 }
 
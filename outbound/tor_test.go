@@ -0,0 +1,58 @@
+package outbound
+
+import (
+	"testing"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/option"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTorCircuitStatus(t *testing.T) {
+	t.Parallel()
+	circuits := parseTorCircuitStatus("1 BUILT $AAAA~relay1,$BBBB~relay2,$CCCC~relay3 BUILD_FLAGS=NEED_CAPACITY PURPOSE=GENERAL TIME_CREATED=2024-01-01T00:00:00.000000\n" +
+		"2 LAUNCHED BUILD_FLAGS=NEED_CAPACITY PURPOSE=CONFLUX_LINKED\n")
+	require.Equal(t, []adapter.CircuitStatus{
+		{
+			ID:      "1",
+			Status:  "BUILT",
+			Path:    []string{"$AAAA~relay1", "$BBBB~relay2", "$CCCC~relay3"},
+			Purpose: "GENERAL",
+		},
+		{
+			ID:      "2",
+			Status:  "LAUNCHED",
+			Purpose: "CONFLUX_LINKED",
+		},
+	}, circuits)
+}
+
+func TestTorBridgeOptions(t *testing.T) {
+	t.Parallel()
+	keyValues, err := newTorBridgeOptions(option.TorOutboundOptions{
+		Bridges: []string{
+			"obfs4 192.0.2.1:443 0123456789ABCDEF0123456789ABCDEF01234567 cert=AAAA iat-mode=0",
+			"192.0.2.2:9001",
+		},
+		PluggableTransports: []option.TorPluggableTransportOptions{{
+			Transports:     []string{"obfs4", "meek_lite"},
+			ExecutablePath: "/usr/bin/lyrebird",
+		}},
+	})
+	require.NoError(t, err)
+	var lines []string
+	for _, keyValue := range keyValues {
+		lines = append(lines, keyValue.Key+"="+keyValue.Val)
+	}
+	require.Equal(t, []string{
+		"ClientTransportPlugin=obfs4,meek_lite exec /usr/bin/lyrebird",
+		"UseBridges=1",
+		"Bridge=obfs4 192.0.2.1:443 0123456789ABCDEF0123456789ABCDEF01234567 cert=AAAA iat-mode=0",
+		"Bridge=192.0.2.2:9001",
+	}, lines)
+	_, err = newTorBridgeOptions(option.TorOutboundOptions{
+		Bridges: []string{"snowflake 192.0.2.3:80 2B280B23E1107BB62ABFC40DDCC8824814F80A72"},
+	})
+	require.Error(t, err)
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/sagernet/sing-box/adapter"
 	"github.com/sagernet/sing-box/common/dialer"
 	"github.com/sagernet/sing-box/common/tls"
+	"github.com/sagernet/sing-box/common/udpobfs"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/log"
 	"github.com/sagernet/sing-box/option"
@@ -66,6 +67,13 @@ func NewTUIC(ctx context.Context, router adapter.Router, logger log.ContextLogge
 	if err != nil {
 		return nil, err
 	}
+	if options.UDPObfs != nil {
+		obfuscator, err := udpobfs.NewObfuscator(*options.UDPObfs)
+		if err != nil {
+			return nil, err
+		}
+		outboundDialer = udpobfs.NewDialer(outboundDialer, obfuscator)
+	}
 	client, err := tuic.NewClient(tuic.ClientOptions{
 		Context:           ctx,
 		Dialer:            outboundDialer,
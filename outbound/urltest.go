@@ -30,7 +30,9 @@ const (
 var (
 	_ adapter.Outbound                = (*URLTest)(nil)
 	_ adapter.OutboundGroup           = (*URLTest)(nil)
+	_ adapter.NetworkOutboundGroup    = (*URLTest)(nil)
 	_ adapter.InterfaceUpdateListener = (*URLTest)(nil)
+	_ adapter.DependencyUpdater       = (*URLTest)(nil)
 )
 
 type URLTest struct {
@@ -108,14 +110,29 @@ func (s *URLTest) Close() error {
 }
 
 func (s *URLTest) Now() string {
-	if s.group.selectedOutboundTCP != nil {
-		return s.group.selectedOutboundTCP.Tag()
-	} else if s.group.selectedOutboundUDP != nil {
-		return s.group.selectedOutboundUDP.Tag()
+	if selected := s.group.selectedOutboundTCP.Load(); selected != nil {
+		return selected.Tag()
+	} else if selected = s.group.selectedOutboundUDP.Load(); selected != nil {
+		return selected.Tag()
 	}
 	return ""
 }
 
+// NowNetwork returns the outbound selected for the network.
+func (s *URLTest) NowNetwork(network string) string {
+	var selected adapter.Outbound
+	switch network {
+	case N.NetworkTCP:
+		selected = s.group.selectedOutboundTCP.Load()
+	case N.NetworkUDP:
+		selected = s.group.selectedOutboundUDP.Load()
+	}
+	if selected != nil {
+		return selected.Tag()
+	}
+	return s.Now()
+}
+
 func (s *URLTest) All() []string {
 	return s.tags
 }
@@ -128,14 +145,18 @@ func (s *URLTest) CheckOutbounds() {
 	s.group.CheckOutbounds(true)
 }
 
+func (s *URLTest) UpdateDependency(detour adapter.Outbound) {
+	s.group.UpdateDependency(detour)
+}
+
 func (s *URLTest) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
 	s.group.Touch()
 	var outbound adapter.Outbound
 	switch N.NetworkName(network) {
 	case N.NetworkTCP:
-		outbound = s.group.selectedOutboundTCP
+		outbound = s.group.selectedOutboundTCP.Load()
 	case N.NetworkUDP:
-		outbound = s.group.selectedOutboundUDP
+		outbound = s.group.selectedOutboundUDP.Load()
 	default:
 		return nil, E.Extend(N.ErrUnknownNetwork, network)
 	}
@@ -153,8 +174,8 @@ func (s *URLTest) DialContext(ctx context.Context, network string, destination M
 	}
 
 	if !s.group.pauseManager.IsNetworkPaused() && s.group.tcpConnectionFailureCount.IncrementConditionReset(MinFailureToReset) {
-		s.logger.Warn("TCP URLTest Outbound ", s.tag, " (", outboundToString(s.group.selectedOutboundTCP), ") failed to connect for ", MinFailureToReset, " times==> test proxies again!")
-		s.group.selectedOutboundTCP = nil
+		s.logger.Warn("TCP URLTest Outbound ", s.tag, " (", outboundToString(s.group.selectedOutboundTCP.Load()), ") failed to connect for ", MinFailureToReset, " times==> test proxies again!")
+		s.group.selectedOutboundTCP.Store(nil)
 		s.CheckOutbounds()
 	}
 
@@ -165,7 +186,7 @@ func (s *URLTest) DialContext(ctx context.Context, network string, destination M
 
 func (s *URLTest) ListenPacket(ctx context.Context, destination M.Socksaddr) (net.PacketConn, error) {
 	s.group.Touch()
-	outbound := s.group.selectedOutboundUDP
+	outbound := s.group.selectedOutboundUDP.Load()
 	if outbound == nil {
 		outbound, _ = s.group.Select(N.NetworkUDP)
 	}
@@ -179,7 +200,7 @@ func (s *URLTest) ListenPacket(ctx context.Context, destination M.Socksaddr) (ne
 		return s.group.interruptGroup.NewPacketConn(conn, interrupt.IsExternalConnectionFromContext(ctx)), nil
 	}
 	if !s.group.pauseManager.IsNetworkPaused() && s.group.udpConnectionFailureCount.IncrementConditionReset(MinFailureToReset) {
-		s.group.selectedOutboundUDP = nil
+		s.group.selectedOutboundUDP.Store(nil)
 		s.group.urlTest(ctx, true)
 	}
 	s.logger.ErrorContext(ctx, err)
@@ -206,7 +227,7 @@ type URLTestGroup struct {
 	ctx                          context.Context
 	router                       adapter.Router
 	logger                       log.Logger
-	outbounds                    []adapter.Outbound
+	outbounds                    atomic.TypedValue[[]adapter.Outbound]
 	link                         string
 	interval                     time.Duration
 	tolerance                    uint16
@@ -214,10 +235,11 @@ type URLTestGroup struct {
 	history                      *urltest.HistoryStorage
 	checking                     atomic.Bool
 	pauseManager                 pause.Manager
-	selectedOutboundTCP          adapter.Outbound
-	selectedOutboundUDP          adapter.Outbound
+	selectedOutboundTCP          atomic.TypedValue[adapter.Outbound]
+	selectedOutboundUDP          atomic.TypedValue[adapter.Outbound]
 	interruptGroup               *interrupt.Group
 	interruptExternalConnections bool
+	updateHook                   func()
 
 	access     sync.Mutex
 	ticker     *time.Ticker
@@ -259,11 +281,10 @@ func NewURLTestGroup(
 	} else {
 		history = urltest.NewHistoryStorage()
 	}
-	return &URLTestGroup{
+	group := &URLTestGroup{
 		ctx:                          ctx,
 		router:                       router,
 		logger:                       logger,
-		outbounds:                    outbounds,
 		link:                         link,
 		interval:                     interval,
 		tolerance:                    tolerance,
@@ -273,7 +294,9 @@ func NewURLTestGroup(
 		pauseManager:                 service.FromContext[pause.Manager](ctx),
 		interruptGroup:               interrupt.NewGroup(),
 		interruptExternalConnections: interruptExternalConnections,
-	}, nil
+	}
+	group.outbounds.Store(outbounds)
+	return group, nil
 }
 
 func (g *URLTestGroup) PostStart() {
@@ -308,26 +331,45 @@ func (g *URLTestGroup) Close() error {
 	return nil
 }
 
+func (g *URLTestGroup) UpdateDependency(detour adapter.Outbound) {
+	g.access.Lock()
+	g.outbounds.Store(common.Map(g.outbounds.Load(), func(it adapter.Outbound) adapter.Outbound {
+		if it.Tag() == detour.Tag() {
+			return detour
+		}
+		return it
+	}))
+	if selected := g.selectedOutboundTCP.Load(); selected != nil && selected.Tag() == detour.Tag() {
+		g.selectedOutboundTCP.Store(detour)
+	}
+	if selected := g.selectedOutboundUDP.Load(); selected != nil && selected.Tag() == detour.Tag() {
+		g.selectedOutboundUDP.Store(detour)
+	}
+	g.access.Unlock()
+	g.history.DeleteURLTestHistory(detour.Tag())
+	go g.CheckOutbounds(true)
+}
+
 func (g *URLTestGroup) Select(network string) (adapter.Outbound, bool) {
 	var minDelay uint16 = TimeoutDelay
 	var minOutbound adapter.Outbound
 	switch network {
 	case N.NetworkTCP:
-		if g.selectedOutboundTCP != nil {
-			if history := g.history.LoadURLTestHistory(RealTag(g.selectedOutboundTCP)); history != nil && history.Delay != TimeoutDelay {
-				minOutbound = g.selectedOutboundTCP
+		if selected := g.selectedOutboundTCP.Load(); selected != nil {
+			if history := g.history.LoadURLTestHistory(RealTag(selected)); history != nil && history.Delay != TimeoutDelay {
+				minOutbound = selected
 				minDelay = history.Delay
 			}
 		}
 	case N.NetworkUDP:
-		if g.selectedOutboundUDP != nil {
-			if history := g.history.LoadURLTestHistory(RealTag(g.selectedOutboundUDP)); history != nil && history.Delay != TimeoutDelay {
-				minOutbound = g.selectedOutboundUDP
+		if selected := g.selectedOutboundUDP.Load(); selected != nil {
+			if history := g.history.LoadURLTestHistory(RealTag(selected)); history != nil && history.Delay != TimeoutDelay {
+				minOutbound = selected
 				minDelay = history.Delay
 			}
 		}
 	}
-	for _, detour := range g.outbounds {
+	for _, detour := range g.outbounds.Load() {
 		if !common.Contains(detour.Network(), network) {
 			continue
 		}
@@ -341,7 +383,7 @@ func (g *URLTestGroup) Select(network string) (adapter.Outbound, bool) {
 		}
 	}
 	if minOutbound == nil {
-		for _, detour := range g.outbounds {
+		for _, detour := range g.outbounds.Load() {
 			if !common.Contains(detour.Network(), network) {
 				continue
 			}
@@ -392,7 +434,7 @@ func (g *URLTestGroup) urlTest(ctx context.Context, force bool) (map[string]uint
 	b, _ := batch.New(ctx, batch.WithConcurrencyNum[any](10))
 	checked := make(map[string]bool)
 	var resultAccess sync.Mutex
-	for _, detour := range g.outbounds {
+	for _, detour := range g.outbounds.Load() {
 		tag := detour.Tag()
 		realTag := RealTag(detour)
 		if checked[realTag] {
@@ -432,18 +474,21 @@ func (g *URLTestGroup) urlTest(ctx context.Context, force bool) (map[string]uint
 	}
 	b.Wait()
 	g.performUpdateCheck()
+	if g.updateHook != nil {
+		g.updateHook()
+	}
 	return result, nil
 }
 
 func (g *URLTestGroup) performUpdateCheck() {
 	var updated bool
-	if outbound, exists := g.Select(N.NetworkTCP); outbound != nil && (g.selectedOutboundTCP == nil || (exists && outbound != g.selectedOutboundTCP)) {
-		g.selectedOutboundTCP = outbound
+	if outbound, exists := g.Select(N.NetworkTCP); outbound != nil && (g.selectedOutboundTCP.Load() == nil || (exists && outbound != g.selectedOutboundTCP.Load())) {
+		g.selectedOutboundTCP.Store(outbound)
 		g.tcpConnectionFailureCount.Reset()
 		updated = true
 	}
-	if outbound, exists := g.Select(N.NetworkUDP); outbound != nil && (g.selectedOutboundUDP == nil || (exists && outbound != g.selectedOutboundUDP)) {
-		g.selectedOutboundUDP = outbound
+	if outbound, exists := g.Select(N.NetworkUDP); outbound != nil && (g.selectedOutboundUDP.Load() == nil || (exists && outbound != g.selectedOutboundUDP.Load())) {
+		g.selectedOutboundUDP.Store(outbound)
 		g.udpConnectionFailureCount.Reset()
 		updated = true
 	}
@@ -35,6 +35,7 @@ import (
 
 var (
 	_ adapter.Outbound                = (*WireGuard)(nil)
+	_ adapter.PeerOutbound            = (*WireGuard)(nil)
 	_ adapter.InterfaceUpdateListener = (*WireGuard)(nil)
 )
 
@@ -58,6 +59,16 @@ type WireGuard struct {
 	fakePacketsDelay []int
 	fakePacketsMode  string
 	lastUpdate       time.Time
+	handshakeTimeout time.Duration
+	peerStates       []wireGuardPeerState
+	done             chan struct{}
+}
+
+type wireGuardPeerState struct {
+	endpointIndex int
+	handshakeAt   time.Time
+	deadline      time.Time
+	upload        uint64
 }
 
 func NewWireGuard(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.WireGuardOutboundOptions) (*WireGuard, error) {
@@ -75,6 +86,7 @@ func NewWireGuard(ctx context.Context, router adapter.Router, logger log.Context
 		workers:      options.Workers,
 		pauseManager: service.FromContext[pause.Manager](ctx),
 		hforwarder:   hforwarder, // hiddify
+		done:         make(chan struct{}),
 	}
 	outbound.fakePackets = []int{0, 0}
 	outbound.fakePacketsSize = []int{0, 0}
@@ -111,6 +123,12 @@ func NewWireGuard(ctx context.Context, router adapter.Router, logger log.Context
 		return nil, err
 	}
 	outbound.peers = peers
+	outbound.peerStates = make([]wireGuardPeerState, len(peers))
+	if options.HandshakeTimeout > 0 {
+		outbound.handshakeTimeout = time.Duration(options.HandshakeTimeout)
+	} else {
+		outbound.handshakeTimeout = C.WireGuardHandshakeTimeout
+	}
 	if len(options.LocalAddress) == 0 {
 		return nil, E.New("missing local address")
 	}
@@ -126,6 +144,16 @@ func NewWireGuard(ctx context.Context, router adapter.Router, logger log.Context
 		return nil, err
 	}
 	outbound.listener = listener
+	if options.Amnezia != nil {
+		if options.GSO {
+			return nil, E.New("amnezia is conflict with gso")
+		}
+		obfuscator, err := wireguard.NewAmneziaObfuscator(*options.Amnezia)
+		if err != nil {
+			return nil, E.Cause(err, "create amnezia obfuscator")
+		}
+		outbound.listener = wireguard.NewAmneziaDialer(listener, obfuscator)
+	}
 	var privateKey string
 	{
 		bytes, err := base64.StdEncoding.DecodeString(options.PrivateKey)
@@ -186,7 +214,7 @@ func (w *WireGuard) start() error {
 			reserved    [3]uint8
 		)
 		peerLen := len(w.peers)
-		if peerLen == 1 {
+		if peerLen == 1 && w.peers[0].EndpointCount() <= 1 {
 			isConnect = true
 			connectAddr = w.peers[0].Endpoint
 			reserved = w.peers[0].Reserved
@@ -246,14 +274,123 @@ func (w *WireGuard) start() error {
 	}
 	w.device = wgDevice
 	w.pauseCallback = w.pauseManager.RegisterCallback(w.onPauseUpdated)
+	if common.Any(w.peers, func(peer wireguard.PeerConfig) bool {
+		return peer.EndpointCount() > 1
+	}) {
+		deadline := time.Now().Add(w.handshakeTimeout)
+		for i := range w.peerStates {
+			w.peerStates[i].deadline = deadline
+		}
+		go w.loopCheckHandshake()
+	}
 
 	return w.tunDevice.Start()
 }
 
+// loopCheckHandshake switches a peer to its next endpoint if it is sending
+// but no handshake completes within the handshake timeout.
+func (w *WireGuard) loopCheckHandshake() {
+	ticker := time.NewTicker(w.handshakeTimeout / 4)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+		}
+		if w.pauseManager.IsDevicePaused() || w.pauseManager.IsNetworkPaused() {
+			continue
+		}
+		peerStatus := w.PeerStatus()
+		now := time.Now()
+		for i, peer := range w.peers {
+			if peer.EndpointCount() < 2 {
+				continue
+			}
+			status, loaded := findPeerStatus(peerStatus, w.peerPublicKey(i))
+			if !loaded {
+				continue
+			}
+			state := &w.peerStates[i]
+			if status.LastHandshake.After(state.handshakeAt) {
+				// sessions are renewed by handshakes every RekeyAfterTime while sending
+				state.handshakeAt = status.LastHandshake
+				state.deadline = status.LastHandshake.Add(device.RekeyAfterTime + w.handshakeTimeout)
+				state.upload = status.Upload
+				continue
+			}
+			if now.Before(state.deadline) {
+				continue
+			}
+			state.deadline = now.Add(w.handshakeTimeout)
+			if status.Upload == state.upload {
+				// idle, handshakes are only initiated while sending
+				continue
+			}
+			state.upload = status.Upload
+			w.switchEndpoint(i)
+		}
+	}
+}
+
+func (w *WireGuard) switchEndpoint(index int) {
+	peer := w.peers[index]
+	state := &w.peerStates[index]
+	for i := 1; i < peer.EndpointCount(); i++ {
+		state.endpointIndex = (state.endpointIndex + 1) % peer.EndpointCount()
+		endpoint, err := peer.ResolveEndpoint(w.ctx, w.router, state.endpointIndex)
+		if err != nil {
+			w.logger.Error(E.Cause(err, "switch endpoint for peer ", index))
+			continue
+		}
+		err = w.device.IpcSet("public_key=" + peer.PublicKey + "\nupdate_only=true\nendpoint=" + endpoint.String())
+		if err != nil {
+			w.logger.Error(E.Cause(err, "switch endpoint for peer ", index))
+			return
+		}
+		w.logger.Info("handshake timeout for peer ", index, ", switched endpoint to ", endpoint)
+		return
+	}
+}
+
+func (w *WireGuard) peerPublicKey(index int) string {
+	publicKey, _ := hex.DecodeString(w.peers[index].PublicKey)
+	return base64.StdEncoding.EncodeToString(publicKey)
+}
+
+func findPeerStatus(peerStatus []adapter.PeerStatus, publicKey string) (adapter.PeerStatus, bool) {
+	for _, status := range peerStatus {
+		if status.PublicKey == publicKey {
+			return status, true
+		}
+	}
+	return adapter.PeerStatus{}, false
+}
+
+func (w *WireGuard) PeerStatus() []adapter.PeerStatus {
+	if w.device == nil {
+		return nil
+	}
+	ipcContent, err := w.device.IpcGet()
+	if err != nil {
+		return nil
+	}
+	peerStatus, err := wireguard.ParsePeerStatus(ipcContent)
+	if err != nil {
+		return nil
+	}
+	return peerStatus
+}
+
 func (w *WireGuard) Close() error {
 	if w.hforwarder != nil { // hiddify
 		w.hforwarder.Close() // hiddify
 	} // hiddify
+	select {
+	case <-w.done:
+	default:
+		close(w.done)
+	}
 	if w.device != nil {
 		w.device.Close()
 	}
@@ -287,12 +424,12 @@ func (w *WireGuard) onPauseUpdated(event int) {
 	case pause.EventDevicePaused:
 		w.device.Down()
 	case pause.EventNetworkPause: // hiddify already handled in Interface Updated
-		err := w.device.Down()
+		w.device.Down()
 		<-time.After(50 * time.Millisecond)
 	case pause.EventDeviceWake:
 		w.device.Up()
 	case pause.EventNetworkWake: // hiddify already handled in Interface Updated
-		err := w.device.Up()
+		w.device.Up()
 		<-time.After(50 * time.Millisecond)
 	}
 }
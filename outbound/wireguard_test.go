@@ -0,0 +1,93 @@
+//go:build with_wireguard && with_gvisor
+
+package outbound
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+	"github.com/sagernet/sing/service/pause"
+
+	"github.com/stretchr/testify/require"
+)
+
+// listenWireGuardEndpoint reports packets sent to an endpoint which never answers handshakes.
+func listenWireGuardEndpoint(t *testing.T) (M.Socksaddr, chan struct{}) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		conn.Close()
+	})
+	received := make(chan struct{}, 1)
+	go func() {
+		buffer := make([]byte, 2048)
+		for {
+			_, _, err := conn.ReadFrom(buffer)
+			if err != nil {
+				return
+			}
+			select {
+			case received <- struct{}{}:
+			default:
+			}
+		}
+	}()
+	return M.SocksaddrFromNet(conn.LocalAddr()), received
+}
+
+func newWireGuardKey(t *testing.T) string {
+	var key [32]byte
+	_, err := rand.Read(key[:])
+	require.NoError(t, err)
+	return base64.StdEncoding.EncodeToString(key[:])
+}
+
+func TestWireGuardEndpointFailover(t *testing.T) {
+	t.Parallel()
+	server, serverReceived := listenWireGuardEndpoint(t)
+	fallback, _ := listenWireGuardEndpoint(t)
+	outbound, err := NewWireGuard(pause.WithDefaultManager(context.Background()), nil, log.NewNOPFactory().Logger(), "wireguard", option.WireGuardOutboundOptions{
+		ServerOptions:    option.ServerOptions{Server: server.AddrString(), ServerPort: server.Port},
+		Endpoints:        []string{fallback.String()},
+		HandshakeTimeout: option.Duration(time.Second),
+		LocalAddress:     []netip.Prefix{netip.MustParsePrefix("10.0.0.2/32")},
+		PrivateKey:       newWireGuardKey(t),
+		PeerPublicKey:    newWireGuardKey(t),
+	})
+	require.NoError(t, err)
+	require.NoError(t, outbound.Start())
+	t.Cleanup(func() {
+		outbound.Close()
+	})
+	// handshakes are only initiated while sending
+	sendCtx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go func() {
+		for sendCtx.Err() == nil {
+			dialCtx, dialCancel := context.WithTimeout(sendCtx, 500*time.Millisecond)
+			conn, err := outbound.DialContext(dialCtx, N.NetworkTCP, M.ParseSocksaddr("10.0.0.1:80"))
+			if err == nil {
+				conn.Close()
+			}
+			dialCancel()
+		}
+	}()
+	select {
+	case <-serverReceived:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no handshake sent to server")
+	}
+	require.Eventually(t, func() bool {
+		peerStatus := outbound.PeerStatus()
+		return len(peerStatus) == 1 && peerStatus[0].Endpoint == fallback.String()
+	}, 5*time.Second, 100*time.Millisecond, "endpoint not switched after handshake timeout")
+}
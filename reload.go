@@ -0,0 +1,218 @@
+package box
+
+import (
+	"strings"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/inbound"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing-box/outbound"
+	"github.com/sagernet/sing-box/route"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	F "github.com/sagernet/sing/common/format"
+)
+
+// Reload applies options by replacing only changed inbounds, outbounds, rules and DNS servers,
+// connections of unchanged outbounds are kept.
+// The plan is returned with an error if the options can only be applied by a restart.
+func (s *Box) Reload(options option.Options) (*ReloadPlan, error) {
+	s.reloadAccess.Lock()
+	defer s.reloadAccess.Unlock()
+	select {
+	case <-s.done:
+		return nil, E.New("reload closed service")
+	default:
+	}
+	plan, err := NewReloadPlan(s.options, options)
+	if err != nil {
+		return nil, err
+	}
+	if plan.NeedRestart() {
+		return plan, E.New("restart required: ", strings.Join(plan.Restart, ", "))
+	}
+	if plan.IsEmpty() {
+		s.options = options
+		return plan, nil
+	}
+
+	oldInbounds := make(map[string]adapter.Inbound)
+	for i, in := range s.inbounds {
+		oldInbounds[optionTag(s.options.Inbounds[i].Tag, i)] = in
+	}
+	oldOutbounds := make(map[string]adapter.Outbound)
+	for i, out := range s.outbounds[:len(s.options.Outbounds)] {
+		oldOutbounds[optionTag(s.options.Outbounds[i].Tag, i)] = out
+	}
+	implicitOutbounds := s.outbounds[len(s.options.Outbounds):]
+
+	var (
+		inbounds         []adapter.Inbound
+		outbounds        []adapter.Outbound
+		createdInbounds  []adapter.Inbound
+		createdOutbounds []adapter.Outbound
+	)
+	closeCreated := func() {
+		for _, in := range createdInbounds {
+			in.Close()
+		}
+		for _, out := range createdOutbounds {
+			common.Close(out)
+		}
+	}
+	for i, inboundOptions := range options.Inbounds {
+		tag := optionTag(inboundOptions.Tag, i)
+		if !plan.Inbounds.replaced(tag) {
+			inbounds = append(inbounds, oldInbounds[tag])
+			continue
+		}
+		in, err := inbound.New(
+			s.ctx,
+			s.router,
+			s.logFactory.NewLogger(F.ToString("inbound/", inboundOptions.Type, "[", tag, "]")),
+			inboundOptions,
+			s.platformInterface,
+		)
+		if err != nil {
+			closeCreated()
+			return plan, E.Cause(err, "parse inbound[", i, "]")
+		}
+		inbounds = append(inbounds, in)
+		createdInbounds = append(createdInbounds, in)
+	}
+	started := make(map[string]bool)
+	for i, outboundOptions := range options.Outbounds {
+		tag := optionTag(outboundOptions.Tag, i)
+		if !plan.Outbounds.replaced(tag) {
+			outbounds = append(outbounds, oldOutbounds[tag])
+			started[tag] = true
+			continue
+		}
+		out, err := outbound.New(
+			s.ctx,
+			s.router,
+			s.logFactory.NewLogger(F.ToString("outbound/", outboundOptions.Type, "[", tag, "]")),
+			tag,
+			outboundOptions)
+		if err != nil {
+			closeCreated()
+			return plan, E.Cause(err, "parse outbound[", i, "]")
+		}
+		outbounds = append(outbounds, out)
+		createdOutbounds = append(createdOutbounds, out)
+	}
+	defaultOutbound := func() adapter.Outbound {
+		var out adapter.Outbound
+		if len(implicitOutbounds) > 0 {
+			out = implicitOutbounds[0]
+			started[out.Tag()] = true
+		} else {
+			var oErr error
+			out, oErr = outbound.New(s.ctx, s.router, s.logFactory.NewLogger("outbound/direct"), "direct", option.Outbound{Type: "direct", Tag: "default"})
+			common.Must(oErr)
+			createdOutbounds = append(createdOutbounds, out)
+		}
+		outbounds = append(outbounds, out)
+		return out
+	}
+	reloadOptions := route.ReloadOptions{
+		LogFactory:      s.logFactory,
+		Inbounds:        inbounds,
+		InboundOptions:  options.Inbounds,
+		Outbounds:       outbounds,
+		DefaultOutbound: defaultOutbound,
+	}
+	if plan.Rules {
+		reloadOptions.Route = common.Ptr(common.PtrValueOrDefault(options.Route))
+	}
+	if plan.DNS {
+		reloadOptions.DNS = common.Ptr(common.PtrValueOrDefault(options.DNS))
+	}
+	err = s.router.Reload(reloadOptions)
+	if err != nil {
+		closeCreated()
+		return plan, E.Cause(err, "reload router")
+	}
+	s.optionsStore.Reset(options.Outbounds)
+	err = s.startOutbounds(outbounds, started)
+	if err == nil {
+		for _, out := range createdOutbounds {
+			if lateOutbound, isLateOutbound := out.(adapter.PostStarter); isLateOutbound {
+				err = lateOutbound.PostStart()
+				if err != nil {
+					err = E.Cause(err, "post-start outbound/", out.Tag())
+					break
+				}
+			}
+		}
+	}
+	if err != nil {
+		rollbackOptions := route.ReloadOptions{
+			LogFactory:     s.logFactory,
+			Inbounds:       s.inbounds,
+			InboundOptions: s.options.Inbounds,
+			Outbounds:      s.outbounds,
+			DefaultOutbound: func() adapter.Outbound {
+				return implicitOutbounds[0]
+			},
+		}
+		if plan.Rules {
+			rollbackOptions.Route = common.Ptr(common.PtrValueOrDefault(s.options.Route))
+		}
+		if plan.DNS {
+			rollbackOptions.DNS = common.Ptr(common.PtrValueOrDefault(s.options.DNS))
+		}
+		s.optionsStore.Reset(s.options.Outbounds)
+		rollbackErr := s.router.Reload(rollbackOptions)
+		if rollbackErr != nil {
+			s.logger.Error(E.Cause(rollbackErr, "rollback router"))
+		}
+		closeCreated()
+		return plan, err
+	}
+
+	for tag, out := range oldOutbounds {
+		if common.Contains(outbounds, out) {
+			continue
+		}
+		err = E.Append(err, common.Close(out), func(err error) error {
+			return E.Cause(err, "close outbound/", out.Type(), "[", tag, "]")
+		})
+	}
+	for _, out := range implicitOutbounds {
+		if common.Contains(outbounds, out) {
+			continue
+		}
+		err = E.Append(err, common.Close(out), func(err error) error {
+			return E.Cause(err, "close outbound/", out.Type(), "[", out.Tag(), "]")
+		})
+	}
+	for tag, in := range oldInbounds {
+		if common.Contains(inbounds, in) {
+			continue
+		}
+		err = E.Append(err, in.Close(), func(err error) error {
+			return E.Cause(err, "close inbound/", in.Type(), "[", tag, "]")
+		})
+	}
+	s.inbounds = inbounds
+	s.outbounds = outbounds
+	s.options = options
+	for _, in := range createdInbounds {
+		startErr := in.Start()
+		if startErr != nil {
+			err = E.Append(err, startErr, func(err error) error {
+				return E.Cause(err, "initialize inbound/", in.Type(), "[", in.Tag(), "]")
+			})
+		}
+	}
+	s.logger.Info("sing-box reloaded: ", plan)
+	return plan, err
+}
+
+func optionTag(tag string, index int) string {
+	if tag == "" {
+		return F.ToString(index)
+	}
+	return tag
+}
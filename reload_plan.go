@@ -0,0 +1,270 @@
+package box
+
+import (
+	"bytes"
+	"strings"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing-box/route"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	F "github.com/sagernet/sing/common/format"
+	"github.com/sagernet/sing/common/json"
+)
+
+// ReloadPlan describes the components replaced by reloading to new options.
+type ReloadPlan struct {
+	// Restart lists the reasons why the new options can only be applied by a restart.
+	Restart   []string      `json:"restart,omitempty"`
+	Inbounds  ReloadChanges `json:"inbounds"`
+	Outbounds ReloadChanges `json:"outbounds"`
+	Rules     bool          `json:"rules,omitempty"`
+	DNS       bool          `json:"dns,omitempty"`
+}
+
+type ReloadChanges struct {
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+	Changed []string `json:"changed,omitempty"`
+}
+
+func (c ReloadChanges) IsEmpty() bool {
+	return len(c.Added) == 0 && len(c.Removed) == 0 && len(c.Changed) == 0
+}
+
+func (c ReloadChanges) replaced(tag string) bool {
+	return common.Contains(c.Added, tag) || common.Contains(c.Changed, tag)
+}
+
+func (p *ReloadPlan) NeedRestart() bool {
+	return len(p.Restart) > 0
+}
+
+func (p *ReloadPlan) IsEmpty() bool {
+	return !p.NeedRestart() && p.Inbounds.IsEmpty() && p.Outbounds.IsEmpty() && !p.Rules && !p.DNS
+}
+
+func (p *ReloadPlan) String() string {
+	if p.NeedRestart() {
+		return "restart: " + strings.Join(p.Restart, ", ")
+	}
+	if p.IsEmpty() {
+		return "no changes"
+	}
+	var parts []string
+	appendChanges := func(name string, changes ReloadChanges) {
+		if len(changes.Added) > 0 {
+			parts = append(parts, F.ToString("add ", name, " [", strings.Join(changes.Added, " "), "]"))
+		}
+		if len(changes.Removed) > 0 {
+			parts = append(parts, F.ToString("remove ", name, " [", strings.Join(changes.Removed, " "), "]"))
+		}
+		if len(changes.Changed) > 0 {
+			parts = append(parts, F.ToString("restart ", name, " [", strings.Join(changes.Changed, " "), "]"))
+		}
+	}
+	appendChanges("inbounds", p.Inbounds)
+	appendChanges("outbounds", p.Outbounds)
+	if p.Rules {
+		parts = append(parts, "replace rules")
+	}
+	if p.DNS {
+		parts = append(parts, "replace dns servers and rules")
+	}
+	return strings.Join(parts, ", ")
+}
+
+// NewReloadPlan compares the options, inbounds and outbounds are compared by tag,
+// outbounds depending on replaced outbounds are replaced too.
+// Values are compared by their JSON encoding, so pointers are passed to use the option marshalers.
+func NewReloadPlan(oldOptions option.Options, newOptions option.Options) (*ReloadPlan, error) {
+	var plan ReloadPlan
+	restartIfChanged := func(name string, oldValue any, newValue any) error {
+		equals, err := jsonEquals(oldValue, newValue)
+		if err != nil {
+			return E.Cause(err, "compare ", name)
+		}
+		if !equals {
+			plan.Restart = append(plan.Restart, name+" changed")
+		}
+		return nil
+	}
+	oldRoute, newRoute := common.PtrValueOrDefault(oldOptions.Route), common.PtrValueOrDefault(newOptions.Route)
+	oldDNS, newDNS := common.PtrValueOrDefault(oldOptions.DNS), common.PtrValueOrDefault(newOptions.DNS)
+	oldRouteRest, newRouteRest := oldRoute, newRoute
+	oldRouteRest.Rules, oldRouteRest.Final, newRouteRest.Rules, newRouteRest.Final = nil, "", nil, ""
+	oldDNSRest, newDNSRest := oldDNS, newDNS
+	oldDNSRest.Servers, oldDNSRest.Rules, oldDNSRest.Final = nil, nil, ""
+	newDNSRest.Servers, newDNSRest.Rules, newDNSRest.Final = nil, nil, ""
+	for _, section := range []struct {
+		name     string
+		oldValue any
+		newValue any
+	}{
+		{"log", oldOptions.Log, newOptions.Log},
+		{"ntp", oldOptions.NTP, newOptions.NTP},
+		{"experimental", oldOptions.Experimental, newOptions.Experimental},
+		{"route", &oldRouteRest, &newRouteRest},
+		{"dns", &oldDNSRest, &newDNSRest},
+	} {
+		err := restartIfChanged(section.name, section.oldValue, section.newValue)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	oldInbounds := taggedOptions(oldOptions.Inbounds, func(it option.Inbound) string { return it.Tag })
+	newInbounds := taggedOptions(newOptions.Inbounds, func(it option.Inbound) string { return it.Tag })
+	var err error
+	plan.Inbounds, err = diffTagged(oldInbounds, newInbounds)
+	if err != nil {
+		return nil, E.Cause(err, "compare inbounds")
+	}
+	systemInbounds := make(map[string]bool)
+	for _, inbound := range append(oldInbounds, newInbounds...) {
+		if systemInbounds[inbound.tag] || !plan.Inbounds.replaced(inbound.tag) && !common.Contains(plan.Inbounds.Removed, inbound.tag) {
+			continue
+		}
+		if inbound.options.Type == C.TypeTun || inbound.options.HTTPOptions.SetSystemProxy || inbound.options.MixedOptions.SetSystemProxy {
+			systemInbounds[inbound.tag] = true
+			plan.Restart = append(plan.Restart, "inbound "+inbound.tag+" changes system settings")
+		}
+	}
+
+	oldOutbounds := taggedOptions(oldOptions.Outbounds, func(it option.Outbound) string { return it.Tag })
+	newOutbounds := taggedOptions(newOptions.Outbounds, func(it option.Outbound) string { return it.Tag })
+	plan.Outbounds, err = diffTagged(oldOutbounds, newOutbounds)
+	if err != nil {
+		return nil, E.Cause(err, "compare outbounds")
+	}
+	outboundReplaced := func(tag string) bool {
+		return plan.Outbounds.replaced(tag) || common.Contains(plan.Outbounds.Removed, tag)
+	}
+	dependencies := make(map[string][]string)
+	for _, outbound := range newOutbounds {
+		dependencies[outbound.tag], err = outboundDependencies(outbound.options)
+		if err != nil {
+			return nil, E.Cause(err, "parse outbound ", outbound.tag)
+		}
+	}
+	for {
+		var expanded bool
+		for _, outbound := range newOutbounds {
+			if outboundReplaced(outbound.tag) || !common.Any(dependencies[outbound.tag], outboundReplaced) {
+				continue
+			}
+			plan.Outbounds.Changed = append(plan.Outbounds.Changed, outbound.tag)
+			expanded = true
+		}
+		if !expanded {
+			break
+		}
+	}
+
+	plan.Rules = len(oldRoute.Rules) != len(newRoute.Rules) || oldRoute.Final != newRoute.Final
+	if !plan.Rules {
+		plan.Rules, err = notJSONEquals(oldRoute.Rules, newRoute.Rules)
+		if err != nil {
+			return nil, E.Cause(err, "compare rules")
+		}
+	}
+	plan.DNS = oldDNS.Final != newDNS.Final || common.Any(newDNS.Servers, func(it option.DNSServerOptions) bool {
+		return it.Detour != "" && outboundReplaced(it.Detour)
+	})
+	if !plan.DNS {
+		plan.DNS, err = notJSONEquals([]any{oldDNS.Servers, oldDNS.Rules}, []any{newDNS.Servers, newDNS.Rules})
+		if err != nil {
+			return nil, E.Cause(err, "compare dns")
+		}
+	}
+	if plan.Rules || plan.DNS {
+		err = route.CheckReload(oldRoute, newRoute, oldDNS, newDNS)
+		if err != nil {
+			plan.Restart = append(plan.Restart, err.Error())
+		}
+	}
+	return &plan, nil
+}
+
+type taggedOption[T any] struct {
+	tag     string
+	options T
+}
+
+func taggedOptions[T any](options []T, getTag func(T) string) []taggedOption[T] {
+	return common.MapIndexed(options, func(index int, it T) taggedOption[T] {
+		tag := getTag(it)
+		if tag == "" {
+			tag = F.ToString(index)
+		}
+		return taggedOption[T]{tag, it}
+	})
+}
+
+func diffTagged[T any](oldOptions []taggedOption[T], newOptions []taggedOption[T]) (ReloadChanges, error) {
+	var changes ReloadChanges
+	oldByTag := make(map[string]T)
+	for _, it := range oldOptions {
+		oldByTag[it.tag] = it.options
+	}
+	newTags := make(map[string]bool)
+	for _, it := range newOptions {
+		newTags[it.tag] = true
+		oldValue, loaded := oldByTag[it.tag]
+		if !loaded {
+			changes.Added = append(changes.Added, it.tag)
+			continue
+		}
+		changed, err := notJSONEquals(&oldValue, &it.options)
+		if err != nil {
+			return ReloadChanges{}, err
+		}
+		if changed {
+			changes.Changed = append(changes.Changed, it.tag)
+		}
+	}
+	for _, it := range oldOptions {
+		if !newTags[it.tag] {
+			changes.Removed = append(changes.Removed, it.tag)
+		}
+	}
+	return changes, nil
+}
+
+// outboundDependencies returns tags in the detour and outbounds fields of the outbound.
+func outboundDependencies(options option.Outbound) ([]string, error) {
+	content, err := json.Marshal(&options)
+	if err != nil {
+		return nil, err
+	}
+	var fields struct {
+		Detour    string   `json:"detour"`
+		Outbounds []string `json:"outbounds"`
+	}
+	err = json.Unmarshal(content, &fields)
+	if err != nil {
+		return nil, err
+	}
+	if fields.Detour != "" {
+		return append(fields.Outbounds, fields.Detour), nil
+	}
+	return fields.Outbounds, nil
+}
+
+func jsonEquals(oldValue any, newValue any) (bool, error) {
+	oldContent, err := json.Marshal(oldValue)
+	if err != nil {
+		return false, err
+	}
+	newContent, err := json.Marshal(newValue)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(oldContent, newContent), nil
+}
+
+func notJSONEquals(oldValue any, newValue any) (bool, error) {
+	equals, err := jsonEquals(oldValue, newValue)
+	return !equals, err
+}
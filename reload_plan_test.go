@@ -0,0 +1,68 @@
+package box
+
+import (
+	"testing"
+
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common/json"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReloadPlan(t *testing.T) {
+	t.Parallel()
+	oldOptions := parseReloadOptions(t, `{
+  "inbounds": [{"type": "mixed", "tag": "mixed-in", "listen_port": 1080}],
+  "outbounds": [
+    {"type": "socks", "tag": "a", "server": "127.0.0.1", "server_port": 1081},
+    {"type": "socks", "tag": "b", "server": "127.0.0.1", "server_port": 1082},
+    {"type": "selector", "tag": "select", "outbounds": ["a", "b"]},
+    {"type": "direct", "tag": "c", "detour": "b"}
+  ],
+  "route": {"rules": [{"domain": "example.com", "outbound": "a"}]}
+}`)
+	newOptions := parseReloadOptions(t, `{
+  "inbounds": [{"type": "mixed", "tag": "mixed-in", "listen_port": 1080}],
+  "outbounds": [
+    {"type": "socks", "tag": "a", "server": "127.0.0.1", "server_port": 1083},
+    {"type": "socks", "tag": "b", "server": "127.0.0.1", "server_port": 1082},
+    {"type": "selector", "tag": "select", "outbounds": ["a", "b"]},
+    {"type": "direct", "tag": "c", "detour": "b"},
+    {"type": "direct", "tag": "d"}
+  ],
+  "route": {"rules": [{"domain": "example.org", "outbound": "a"}]}
+}`)
+	plan, err := NewReloadPlan(oldOptions, newOptions)
+	require.NoError(t, err)
+	require.False(t, plan.NeedRestart())
+	require.True(t, plan.Inbounds.IsEmpty())
+	require.Equal(t, []string{"d"}, plan.Outbounds.Added)
+	require.Equal(t, []string{"a", "select"}, plan.Outbounds.Changed)
+	require.True(t, plan.Rules)
+	require.False(t, plan.DNS)
+
+	plan, err = NewReloadPlan(oldOptions, oldOptions)
+	require.NoError(t, err)
+	require.True(t, plan.IsEmpty())
+}
+
+func TestReloadPlanRestart(t *testing.T) {
+	t.Parallel()
+	oldOptions := parseReloadOptions(t, `{
+  "inbounds": [{"type": "tun", "tag": "tun-in", "inet4_address": "172.19.0.1/30"}]
+}`)
+	newOptions := parseReloadOptions(t, `{
+  "log": {"level": "debug"},
+  "inbounds": [{"type": "tun", "tag": "tun-in", "inet4_address": "172.19.0.1/28"}]
+}`)
+	plan, err := NewReloadPlan(oldOptions, newOptions)
+	require.NoError(t, err)
+	require.True(t, plan.NeedRestart())
+	require.Len(t, plan.Restart, 2)
+}
+
+func parseReloadOptions(t *testing.T, content string) option.Options {
+	options, err := json.UnmarshalExtended[option.Options]([]byte(content))
+	require.NoError(t, err)
+	return options
+}
@@ -0,0 +1,95 @@
+package box
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+
+	F "github.com/sagernet/sing/common/format"
+	"github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+	"github.com/sagernet/sing/protocol/socks"
+
+	"github.com/stretchr/testify/require"
+)
+
+func freePort(t *testing.T) uint16 {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	return uint16(listener.Addr().(*net.TCPAddr).Port)
+}
+
+func startEchoServer(t *testing.T) metadata.Socksaddr {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				io.Copy(conn, conn)
+			}()
+		}
+	}()
+	return metadata.SocksaddrFromNet(listener.Addr())
+}
+
+func requireEcho(t *testing.T, conn net.Conn) {
+	_, err := conn.Write([]byte("hello"))
+	require.NoError(t, err)
+	response := make([]byte, 5)
+	_, err = io.ReadFull(conn, response)
+	require.NoError(t, err)
+	require.Equal(t, "hello", string(response))
+}
+
+func TestReloadKeepsConnections(t *testing.T) {
+	t.Parallel()
+	echoServer := startEchoServer(t)
+	portA, portB, portC := freePort(t), freePort(t), freePort(t)
+	oldContent := `{
+  "log": {"disabled": true},
+  "inbounds": [
+    {"type": "socks", "tag": "a", "listen": "127.0.0.1", "listen_port": ` + F.ToString(portA) + `, "rate_limit": {"down_mbps": 100}},
+    {"type": "socks", "tag": "b", "listen": "127.0.0.1", "listen_port": ` + F.ToString(portB) + `}
+  ],
+  "outbounds": [{"type": "direct", "tag": "direct"}, {"type": "block", "tag": "block"}],
+  "route": {"rules": [{"domain": "example.com", "outbound": "block"}]}
+}`
+	newContent := `{
+  "log": {"disabled": true},
+  "inbounds": [
+    {"type": "socks", "tag": "a", "listen": "127.0.0.1", "listen_port": ` + F.ToString(portA) + `, "rate_limit": {"down_mbps": 100}},
+    {"type": "socks", "tag": "b", "listen": "127.0.0.1", "listen_port": ` + F.ToString(portC) + `}
+  ],
+  "outbounds": [{"type": "direct", "tag": "direct"}, {"type": "block", "tag": "block"}],
+  "route": {"rules": [{"domain": "example.org", "outbound": "block"}]}
+}`
+	instance := startTestBox(t, oldContent)
+	conn, err := socks.NewClient(N.SystemDialer, metadata.ParseSocksaddrHostPort("127.0.0.1", portA), socks.Version5, "", "").
+		DialContext(context.Background(), N.NetworkTCP, echoServer)
+	require.NoError(t, err)
+	defer conn.Close()
+	requireEcho(t, conn)
+
+	plan, err := instance.Reload(parseReloadOptions(t, newContent))
+	require.NoError(t, err)
+	require.Equal(t, []string{"b"}, plan.Inbounds.Changed)
+	require.True(t, plan.Rules)
+
+	// the connection on the unchanged inbound survives reloading
+	requireEcho(t, conn)
+	for _, port := range []uint16{portA, portC} {
+		newConn, err := socks.NewClient(N.SystemDialer, metadata.ParseSocksaddrHostPort("127.0.0.1", port), socks.Version5, "", "").
+			DialContext(context.Background(), N.NetworkTCP, echoServer)
+		require.NoError(t, err)
+		requireEcho(t, newConn)
+		newConn.Close()
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"os/user"
 	"runtime"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/sagernet/sing-box/adapter"
@@ -18,6 +19,7 @@ import (
 	"github.com/sagernet/sing-box/common/geoip"
 	"github.com/sagernet/sing-box/common/geosite"
 	"github.com/sagernet/sing-box/common/process"
+	"github.com/sagernet/sing-box/common/ratelimit"
 	"github.com/sagernet/sing-box/common/sniff"
 	"github.com/sagernet/sing-box/common/taskmonitor"
 	C "github.com/sagernet/sing-box/constant"
@@ -53,9 +55,15 @@ var _ adapter.Router = (*Router)(nil)
 
 type Router struct {
 	ctx                                  context.Context
+	access                               sync.RWMutex
+	outboundAccess                       sync.Locker
+	outboundUpdater                      OutboundUpdateFunc
+	logFactory                           log.Factory
 	logger                               log.ContextLogger
 	dnsLogger                            log.ContextLogger
+	inbounds                             []adapter.Inbound
 	inboundByTag                         map[string]adapter.Inbound
+	inboundRateLimiters                  map[string]ratelimit.Limiters
 	outbounds                            []adapter.Outbound
 	sortedOutboundsByDependenciesHiddify []adapter.Outbound // hiddify
 	outboundByTag                        map[string]adapter.Outbound
@@ -81,6 +89,7 @@ type Router struct {
 	transports                           []dns.Transport
 	transportMap                         map[string]dns.Transport
 	transportDomainStrategy              map[dns.Transport]dns.DomainStrategy
+	transportDetours                     map[string]string
 	dnsReverseMapping                    *DNSReverseMapping
 	fakeIPStore                          adapter.FakeIPStore
 	interfaceFinder                      *control.DefaultInterfaceFinder
@@ -93,9 +102,11 @@ type Router struct {
 	powerListener                        winpowrprof.EventListener
 	processSearcher                      process.Searcher
 	timeService                          *ntp.Service
+	timeServiceDetour                    string
 	pauseManager                         pause.Manager
 	clashServer                          adapter.ClashServer
 	v2rayServer                          adapter.V2RayServer
+	metricsServer                        adapter.MetricsServer
 	platformInterface                    platform.Interface
 	needWIFIState                        bool
 	needPackageManager                   bool
@@ -114,6 +125,8 @@ func NewRouter(
 ) (*Router, error) {
 	router := &Router{
 		ctx:                   ctx,
+		outboundAccess:        new(sync.Mutex),
+		logFactory:            logFactory,
 		logger:                logFactory.NewLogger("router"),
 		dnsLogger:             logFactory.NewLogger("dns"),
 		outboundByTag:         make(map[string]adapter.Outbound),
@@ -140,6 +153,7 @@ func NewRouter(
 		}),
 		staticDns: createEntries(dnsOptions.StaticIPs), // hiddify
 	}
+	router.inboundRateLimiters = newInboundRateLimiters(inbounds)
 	router.dnsClient = dns.NewClient(dns.ClientOptions{
 		DisableCache:     dnsOptions.DNSClientOptions.DisableCache,
 		DisableExpire:    dnsOptions.DNSClientOptions.DisableExpire,
@@ -182,12 +196,126 @@ func NewRouter(
 		router.ruleSetMap[ruleSetOptions.Tag] = ruleSet
 	}
 
+	ctx = adapter.ContextWithRouter(ctx, router)
+	transports, checkDNSLoopDomain, err := router.newDNSTransports(ctx, logFactory, dnsOptions)
+	if err != nil {
+		return nil, err
+	}
+	router.transports = transports.transports
+	router.transportMap = transports.transportMap
+	router.transportDomainStrategy = transports.domainStrategy
+	router.transportDetours = transports.detours
+	router.defaultTransport = transports.defaultTransport
+
+	if dnsOptions.ReverseMapping {
+		router.dnsReverseMapping = NewDNSReverseMapping()
+	}
+
+	if fakeIPOptions := dnsOptions.FakeIP; fakeIPOptions != nil && dnsOptions.FakeIP.Enabled {
+		var inet4Range netip.Prefix
+		var inet6Range netip.Prefix
+		if fakeIPOptions.Inet4Range != nil {
+			inet4Range = *fakeIPOptions.Inet4Range
+		}
+		if fakeIPOptions.Inet6Range != nil {
+			inet6Range = *fakeIPOptions.Inet6Range
+		}
+		fakeIPStore, err := fakeip.NewStore(ctx, router.logger, inet4Range, inet6Range, fakeIPOptions.Pools)
+		if err != nil {
+			return nil, err
+		}
+		router.fakeIPStore = fakeIPStore
+	}
+
+	usePlatformDefaultInterfaceMonitor := platformInterface != nil && platformInterface.UsePlatformDefaultInterfaceMonitor()
+	needInterfaceMonitor := options.AutoDetectInterface || common.Any(inbounds, func(inbound option.Inbound) bool {
+		return inbound.HTTPOptions.SetSystemProxy || inbound.MixedOptions.SetSystemProxy || inbound.TunOptions.AutoRoute
+	})
+
+	if !usePlatformDefaultInterfaceMonitor {
+		networkMonitor, err := tun.NewNetworkUpdateMonitor(router.logger)
+		if !((err != nil && !needInterfaceMonitor) || errors.Is(err, os.ErrInvalid)) {
+			if err != nil {
+				return nil, err
+			}
+			router.networkMonitor = networkMonitor
+			networkMonitor.RegisterCallback(func() {
+				_ = router.interfaceFinder.Update()
+			})
+			interfaceMonitor, err := tun.NewDefaultInterfaceMonitor(router.networkMonitor, router.logger, tun.DefaultInterfaceMonitorOptions{
+				OverrideAndroidVPN:    options.OverrideAndroidVPN,
+				UnderNetworkExtension: platformInterface != nil && platformInterface.UnderNetworkExtension(),
+			})
+			if err != nil {
+				return nil, E.New("auto_detect_interface unsupported on current platform")
+			}
+			interfaceMonitor.RegisterCallback(router.notifyNetworkUpdate)
+			router.interfaceMonitor = interfaceMonitor
+			// go func() {
+			// 	<-time.After(10 * time.Second)
+			// 	router.notifyNetworkUpdate(3)
+			// 	<-time.After(10 * time.Second)
+			// 	router.notifyNetworkUpdate(3)
+			// }()
+		}
+	} else {
+		interfaceMonitor := platformInterface.CreateDefaultInterfaceMonitor(router.logger)
+		interfaceMonitor.RegisterCallback(router.notifyNetworkUpdate)
+
+		router.interfaceMonitor = interfaceMonitor
+	}
+
+	if ntpOptions.Enabled {
+		ntpDialer, err := dialer.New(router, ntpOptions.DialerOptions)
+		if err != nil {
+			return nil, E.Cause(err, "create NTP service")
+		}
+		timeService := ntp.NewService(ntp.Options{
+			Context:       ctx,
+			Dialer:        ntpDialer,
+			Logger:        logFactory.NewLogger("ntp"),
+			Server:        ntpOptions.ServerOptions.Build(),
+			Interval:      time.Duration(ntpOptions.Interval),
+			WriteToSystem: ntpOptions.WriteToSystem,
+		})
+		service.MustRegister[ntp.TimeService](ctx, timeService)
+		router.timeService = timeService
+		router.timeServiceDetour = ntpOptions.Detour
+	}
+
+	for domain, tag := range checkDNSLoopDomain {
+		addrs, err := router.lookupStaticIP(domain, dns.DomainStrategyAsIS)
+		if err == nil && addrs != nil && len(addrs) != 0 {
+			continue
+		}
+		ctx, metadata := adapter.AppendContext(ctx)
+		metadata.Domain = domain
+		ctx, dnstransport, _, _, _ := router.matchDNS(ctx, false, 0, true)
+
+		if dnstransport != nil && dnstransport.Name() == tag {
+			return nil, E.New("Dns Loop Detected[", tag, "]")
+		}
+	}
+	return router, nil
+}
+
+type dnsTransports struct {
+	transports       []dns.Transport
+	transportMap     map[string]dns.Transport
+	domainStrategy   map[dns.Transport]dns.DomainStrategy
+	detours          map[string]string
+	defaultTransport dns.Transport
+}
+
+// newDNSTransports creates the transports of DNS servers, ctx must contain the router.
+func (r *Router) newDNSTransports(ctx context.Context, logFactory log.Factory, dnsOptions option.DNSOptions) (*dnsTransports, map[string]string, error) {
 	transports := make([]dns.Transport, len(dnsOptions.Servers))
 	dummyTransportMap := make(map[string]dns.Transport)
 	transportMap := make(map[string]dns.Transport)
 	transportTags := make([]string, len(dnsOptions.Servers))
 	transportTagMap := make(map[string]bool)
 	transportDomainStrategy := make(map[dns.Transport]dns.DomainStrategy)
+	transportDetours := make(map[string]string)
 	for i, server := range dnsOptions.Servers {
 		var tag string
 		if server.Tag != "" {
@@ -196,13 +324,12 @@ func NewRouter(
 			tag = F.ToString(i)
 		}
 		if transportTagMap[tag] {
-			return nil, E.New("duplicate dns server tag: ", tag)
+			return nil, nil, E.New("duplicate dns server tag: ", tag)
 		}
 		transportTags[i] = tag
 		transportTagMap[tag] = true
 	}
 	checkDNSLoopDomain := map[string]string{}
-	ctx = adapter.ContextWithRouter(ctx, router)
 	for {
 		lastLen := len(dummyTransportMap)
 		for i, server := range dnsOptions.Servers {
@@ -212,9 +339,10 @@ func NewRouter(
 			}
 			var detour N.Dialer
 			if server.Detour == "" {
-				detour = dialer.NewRouter(router)
+				detour = dialer.NewRouter(r)
 			} else {
-				detour = dialer.NewDetour(router, server.Detour)
+				detour = dialer.NewDetour(r, server.Detour)
+				transportDetours[tag] = server.Detour
 			}
 			checkDNSLoopDomainName := ""
 			switch server.Address {
@@ -231,15 +359,15 @@ func NewRouter(
 				notIpAddress := !M.ParseSocksaddr(serverAddress).Addr.IsValid()
 				if server.AddressResolver != "" {
 					if !transportTagMap[server.AddressResolver] {
-						return nil, E.New("parse dns server[", tag, "]: address resolver not found: ", server.AddressResolver)
+						return nil, nil, E.New("parse dns server[", tag, "]: address resolver not found: ", server.AddressResolver)
 					}
 					if upstream, exists := dummyTransportMap[server.AddressResolver]; exists {
-						detour = dns.NewDialerWrapper(detour, router.dnsClient, upstream, dns.DomainStrategy(server.AddressStrategy), time.Duration(server.AddressFallbackDelay))
+						detour = dns.NewDialerWrapper(detour, r.dnsClient, upstream, dns.DomainStrategy(server.AddressStrategy), time.Duration(server.AddressFallbackDelay))
 					} else {
 						continue
 					}
 				} else if notIpAddress && strings.Contains(server.Address, ".") {
-					// return nil, E.New("parse dns server[", tag, "]: missing address_resolver")
+					// return nil, nil, E.New("parse dns server[", tag, "]: missing address_resolver")
 					checkDNSLoopDomainName = serverURL.Host
 				}
 			}
@@ -258,7 +386,7 @@ func NewRouter(
 				ClientSubnet: clientSubnet,
 			})
 			if err != nil {
-				return nil, E.Cause(err, "parse dns server[", tag, "]")
+				return nil, nil, E.Cause(err, "parse dns server[", tag, "]")
 			} else {
 				if checkDNSLoopDomainName != "" {
 					checkDNSLoopDomain[checkDNSLoopDomainName] = transport.Name()
@@ -289,13 +417,13 @@ func NewRouter(
 		if len(unresolvedTags) == 0 {
 			panic(F.ToString("unexpected unresolved dns servers: ", len(transports), " ", len(dummyTransportMap), " ", len(transportMap)))
 		}
-		return nil, E.New("found circular reference in dns servers: ", strings.Join(unresolvedTags, " "))
+		return nil, nil, E.New("found circular reference in dns servers: ", strings.Join(unresolvedTags, " "))
 	}
 	var defaultTransport dns.Transport
 	if dnsOptions.Final != "" {
 		defaultTransport = dummyTransportMap[dnsOptions.Final]
 		if defaultTransport == nil {
-			return nil, E.New("default dns server not found: ", dnsOptions.Final)
+			return nil, nil, E.New("default dns server not found: ", dnsOptions.Final)
 		}
 	}
 	if defaultTransport == nil {
@@ -304,104 +432,21 @@ func NewRouter(
 				Context: ctx,
 				Name:    "local",
 				Address: "local",
-				Dialer:  common.Must1(dialer.NewDefault(router, option.DialerOptions{})),
+				Dialer:  common.Must1(dialer.NewDefault(r, option.DialerOptions{})),
 			})))
 		}
 		defaultTransport = transports[0]
 	}
 	if _, isFakeIP := defaultTransport.(adapter.FakeIPTransport); isFakeIP {
-		return nil, E.New("default DNS server cannot be fakeip")
-	}
-	router.defaultTransport = defaultTransport
-	router.transports = transports
-	router.transportMap = transportMap
-	router.transportDomainStrategy = transportDomainStrategy
-
-	if dnsOptions.ReverseMapping {
-		router.dnsReverseMapping = NewDNSReverseMapping()
-	}
-
-	if fakeIPOptions := dnsOptions.FakeIP; fakeIPOptions != nil && dnsOptions.FakeIP.Enabled {
-		var inet4Range netip.Prefix
-		var inet6Range netip.Prefix
-		if fakeIPOptions.Inet4Range != nil {
-			inet4Range = *fakeIPOptions.Inet4Range
-		}
-		if fakeIPOptions.Inet6Range != nil {
-			inet6Range = *fakeIPOptions.Inet6Range
-		}
-		router.fakeIPStore = fakeip.NewStore(ctx, router.logger, inet4Range, inet6Range)
-	}
-
-	usePlatformDefaultInterfaceMonitor := platformInterface != nil && platformInterface.UsePlatformDefaultInterfaceMonitor()
-	needInterfaceMonitor := options.AutoDetectInterface || common.Any(inbounds, func(inbound option.Inbound) bool {
-		return inbound.HTTPOptions.SetSystemProxy || inbound.MixedOptions.SetSystemProxy || inbound.TunOptions.AutoRoute
-	})
-
-	if !usePlatformDefaultInterfaceMonitor {
-		networkMonitor, err := tun.NewNetworkUpdateMonitor(router.logger)
-		if !((err != nil && !needInterfaceMonitor) || errors.Is(err, os.ErrInvalid)) {
-			if err != nil {
-				return nil, err
-			}
-			router.networkMonitor = networkMonitor
-			networkMonitor.RegisterCallback(func() {
-				_ = router.interfaceFinder.Update()
-			})
-			interfaceMonitor, err := tun.NewDefaultInterfaceMonitor(router.networkMonitor, router.logger, tun.DefaultInterfaceMonitorOptions{
-				OverrideAndroidVPN:    options.OverrideAndroidVPN,
-				UnderNetworkExtension: platformInterface != nil && platformInterface.UnderNetworkExtension(),
-			})
-			if err != nil {
-				return nil, E.New("auto_detect_interface unsupported on current platform")
-			}
-			interfaceMonitor.RegisterCallback(router.notifyNetworkUpdate)
-			router.interfaceMonitor = interfaceMonitor
-			// go func() {
-			// 	<-time.After(10 * time.Second)
-			// 	router.notifyNetworkUpdate(3)
-			// 	<-time.After(10 * time.Second)
-			// 	router.notifyNetworkUpdate(3)
-			// }()
-		}
-	} else {
-		interfaceMonitor := platformInterface.CreateDefaultInterfaceMonitor(router.logger)
-		interfaceMonitor.RegisterCallback(router.notifyNetworkUpdate)
-
-		router.interfaceMonitor = interfaceMonitor
-	}
-
-	if ntpOptions.Enabled {
-		ntpDialer, err := dialer.New(router, ntpOptions.DialerOptions)
-		if err != nil {
-			return nil, E.Cause(err, "create NTP service")
-		}
-		timeService := ntp.NewService(ntp.Options{
-			Context:       ctx,
-			Dialer:        ntpDialer,
-			Logger:        logFactory.NewLogger("ntp"),
-			Server:        ntpOptions.ServerOptions.Build(),
-			Interval:      time.Duration(ntpOptions.Interval),
-			WriteToSystem: ntpOptions.WriteToSystem,
-		})
-		service.MustRegister[ntp.TimeService](ctx, timeService)
-		router.timeService = timeService
-	}
-
-	for domain, tag := range checkDNSLoopDomain {
-		addrs, err := router.lookupStaticIP(domain, dns.DomainStrategyAsIS)
-		if err == nil && addrs != nil && len(addrs) != 0 {
-			continue
-		}
-		ctx, metadata := adapter.AppendContext(ctx)
-		metadata.Domain = domain
-		ctx, dnstransport, _, _, _ := router.matchDNS(ctx, false, 0, true)
-
-		if dnstransport != nil && dnstransport.Name() == tag {
-			return nil, E.New("Dns Loop Detected[", tag, "]")
-		}
-	}
-	return router, nil
+		return nil, nil, E.New("default DNS server cannot be fakeip")
+	}
+	return &dnsTransports{
+		transports:       transports,
+		transportMap:     transportMap,
+		domainStrategy:   transportDomainStrategy,
+		detours:          transportDetours,
+		defaultTransport: defaultTransport,
+	}, checkDNSLoopDomain, nil
 }
 
 func (r *Router) Initialize(inbounds []adapter.Inbound, outbounds []adapter.Outbound, defaultOutbound func() adapter.Outbound) error {
@@ -454,6 +499,7 @@ func (r *Router) Initialize(inbounds []adapter.Inbound, outbounds []adapter.Outb
 		outbounds = append(outbounds, detour)
 		outboundByTag[detour.Tag()] = detour
 	}
+	r.inbounds = inbounds
 	r.inboundByTag = inboundByTag
 	r.outbounds = outbounds
 	r.defaultOutboundForConnection = defaultOutboundForConnection
@@ -468,10 +514,25 @@ func (r *Router) Initialize(inbounds []adapter.Inbound, outbounds []adapter.Outb
 	return nil
 }
 
+func (r *Router) Inbounds() []adapter.Inbound {
+	r.access.RLock()
+	defer r.access.RUnlock()
+	return r.inbounds
+}
+
+func (r *Router) Inbound(tag string) (adapter.Inbound, bool) {
+	r.access.RLock()
+	defer r.access.RUnlock()
+	inbound, loaded := r.inboundByTag[tag]
+	return inbound, loaded
+}
+
 func (r *Router) Outbounds() []adapter.Outbound {
 	if !r.started {
 		return nil
 	}
+	r.access.RLock()
+	defer r.access.RUnlock()
 	return r.outbounds
 }
 
@@ -620,6 +681,12 @@ func (r *Router) Close() error {
 		})
 		monitor.Finish()
 	}
+	for _, rule := range r.rules {
+		rule.RateLimiters().Close()
+	}
+	for _, limiters := range r.inboundRateLimiters {
+		limiters.Close()
+	}
 	for i, rule := range r.dnsRules {
 		monitor.Start("close dns rule[", i, "]")
 		err = E.Append(err, rule.Close(), func(err error) error {
@@ -775,11 +842,15 @@ func (r *Router) PostStart() error {
 }
 
 func (r *Router) Outbound(tag string) (adapter.Outbound, bool) {
+	r.access.RLock()
+	defer r.access.RUnlock()
 	outbound, loaded := r.outboundByTag[tag]
 	return outbound, loaded
 }
 
 func (r *Router) DefaultOutbound(network string) (adapter.Outbound, error) {
+	r.access.RLock()
+	defer r.access.RUnlock()
 	if network == N.NetworkTCP {
 		if r.defaultOutboundForConnection == nil {
 			return nil, E.New("missing default outbound for TCP connections")
@@ -807,6 +878,8 @@ func (r *Router) NeedWIFIState() bool {
 }
 
 func (r *Router) RouteConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext) error {
+	metadata.Network = N.NetworkTCP
+	ctx = log.ContextWithFields(ctx, metadata.LogFields())
 	if r.pauseManager.IsDevicePaused() {
 		return E.New("reject connection to ", metadata.Destination, " while device paused")
 	}
@@ -815,7 +888,7 @@ func (r *Router) RouteConnection(ctx context.Context, conn net.Conn, metadata ad
 		if metadata.LastInbound == metadata.InboundDetour {
 			return E.New("routing loop on detour: ", metadata.InboundDetour)
 		}
-		detour := r.inboundByTag[metadata.InboundDetour]
+		detour, _ := r.Inbound(metadata.InboundDetour)
 		if detour == nil {
 			return E.New("inbound detour not found: ", metadata.InboundDetour)
 		}
@@ -836,7 +909,6 @@ func (r *Router) RouteConnection(ctx context.Context, conn net.Conn, metadata ad
 		return nil
 	}
 	conntrack.KillerCheck()
-	metadata.Network = N.NetworkTCP
 	switch metadata.Destination.Fqdn {
 	case mux.Destination.Fqdn:
 		return E.New("global multiplex is deprecated since sing-box v1.7.0, enable multiplex in inbound options instead.")
@@ -859,6 +931,7 @@ func (r *Router) RouteConnection(ctx context.Context, conn net.Conn, metadata ad
 			Port: metadata.Destination.Port,
 		}
 		metadata.FakeIP = true
+		defer r.fakeIPStore.Acquire(metadata.OriginDestination.Addr)()
 		r.logger.DebugContext(ctx, "found fakeip domain: ", domain)
 	}
 
@@ -901,6 +974,7 @@ func (r *Router) RouteConnection(ctx context.Context, conn net.Conn, metadata ad
 		}
 	}
 
+	ctx = log.ContextWithFields(ctx, metadata.LogFields())
 	if metadata.Destination.IsFqdn() && dns.DomainStrategy(metadata.InboundOptions.DomainStrategy) != dns.DomainStrategyAsIS {
 		addresses, err := r.Lookup(adapter.WithContext(ctx, &metadata), metadata.Destination.Fqdn, dns.DomainStrategy(metadata.InboundOptions.DomainStrategy))
 		if err != nil {
@@ -914,27 +988,48 @@ func (r *Router) RouteConnection(ctx context.Context, conn net.Conn, metadata ad
 	} else if metadata.Destination.IsIPv6() {
 		metadata.IPVersion = 6
 	}
-	ctx, matchedRule, detour, err := r.match(ctx, &metadata, r.defaultOutboundForConnection)
+	defaultOutbound, err := r.DefaultOutbound(N.NetworkTCP)
+	if err != nil {
+		return err
+	}
+	ctx, matchedRule, detour, err := r.match(ctx, &metadata, defaultOutbound)
 	if err != nil {
 		return err
 	}
 	if !common.Contains(detour.Network(), N.NetworkTCP) {
 		return E.New("missing supported outbound, closing connection")
 	}
+	conn = r.rateLimitConn(metadata, matchedRule, conn)
+	var trackers []adapter.Tracker
 	if r.clashServer != nil {
 		trackerConn, tracker := r.clashServer.RoutedConnection(ctx, conn, metadata, matchedRule)
 		defer tracker.Leave()
 		conn = trackerConn
+		trackers = append(trackers, tracker)
+	}
+	if r.metricsServer != nil {
+		trackerConn, tracker := r.metricsServer.RoutedConnection(ctx, conn, metadata, detour)
+		defer tracker.Leave()
+		conn = trackerConn
+		trackers = append(trackers, tracker)
 	}
 	if r.v2rayServer != nil {
 		if statsService := r.v2rayServer.StatsService(); statsService != nil {
 			conn = statsService.RoutedConnection(metadata.Inbound, detour.Tag(), metadata.User, conn)
 		}
 	}
-	return detour.NewConnection(ctx, conn, metadata)
+	err = detour.NewConnection(ctx, conn, metadata)
+	if err != nil {
+		for _, tracker := range trackers {
+			tracker.SetError(err)
+		}
+	}
+	return err
 }
 
 func (r *Router) RoutePacketConnection(ctx context.Context, conn N.PacketConn, metadata adapter.InboundContext) error {
+	metadata.Network = N.NetworkUDP
+	ctx = log.ContextWithFields(ctx, metadata.LogFields())
 	if r.pauseManager.IsDevicePaused() {
 		return E.New("reject packet connection to ", metadata.Destination, " while device paused")
 	}
@@ -942,7 +1037,7 @@ func (r *Router) RoutePacketConnection(ctx context.Context, conn N.PacketConn, m
 		if metadata.LastInbound == metadata.InboundDetour {
 			return E.New("routing loop on detour: ", metadata.InboundDetour)
 		}
-		detour := r.inboundByTag[metadata.InboundDetour]
+		detour, _ := r.Inbound(metadata.InboundDetour)
 		if detour == nil {
 			return E.New("inbound detour not found: ", metadata.InboundDetour)
 		}
@@ -963,7 +1058,6 @@ func (r *Router) RoutePacketConnection(ctx context.Context, conn N.PacketConn, m
 		return nil
 	}
 	conntrack.KillerCheck()
-	metadata.Network = N.NetworkUDP
 
 	if r.fakeIPStore != nil && r.fakeIPStore.Contains(metadata.Destination.Addr) {
 		domain, loaded := r.fakeIPStore.Lookup(metadata.Destination.Addr)
@@ -976,6 +1070,7 @@ func (r *Router) RoutePacketConnection(ctx context.Context, conn N.PacketConn, m
 			Port: metadata.Destination.Port,
 		}
 		metadata.FakeIP = true
+		defer r.fakeIPStore.Acquire(metadata.OriginDestination.Addr)()
 		r.logger.DebugContext(ctx, "found fakeip domain: ", domain)
 	}
 
@@ -1044,6 +1139,7 @@ func (r *Router) RoutePacketConnection(ctx context.Context, conn N.PacketConn, m
 			r.logger.DebugContext(ctx, "found reserve mapped domain: ", metadata.Domain)
 		}
 	}
+	ctx = log.ContextWithFields(ctx, metadata.LogFields())
 	if metadata.Destination.IsFqdn() && dns.DomainStrategy(metadata.InboundOptions.DomainStrategy) != dns.DomainStrategyAsIS {
 		addresses, err := r.Lookup(adapter.WithContext(ctx, &metadata), metadata.Destination.Fqdn, dns.DomainStrategy(metadata.InboundOptions.DomainStrategy))
 		if err != nil {
@@ -1057,17 +1153,30 @@ func (r *Router) RoutePacketConnection(ctx context.Context, conn N.PacketConn, m
 	} else if metadata.Destination.IsIPv6() {
 		metadata.IPVersion = 6
 	}
-	ctx, matchedRule, detour, err := r.match(ctx, &metadata, r.defaultOutboundForPacketConnection)
+	defaultOutbound, err := r.DefaultOutbound(N.NetworkUDP)
+	if err != nil {
+		return err
+	}
+	ctx, matchedRule, detour, err := r.match(ctx, &metadata, defaultOutbound)
 	if err != nil {
 		return err
 	}
 	if !common.Contains(detour.Network(), N.NetworkUDP) {
 		return E.New("missing supported outbound, closing packet connection")
 	}
+	conn = r.rateLimitPacketConn(metadata, matchedRule, conn)
+	var trackers []adapter.Tracker
 	if r.clashServer != nil {
 		trackerConn, tracker := r.clashServer.RoutedPacketConnection(ctx, conn, metadata, matchedRule)
 		defer tracker.Leave()
 		conn = trackerConn
+		trackers = append(trackers, tracker)
+	}
+	if r.metricsServer != nil {
+		trackerConn, tracker := r.metricsServer.RoutedPacketConnection(ctx, conn, metadata, detour)
+		defer tracker.Leave()
+		conn = trackerConn
+		trackers = append(trackers, tracker)
 	}
 	if r.v2rayServer != nil {
 		if statsService := r.v2rayServer.StatsService(); statsService != nil {
@@ -1077,7 +1186,13 @@ func (r *Router) RoutePacketConnection(ctx context.Context, conn N.PacketConn, m
 	if metadata.FakeIP {
 		conn = bufio.NewNATPacketConn(bufio.NewNetPacketConn(conn), metadata.OriginDestination, metadata.Destination)
 	}
-	return detour.NewPacketConnection(ctx, conn, metadata)
+	err = detour.NewPacketConnection(ctx, conn, metadata)
+	if err != nil {
+		for _, tracker := range trackers {
+			tracker.SetError(err)
+		}
+	}
+	return err
 }
 
 func (r *Router) match(ctx context.Context, metadata *adapter.InboundContext, defaultOutbound adapter.Outbound) (context.Context, adapter.Rule, adapter.Outbound, error) {
@@ -1088,6 +1203,14 @@ func (r *Router) match(ctx context.Context, metadata *adapter.InboundContext, de
 		}
 	}
 	ctx = outbound.ContextWithTag(ctx, matchOutbound.Tag())
+	logFields := metadata.LogFields()
+	logFields.Outbound = matchOutbound.Tag()
+	if matchRule != nil {
+		logFields.Rule = matchRule.String()
+	} else {
+		logFields.Rule = "final"
+	}
+	ctx = log.ContextWithFields(ctx, logFields)
 	return ctx, matchRule, matchOutbound, nil
 }
 
@@ -1123,7 +1246,7 @@ func (r *Router) match0(ctx context.Context, metadata *adapter.InboundContext, d
 			metadata.ProcessInfo = processInfo
 		}
 	}
-	for i, rule := range r.rules {
+	for i, rule := range r.Rules() {
 		metadata.ResetRuleCache()
 		if rule.Match(metadata) {
 			detour := rule.Outbound()
@@ -1192,6 +1315,8 @@ func (r *Router) DefaultMark() int {
 }
 
 func (r *Router) Rules() []adapter.Rule {
+	r.access.RLock()
+	defer r.access.RUnlock()
 	return r.rules
 }
 
@@ -1227,6 +1352,14 @@ func (r *Router) SetV2RayServer(server adapter.V2RayServer) {
 	r.v2rayServer = server
 }
 
+func (r *Router) MetricsServer() adapter.MetricsServer {
+	return r.metricsServer
+}
+
+func (r *Router) SetMetricsServer(server adapter.MetricsServer) {
+	r.metricsServer = server
+}
+
 func (r *Router) OnPackagesUpdated(packages int, sharedUsers int) {
 	r.logger.Info("updated packages list: ", packages, " packages, ", sharedUsers, " shared users")
 }
@@ -1276,7 +1409,10 @@ func (r *Router) ResetNetwork() error {
 		}
 	}
 
-	for _, transport := range r.transports {
+	r.access.RLock()
+	transports := r.transports
+	r.access.RUnlock()
+	for _, transport := range transports {
 		transport.Reset()
 	}
 	return nil
@@ -1298,11 +1434,14 @@ func (r *Router) updateWIFIState() {
 }
 
 func (r *Router) SortedOutboundsByDependenciesHiddify() []adapter.Outbound { // hiddify
+	r.access.RLock()
+	defer r.access.RUnlock()
 	return r.sortedOutboundsByDependenciesHiddify
 }
 
 func (r *Router) doSortOutboundsByDependencies() {
 	started := make(map[string]bool)
+	r.sortedOutboundsByDependenciesHiddify = nil
 
 	var appendOutbounds func(out adapter.Outbound)
 	appendOutbounds = func(out adapter.Outbound) {
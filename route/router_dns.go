@@ -44,8 +44,13 @@ func (r *Router) matchDNS(ctx context.Context, allowFakeIP bool, index int, isAd
 	if metadata == nil {
 		panic("no context")
 	}
-	if index < len(r.dnsRules) {
-		dnsRules := r.dnsRules
+	r.access.RLock()
+	dnsRules := r.dnsRules
+	transportMap := r.transportMap
+	transportDomainStrategy := r.transportDomainStrategy
+	defaultTransport := r.defaultTransport
+	r.access.RUnlock()
+	if index < len(dnsRules) {
 		if index != -1 {
 			dnsRules = dnsRules[index+1:]
 		}
@@ -56,7 +61,7 @@ func (r *Router) matchDNS(ctx context.Context, allowFakeIP bool, index int, isAd
 			metadata.ResetRuleCache()
 			if rule.Match(metadata) {
 				detour := rule.Outbound()
-				transport, loaded := r.transportMap[detour]
+				transport, loaded := transportMap[detour]
 				if !loaded {
 					r.dnsLogger.ErrorContext(ctx, "transport not found: ", detour)
 					continue
@@ -79,7 +84,7 @@ func (r *Router) matchDNS(ctx context.Context, allowFakeIP bool, index int, isAd
 				if clientSubnet := rule.ClientSubnet(); clientSubnet != nil {
 					ctx = dns.ContextWithClientSubnet(ctx, *clientSubnet)
 				}
-				if domainStrategy, dsLoaded := r.transportDomainStrategy[transport]; dsLoaded {
+				if domainStrategy, dsLoaded := transportDomainStrategy[transport]; dsLoaded {
 					return ctx, transport, domainStrategy, rule, ruleIndex
 				} else {
 					return ctx, transport, r.defaultDomainStrategy, rule, ruleIndex
@@ -87,10 +92,10 @@ func (r *Router) matchDNS(ctx context.Context, allowFakeIP bool, index int, isAd
 			}
 		}
 	}
-	if domainStrategy, dsLoaded := r.transportDomainStrategy[r.defaultTransport]; dsLoaded {
-		return ctx, r.defaultTransport, domainStrategy, nil, -1
+	if domainStrategy, dsLoaded := transportDomainStrategy[defaultTransport]; dsLoaded {
+		return ctx, defaultTransport, domainStrategy, nil, -1
 	} else {
-		return ctx, r.defaultTransport, r.defaultDomainStrategy, nil, -1
+		return ctx, defaultTransport, r.defaultDomainStrategy, nil, -1
 	}
 }
 
@@ -105,6 +110,11 @@ func (r *Router) Exchange(ctx context.Context, message *mDNS.Msg) (*mDNS.Msg, er
 		err       error
 	)
 	response, cached = r.dnsClient.ExchangeCache(ctx, message)
+	if r.metricsServer != nil {
+		defer func() {
+			r.metricsServer.DNSQuery(cached, err)
+		}()
+	}
 	if !cached {
 		var metadata *adapter.InboundContext
 		ctx, metadata = adapter.AppendContext(ctx)
@@ -189,6 +199,11 @@ func (r *Router) Lookup(ctx context.Context, domain string, strategy dns.DomainS
 		err           error
 	)
 	responseAddrs, cached = r.dnsClient.LookupCache(ctx, domain, strategy)
+	if r.metricsServer != nil {
+		defer func() {
+			r.metricsServer.DNSQuery(cached, err)
+		}()
+	}
 	if cached {
 		return responseAddrs, nil
 	}
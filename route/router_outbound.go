@@ -0,0 +1,214 @@
+package route
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing-box/outbound"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	F "github.com/sagernet/sing/common/format"
+	N "github.com/sagernet/sing/common/network"
+)
+
+// OutboundUpdateFunc is called with the outbound access held after an outbound is changed at runtime,
+// oldOutbound is nil for added outbounds and options is nil for removed ones.
+type OutboundUpdateFunc func(options *option.Outbound, oldOutbound adapter.Outbound, newOutbound adapter.Outbound)
+
+// SetOutboundUpdater serialises runtime outbound changes with access and reports them to update,
+// so that the owner of the router can keep its state in sync.
+func (r *Router) SetOutboundUpdater(access sync.Locker, update OutboundUpdateFunc) {
+	r.outboundAccess = access
+	r.outboundUpdater = update
+}
+
+func (r *Router) AddOutbound(options option.Outbound) error {
+	r.outboundAccess.Lock()
+	defer r.outboundAccess.Unlock()
+	if options.Tag == "" {
+		return E.New("missing outbound tag")
+	}
+	if _, loaded := r.Outbound(options.Tag); loaded {
+		return E.New("outbound already exists: ", options.Tag)
+	}
+	detour, err := r.newOutbound(options)
+	if err != nil {
+		return err
+	}
+	r.access.Lock()
+	r.outbounds = append(append([]adapter.Outbound(nil), r.outbounds...), detour)
+	r.outboundByTag[detour.Tag()] = detour
+	r.doSortOutboundsByDependencies()
+	r.access.Unlock()
+	if r.outboundUpdater != nil {
+		r.outboundUpdater(&options, nil, detour)
+	}
+	r.logger.Info("added outbound/", detour.Type(), "[", detour.Tag(), "]")
+	return nil
+}
+
+func (r *Router) ReplaceOutbound(options option.Outbound) error {
+	r.outboundAccess.Lock()
+	defer r.outboundAccess.Unlock()
+	oldOutbound, loaded := r.Outbound(options.Tag)
+	if !loaded {
+		return E.New("outbound not found: ", options.Tag)
+	}
+	detour, err := r.newOutbound(options)
+	if err != nil {
+		return err
+	}
+	r.access.Lock()
+	if oldOutbound == r.defaultOutboundForConnection && !common.Contains(detour.Network(), N.NetworkTCP) ||
+		oldOutbound == r.defaultOutboundForPacketConnection && !common.Contains(detour.Network(), N.NetworkUDP) {
+		r.access.Unlock()
+		common.Close(detour)
+		return E.New("missing network of default outbound: ", options.Tag)
+	}
+	r.outbounds = common.Map(r.outbounds, func(it adapter.Outbound) adapter.Outbound {
+		if it == oldOutbound {
+			return detour
+		}
+		return it
+	})
+	r.outboundByTag[detour.Tag()] = detour
+	if r.defaultOutboundForConnection == oldOutbound {
+		r.defaultOutboundForConnection = detour
+	}
+	if r.defaultOutboundForPacketConnection == oldOutbound {
+		r.defaultOutboundForPacketConnection = detour
+	}
+	r.doSortOutboundsByDependencies()
+	dependents := common.Filter(r.outbounds, func(it adapter.Outbound) bool {
+		return common.Contains(it.Dependencies(), detour.Tag())
+	})
+	r.access.Unlock()
+	if r.outboundUpdater != nil {
+		r.outboundUpdater(&options, oldOutbound, detour)
+	}
+	for _, dependent := range dependents {
+		if updater, isUpdater := dependent.(adapter.DependencyUpdater); isUpdater {
+			updater.UpdateDependency(detour)
+		}
+	}
+	r.logger.Info("replaced outbound/", detour.Type(), "[", detour.Tag(), "]")
+	return common.Close(oldOutbound)
+}
+
+func (r *Router) RemoveOutbound(tag string) error {
+	r.outboundAccess.Lock()
+	defer r.outboundAccess.Unlock()
+	r.access.Lock()
+	detour, loaded := r.outboundByTag[tag]
+	if !loaded {
+		r.access.Unlock()
+		return E.New("outbound not found: ", tag)
+	}
+	err := r.checkOutboundUnused(detour)
+	if err != nil {
+		r.access.Unlock()
+		return err
+	}
+	r.outbounds = common.Filter(r.outbounds, func(it adapter.Outbound) bool {
+		return it != detour
+	})
+	delete(r.outboundByTag, tag)
+	r.doSortOutboundsByDependencies()
+	r.access.Unlock()
+	if r.outboundUpdater != nil {
+		r.outboundUpdater(nil, detour, nil)
+	}
+	r.logger.Info("removed outbound/", detour.Type(), "[", tag, "]")
+	return common.Close(detour)
+}
+
+func (r *Router) newOutbound(options option.Outbound) (adapter.Outbound, error) {
+	detour, err := outbound.New(
+		r.ctx,
+		r,
+		r.logFactory.NewLogger(F.ToString("outbound/", options.Type, "[", options.Tag, "]")),
+		options.Tag,
+		options)
+	if err != nil {
+		return nil, E.Cause(err, "parse outbound/", options.Type, "[", options.Tag, "]")
+	}
+	err = r.checkOutboundDependencies(detour)
+	if err == nil {
+		if starter, isStarter := detour.(common.Starter); isStarter {
+			err = starter.Start()
+		}
+	}
+	if err == nil {
+		if postStarter, isPostStarter := detour.(adapter.PostStarter); isPostStarter {
+			err = postStarter.PostStart()
+		}
+	}
+	if err != nil {
+		common.Close(detour)
+		return nil, E.Cause(err, "initialize outbound/", options.Type, "[", options.Tag, "]")
+	}
+	return detour, nil
+}
+
+// checkOutboundDependencies checks that dependencies of the new outbound exist
+// and do not depend on the outbound to be replaced.
+func (r *Router) checkOutboundDependencies(detour adapter.Outbound) error {
+	r.access.RLock()
+	defer r.access.RUnlock()
+	checked := make(map[string]bool)
+	var lintOutbound func(oTree []string, dependencies []string) error
+	lintOutbound = func(oTree []string, dependencies []string) error {
+		for _, dependency := range dependencies {
+			if dependency == detour.Tag() {
+				return E.New("circular outbound dependency: ", strings.Join(oTree, " -> "), " -> ", dependency)
+			}
+			if checked[dependency] {
+				continue
+			}
+			checked[dependency] = true
+			dependencyOutbound, loaded := r.outboundByTag[dependency]
+			if !loaded {
+				return E.New("dependency[", dependency, "] not found for outbound[", oTree[len(oTree)-1], "]")
+			}
+			err := lintOutbound(append(append([]string(nil), oTree...), dependency), dependencyOutbound.Dependencies())
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return lintOutbound([]string{detour.Tag()}, detour.Dependencies())
+}
+
+func (r *Router) checkOutboundUnused(detour adapter.Outbound) error {
+	if detour == r.defaultOutboundForConnection || detour == r.defaultOutboundForPacketConnection {
+		return E.New("outbound ", detour.Tag(), " is the default outbound")
+	}
+	for _, it := range r.outbounds {
+		if common.Contains(it.Dependencies(), detour.Tag()) {
+			return E.New("outbound ", detour.Tag(), " is used by outbound ", it.Tag())
+		}
+	}
+	for i, rule := range r.rules {
+		if rule.Outbound() == detour.Tag() {
+			return E.New("outbound ", detour.Tag(), " is used by rule[", i, "]")
+		}
+	}
+	for server, serverDetour := range r.transportDetours {
+		if serverDetour == detour.Tag() {
+			return E.New("outbound ", detour.Tag(), " is used by dns server[", server, "]")
+		}
+	}
+	for _, ruleSet := range r.ruleSets {
+		remoteRuleSet, isRemote := ruleSet.(*RemoteRuleSet)
+		if isRemote && remoteRuleSet.options.RemoteOptions.DownloadDetour == detour.Tag() {
+			return E.New("outbound ", detour.Tag(), " is used by rule-set[", remoteRuleSet.options.Tag, "]")
+		}
+	}
+	if r.timeService != nil && r.timeServiceDetour == detour.Tag() {
+		return E.New("outbound ", detour.Tag(), " is used by ntp")
+	}
+	return nil
+}
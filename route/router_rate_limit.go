@@ -0,0 +1,86 @@
+package route
+
+import (
+	"net"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/ratelimit"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	N "github.com/sagernet/sing/common/network"
+)
+
+func newInboundRateLimiters(inbounds []option.Inbound) map[string]ratelimit.Limiters {
+	rateLimiters := make(map[string]ratelimit.Limiters)
+	for _, inbound := range inbounds {
+		if inbound.Tag == "" {
+			continue
+		}
+		rawOptions, err := inbound.RawOptions()
+		if err != nil {
+			continue
+		}
+		wrapper, isWrapper := rawOptions.(option.InboundOptionsWrapper)
+		if !isWrapper {
+			continue
+		}
+		inboundOptions := wrapper.TakeInboundOptions()
+		if inboundOptions.RateLimit == nil {
+			continue
+		}
+		limiters := ratelimit.NewLimiters(*inboundOptions.RateLimit)
+		if !limiters.IsEmpty() {
+			rateLimiters[inbound.Tag] = limiters
+		}
+	}
+	return rateLimiters
+}
+
+// reloadInboundRateLimiters creates limiters for inbounds replaced by reloading and keeps the ones
+// of unchanged inbounds, so that their buckets are not reset. inbounds and inboundOptions are aligned.
+func (r *Router) reloadInboundRateLimiters(inbounds []adapter.Inbound, inboundOptions []option.Inbound) map[string]ratelimit.Limiters {
+	unchanged := func(index int) bool {
+		return index < len(inbounds) && common.Contains(r.inbounds, inbounds[index])
+	}
+	rateLimiters := newInboundRateLimiters(common.FilterIndexed(inboundOptions, func(index int, it option.Inbound) bool {
+		return !unchanged(index)
+	}))
+	for i, inbound := range inboundOptions {
+		if !unchanged(i) {
+			continue
+		}
+		if limiters, loaded := r.inboundRateLimiters[inbound.Tag]; loaded {
+			rateLimiters[inbound.Tag] = limiters
+		}
+	}
+	return rateLimiters
+}
+
+// rateLimitConn applies limits of the inbound and the matched rule to conn,
+// conn is returned as is if there are none.
+func (r *Router) rateLimitConn(metadata adapter.InboundContext, matchedRule adapter.Rule, conn net.Conn) net.Conn {
+	if limiters, loaded := r.inboundRateLimiter(metadata.Inbound); loaded {
+		conn = limiters.Conn(conn)
+	}
+	if matchedRule != nil {
+		conn = matchedRule.RateLimiters().Conn(conn)
+	}
+	return conn
+}
+
+func (r *Router) rateLimitPacketConn(metadata adapter.InboundContext, matchedRule adapter.Rule, conn N.PacketConn) N.PacketConn {
+	if limiters, loaded := r.inboundRateLimiter(metadata.Inbound); loaded {
+		conn = limiters.PacketConn(conn)
+	}
+	if matchedRule != nil {
+		conn = matchedRule.RateLimiters().PacketConn(conn)
+	}
+	return conn
+}
+
+func (r *Router) inboundRateLimiter(inbound string) (ratelimit.Limiters, bool) {
+	r.access.RLock()
+	defer r.access.RUnlock()
+	limiters, loaded := r.inboundRateLimiters[inbound]
+	return limiters, loaded
+}
@@ -0,0 +1,168 @@
+package route
+
+import (
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+// ReloadOptions are the components replaced by Reload.
+type ReloadOptions struct {
+	LogFactory      log.Factory
+	Inbounds        []adapter.Inbound
+	InboundOptions  []option.Inbound
+	Outbounds       []adapter.Outbound
+	DefaultOutbound func() adapter.Outbound
+	// Route replaces rules and final if not nil.
+	Route *option.RouteOptions
+	// DNS replaces DNS servers, rules and final if not nil.
+	DNS *option.DNSOptions
+}
+
+// Reload swaps inbounds, outbounds, rules and DNS servers at once, connections being routed keep
+// the components they matched. Inbounds and outbounds are not started or closed here.
+func (r *Router) Reload(options ReloadOptions) error {
+	var (
+		rules      []adapter.Rule
+		dnsRules   []adapter.DNSRule
+		transports *dnsTransports
+	)
+	closeNew := func() {
+		for _, rule := range rules {
+			rule.Close()
+		}
+		for _, rule := range dnsRules {
+			rule.Close()
+		}
+		if transports != nil {
+			for _, transport := range transports.transports {
+				transport.Close()
+			}
+		}
+	}
+	if options.Route != nil {
+		for i, ruleOptions := range options.Route.Rules {
+			routeRule, err := NewRule(r, r.logger, ruleOptions, true)
+			if err != nil {
+				closeNew()
+				return E.Cause(err, "parse rule[", i, "]")
+			}
+			rules = append(rules, routeRule)
+		}
+	}
+	if options.DNS != nil {
+		for i, dnsRuleOptions := range options.DNS.Rules {
+			dnsRule, err := NewDNSRule(r, r.logger, dnsRuleOptions, true)
+			if err != nil {
+				closeNew()
+				return E.Cause(err, "parse dns rule[", i, "]")
+			}
+			dnsRules = append(dnsRules, dnsRule)
+		}
+		var err error
+		transports, _, err = r.newDNSTransports(adapter.ContextWithRouter(r.ctx, r), options.LogFactory, *options.DNS)
+		if err != nil {
+			closeNew()
+			return err
+		}
+	}
+	err := r.startReloaded(rules, dnsRules, transports)
+	if err != nil {
+		closeNew()
+		return err
+	}
+	r.access.Lock()
+	oldRules, oldDefaultDetour := r.rules, r.defaultDetour
+	oldInbounds, oldOutbounds := r.inbounds, r.outbounds
+	inboundRateLimiters := r.reloadInboundRateLimiters(options.Inbounds, options.InboundOptions)
+	if options.Route != nil {
+		r.rules = rules
+		r.defaultDetour = options.Route.Final
+	}
+	err = r.Initialize(options.Inbounds, options.Outbounds, options.DefaultOutbound)
+	if err != nil {
+		r.rules, r.defaultDetour = oldRules, oldDefaultDetour
+		common.Must(r.Initialize(oldInbounds, oldOutbounds, options.DefaultOutbound))
+		r.access.Unlock()
+		closeNew()
+		return err
+	}
+	r.inboundRateLimiters = inboundRateLimiters
+	oldDNSRules, oldTransports := r.dnsRules, r.transports
+	if options.DNS != nil {
+		r.dnsRules = dnsRules
+		r.transports = transports.transports
+		r.transportMap = transports.transportMap
+		r.transportDomainStrategy = transports.domainStrategy
+		r.transportDetours = transports.detours
+		r.defaultTransport = transports.defaultTransport
+	}
+	r.access.Unlock()
+	if options.Route != nil {
+		for i, rule := range oldRules {
+			err = E.Append(err, rule.Close(), func(err error) error {
+				return E.Cause(err, "close rule[", i, "]")
+			})
+		}
+	}
+	if options.DNS != nil {
+		for i, rule := range oldDNSRules {
+			err = E.Append(err, rule.Close(), func(err error) error {
+				return E.Cause(err, "close dns rule[", i, "]")
+			})
+		}
+		for i, transport := range oldTransports {
+			err = E.Append(err, transport.Close(), func(err error) error {
+				return E.Cause(err, "close dns transport[", i, "]")
+			})
+		}
+		r.dnsClient.ClearCache()
+	}
+	return err
+}
+
+func (r *Router) startReloaded(rules []adapter.Rule, dnsRules []adapter.DNSRule, transports *dnsTransports) error {
+	for i, rule := range rules {
+		err := rule.Start()
+		if err != nil {
+			return E.Cause(err, "initialize rule[", i, "]")
+		}
+	}
+	for i, rule := range dnsRules {
+		err := rule.Start()
+		if err != nil {
+			return E.Cause(err, "initialize DNS rule[", i, "]")
+		}
+	}
+	if transports != nil {
+		for i, transport := range transports.transports {
+			err := transport.Start()
+			if err != nil {
+				return E.Cause(err, "initialize DNS server[", i, "]")
+			}
+		}
+	}
+	return nil
+}
+
+// CheckReload returns the reason if changes between the options can not be applied by Reload.
+func CheckReload(oldRoute, newRoute option.RouteOptions, oldDNS, newDNS option.DNSOptions) error {
+	if hasRule(oldRoute.Rules, isGeoIPRule) != hasRule(newRoute.Rules, isGeoIPRule) ||
+		hasDNSRule(oldDNS.Rules, isGeoIPDNSRule) != hasDNSRule(newDNS.Rules, isGeoIPDNSRule) {
+		return E.New("geoip rules are added or removed")
+	}
+	if hasRule(newRoute.Rules, isGeositeRule) || hasDNSRule(newDNS.Rules, isGeositeDNSRule) {
+		return E.New("geosite rules are used")
+	}
+	if hasRule(oldRoute.Rules, isProcessRule) != hasRule(newRoute.Rules, isProcessRule) ||
+		hasDNSRule(oldDNS.Rules, isProcessDNSRule) != hasDNSRule(newDNS.Rules, isProcessDNSRule) {
+		return E.New("process rules are added or removed")
+	}
+	if hasRule(oldRoute.Rules, isWIFIRule) != hasRule(newRoute.Rules, isWIFIRule) ||
+		hasDNSRule(oldDNS.Rules, isWIFIDNSRule) != hasDNSRule(newDNS.Rules, isWIFIDNSRule) {
+		return E.New("wifi rules are added or removed")
+	}
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/ratelimit"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing/common"
 	F "github.com/sagernet/sing/common/format"
@@ -21,6 +22,7 @@ type abstractDefaultRule struct {
 	ruleSetItem             RuleItem
 	invert                  bool
 	outbound                string
+	rateLimiters            ratelimit.Limiters
 }
 
 func (r *abstractDefaultRule) Type() string {
@@ -150,6 +152,10 @@ func (r *abstractDefaultRule) Outbound() string {
 	return r.outbound
 }
 
+func (r *abstractDefaultRule) RateLimiters() ratelimit.Limiters {
+	return r.rateLimiters
+}
+
 func (r *abstractDefaultRule) String() string {
 	if !r.invert {
 		return strings.Join(F.MapToString(r.allItems), " ")
@@ -159,10 +165,11 @@ func (r *abstractDefaultRule) String() string {
 }
 
 type abstractLogicalRule struct {
-	rules    []adapter.HeadlessRule
-	mode     string
-	invert   bool
-	outbound string
+	rules        []adapter.HeadlessRule
+	mode         string
+	invert       bool
+	outbound     string
+	rateLimiters ratelimit.Limiters
 }
 
 func (r *abstractLogicalRule) Type() string {
@@ -226,6 +233,10 @@ func (r *abstractLogicalRule) Outbound() string {
 	return r.outbound
 }
 
+func (r *abstractLogicalRule) RateLimiters() ratelimit.Limiters {
+	return r.rateLimiters
+}
+
 func (r *abstractLogicalRule) String() string {
 	var op string
 	switch r.mode {
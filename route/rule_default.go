@@ -2,6 +2,7 @@ package route
 
 import (
 	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/ratelimit"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/log"
 	"github.com/sagernet/sing-box/option"
@@ -49,6 +50,9 @@ func NewDefaultRule(router adapter.Router, logger log.ContextLogger, options opt
 			outbound: options.Outbound,
 		},
 	}
+	if options.RateLimit != nil {
+		rule.rateLimiters = ratelimit.NewLimiters(*options.RateLimit)
+	}
 	if len(options.Inbound) > 0 {
 		item := NewInboundRule(options.Inbound)
 		rule.items = append(rule.items, item)
@@ -79,6 +83,16 @@ func NewDefaultRule(router adapter.Router, logger log.ContextLogger, options opt
 		rule.items = append(rule.items, item)
 		rule.allItems = append(rule.allItems, item)
 	}
+	if len(options.ProxyProtocolAuthority) > 0 {
+		item := NewProxyProtocolAuthorityItem(options.ProxyProtocolAuthority)
+		rule.items = append(rule.items, item)
+		rule.allItems = append(rule.allItems, item)
+	}
+	if len(options.ProxyProtocolALPN) > 0 {
+		item := NewProxyProtocolALPNItem(options.ProxyProtocolALPN)
+		rule.items = append(rule.items, item)
+		rule.allItems = append(rule.allItems, item)
+	}
 	if len(options.Domain) > 0 || len(options.DomainSuffix) > 0 {
 		item := NewDomainItem(options.Domain, options.DomainSuffix)
 		rule.destinationAddressItems = append(rule.destinationAddressItems, item)
@@ -226,6 +240,9 @@ func NewLogicalRule(router adapter.Router, logger log.ContextLogger, options opt
 			outbound: options.Outbound,
 		},
 	}
+	if options.RateLimit != nil {
+		r.rateLimiters = ratelimit.NewLimiters(*options.RateLimit)
+	}
 	switch options.Mode {
 	case C.LogicalTypeAnd:
 		r.mode = C.LogicalTypeAnd
@@ -0,0 +1,73 @@
+package route
+
+import (
+	"strings"
+
+	"github.com/sagernet/sing-box/adapter"
+	F "github.com/sagernet/sing/common/format"
+)
+
+var (
+	_ RuleItem = (*ProxyProtocolAuthorityItem)(nil)
+	_ RuleItem = (*ProxyProtocolALPNItem)(nil)
+)
+
+type ProxyProtocolAuthorityItem struct {
+	authorities  []string
+	authorityMap map[string]bool
+}
+
+func NewProxyProtocolAuthorityItem(authorities []string) *ProxyProtocolAuthorityItem {
+	authorityMap := make(map[string]bool)
+	for _, authority := range authorities {
+		authorityMap[strings.ToLower(authority)] = true
+	}
+	return &ProxyProtocolAuthorityItem{
+		authorities:  authorities,
+		authorityMap: authorityMap,
+	}
+}
+
+func (r *ProxyProtocolAuthorityItem) Match(metadata *adapter.InboundContext) bool {
+	if metadata.ProxyProtocol == nil {
+		return false
+	}
+	return r.authorityMap[strings.ToLower(metadata.ProxyProtocol.Authority)]
+}
+
+func (r *ProxyProtocolAuthorityItem) String() string {
+	if len(r.authorities) == 1 {
+		return F.ToString("proxy_protocol_authority=", r.authorities[0])
+	}
+	return F.ToString("proxy_protocol_authority=[", strings.Join(r.authorities, " "), "]")
+}
+
+type ProxyProtocolALPNItem struct {
+	alpnList []string
+	alpnMap  map[string]bool
+}
+
+func NewProxyProtocolALPNItem(alpnList []string) *ProxyProtocolALPNItem {
+	alpnMap := make(map[string]bool)
+	for _, alpn := range alpnList {
+		alpnMap[alpn] = true
+	}
+	return &ProxyProtocolALPNItem{
+		alpnList: alpnList,
+		alpnMap:  alpnMap,
+	}
+}
+
+func (r *ProxyProtocolALPNItem) Match(metadata *adapter.InboundContext) bool {
+	if metadata.ProxyProtocol == nil {
+		return false
+	}
+	return r.alpnMap[metadata.ProxyProtocol.ALPN]
+}
+
+func (r *ProxyProtocolALPNItem) String() string {
+	if len(r.alpnList) == 1 {
+		return F.ToString("proxy_protocol_alpn=", r.alpnList[0])
+	}
+	return F.ToString("proxy_protocol_alpn=[", strings.Join(r.alpnList, " "), "]")
+}
@@ -8,9 +8,11 @@ import (
 	"net/http"
 	"runtime"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/dialer"
 	"github.com/sagernet/sing-box/common/srs"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/option"
@@ -24,7 +26,7 @@ import (
 	"github.com/sagernet/sing/service/pause"
 )
 
-var _ adapter.RuleSet = (*RemoteRuleSet)(nil)
+var _ adapter.UpdatableRuleSet = (*RemoteRuleSet)(nil)
 
 type RemoteRuleSet struct {
 	ctx            context.Context
@@ -38,6 +40,8 @@ type RemoteRuleSet struct {
 	rules          []adapter.HeadlessRule
 	lastUpdated    time.Time
 	lastEtag       string
+	updateAccess   sync.RWMutex
+	lastError      error
 	updateTicker   *time.Ticker
 	pauseManager   pause.Manager
 }
@@ -75,21 +79,18 @@ func (s *RemoteRuleSet) String() string {
 }
 
 func (s *RemoteRuleSet) StartContext(ctx context.Context, startContext adapter.RuleSetStartContext) error {
-	var dialer N.Dialer
+	// outbounds are looked up on each update, since they may be replaced by reloading
 	if s.options.RemoteOptions.DownloadDetour != "" {
-		outbound, loaded := s.router.Outbound(s.options.RemoteOptions.DownloadDetour)
-		if !loaded {
+		if _, loaded := s.router.Outbound(s.options.RemoteOptions.DownloadDetour); !loaded {
 			return E.New("download_detour not found: ", s.options.RemoteOptions.DownloadDetour)
 		}
-		dialer = outbound
+		s.dialer = dialer.NewDetour(s.router, s.options.RemoteOptions.DownloadDetour)
 	} else {
-		outbound, err := s.router.DefaultOutbound(N.NetworkTCP)
-		if err != nil {
+		if _, err := s.router.DefaultOutbound(N.NetworkTCP); err != nil {
 			return err
 		}
-		dialer = outbound
+		s.dialer = dialer.NewRouter(s.router)
 	}
-	s.dialer = dialer
 	cacheFile := service.FromContext[adapter.CacheFile](s.ctx)
 	if cacheFile != nil {
 		if savedSet := cacheFile.LoadRuleSet(s.options.Tag); savedSet != nil {
@@ -115,6 +116,27 @@ func (s *RemoteRuleSet) StartContext(ctx context.Context, startContext adapter.R
 	return nil
 }
 
+func (s *RemoteRuleSet) LastUpdated() time.Time {
+	s.updateAccess.RLock()
+	defer s.updateAccess.RUnlock()
+	return s.lastUpdated
+}
+
+func (s *RemoteRuleSet) LastUpdateError() error {
+	s.updateAccess.RLock()
+	defer s.updateAccess.RUnlock()
+	return s.lastError
+}
+
+func (s *RemoteRuleSet) setUpdated(lastUpdated time.Time, err error) {
+	s.updateAccess.Lock()
+	defer s.updateAccess.Unlock()
+	if err == nil {
+		s.lastUpdated = lastUpdated
+	}
+	s.lastError = err
+}
+
 func (s *RemoteRuleSet) Metadata() adapter.RuleSetMetadata {
 	return s.metadata
 }
@@ -180,6 +202,14 @@ func (s *RemoteRuleSet) loopUpdate() {
 }
 
 func (s *RemoteRuleSet) fetchOnce(ctx context.Context, startContext adapter.RuleSetStartContext) error {
+	err := s.fetchOnce0(ctx, startContext)
+	if err != nil {
+		s.setUpdated(time.Time{}, err)
+	}
+	return err
+}
+
+func (s *RemoteRuleSet) fetchOnce0(ctx context.Context, startContext adapter.RuleSetStartContext) error {
 	s.logger.Debug("updating rule-set ", s.options.Tag, " from URL: ", s.options.RemoteOptions.URL)
 	var httpClient *http.Client
 	if startContext != nil {
@@ -209,7 +239,7 @@ func (s *RemoteRuleSet) fetchOnce(ctx context.Context, startContext adapter.Rule
 	switch response.StatusCode {
 	case http.StatusOK:
 	case http.StatusNotModified:
-		s.lastUpdated = time.Now()
+		s.setUpdated(time.Now(), nil)
 		cacheFile := service.FromContext[adapter.CacheFile](s.ctx)
 		if cacheFile != nil {
 			savedRuleSet := cacheFile.LoadRuleSet(s.options.Tag)
@@ -242,7 +272,7 @@ func (s *RemoteRuleSet) fetchOnce(ctx context.Context, startContext adapter.Rule
 	if eTagHeader != "" {
 		s.lastEtag = eTagHeader
 	}
-	s.lastUpdated = time.Now()
+	s.setUpdated(time.Now(), nil)
 	cacheFile := service.FromContext[adapter.CacheFile](s.ctx)
 	if cacheFile != nil {
 		err = cacheFile.SaveRuleSet(s.options.Tag, &adapter.SavedRuleSet{
@@ -6,7 +6,7 @@ import (
 
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/option"
-	"github.com/sagernet/sing-quic/hysteria2"
+	"github.com/sagernet/sing-box/transport/hysteria2"
 )
 
 func TestHysteria2Self(t *testing.T) {
@@ -12,17 +12,24 @@ import (
 
 func TestTUICSelf(t *testing.T) {
 	t.Run("self", func(t *testing.T) {
-		testTUICSelf(t, false, false)
+		testTUICSelf(t, false, false, nil)
 	})
 	t.Run("self-udp-stream", func(t *testing.T) {
-		testTUICSelf(t, true, false)
+		testTUICSelf(t, true, false, nil)
 	})
 	t.Run("self-early", func(t *testing.T) {
-		testTUICSelf(t, false, true)
+		testTUICSelf(t, false, true, nil)
+	})
+	t.Run("self-udp-obfs", func(t *testing.T) {
+		testTUICSelf(t, false, false, &option.UDPObfsOptions{
+			Password:   "password",
+			MaxPadding: 64,
+			Header:     "dtls",
+		})
 	})
 }
 
-func testTUICSelf(t *testing.T, udpStream bool, zeroRTTHandshake bool) {
+func testTUICSelf(t *testing.T, udpStream bool, zeroRTTHandshake bool, udpObfs *option.UDPObfsOptions) {
 	_, certPem, keyPem := createSelfSignedCertificate(t, "example.org")
 	var udpRelayMode string
 	if udpStream {
@@ -51,6 +58,7 @@ func testTUICSelf(t *testing.T, udpStream bool, zeroRTTHandshake bool) {
 						UUID: uuid.Nil.String(),
 					}},
 					ZeroRTTHandshake: zeroRTTHandshake,
+					UDPObfs:          udpObfs,
 					InboundTLSOptionsContainer: option.InboundTLSOptionsContainer{
 						TLS: &option.InboundTLSOptions{
 							Enabled:         true,
@@ -77,6 +85,7 @@ func testTUICSelf(t *testing.T, udpStream bool, zeroRTTHandshake bool) {
 					UUID:             uuid.Nil.String(),
 					UDPRelayMode:     udpRelayMode,
 					ZeroRTTHandshake: zeroRTTHandshake,
+					UDPObfs:          udpObfs,
 					OutboundTLSOptionsContainer: option.OutboundTLSOptionsContainer{
 						TLS: &option.OutboundTLSOptions{
 							Enabled:         true,
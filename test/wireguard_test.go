@@ -5,8 +5,12 @@ import (
 	"testing"
 	"time"
 
+	"github.com/sagernet/sing-box/adapter"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common/json"
+
+	"github.com/stretchr/testify/require"
 )
 
 func _TestWireGuard(t *testing.T) {
@@ -49,3 +53,112 @@ func _TestWireGuard(t *testing.T) {
 	})
 	testSuitWg(t, clientPort, testPort)
 }
+
+func TestWireGuardInbound(t *testing.T) {
+	t.Run("self", func(t *testing.T) {
+		testWireGuardInbound(t, false, nil)
+	})
+	t.Run("add-peer", func(t *testing.T) {
+		testWireGuardInbound(t, true, nil)
+	})
+	t.Run("amnezia", func(t *testing.T) {
+		testWireGuardInbound(t, false, &option.WireGuardAmneziaOptions{
+			JunkPacketCount:            4,
+			JunkPacketMinSize:          40,
+			JunkPacketMaxSize:          70,
+			InitPacketJunkSize:         15,
+			ResponsePacketJunkSize:     18,
+			InitPacketMagicHeader:      1020325451,
+			ResponsePacketMagicHeader:  3288052141,
+			UnderloadPacketMagicHeader: 1766607858,
+			TransportPacketMagicHeader: 2528465083,
+		})
+	})
+}
+
+func testWireGuardInbound(t *testing.T, addPeer bool, amnezia *option.WireGuardAmneziaOptions) {
+	peer := option.WireGuardPeer{
+		PublicKey:  "f2HvXxzHbzyyr/QTY50gMJEvK+ncunuR8WxvoQHutWw=",
+		AllowedIPs: []string{"10.0.0.2/32"},
+	}
+	var peers []option.WireGuardPeer
+	if !addPeer {
+		peers = append(peers, peer)
+	}
+	instance := startInstance(t, option.Options{
+		Inbounds: []option.Inbound{
+			{
+				Type: C.TypeWireGuard,
+				Tag:  "wg-in",
+				WireGuardOptions: option.WireGuardInboundOptions{
+					ListenOptions: option.ListenOptions{
+						Listen:     option.NewListenAddress(netip.IPv4Unspecified()),
+						ListenPort: serverPort,
+					},
+					LocalAddress: []netip.Prefix{netip.MustParsePrefix("10.0.0.1/24")},
+					PrivateKey:   "OMNvTmTP+fWSIvzK6jHOXGtwWHlF+1JgvFhmURO9rWU=",
+					Peers:        peers,
+					Amnezia:      amnezia,
+				},
+			},
+		},
+		Outbounds: []option.Outbound{
+			{
+				Type: C.TypeBlock,
+			},
+			{
+				Type: C.TypeDirect,
+				Tag:  "loopback",
+				DirectOptions: option.DirectOutboundOptions{
+					OverrideAddress: "127.0.0.1",
+				},
+			},
+		},
+		Route: &option.RouteOptions{
+			Rules: []option.Rule{
+				{
+					DefaultOptions: option.DefaultRule{
+						AuthUser: []string{peer.PublicKey},
+						Outbound: "loopback",
+					},
+				},
+			},
+		},
+	})
+	if addPeer {
+		inbound, loaded := instance.Router().Inbound("wg-in")
+		require.True(t, loaded)
+		content, err := json.Marshal([]option.WireGuardPeer{peer})
+		require.NoError(t, err)
+		require.NoError(t, inbound.(adapter.UserManager).AddUsers(content))
+	}
+	startInstance(t, option.Options{
+		Inbounds: []option.Inbound{
+			{
+				Type: C.TypeMixed,
+				MixedOptions: option.HTTPMixedInboundOptions{
+					ListenOptions: option.ListenOptions{
+						Listen:     option.NewListenAddress(netip.IPv4Unspecified()),
+						ListenPort: clientPort,
+					},
+				},
+			},
+		},
+		Outbounds: []option.Outbound{
+			{
+				Type: C.TypeWireGuard,
+				WireGuardOptions: option.WireGuardOutboundOptions{
+					ServerOptions: option.ServerOptions{
+						Server:     "127.0.0.1",
+						ServerPort: serverPort,
+					},
+					LocalAddress:  []netip.Prefix{netip.MustParsePrefix("10.0.0.2/32")},
+					PrivateKey:    "kB/ckEYEwsoode0LXLpC/54fvndDa3ZbJE+SdTph72I=",
+					PeerPublicKey: "vnu3km49GKUcl2F4BmL1AmYx7FqWYDxfVsVN0/zxvTc=",
+					Amnezia:       amnezia,
+				},
+			},
+		},
+	})
+	testSuitWg(t, clientPort, testPort)
+}
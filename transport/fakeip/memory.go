@@ -81,9 +81,43 @@ func (s *MemoryStorage) FakeIPLoadDomain(domain string, isIPv6 bool) (netip.Addr
 	}
 }
 
+func (s *MemoryStorage) FakeIPLoadAll() (map[netip.Addr]string, error) {
+	s.addressAccess.RLock()
+	defer s.addressAccess.RUnlock()
+	mappings := make(map[netip.Addr]string, len(s.addressCache))
+	for address, domain := range s.addressCache {
+		mappings[address] = domain
+	}
+	return mappings, nil
+}
+
+func (s *MemoryStorage) FakeIPDelete(address netip.Addr) error {
+	s.addressAccess.Lock()
+	s.domainAccess.Lock()
+	if domain, loaded := s.addressCache[address]; loaded {
+		delete(s.addressCache, address)
+		if address.Is4() {
+			if s.domainCache4[domain] == address {
+				delete(s.domainCache4, domain)
+			}
+		} else {
+			if s.domainCache6[domain] == address {
+				delete(s.domainCache6, domain)
+			}
+		}
+	}
+	s.domainAccess.Unlock()
+	s.addressAccess.Unlock()
+	return nil
+}
+
 func (s *MemoryStorage) FakeIPReset() error {
+	s.addressAccess.Lock()
+	s.domainAccess.Lock()
 	s.addressCache = make(map[netip.Addr]string)
 	s.domainCache4 = make(map[string]netip.Addr)
 	s.domainCache6 = make(map[string]netip.Addr)
+	s.domainAccess.Unlock()
+	s.addressAccess.Unlock()
 	return nil
 }
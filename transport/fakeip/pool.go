@@ -0,0 +1,122 @@
+package fakeip
+
+import (
+	"net/netip"
+
+	"github.com/sagernet/sing/common/x/list"
+)
+
+type addressEntry struct {
+	address netip.Addr
+	domain  string
+}
+
+// addressRange allocates addresses of a pool in order until all are used,
+// then reuses the least recently used ones.
+type addressRange struct {
+	pool     string
+	prefix   netip.Prefix
+	size     uint64
+	current  netip.Addr
+	lru      list.List[addressEntry]
+	elements map[netip.Addr]*list.Element[addressEntry]
+	domains  map[string]*list.Element[addressEntry]
+}
+
+func newAddressRange(pool string, prefix netip.Prefix) *addressRange {
+	addressRange := &addressRange{
+		pool:     pool,
+		prefix:   prefix,
+		elements: make(map[netip.Addr]*list.Element[addressEntry]),
+		domains:  make(map[string]*list.Element[addressEntry]),
+	}
+	// ranges larger than 2^63 addresses are never exhausted in practice
+	if hostBits := prefix.Addr().BitLen() - prefix.Bits(); hostBits < 63 {
+		addressRange.size = 1<<hostBits - 2
+	}
+	addressRange.current = addressRange.first()
+	return addressRange
+}
+
+// first returns the first address to allocate, the network address and the next one are reserved.
+func (r *addressRange) first() netip.Addr {
+	return r.prefix.Addr().Next().Next()
+}
+
+func (r *addressRange) exhausted() bool {
+	return r.size > 0 && uint64(len(r.elements)) >= r.size
+}
+
+// load returns the domain of the address and marks it as most recently used.
+func (r *addressRange) load(address netip.Addr) (string, bool) {
+	element, loaded := r.elements[address]
+	if !loaded {
+		return "", false
+	}
+	r.lru.MoveToBack(element)
+	return element.Value.domain, true
+}
+
+// loadDomain returns the address of the domain and marks it as most recently used.
+func (r *addressRange) loadDomain(domain string) (netip.Addr, bool) {
+	element, loaded := r.domains[domain]
+	if !loaded {
+		return netip.Addr{}, false
+	}
+	r.lru.MoveToBack(element)
+	return element.Value.address, true
+}
+
+// store maps the address to the domain as most recently used, replacing the previous domain of the address.
+func (r *addressRange) store(address netip.Addr, domain string) {
+	element, loaded := r.elements[address]
+	if loaded {
+		if r.domains[element.Value.domain] == element {
+			delete(r.domains, element.Value.domain)
+		}
+		element.Value.domain = domain
+		r.lru.MoveToBack(element)
+	} else {
+		element = r.lru.PushBack(addressEntry{address, domain})
+		r.elements[address] = element
+	}
+	r.domains[domain] = element
+}
+
+func (r *addressRange) remove(address netip.Addr) {
+	if element, loaded := r.elements[address]; loaded {
+		if r.domains[element.Value.domain] == element {
+			delete(r.domains, element.Value.domain)
+		}
+		r.lru.Remove(element)
+		delete(r.elements, address)
+	}
+}
+
+func (r *addressRange) reset() {
+	r.lru.Init()
+	r.elements = make(map[netip.Addr]*list.Element[addressEntry])
+	r.domains = make(map[string]*list.Element[addressEntry])
+}
+
+// allocate returns an unused address, or the least recently used one not in use if all are used.
+func (r *addressRange) allocate(inUse func(address netip.Addr) bool) (netip.Addr, bool) {
+	if !r.exhausted() {
+		for i := uint64(0); r.size == 0 || i < r.size; i++ {
+			address := r.current.Next()
+			if !r.prefix.Contains(address) {
+				address = r.first()
+			}
+			r.current = address
+			if _, loaded := r.elements[address]; !loaded && !inUse(address) {
+				return address, true
+			}
+		}
+	}
+	for element := r.lru.Front(); element != nil; element = element.Next() {
+		if !inUse(element.Value.address) {
+			return element.Value.address, true
+		}
+	}
+	return netip.Addr{}, false
+}
@@ -3,6 +3,7 @@ package fakeip
 import (
 	"context"
 	"net/netip"
+	"net/url"
 	"os"
 
 	"github.com/sagernet/sing-box/adapter"
@@ -27,18 +28,30 @@ func init() {
 type Transport struct {
 	name   string
 	router adapter.Router
+	pool   string
 	store  adapter.FakeIPStore
 	logger logger.ContextLogger
 }
 
+// NewTransport creates a transport for the default pool with address fakeip,
+// or for a named pool with address fakeip://<pool>.
 func NewTransport(options dns.TransportOptions) (*Transport, error) {
 	router := adapter.RouterFromContext(options.Context)
 	if router == nil {
 		return nil, E.New("missing router in context")
 	}
+	var pool string
+	if options.Address != "fakeip" {
+		serverURL, err := url.Parse(options.Address)
+		if err != nil {
+			return nil, err
+		}
+		pool = serverURL.Host
+	}
 	return &Transport{
 		name:   options.Name,
 		router: router,
+		pool:   pool,
 		logger: options.Logger,
 	}, nil
 }
@@ -52,6 +65,9 @@ func (s *Transport) Start() error {
 	if s.store == nil {
 		return E.New("fakeip not enabled")
 	}
+	if store, isStore := s.store.(*Store); isStore && s.pool != "" && !store.hasPool(s.pool) {
+		return E.New("fakeip pool not found: ", s.pool)
+	}
 	return nil
 }
 
@@ -73,14 +89,14 @@ func (s *Transport) Exchange(ctx context.Context, message *mDNS.Msg) (*mDNS.Msg,
 func (s *Transport) Lookup(ctx context.Context, domain string, strategy dns.DomainStrategy) ([]netip.Addr, error) {
 	var addresses []netip.Addr
 	if strategy != dns.DomainStrategyUseIPv6 {
-		inet4Address, err := s.store.Create(domain, false)
+		inet4Address, err := s.store.Create(s.pool, domain, false)
 		if err != nil {
 			return nil, err
 		}
 		addresses = append(addresses, inet4Address)
 	}
 	if strategy != dns.DomainStrategyUseIPv4 {
-		inet6Address, err := s.store.Create(domain, true)
+		inet6Address, err := s.store.Create(s.pool, domain, true)
 		if err != nil {
 			return nil, err
 		}
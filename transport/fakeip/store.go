@@ -3,8 +3,11 @@ package fakeip
 import (
 	"context"
 	"net/netip"
+	"sort"
+	"sync"
 
 	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/option"
 	E "github.com/sagernet/sing/common/exceptions"
 	"github.com/sagernet/sing/common/logger"
 	"github.com/sagernet/sing/service"
@@ -13,22 +16,84 @@ import (
 var _ adapter.FakeIPStore = (*Store)(nil)
 
 type Store struct {
-	ctx          context.Context
-	logger       logger.Logger
-	inet4Range   netip.Prefix
-	inet6Range   netip.Prefix
-	storage      adapter.FakeIPStorage
-	inet4Current netip.Addr
-	inet6Current netip.Addr
+	ctx         context.Context
+	logger      logger.Logger
+	inet4Range  netip.Prefix
+	inet6Range  netip.Prefix
+	storage     adapter.FakeIPStorage
+	access      sync.Mutex
+	ranges      []*addressRange
+	connections map[netip.Addr]int
 }
 
-func NewStore(ctx context.Context, logger logger.Logger, inet4Range netip.Prefix, inet6Range netip.Prefix) *Store {
-	return &Store{
-		ctx:        ctx,
-		logger:     logger,
-		inet4Range: inet4Range,
-		inet6Range: inet6Range,
+func NewStore(ctx context.Context, logger logger.Logger, inet4Range netip.Prefix, inet6Range netip.Prefix, pools []option.DNSFakeIPPoolOptions) (*Store, error) {
+	store := &Store{
+		ctx:         ctx,
+		logger:      logger,
+		inet4Range:  inet4Range,
+		inet6Range:  inet6Range,
+		connections: make(map[netip.Addr]int),
 	}
+	store.addRange("", inet4Range)
+	store.addRange("", inet6Range)
+	for i, pool := range pools {
+		if pool.Tag == "" {
+			return nil, E.New("missing tag for fakeip pool ", i)
+		}
+		if store.hasPool(pool.Tag) {
+			return nil, E.New("duplicate fakeip pool: ", pool.Tag)
+		}
+		if pool.Inet4Range == nil && pool.Inet6Range == nil {
+			return nil, E.New("missing address range for fakeip pool ", pool.Tag)
+		}
+		if pool.Inet4Range != nil {
+			store.addRange(pool.Tag, *pool.Inet4Range)
+		}
+		if pool.Inet6Range != nil {
+			store.addRange(pool.Tag, *pool.Inet6Range)
+		}
+	}
+	for i, addressRange := range store.ranges {
+		for _, otherRange := range store.ranges[:i] {
+			if addressRange.prefix.Overlaps(otherRange.prefix) {
+				return nil, E.New("fakeip address range ", addressRange.prefix, " overlaps with ", otherRange.prefix)
+			}
+		}
+	}
+	return store, nil
+}
+
+func (s *Store) addRange(pool string, prefix netip.Prefix) {
+	if prefix.IsValid() {
+		s.ranges = append(s.ranges, newAddressRange(pool, prefix.Masked()))
+	}
+}
+
+func (s *Store) hasPool(pool string) bool {
+	for _, addressRange := range s.ranges {
+		if addressRange.pool == pool {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Store) findRange(pool string, isIPv6 bool) *addressRange {
+	for _, addressRange := range s.ranges {
+		if addressRange.pool == pool && addressRange.prefix.Addr().Is6() == isIPv6 {
+			return addressRange
+		}
+	}
+	return nil
+}
+
+func (s *Store) rangeOf(address netip.Addr) *addressRange {
+	for _, addressRange := range s.ranges {
+		if addressRange.prefix.Contains(address) {
+			return addressRange
+		}
+	}
+	return nil
 }
 
 func (s *Store) Start() error {
@@ -40,79 +105,235 @@ func (s *Store) Start() error {
 	if storage == nil {
 		storage = NewMemoryStorage()
 	}
+	return s.start(storage)
+}
+
+// start restores the mappings and allocation state from the storage if the address ranges are unchanged.
+func (s *Store) start(storage adapter.FakeIPStorage) error {
 	metadata := storage.FakeIPMetadata()
-	if metadata != nil && metadata.Inet4Range == s.inet4Range && metadata.Inet6Range == s.inet6Range {
-		s.inet4Current = metadata.Inet4Current
-		s.inet6Current = metadata.Inet6Current
-	} else {
-		if s.inet4Range.IsValid() {
-			s.inet4Current = s.inet4Range.Addr().Next().Next()
+	if metadata != nil && s.loadMetadata(metadata) {
+		mappings, err := storage.FakeIPLoadAll()
+		if err != nil {
+			return E.Cause(err, "load fakeip mappings")
 		}
-		if s.inet6Range.IsValid() {
-			s.inet6Current = s.inet6Range.Addr().Next().Next()
+		addresses := make([]netip.Addr, 0, len(mappings))
+		for address := range mappings {
+			addresses = append(addresses, address)
 		}
+		sort.Slice(addresses, func(i, j int) bool {
+			return addresses[i].Less(addresses[j])
+		})
+		for _, address := range addresses {
+			if addressRange := s.rangeOf(address); addressRange != nil {
+				addressRange.store(address, mappings[address])
+			}
+		}
+	} else {
 		_ = storage.FakeIPReset()
 	}
 	s.storage = storage
 	return nil
 }
 
+// loadMetadata restores the allocation state of all pools,
+// it returns false if the address ranges changed since the metadata was saved.
+func (s *Store) loadMetadata(metadata *adapter.FakeIPMetadata) bool {
+	if metadata.Inet4Range != s.inet4Range || metadata.Inet6Range != s.inet6Range {
+		return false
+	}
+	pools := s.poolMetadata()
+	if len(pools) != len(metadata.Pools) {
+		return false
+	}
+	for i, pool := range pools {
+		savedPool := metadata.Pools[i]
+		if savedPool.Tag != pool.Tag || savedPool.Inet4Range != pool.Inet4Range || savedPool.Inet6Range != pool.Inet6Range {
+			return false
+		}
+	}
+	s.loadCurrent("", metadata.Inet4Current, metadata.Inet6Current)
+	for _, pool := range metadata.Pools {
+		s.loadCurrent(pool.Tag, pool.Inet4Current, pool.Inet6Current)
+	}
+	return true
+}
+
+func (s *Store) loadCurrent(pool string, inet4Current netip.Addr, inet6Current netip.Addr) {
+	if inet4Range := s.findRange(pool, false); inet4Range != nil && inet4Range.prefix.Contains(inet4Current) {
+		inet4Range.current = inet4Current
+	}
+	if inet6Range := s.findRange(pool, true); inet6Range != nil && inet6Range.prefix.Contains(inet6Current) {
+		inet6Range.current = inet6Current
+	}
+}
+
 func (s *Store) Contains(address netip.Addr) bool {
-	return s.inet4Range.Contains(address) || s.inet6Range.Contains(address)
+	return s.rangeOf(address) != nil
 }
 
 func (s *Store) Close() error {
 	if s.storage == nil {
 		return nil
 	}
-	return s.storage.FakeIPSaveMetadata(&adapter.FakeIPMetadata{
-		Inet4Range:   s.inet4Range,
-		Inet6Range:   s.inet6Range,
-		Inet4Current: s.inet4Current,
-		Inet6Current: s.inet6Current,
-	})
+	s.access.Lock()
+	defer s.access.Unlock()
+	return s.storage.FakeIPSaveMetadata(s.metadata())
 }
 
-func (s *Store) Create(domain string, isIPv6 bool) (netip.Addr, error) {
-	if address, loaded := s.storage.FakeIPLoadDomain(domain, isIPv6); loaded {
-		return address, nil
+// metadata returns the allocation state of all pools.
+func (s *Store) metadata() *adapter.FakeIPMetadata {
+	metadata := &adapter.FakeIPMetadata{
+		Inet4Range: s.inet4Range,
+		Inet6Range: s.inet6Range,
+		Pools:      s.poolMetadata(),
 	}
-	var address netip.Addr
-	if !isIPv6 {
-		if !s.inet4Current.IsValid() {
-			return netip.Addr{}, E.New("missing IPv4 fakeip address range")
+	if inet4Range := s.findRange("", false); inet4Range != nil {
+		metadata.Inet4Current = inet4Range.current
+	}
+	if inet6Range := s.findRange("", true); inet6Range != nil {
+		metadata.Inet6Current = inet6Range.current
+	}
+	return metadata
+}
+
+// poolMetadata returns the allocation state of named pools in configuration order.
+func (s *Store) poolMetadata() []adapter.FakeIPPoolMetadata {
+	var pools []adapter.FakeIPPoolMetadata
+	for _, addressRange := range s.ranges {
+		if addressRange.pool == "" {
+			continue
 		}
-		nextAddress := s.inet4Current.Next()
-		if !s.inet4Range.Contains(nextAddress) {
-			nextAddress = s.inet4Range.Addr().Next().Next()
+		if len(pools) == 0 || pools[len(pools)-1].Tag != addressRange.pool {
+			pools = append(pools, adapter.FakeIPPoolMetadata{Tag: addressRange.pool})
 		}
-		s.inet4Current = nextAddress
-		address = nextAddress
-	} else {
-		if !s.inet6Current.IsValid() {
-			return netip.Addr{}, E.New("missing IPv6 fakeip address range")
+		pool := &pools[len(pools)-1]
+		if addressRange.prefix.Addr().Is4() {
+			pool.Inet4Range = addressRange.prefix
+			pool.Inet4Current = addressRange.current
+		} else {
+			pool.Inet6Range = addressRange.prefix
+			pool.Inet6Current = addressRange.current
 		}
-		nextAddress := s.inet6Current.Next()
-		if !s.inet6Range.Contains(nextAddress) {
-			nextAddress = s.inet6Range.Addr().Next().Next()
+	}
+	return pools
+}
+
+func (s *Store) Create(pool string, domain string, isIPv6 bool) (netip.Addr, error) {
+	s.access.Lock()
+	defer s.access.Unlock()
+	addressRange := s.findRange(pool, isIPv6)
+	if addressRange == nil {
+		if pool != "" && !s.hasPool(pool) {
+			return netip.Addr{}, E.New("fakeip pool not found: ", pool)
+		}
+		if !isIPv6 {
+			return netip.Addr{}, E.New("missing IPv4 fakeip address range")
+		} else {
+			return netip.Addr{}, E.New("missing IPv6 fakeip address range")
 		}
-		s.inet6Current = nextAddress
-		address = nextAddress
 	}
-	s.storage.FakeIPStoreAsync(address, domain, s.logger)
-	s.storage.FakeIPSaveMetadataAsync(&adapter.FakeIPMetadata{
-		Inet4Range:   s.inet4Range,
-		Inet6Range:   s.inet6Range,
-		Inet4Current: s.inet4Current,
-		Inet6Current: s.inet6Current,
+	if address, loaded := addressRange.loadDomain(domain); loaded {
+		return address, nil
+	}
+	address, loaded := addressRange.allocate(func(address netip.Addr) bool {
+		return s.connections[address] > 0
 	})
+	if !loaded {
+		return netip.Addr{}, E.New("fakeip address range exhausted: ", addressRange.prefix)
+	}
+	addressRange.store(address, domain)
+	s.storage.FakeIPStoreAsync(address, domain, s.logger)
+	s.storage.FakeIPSaveMetadataAsync(s.metadata())
 	return address, nil
 }
 
 func (s *Store) Lookup(address netip.Addr) (string, bool) {
-	return s.storage.FakeIPLoad(address)
+	s.access.Lock()
+	defer s.access.Unlock()
+	addressRange := s.rangeOf(address)
+	if addressRange == nil {
+		return "", false
+	}
+	return addressRange.load(address)
+}
+
+func (s *Store) Mapping(address netip.Addr) (adapter.FakeIPMapping, bool) {
+	s.access.Lock()
+	defer s.access.Unlock()
+	addressRange := s.rangeOf(address)
+	if addressRange == nil {
+		return adapter.FakeIPMapping{}, false
+	}
+	element, loaded := addressRange.elements[address]
+	if !loaded {
+		return adapter.FakeIPMapping{}, false
+	}
+	return adapter.FakeIPMapping{
+		Address:     address,
+		Domain:      element.Value.domain,
+		Pool:        addressRange.pool,
+		Connections: s.connections[address],
+	}, true
+}
+
+func (s *Store) Acquire(address netip.Addr) func() {
+	s.access.Lock()
+	s.connections[address]++
+	s.access.Unlock()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			s.access.Lock()
+			if s.connections[address] > 1 {
+				s.connections[address]--
+			} else {
+				delete(s.connections, address)
+			}
+			s.access.Unlock()
+		})
+	}
+}
+
+func (s *Store) Mappings() ([]adapter.FakeIPMapping, error) {
+	addresses, err := s.storage.FakeIPLoadAll()
+	if err != nil {
+		return nil, err
+	}
+	s.access.Lock()
+	mappings := make([]adapter.FakeIPMapping, 0, len(addresses))
+	for address, domain := range addresses {
+		addressRange := s.rangeOf(address)
+		if addressRange == nil {
+			continue
+		}
+		mappings = append(mappings, adapter.FakeIPMapping{
+			Address:     address,
+			Domain:      domain,
+			Pool:        addressRange.pool,
+			Connections: s.connections[address],
+		})
+	}
+	s.access.Unlock()
+	sort.Slice(mappings, func(i, j int) bool {
+		return mappings[i].Address.Less(mappings[j].Address)
+	})
+	return mappings, nil
+}
+
+func (s *Store) Delete(address netip.Addr) error {
+	s.access.Lock()
+	defer s.access.Unlock()
+	if addressRange := s.rangeOf(address); addressRange != nil {
+		addressRange.remove(address)
+	}
+	return s.storage.FakeIPDelete(address)
 }
 
 func (s *Store) Reset() error {
+	s.access.Lock()
+	defer s.access.Unlock()
+	for _, addressRange := range s.ranges {
+		addressRange.reset()
+	}
 	return s.storage.FakeIPReset()
 }
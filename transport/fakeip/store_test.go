@@ -0,0 +1,235 @@
+package fakeip
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+
+	"github.com/stretchr/testify/require"
+)
+
+func startTestStore(t *testing.T, storage adapter.FakeIPStorage, inet4Range string, pools []option.DNSFakeIPPoolOptions) *Store {
+	store, err := NewStore(context.Background(), log.NewNOPFactory().Logger(), netip.MustParsePrefix(inet4Range), netip.Prefix{}, pools)
+	require.NoError(t, err)
+	if storage == nil {
+		require.NoError(t, store.Start())
+	} else {
+		require.NoError(t, store.start(storage))
+	}
+	return store
+}
+
+func TestStoreEviction(t *testing.T) {
+	t.Parallel()
+	// a /29 has 6 usable addresses: .2 to .7
+	store := startTestStore(t, nil, "198.18.0.0/29", nil)
+	var addresses []netip.Addr
+	for i := 0; i < 6; i++ {
+		address, err := store.Create("", testDomain(i), false)
+		require.NoError(t, err)
+		addresses = append(addresses, address)
+	}
+	require.Equal(t, netip.MustParseAddr("198.18.0.3"), addresses[0])
+	address, err := store.Create("", testDomain(0), false)
+	require.NoError(t, err)
+	require.Equal(t, addresses[0], address)
+
+	// domain 1 is now the least recently used and is evicted
+	address, err = store.Create("", "new.example", false)
+	require.NoError(t, err)
+	require.Equal(t, addresses[1], address)
+	domain, loaded := store.Lookup(address)
+	require.True(t, loaded)
+	require.Equal(t, "new.example", domain)
+	address, err = store.Create("", testDomain(1), false)
+	require.NoError(t, err)
+	require.Equal(t, addresses[2], address)
+
+	// lookups mark addresses as used
+	_, loaded = store.Lookup(addresses[3])
+	require.True(t, loaded)
+	address, err = store.Create("", "other.example", false)
+	require.NoError(t, err)
+	require.Equal(t, addresses[4], address)
+}
+
+func TestStoreAcquire(t *testing.T) {
+	t.Parallel()
+	store := startTestStore(t, nil, "198.18.0.0/30", nil)
+	// a /30 has 2 usable addresses
+	first, err := store.Create("", testDomain(0), false)
+	require.NoError(t, err)
+	second, err := store.Create("", testDomain(1), false)
+	require.NoError(t, err)
+	release := store.Acquire(first)
+	mapping, loaded := store.Mapping(first)
+	require.True(t, loaded)
+	require.Equal(t, 1, mapping.Connections)
+
+	// the least recently used address is in use, so the next one is evicted
+	address, err := store.Create("", testDomain(2), false)
+	require.NoError(t, err)
+	require.Equal(t, second, address)
+
+	secondRelease := store.Acquire(second)
+	_, err = store.Create("", testDomain(3), false)
+	require.Error(t, err)
+
+	release()
+	release()
+	secondRelease()
+	mapping, _ = store.Mapping(first)
+	require.Zero(t, mapping.Connections)
+	address, err = store.Create("", testDomain(3), false)
+	require.NoError(t, err)
+	require.Equal(t, first, address)
+}
+
+func TestStorePools(t *testing.T) {
+	t.Parallel()
+	store := startTestStore(t, nil, "198.18.0.0/24", []option.DNSFakeIPPoolOptions{
+		{Tag: "a", Inet4Range: common.Ptr(netip.MustParsePrefix("198.19.0.0/24"))},
+		{Tag: "b", Inet6Range: common.Ptr(netip.MustParsePrefix("fc00::/112"))},
+	})
+	defaultAddress, err := store.Create("", "example.com", false)
+	require.NoError(t, err)
+	poolAddress, err := store.Create("a", "example.com", false)
+	require.NoError(t, err)
+	require.True(t, netip.MustParsePrefix("198.19.0.0/24").Contains(poolAddress))
+
+	// the same domain has an address per pool
+	address, err := store.Create("", "example.com", false)
+	require.NoError(t, err)
+	require.Equal(t, defaultAddress, address)
+	address, err = store.Create("a", "example.com", false)
+	require.NoError(t, err)
+	require.Equal(t, poolAddress, address)
+	mapping, loaded := store.Mapping(poolAddress)
+	require.True(t, loaded)
+	require.Equal(t, "a", mapping.Pool)
+
+	address, err = store.Create("b", "example.com", true)
+	require.NoError(t, err)
+	require.True(t, address.Is6())
+	_, err = store.Create("b", "example.com", false)
+	require.Error(t, err)
+	_, err = store.Create("", "example.com", true)
+	require.Error(t, err)
+	_, err = store.Create("c", "example.com", false)
+	require.Error(t, err)
+
+	_, err = NewStore(context.Background(), log.NewNOPFactory().Logger(), netip.MustParsePrefix("198.18.0.0/15"), netip.Prefix{}, []option.DNSFakeIPPoolOptions{
+		{Tag: "a", Inet4Range: common.Ptr(netip.MustParsePrefix("198.19.0.0/24"))},
+	})
+	require.Error(t, err)
+}
+
+func TestStoreDelete(t *testing.T) {
+	t.Parallel()
+	store := startTestStore(t, nil, "198.18.0.0/24", nil)
+	address, err := store.Create("", "example.com", false)
+	require.NoError(t, err)
+	require.NoError(t, store.Delete(address))
+	_, loaded := store.Lookup(address)
+	require.False(t, loaded)
+	_, loaded = store.Mapping(address)
+	require.False(t, loaded)
+	mappings, err := store.Mappings()
+	require.NoError(t, err)
+	require.Empty(t, mappings)
+
+	// the domain gets a new address
+	newAddress, err := store.Create("", "example.com", false)
+	require.NoError(t, err)
+	require.NotEqual(t, address, newAddress)
+
+	require.NoError(t, store.Reset())
+	_, loaded = store.Lookup(newAddress)
+	require.False(t, loaded)
+}
+
+func TestStoreRestart(t *testing.T) {
+	t.Parallel()
+	storage := newTestStorage()
+	pools := []option.DNSFakeIPPoolOptions{
+		{Tag: "a", Inet4Range: common.Ptr(netip.MustParsePrefix("198.19.0.0/29"))},
+		{Tag: "b", Inet4Range: common.Ptr(netip.MustParsePrefix("198.20.0.0/24"))},
+	}
+	store := startTestStore(t, storage, "198.18.0.0/29", pools)
+	var addresses []netip.Addr
+	for i := 0; i < 6; i++ {
+		address, err := store.Create("a", testDomain(i), false)
+		require.NoError(t, err)
+		addresses = append(addresses, address)
+	}
+	defaultAddress, err := store.Create("", "example.com", false)
+	require.NoError(t, err)
+	deletedAddress, err := store.Create("b", "deleted.example", false)
+	require.NoError(t, err)
+	require.NoError(t, store.Delete(deletedAddress))
+	require.NoError(t, store.Close())
+
+	// mappings and allocation state of named pools survive restarts
+	store = startTestStore(t, storage, "198.18.0.0/29", pools)
+	domain, loaded := store.Lookup(addresses[0])
+	require.True(t, loaded)
+	require.Equal(t, testDomain(0), domain)
+	address, err := store.Create("a", testDomain(5), false)
+	require.NoError(t, err)
+	require.Equal(t, addresses[5], address)
+	address, err = store.Create("", "example.com", false)
+	require.NoError(t, err)
+	require.Equal(t, defaultAddress, address)
+	address, err = store.Create("b", "new.example", false)
+	require.NoError(t, err)
+	require.Equal(t, deletedAddress.Next(), address)
+	// the pool is used up, so the least recently used address is reused
+	address, err = store.Create("a", "new.example", false)
+	require.NoError(t, err)
+	require.Equal(t, addresses[1], address)
+	require.NoError(t, store.Close())
+
+	// changed ranges reset the storage
+	store = startTestStore(t, storage, "198.18.0.0/24", pools)
+	_, loaded = store.Lookup(addresses[0])
+	require.False(t, loaded)
+}
+
+// testStorage is a memory storage keeping metadata, like a cache file across restarts.
+type testStorage struct {
+	*MemoryStorage
+	savedMetadata *adapter.FakeIPMetadata
+}
+
+func newTestStorage() *testStorage {
+	return &testStorage{MemoryStorage: NewMemoryStorage()}
+}
+
+func (s *testStorage) FakeIPMetadata() *adapter.FakeIPMetadata {
+	if s.savedMetadata == nil {
+		return nil
+	}
+	data, err := s.savedMetadata.MarshalBinary()
+	if err != nil {
+		return nil
+	}
+	var metadata adapter.FakeIPMetadata
+	if metadata.UnmarshalBinary(data) != nil {
+		return nil
+	}
+	return &metadata
+}
+
+func (s *testStorage) FakeIPSaveMetadata(metadata *adapter.FakeIPMetadata) error {
+	s.savedMetadata = metadata
+	return nil
+}
+
+func testDomain(i int) string {
+	return string(rune('a'+i)) + ".example"
+}
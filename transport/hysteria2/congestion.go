@@ -0,0 +1,48 @@
+package hysteria2
+
+import (
+	"context"
+	"time"
+
+	"github.com/sagernet/quic-go"
+	"github.com/sagernet/quic-go/congestion"
+	congestion_meta1 "github.com/sagernet/sing-quic/congestion_meta1"
+	congestion_meta2 "github.com/sagernet/sing-quic/congestion_meta2"
+	hyCC "github.com/sagernet/sing-quic/hysteria/congestion"
+	"github.com/sagernet/sing/common/logger"
+	"github.com/sagernet/sing/common/ntp"
+)
+
+const (
+	CongestionControlBrutal  = "brutal"
+	CongestionControlBBR     = "bbr"
+	CongestionControlNewReno = "new_reno"
+)
+
+// setCongestion applies the named congestion control to the connection,
+// brutal falls back to bbr if the bandwidth is unknown.
+func setCongestion(ctx context.Context, connection quic.Connection, congestionName string, sendBPS uint64, brutalDebug bool, logger logger.Logger) {
+	if congestionName == CongestionControlBrutal && sendBPS > 0 {
+		connection.SetCongestionControl(hyCC.NewBrutalSender(sendBPS, brutalDebug, logger))
+		return
+	}
+	timeFunc := ntp.TimeFuncFromContext(ctx)
+	if timeFunc == nil {
+		timeFunc = time.Now
+	}
+	switch congestionName {
+	case CongestionControlNewReno:
+		connection.SetCongestionControl(congestion_meta1.NewCubicSender(
+			congestion_meta1.DefaultClock{TimeFunc: timeFunc},
+			congestion.ByteCount(connection.Config().InitialPacketSize),
+			true,
+			nil,
+		))
+	default:
+		connection.SetCongestionControl(congestion_meta2.NewBbrSender(
+			congestion_meta2.DefaultClock{TimeFunc: timeFunc},
+			congestion.ByteCount(connection.Config().InitialPacketSize),
+			congestion.ByteCount(congestion_meta1.InitialCongestionWindow),
+		))
+	}
+}
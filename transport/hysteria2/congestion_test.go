@@ -0,0 +1,79 @@
+package hysteria2
+
+import (
+	"context"
+	"path"
+	"reflect"
+	"testing"
+
+	"github.com/sagernet/quic-go"
+	"github.com/sagernet/quic-go/congestion"
+	"github.com/sagernet/sing-box/transport/hysteria2/internal/protocol"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testConnection struct {
+	quic.Connection
+	congestionControl congestion.CongestionControl
+}
+
+func (c *testConnection) Config() *quic.Config {
+	return &quic.Config{InitialPacketSize: 1252}
+}
+
+func (c *testConnection) SetCongestionControl(cc congestion.CongestionControl) {
+	c.congestionControl = cc
+}
+
+func congestionType(cc congestion.CongestionControl) string {
+	senderType := reflect.TypeOf(cc).Elem()
+	name := path.Base(senderType.PkgPath()) + "." + senderType.Name()
+	if name == "congestion_meta1.cubicSender" && reflect.ValueOf(cc).Elem().FieldByName("reno").Bool() {
+		return "new_reno"
+	}
+	return name
+}
+
+func TestSetCongestion(t *testing.T) {
+	t.Parallel()
+	for _, testCase := range []struct {
+		name     string
+		sendBPS  uint64
+		expected string
+	}{
+		{CongestionControlBrutal, 1000000, "congestion.BrutalSender"},
+		{CongestionControlBrutal, 0, "congestion_meta2.bbrSender"},
+		{CongestionControlBBR, 1000000, "congestion_meta2.bbrSender"},
+		{CongestionControlNewReno, 1000000, "new_reno"},
+	} {
+		conn := &testConnection{}
+		setCongestion(context.Background(), conn, testCase.name, testCase.sendBPS, false, nil)
+		require.Equal(t, testCase.expected, congestionType(conn.congestionControl), testCase.name)
+	}
+}
+
+func TestClientCongestionName(t *testing.T) {
+	t.Parallel()
+	client := &Client{}
+	require.Equal(t, CongestionControlBrutal, client.congestionName(protocol.AuthResponse{}, 1000000))
+	require.Equal(t, CongestionControlBBR, client.congestionName(protocol.AuthResponse{RxAuto: true}, 1000000))
+	require.Equal(t, CongestionControlBBR, client.congestionName(protocol.AuthResponse{}, 0))
+	for _, name := range []string{CongestionControlBrutal, CongestionControlBBR, CongestionControlNewReno} {
+		client.congestionControl = name
+		require.Equal(t, name, client.congestionName(protocol.AuthResponse{RxAuto: true}, 1000000))
+	}
+}
+
+func TestServiceCongestionName(t *testing.T) {
+	t.Parallel()
+	service := &Service[int]{}
+	require.Equal(t, CongestionControlBrutal, service.congestionName(protocol.AuthRequest{Rx: 1000000}))
+	require.Equal(t, CongestionControlBBR, service.congestionName(protocol.AuthRequest{}))
+	service.ignoreClientBandwidth = true
+	require.Equal(t, CongestionControlBBR, service.congestionName(protocol.AuthRequest{Rx: 1000000}))
+	for _, name := range []string{CongestionControlBrutal, CongestionControlBBR, CongestionControlNewReno} {
+		service.congestionControl = name
+		require.Equal(t, name, service.congestionName(protocol.AuthRequest{Rx: 1000000}))
+	}
+}
@@ -3,6 +3,7 @@ package sip003
 import (
 	"bytes"
 	"fmt"
+	"sort"
 )
 
 // mod from https://github.com/shadowsocks/v2ray-plugin/blob/master/args.go
@@ -30,6 +31,29 @@ func (args Args) Add(key, value string) {
 	args[key] = append(args[key], value)
 }
 
+// String encodes args in the format of SS_PLUGIN_OPTIONS, keys are sorted.
+func (args Args) String() string {
+	keys := make([]string, 0, len(args))
+	for key := range args {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var buf bytes.Buffer
+	for _, key := range keys {
+		for _, value := range args[key] {
+			if buf.Len() > 0 {
+				buf.WriteByte(';')
+			}
+			buf.WriteString(backslashEscape(key, []byte{'=', ';'}))
+			if value != "" {
+				buf.WriteByte('=')
+				buf.WriteString(backslashEscape(value, []byte{'=', ';'}))
+			}
+		}
+	}
+	return buf.String()
+}
+
 // Return the index of the next unescaped byte in s that is in the term set, or
 // else the length of the string if no terminators appear. Additionally return
 // the unescaped string up to the returned index.
@@ -0,0 +1,257 @@
+package sip003
+
+import (
+	"context"
+	"net"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	F "github.com/sagernet/sing/common/format"
+	"github.com/sagernet/sing/common/logger"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+)
+
+const (
+	externalStartTimeout = 5 * time.Second
+	externalRestartDelay = time.Second
+)
+
+var _ Plugin = (*External)(nil)
+
+// External runs a SIP003 plugin binary in client mode and dials its local port.
+//
+// The binary connects to the server by itself, so the dialer of the outbound is not used.
+type External struct {
+	path       string
+	options    string
+	serverAddr M.Socksaddr
+	access     sync.Mutex
+	process    *externalProcess
+	localAddr  M.Socksaddr
+	closed     bool
+}
+
+func NewExternal(path string, pluginOpts string, serverAddr M.Socksaddr) *External {
+	return &External{
+		path:       path,
+		options:    pluginOpts,
+		serverAddr: serverAddr,
+	}
+}
+
+func (p *External) DialContext(ctx context.Context) (net.Conn, error) {
+	localAddr, started, err := p.start()
+	if err != nil {
+		return nil, err
+	}
+	var dialer net.Dialer
+	if !started {
+		return dialer.DialContext(ctx, N.NetworkTCP, localAddr.String())
+	}
+	// The plugin was just started and may not be listening yet.
+	ctx, cancel := context.WithTimeout(ctx, externalStartTimeout)
+	defer cancel()
+	for {
+		conn, err := dialer.DialContext(ctx, N.NetworkTCP, localAddr.String())
+		if err == nil {
+			return conn, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, E.Cause(err, "connect to plugin ", p.path)
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
+
+func (p *External) start() (localAddr M.Socksaddr, started bool, err error) {
+	p.access.Lock()
+	defer p.access.Unlock()
+	if p.closed {
+		return M.Socksaddr{}, false, net.ErrClosed
+	}
+	if p.process != nil && !p.process.Exited() {
+		return p.localAddr, false, nil
+	}
+	localAddr, err = pickLocalAddr()
+	if err != nil {
+		return M.Socksaddr{}, false, err
+	}
+	p.process, err = startExternalProcess(p.path, p.serverAddr, localAddr, p.options)
+	if err != nil {
+		return M.Socksaddr{}, false, err
+	}
+	p.localAddr = localAddr
+	return localAddr, true, nil
+}
+
+func (p *External) Close() error {
+	p.access.Lock()
+	defer p.access.Unlock()
+	p.closed = true
+	return common.Close(common.PtrOrNil(p.process))
+}
+
+var _ ServerPlugin = (*ExternalServer)(nil)
+
+// ExternalServer runs a SIP003 plugin binary in server mode, the binary listens on the
+// address of the inbound and forwards plain Shadowsocks connections to a loopback listener.
+type ExternalServer struct {
+	logger  logger.ContextLogger
+	path    string
+	options string
+}
+
+func NewExternalServer(logger logger.ContextLogger, path string, pluginOpts string) *ExternalServer {
+	return &ExternalServer{
+		logger:  logger,
+		path:    path,
+		options: pluginOpts,
+	}
+}
+
+func (p *ExternalServer) Listen(ctx context.Context, listenAddr M.Socksaddr, listen func(listenAddr M.Socksaddr) (net.Listener, error)) (net.Listener, error) {
+	listener, err := listen(M.ParseSocksaddrHostPort("127.0.0.1", 0))
+	if err != nil {
+		return nil, err
+	}
+	localAddr := M.SocksaddrFromNet(listener.Addr())
+	process, err := startExternalProcess(p.path, listenAddr, localAddr, p.options)
+	if err != nil {
+		listener.Close()
+		return nil, err
+	}
+	serverListener := &externalServerListener{
+		Listener:   listener,
+		logger:     p.logger,
+		path:       p.path,
+		options:    p.options,
+		listenAddr: listenAddr,
+		localAddr:  localAddr,
+		process:    process,
+		done:       make(chan struct{}),
+	}
+	go serverListener.keepAlive()
+	return serverListener, nil
+}
+
+type externalServerListener struct {
+	net.Listener
+	logger     logger.ContextLogger
+	path       string
+	options    string
+	listenAddr M.Socksaddr
+	localAddr  M.Socksaddr
+	access     sync.Mutex
+	process    *externalProcess
+	done       chan struct{}
+	closeOnce  sync.Once
+}
+
+// keepAlive restarts the plugin when it exits until the listener is closed.
+func (l *externalServerListener) keepAlive() {
+	for {
+		l.access.Lock()
+		process := l.process
+		l.access.Unlock()
+		select {
+		case <-process.done:
+		case <-l.done:
+			return
+		}
+		l.logger.Warn("plugin ", l.path, " exited, restarting")
+		select {
+		case <-time.After(externalRestartDelay):
+		case <-l.done:
+			return
+		}
+		l.access.Lock()
+		select {
+		case <-l.done:
+			l.access.Unlock()
+			return
+		default:
+		}
+		newProcess, err := startExternalProcess(l.path, l.listenAddr, l.localAddr, l.options)
+		if err != nil {
+			l.logger.Error(err)
+		} else {
+			l.process = newProcess
+		}
+		l.access.Unlock()
+	}
+}
+
+func (l *externalServerListener) Close() error {
+	l.closeOnce.Do(func() {
+		l.access.Lock()
+		close(l.done)
+		l.access.Unlock()
+	})
+	l.access.Lock()
+	process := l.process
+	l.access.Unlock()
+	return common.Close(l.Listener, process)
+}
+
+type externalProcess struct {
+	cmd  *exec.Cmd
+	done chan struct{}
+}
+
+// startExternalProcess starts a SIP003 plugin binary, remoteAddr and localAddr are passed
+// as SS_REMOTE_HOST/SS_REMOTE_PORT and SS_LOCAL_HOST/SS_LOCAL_PORT.
+func startExternalProcess(path string, remoteAddr M.Socksaddr, localAddr M.Socksaddr, pluginOpts string) (*externalProcess, error) {
+	cmd := exec.Command(path)
+	cmd.Env = append(os.Environ(),
+		"SS_REMOTE_HOST="+remoteAddr.AddrString(),
+		"SS_REMOTE_PORT="+F.ToString(remoteAddr.Port),
+		"SS_LOCAL_HOST="+localAddr.AddrString(),
+		"SS_LOCAL_PORT="+F.ToString(localAddr.Port),
+		"SS_PLUGIN_OPTIONS="+pluginOpts,
+	)
+	err := cmd.Start()
+	if err != nil {
+		return nil, E.Cause(err, "start plugin ", path)
+	}
+	process := &externalProcess{
+		cmd:  cmd,
+		done: make(chan struct{}),
+	}
+	go func() {
+		cmd.Wait()
+		close(process.done)
+	}()
+	return process, nil
+}
+
+func (p *externalProcess) Exited() bool {
+	select {
+	case <-p.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func (p *externalProcess) Close() error {
+	if !p.Exited() {
+		p.cmd.Process.Kill()
+	}
+	<-p.done
+	return nil
+}
+
+func pickLocalAddr() (M.Socksaddr, error) {
+	listener, err := net.Listen(N.NetworkTCP, "127.0.0.1:0")
+	if err != nil {
+		return M.Socksaddr{}, E.Cause(err, "pick local port for plugin")
+	}
+	defer listener.Close()
+	return M.SocksaddrFromNet(listener.Addr()), nil
+}
@@ -0,0 +1,157 @@
+package sip003
+
+import (
+	"context"
+	"io"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/log"
+	M "github.com/sagernet/sing/common/metadata"
+
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testPluginOptions       = "sip003-test-plugin"
+	testServerPluginOptions = "sip003-test-server-plugin"
+)
+
+// TestMain runs the test binary as a SIP003 plugin if it is started by the external plugin runner.
+// Client plugins forward SS_LOCAL to SS_REMOTE, server plugins forward SS_REMOTE to SS_LOCAL.
+func TestMain(m *testing.M) {
+	localAddr := net.JoinHostPort(os.Getenv("SS_LOCAL_HOST"), os.Getenv("SS_LOCAL_PORT"))
+	remoteAddr := net.JoinHostPort(os.Getenv("SS_REMOTE_HOST"), os.Getenv("SS_REMOTE_PORT"))
+	switch os.Getenv("SS_PLUGIN_OPTIONS") {
+	case testPluginOptions:
+		runTestPlugin(localAddr, remoteAddr)
+	case testServerPluginOptions:
+		runTestPlugin(remoteAddr, localAddr)
+	default:
+		os.Exit(m.Run())
+	}
+}
+
+func runTestPlugin(listenAddr string, forwardAddr string) {
+	listener, err := net.Listen("tcp", listenAddr)
+	if err != nil {
+		os.Exit(1)
+	}
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			os.Exit(1)
+		}
+		go func() {
+			defer conn.Close()
+			remoteConn, err := net.Dial("tcp", forwardAddr)
+			if err != nil {
+				return
+			}
+			defer remoteConn.Close()
+			go io.Copy(remoteConn, conn)
+			io.Copy(conn, remoteConn)
+		}()
+	}
+}
+
+func startEchoServer(t *testing.T) M.Socksaddr {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				io.Copy(conn, conn)
+			}()
+		}
+	}()
+	return M.SocksaddrFromNet(listener.Addr())
+}
+
+func requireEcho(t *testing.T, conn net.Conn) {
+	defer conn.Close()
+	_, err := conn.Write([]byte("hello"))
+	require.NoError(t, err)
+	// mux streams can't be read with short buffers
+	var response []byte
+	buffer := make([]byte, 1024)
+	for len(response) < 5 {
+		n, err := conn.Read(buffer)
+		require.NoError(t, err)
+		response = append(response, buffer[:n]...)
+	}
+	require.Equal(t, "hello", string(response))
+}
+
+func TestExternalPlugin(t *testing.T) {
+	t.Parallel()
+	plugin, err := CreatePlugin(context.Background(), "kcptun", os.Args[0], testPluginOptions, nil, nil, startEchoServer(t))
+	require.NoError(t, err)
+	external := plugin.(*External)
+	defer external.Close()
+	conn, err := external.DialContext(context.Background())
+	require.NoError(t, err)
+	requireEcho(t, conn)
+
+	// the plugin is restarted after it exits
+	external.access.Lock()
+	external.process.cmd.Process.Kill()
+	<-external.process.done
+	external.access.Unlock()
+	conn, err = external.DialContext(context.Background())
+	require.NoError(t, err)
+	requireEcho(t, conn)
+
+	require.NoError(t, external.Close())
+	_, err = external.DialContext(context.Background())
+	require.ErrorIs(t, err, net.ErrClosed)
+}
+
+func TestExternalServerPlugin(t *testing.T) {
+	t.Parallel()
+	plugin, err := CreateServerPlugin(context.Background(), log.NewNOPFactory().Logger(), "kcptun", os.Args[0], testServerPluginOptions)
+	require.NoError(t, err)
+	listenAddr, err := pickLocalAddr()
+	require.NoError(t, err)
+	listener, err := plugin.Listen(context.Background(), listenAddr, func(listenAddr M.Socksaddr) (net.Listener, error) {
+		return net.Listen("tcp", listenAddr.String())
+	})
+	require.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				io.Copy(conn, conn)
+			}()
+		}
+	}()
+	var conn net.Conn
+	require.Eventually(t, func() bool {
+		conn, err = net.Dial("tcp", listenAddr.String())
+		return err == nil
+	}, 5*time.Second, 50*time.Millisecond)
+	requireEcho(t, conn)
+}
+
+func TestPluginPathRequired(t *testing.T) {
+	t.Parallel()
+	_, err := CreatePlugin(context.Background(), "kcptun", "", "", nil, nil, M.ParseSocksaddr("127.0.0.1:1"))
+	require.ErrorContains(t, err, "kcptun is not built in")
+	_, err = CreateServerPlugin(context.Background(), log.NewNOPFactory().Logger(), "kcptun", "", "")
+	require.ErrorContains(t, err, "kcptun is not built in")
+	_, err = CreatePlugin(context.Background(), "gost-plugin", "", "mode=quic", nil, nil, M.ParseSocksaddr("127.0.0.1:1"))
+	require.Error(t, err)
+}
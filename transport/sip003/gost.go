@@ -0,0 +1,102 @@
+package sip003
+
+import (
+	"context"
+	"net"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/tls"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing-box/transport/v2ray"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+)
+
+func init() {
+	RegisterPlugin("gost-plugin", newGostPlugin)
+}
+
+// newGostPlugin implements the ws, wss and tls modes of gost-plugin,
+// other modes require plugin_path to run the gost-plugin binary.
+func newGostPlugin(ctx context.Context, pluginOpts Args, router adapter.Router, dialer N.Dialer, serverAddr M.Socksaddr) (Plugin, error) {
+	mode := "ws"
+	if modeOpt, loaded := pluginOpts.Get("mode"); loaded {
+		mode = modeOpt
+	}
+	var tlsOptions option.OutboundTLSOptions
+	host := serverAddr.AddrString()
+	if hostOpt, loaded := pluginOpts.Get("host"); loaded {
+		host = hostOpt
+	}
+	tlsOptions.ServerName = host
+	if serverName, loaded := pluginOpts.Get("serverName"); loaded {
+		tlsOptions.ServerName = serverName
+	}
+	if certPath, loaded := pluginOpts.Get("cert"); loaded {
+		tlsOptions.CertificatePath = certPath
+	}
+	if _, loaded := pluginOpts.Get("insecure"); loaded {
+		tlsOptions.Insecure = true
+	}
+	path := "/ws"
+	if pathOpt, loaded := pluginOpts.Get("path"); loaded {
+		path = pathOpt
+	}
+	switch mode {
+	case "ws", "wss":
+		var tlsClient tls.Config
+		if mode == "wss" {
+			tlsOptions.Enabled = true
+			var err error
+			tlsClient, err = tls.NewClient(ctx, serverAddr.AddrString(), tlsOptions)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return v2ray.NewClientTransport(ctx, dialer, serverAddr, option.V2RayTransportOptions{
+			Type: C.V2RayTransportTypeWebsocket,
+			WebsocketOptions: option.V2RayWebsocketOptions{
+				Headers: map[string]option.Listable[string]{
+					"Host": []string{host},
+				},
+				Path: path,
+			},
+		}, tlsClient)
+	case "tls":
+		tlsOptions.Enabled = true
+		tlsClient, err := tls.NewClient(ctx, serverAddr.AddrString(), tlsOptions)
+		if err != nil {
+			return nil, err
+		}
+		return &gostTLS{
+			dialer:     dialer,
+			serverAddr: serverAddr,
+			tlsConfig:  tlsClient,
+		}, nil
+	default:
+		return nil, E.New("gost-plugin: unsupported mode: ", mode, ", set plugin_path to run the gost-plugin binary")
+	}
+}
+
+var _ Plugin = (*gostTLS)(nil)
+
+type gostTLS struct {
+	dialer     N.Dialer
+	serverAddr M.Socksaddr
+	tlsConfig  tls.Config
+}
+
+func (p *gostTLS) DialContext(ctx context.Context) (net.Conn, error) {
+	conn, err := p.dialer.DialContext(ctx, N.NetworkTCP, p.serverAddr)
+	if err != nil {
+		return nil, err
+	}
+	tlsConn, err := tls.ClientHandshake(ctx, conn, p.tlsConfig)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return tlsConn, nil
+}
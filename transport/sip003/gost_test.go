@@ -0,0 +1,92 @@
+package sip003
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	sTLS "github.com/sagernet/sing-box/common/tls"
+	"github.com/sagernet/sing-box/log"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+
+	"github.com/stretchr/testify/require"
+)
+
+// startV2RayPluginServer listens with the v2ray-plugin server and echoes decoded connections.
+func startV2RayPluginServer(t *testing.T, pluginOpts string) M.Socksaddr {
+	plugin, err := CreateServerPlugin(context.Background(), log.NewNOPFactory().Logger(), "v2ray-plugin", "", pluginOpts)
+	require.NoError(t, err)
+	listener, err := plugin.Listen(context.Background(), M.ParseSocksaddr("127.0.0.1:0"), func(listenAddr M.Socksaddr) (net.Listener, error) {
+		return net.Listen("tcp", listenAddr.String())
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				io.Copy(conn, conn)
+			}()
+		}
+	}()
+	return M.SocksaddrFromNet(listener.Addr())
+}
+
+func TestV2RayPluginServer(t *testing.T) {
+	t.Parallel()
+	serverAddr := startV2RayPluginServer(t, "path=/ws")
+	plugin, err := CreatePlugin(context.Background(), "v2ray-plugin", "", "path=/ws", nil, N.SystemDialer, serverAddr)
+	require.NoError(t, err)
+	for i := 0; i < 2; i++ {
+		conn, err := plugin.DialContext(context.Background())
+		require.NoError(t, err)
+		requireEcho(t, conn)
+	}
+
+	_, err = CreateServerPlugin(context.Background(), log.NewNOPFactory().Logger(), "v2ray-plugin", "", "mode=quic")
+	require.Error(t, err)
+}
+
+func TestGostPluginWebsocket(t *testing.T) {
+	t.Parallel()
+	serverAddr := startV2RayPluginServer(t, "path=/gost;mux=0")
+	plugin, err := CreatePlugin(context.Background(), "gost-plugin", "", "mode=ws;path=/gost", nil, N.SystemDialer, serverAddr)
+	require.NoError(t, err)
+	conn, err := plugin.DialContext(context.Background())
+	require.NoError(t, err)
+	requireEcho(t, conn)
+}
+
+func TestGostPluginTLS(t *testing.T) {
+	t.Parallel()
+	certificate, err := sTLS.GenerateCertificate(time.Now, "example.com")
+	require.NoError(t, err)
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{*certificate}})
+	require.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				io.Copy(conn, conn)
+			}()
+		}
+	}()
+	plugin, err := CreatePlugin(context.Background(), "gost-plugin", "", "mode=tls;serverName=example.com;insecure", nil, N.SystemDialer, M.SocksaddrFromNet(listener.Addr()))
+	require.NoError(t, err)
+	conn, err := plugin.DialContext(context.Background())
+	require.NoError(t, err)
+	requireEcho(t, conn)
+}
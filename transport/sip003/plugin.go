@@ -5,7 +5,9 @@ import (
 	"net"
 
 	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing/common"
 	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/logger"
 	M "github.com/sagernet/sing/common/metadata"
 	N "github.com/sagernet/sing/common/network"
 )
@@ -16,7 +18,20 @@ type Plugin interface {
 	DialContext(ctx context.Context) (net.Conn, error)
 }
 
-var plugins map[string]PluginConstructor
+type ServerPluginConstructor func(ctx context.Context, logger logger.ContextLogger, pluginArgs Args) (ServerPlugin, error)
+
+type ServerPlugin interface {
+	// Listen listens on listenAddr with listen and returns a listener accepting plain Shadowsocks connections.
+	Listen(ctx context.Context, listenAddr M.Socksaddr, listen func(listenAddr M.Socksaddr) (net.Listener, error)) (net.Listener, error)
+}
+
+var (
+	plugins       map[string]PluginConstructor
+	serverPlugins map[string]ServerPluginConstructor
+)
+
+// externalPlugins are known plugins without a built-in implementation, they only run with plugin_path.
+var externalPlugins = []string{"kcptun"}
 
 func RegisterPlugin(name string, constructor PluginConstructor) {
 	if plugins == nil {
@@ -25,14 +40,48 @@ func RegisterPlugin(name string, constructor PluginConstructor) {
 	plugins[name] = constructor
 }
 
-func CreatePlugin(ctx context.Context, name string, pluginArgs string, router adapter.Router, dialer N.Dialer, serverAddr M.Socksaddr) (Plugin, error) {
+func RegisterServerPlugin(name string, constructor ServerPluginConstructor) {
+	if serverPlugins == nil {
+		serverPlugins = make(map[string]ServerPluginConstructor)
+	}
+	serverPlugins[name] = constructor
+}
+
+// CreatePlugin creates a built-in plugin, or runs the plugin binary at pluginPath if set.
+// Binaries are never looked up by name, so that configurations can't run arbitrary executables.
+func CreatePlugin(ctx context.Context, name string, pluginPath string, pluginArgs string, router adapter.Router, dialer N.Dialer, serverAddr M.Socksaddr) (Plugin, error) {
 	pluginOptions, err := ParsePluginOptions(pluginArgs)
 	if err != nil {
 		return nil, E.Cause(err, "parse plugin_opts")
 	}
+	if pluginPath != "" {
+		return NewExternal(pluginPath, pluginArgs, serverAddr), nil
+	}
+	if common.Contains(externalPlugins, name) {
+		return nil, E.New("plugin ", name, " is not built in, set plugin_path to the ", name, " client binary")
+	}
 	constructor, loaded := plugins[name]
 	if !loaded {
-		return nil, E.New("plugin not found: ", name)
+		return nil, E.New("plugin not found: ", name, ", set plugin_path to run it as an external plugin")
 	}
 	return constructor(ctx, pluginOptions, router, dialer, serverAddr)
 }
+
+// CreateServerPlugin creates a built-in server plugin, or runs the plugin binary at pluginPath if set.
+func CreateServerPlugin(ctx context.Context, logger logger.ContextLogger, name string, pluginPath string, pluginArgs string) (ServerPlugin, error) {
+	pluginOptions, err := ParsePluginOptions(pluginArgs)
+	if err != nil {
+		return nil, E.Cause(err, "parse plugin_opts")
+	}
+	if pluginPath != "" {
+		return NewExternalServer(logger, pluginPath, pluginArgs), nil
+	}
+	if common.Contains(externalPlugins, name) {
+		return nil, E.New("server plugin ", name, " is not built in, set plugin_path to the ", name, " server binary")
+	}
+	constructor, loaded := serverPlugins[name]
+	if !loaded {
+		return nil, E.New("server plugin not found: ", name, ", set plugin_path to run it as an external plugin")
+	}
+	return constructor(ctx, logger, pluginOptions)
+}
@@ -0,0 +1,208 @@
+package sip003
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"sync"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/tls"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing-box/transport/v2ray"
+	"github.com/sagernet/sing-vmess"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/logger"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+)
+
+func init() {
+	RegisterServerPlugin("v2ray-plugin", newV2RayPluginServer)
+}
+
+var _ ServerPlugin = (*V2RayPluginServer)(nil)
+
+// V2RayPluginServer is the server mode of v2ray-plugin, only the websocket mode is supported.
+type V2RayPluginServer struct {
+	ctx              context.Context
+	logger           logger.ContextLogger
+	transportOptions option.V2RayTransportOptions
+	tlsOptions       option.InboundTLSOptions
+	mux              bool
+}
+
+func newV2RayPluginServer(ctx context.Context, logger logger.ContextLogger, pluginOpts Args) (ServerPlugin, error) {
+	var tlsOptions option.InboundTLSOptions
+	if _, loaded := pluginOpts.Get("tls"); loaded {
+		tlsOptions.Enabled = true
+	}
+	if certPath, loaded := pluginOpts.Get("cert"); loaded {
+		tlsOptions.CertificatePath = certPath
+	}
+	if keyPath, loaded := pluginOpts.Get("key"); loaded {
+		tlsOptions.KeyPath = keyPath
+	}
+	if host, loaded := pluginOpts.Get("host"); loaded {
+		tlsOptions.ServerName = host
+	}
+	if modeOpt, loaded := pluginOpts.Get("mode"); loaded && modeOpt != "websocket" {
+		return nil, E.New("v2ray-plugin: unsupported server mode: " + modeOpt)
+	}
+	path := "/"
+	if pathOpt, loaded := pluginOpts.Get("path"); loaded {
+		path = pathOpt
+	}
+	mux := true
+	if muxOpt, loaded := pluginOpts.Get("mux"); loaded {
+		muxVal, err := strconv.Atoi(muxOpt)
+		if err != nil {
+			return nil, E.Cause(err, "parse mux value")
+		}
+		mux = muxVal > 0
+	}
+	return &V2RayPluginServer{
+		ctx:    ctx,
+		logger: logger,
+		transportOptions: option.V2RayTransportOptions{
+			Type: C.V2RayTransportTypeWebsocket,
+			WebsocketOptions: option.V2RayWebsocketOptions{
+				Path: path,
+			},
+		},
+		tlsOptions: tlsOptions,
+		mux:        mux,
+	}, nil
+}
+
+func (s *V2RayPluginServer) Listen(ctx context.Context, listenAddr M.Socksaddr, listen func(listenAddr M.Socksaddr) (net.Listener, error)) (net.Listener, error) {
+	var tlsConfig tls.ServerConfig
+	if s.tlsOptions.Enabled {
+		var err error
+		tlsConfig, err = tls.NewServer(s.ctx, s.logger, s.tlsOptions)
+		if err != nil {
+			return nil, err
+		}
+		err = tlsConfig.Start()
+		if err != nil {
+			return nil, err
+		}
+	}
+	listener, err := listen(listenAddr)
+	if err != nil {
+		common.Close(tlsConfig)
+		return nil, err
+	}
+	pluginListener := newPluginListener(listener.Addr())
+	transport, err := v2ray.NewServerTransport(s.ctx, s.transportOptions, tlsConfig, &v2rayPluginServerHandler{
+		ctx:      s.ctx,
+		logger:   s.logger,
+		mux:      s.mux,
+		listener: pluginListener,
+	})
+	if err != nil {
+		common.Close(listener, tlsConfig)
+		return nil, err
+	}
+	pluginListener.closers = []any{transport, listener, tlsConfig}
+	go func() {
+		sErr := transport.Serve(listener)
+		if sErr != nil && !E.IsClosed(sErr) {
+			s.logger.Error("v2ray-plugin serve error: ", sErr)
+		}
+	}()
+	return pluginListener, nil
+}
+
+type v2rayPluginServerHandler struct {
+	ctx      context.Context
+	logger   logger.ContextLogger
+	mux      bool
+	listener *pluginListener
+}
+
+func (h *v2rayPluginServerHandler) NewConnection(ctx context.Context, conn net.Conn, metadata M.Metadata) error {
+	if !h.mux {
+		return h.listener.inject(conn)
+	}
+	defer conn.Close()
+	return vmess.HandleMuxConnection(h.ctx, conn, (*v2rayPluginMuxHandler)(h))
+}
+
+func (h *v2rayPluginServerHandler) NewError(ctx context.Context, err error) {
+	if E.IsClosedOrCanceled(err) {
+		h.logger.DebugContext(ctx, err)
+		return
+	}
+	h.logger.ErrorContext(ctx, err)
+}
+
+var _ adapter.V2RayServerTransportHandler = (*v2rayPluginServerHandler)(nil)
+
+var _ vmess.Handler = (*v2rayPluginMuxHandler)(nil)
+
+// v2rayPluginMuxHandler handles streams of a multiplexed v2ray-plugin connection.
+type v2rayPluginMuxHandler v2rayPluginServerHandler
+
+func (h *v2rayPluginMuxHandler) NewConnection(ctx context.Context, conn net.Conn, metadata M.Metadata) error {
+	return h.listener.inject(conn)
+}
+
+func (h *v2rayPluginMuxHandler) NewPacketConnection(ctx context.Context, conn N.PacketConn, metadata M.Metadata) error {
+	return E.New("v2ray-plugin: UDP unsupported")
+}
+
+func (h *v2rayPluginMuxHandler) NewError(ctx context.Context, err error) {
+	(*v2rayPluginServerHandler)(h).NewError(ctx, err)
+}
+
+// pluginListener accepts plain connections decoded by a built-in server plugin.
+type pluginListener struct {
+	addr      net.Addr
+	conns     chan net.Conn
+	done      chan struct{}
+	closeOnce sync.Once
+	closers   []any
+}
+
+func newPluginListener(addr net.Addr) *pluginListener {
+	return &pluginListener{
+		addr:  addr,
+		conns: make(chan net.Conn),
+		done:  make(chan struct{}),
+	}
+}
+
+func (l *pluginListener) inject(conn net.Conn) error {
+	select {
+	case l.conns <- conn:
+		return nil
+	case <-l.done:
+		conn.Close()
+		return net.ErrClosed
+	}
+}
+
+func (l *pluginListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.done:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *pluginListener) Close() error {
+	var err error
+	l.closeOnce.Do(func() {
+		close(l.done)
+		err = common.Close(l.closers...)
+	})
+	return err
+}
+
+func (l *pluginListener) Addr() net.Addr {
+	return l.addr
+}
@@ -40,6 +40,9 @@ func NewClient(ctx context.Context, dialer N.Dialer, serverAddr M.Socksaddr, opt
 			tlsConfig.SetNextProtos([]string{http2.NextProtoTLS})
 		}
 		dialOptions = append(dialOptions, grpc.WithTransportCredentials(NewTLSTransportCredentials(tlsConfig)))
+		if frontingHost := tls.FrontingHost(tlsConfig); frontingHost != "" {
+			dialOptions = append(dialOptions, grpc.WithAuthority(M.ParseSocksaddrHostPort(frontingHost, serverAddr.Port).String()))
+		}
 	} else {
 		dialOptions = append(dialOptions, grpc.WithTransportCredentials(insecure.NewCredentials()))
 	}
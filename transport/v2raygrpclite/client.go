@@ -39,7 +39,9 @@ type Client struct {
 
 func NewClient(ctx context.Context, dialer N.Dialer, serverAddr M.Socksaddr, options option.V2RayGRPCOptions, tlsConfig tls.Config) adapter.V2RayClientTransport {
 	var host string
-	if tlsConfig != nil && tlsConfig.ServerName() != "" {
+	if frontingHost := tls.FrontingHost(tlsConfig); frontingHost != "" {
+		host = M.ParseSocksaddrHostPort(frontingHost, serverAddr.Port).String()
+	} else if tlsConfig != nil && tlsConfig.ServerName() != "" {
 		host = M.ParseSocksaddrHostPort(tlsConfig.ServerName(), serverAddr.Port).String()
 	} else {
 		host = serverAddr.String()
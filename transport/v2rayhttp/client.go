@@ -59,6 +59,12 @@ func NewClient(ctx context.Context, dialer N.Dialer, serverAddr M.Socksaddr, opt
 			},
 		}
 	}
+	host := options.Host
+	if len(host) == 0 {
+		if frontingHost := tls.FrontingHost(tlsConfig); frontingHost != "" {
+			host = []string{frontingHost}
+		}
+	}
 	if options.Method == "" {
 		options.Method = http.MethodPut
 	}
@@ -90,7 +96,7 @@ func NewClient(ctx context.Context, dialer N.Dialer, serverAddr M.Socksaddr, opt
 		dialer:     dialer,
 		serverAddr: serverAddr,
 		requestURL: requestURL,
-		host:       options.Host,
+		host:       host,
 		method:     options.Method,
 		headers:    headers,
 		transport:  transport,
@@ -39,6 +39,8 @@ func NewClient(ctx context.Context, dialer N.Dialer, serverAddr M.Socksaddr, opt
 	var host string
 	if options.Host != "" {
 		host = options.Host
+	} else if frontingHost := tls.FrontingHost(tlsConfig); frontingHost != "" {
+		host = frontingHost
 	} else if tlsConfig != nil && tlsConfig.ServerName() != "" {
 		host = tlsConfig.ServerName()
 	} else {
@@ -11,6 +11,7 @@ import (
 	"github.com/sagernet/quic-go/http3"
 	"github.com/sagernet/sing-box/adapter"
 	"github.com/sagernet/sing-box/common/tls"
+	"github.com/sagernet/sing-box/common/udpobfs"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/option"
 	"github.com/sagernet/sing-quic"
@@ -40,6 +41,13 @@ func NewClient(ctx context.Context, dialer N.Dialer, serverAddr M.Socksaddr, opt
 	if len(tlsConfig.NextProtos()) == 0 {
 		tlsConfig.SetNextProtos([]string{http3.NextProtoH3})
 	}
+	if options.UDPObfs != nil {
+		obfuscator, err := udpobfs.NewObfuscator(*options.UDPObfs)
+		if err != nil {
+			return nil, err
+		}
+		dialer = udpobfs.NewDialer(dialer, obfuscator)
+	}
 	return &Client{
 		ctx:        ctx,
 		dialer:     dialer,
@@ -11,6 +11,7 @@ import (
 	"github.com/sagernet/quic-go/http3"
 	"github.com/sagernet/sing-box/adapter"
 	"github.com/sagernet/sing-box/common/tls"
+	"github.com/sagernet/sing-box/common/udpobfs"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/option"
 	"github.com/sagernet/sing-quic"
@@ -28,6 +29,7 @@ type Server struct {
 	handler      adapter.V2RayServerTransportHandler
 	udpListener  net.PacketConn
 	quicListener qtls.Listener
+	udpObfs      *udpobfs.Obfuscator
 }
 
 func NewServer(ctx context.Context, options option.V2RayQUICOptions, tlsConfig tls.ServerConfig, handler adapter.V2RayServerTransportHandler) (adapter.V2RayServerTransport, error) {
@@ -43,6 +45,13 @@ func NewServer(ctx context.Context, options option.V2RayQUICOptions, tlsConfig t
 		quicConfig: quicConfig,
 		handler:    handler,
 	}
+	if options.UDPObfs != nil {
+		var err error
+		server.udpObfs, err = udpobfs.NewObfuscator(*options.UDPObfs)
+		if err != nil {
+			return nil, err
+		}
+	}
 	return server, nil
 }
 
@@ -55,6 +64,9 @@ func (s *Server) Serve(listener net.Listener) error {
 }
 
 func (s *Server) ServePacket(listener net.PacketConn) error {
+	if s.udpObfs != nil {
+		listener = udpobfs.NewPacketConn(listener, s.udpObfs)
+	}
 	quicListener, err := qtls.Listen(listener, s.tlsConfig, s.quicConfig)
 	if err != nil {
 		return err
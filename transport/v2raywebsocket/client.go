@@ -59,6 +59,8 @@ func NewClient(ctx context.Context, dialer N.Dialer, serverAddr M.Socksaddr, opt
 	if host := headers.Get("Host"); host != "" {
 		headers.Del("Host")
 		requestURL.Host = host
+	} else if frontingHost := tls.FrontingHost(tlsConfig); frontingHost != "" {
+		requestURL.Host = M.ParseSocksaddrHostPort(frontingHost, serverAddr.Port).String()
 	}
 	if headers.Get("User-Agent") == "" {
 		headers.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/122.0.0.0 Safari/537.36")
@@ -0,0 +1,213 @@
+package wireguard
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	mRand "math/rand"
+	"net"
+
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/bufio"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+	"github.com/sagernet/wireguard-go/device"
+)
+
+const (
+	amneziaMaxJunkCount  = 128
+	amneziaMaxPacketSize = 1280
+)
+
+var errAmneziaInvalidPacket = E.New("invalid AmneziaWG packet")
+
+// AmneziaObfuscator implements the AmneziaWG packet format: junk packets sent before handshake initiations,
+// random bytes prepended to handshake messages, and magic headers replacing the message types.
+type AmneziaObfuscator struct {
+	junkCount        int
+	junkMinSize      int
+	junkMaxSize      int
+	initJunkSize     int
+	responseJunkSize int
+	headers          [4]uint32
+}
+
+func NewAmneziaObfuscator(options option.WireGuardAmneziaOptions) (*AmneziaObfuscator, error) {
+	obfuscator := &AmneziaObfuscator{
+		junkCount:        options.JunkPacketCount,
+		junkMinSize:      options.JunkPacketMinSize,
+		junkMaxSize:      options.JunkPacketMaxSize,
+		initJunkSize:     options.InitPacketJunkSize,
+		responseJunkSize: options.ResponsePacketJunkSize,
+		headers: [4]uint32{
+			options.InitPacketMagicHeader,
+			options.ResponsePacketMagicHeader,
+			options.UnderloadPacketMagicHeader,
+			options.TransportPacketMagicHeader,
+		},
+	}
+	if obfuscator.junkCount < 0 || obfuscator.junkCount > amneziaMaxJunkCount {
+		return nil, E.New("junk_packet_count must be between 0 and ", amneziaMaxJunkCount)
+	}
+	if obfuscator.junkCount > 0 {
+		if obfuscator.junkMinSize < 0 || obfuscator.junkMinSize > obfuscator.junkMaxSize || obfuscator.junkMaxSize > amneziaMaxPacketSize {
+			return nil, E.New("junk packet size must be 0 <= junk_packet_min_size <= junk_packet_max_size <= ", amneziaMaxPacketSize)
+		}
+	}
+	if obfuscator.initJunkSize < 0 || obfuscator.initJunkSize > amneziaMaxPacketSize-device.MessageInitiationSize {
+		return nil, E.New("init_packet_junk_size must be between 0 and ", amneziaMaxPacketSize-device.MessageInitiationSize)
+	}
+	if obfuscator.responseJunkSize < 0 || obfuscator.responseJunkSize > amneziaMaxPacketSize-device.MessageResponseSize {
+		return nil, E.New("response_packet_junk_size must be between 0 and ", amneziaMaxPacketSize-device.MessageResponseSize)
+	}
+	if obfuscator.initJunkSize+device.MessageInitiationSize == obfuscator.responseJunkSize+device.MessageResponseSize {
+		return nil, E.New("init and response packets must have different sizes")
+	}
+	for i := range obfuscator.headers {
+		if obfuscator.headers[i] == 0 {
+			obfuscator.headers[i] = uint32(i + 1)
+		}
+	}
+	if len(common.Uniq(obfuscator.headers[:])) != len(obfuscator.headers) {
+		return nil, E.New("magic headers must be different")
+	}
+	return obfuscator, nil
+}
+
+// Encode returns the obfuscated packet, in a new slice for handshake messages
+// and by replacing the header in place for others.
+func (o *AmneziaObfuscator) Encode(packet []byte) []byte {
+	if len(packet) < 4 {
+		return packet
+	}
+	// only the first byte is the message type, the others may carry reserved bytes
+	messageType := uint32(packet[0])
+	switch {
+	case messageType == device.MessageInitiationType && len(packet) == device.MessageInitiationSize:
+		return o.encodeHandshake(packet, o.initJunkSize, o.headers[0])
+	case messageType == device.MessageResponseType && len(packet) == device.MessageResponseSize:
+		return o.encodeHandshake(packet, o.responseJunkSize, o.headers[1])
+	case messageType == device.MessageCookieReplyType && len(packet) == device.MessageCookieReplySize:
+		binary.LittleEndian.PutUint32(packet, o.headers[2])
+	case messageType == device.MessageTransportType && len(packet) >= device.MessageTransportSize:
+		binary.LittleEndian.PutUint32(packet, o.headers[3])
+	}
+	return packet
+}
+
+func (o *AmneziaObfuscator) encodeHandshake(packet []byte, junkSize int, header uint32) []byte {
+	encoded := make([]byte, junkSize+len(packet))
+	rand.Read(encoded[:junkSize])
+	copy(encoded[junkSize:], packet)
+	binary.LittleEndian.PutUint32(encoded[junkSize:], header)
+	return encoded
+}
+
+// Decode restores the message type of the packet in place and returns the message.
+func (o *AmneziaObfuscator) Decode(packet []byte) ([]byte, error) {
+	switch {
+	case o.matchHeader(packet, o.initJunkSize, device.MessageInitiationSize, 0):
+		packet = packet[o.initJunkSize:]
+	case o.matchHeader(packet, o.responseJunkSize, device.MessageResponseSize, 1):
+		packet = packet[o.responseJunkSize:]
+	case o.matchHeader(packet, 0, device.MessageCookieReplySize, 2):
+	case len(packet) >= device.MessageTransportSize && binary.LittleEndian.Uint32(packet) == o.headers[3]:
+		binary.LittleEndian.PutUint32(packet, device.MessageTransportType)
+		return packet, nil
+	default:
+		return nil, errAmneziaInvalidPacket
+	}
+	binary.LittleEndian.PutUint32(packet, uint32(device.MessageInitiationType+common.Index(o.headers[:], func(it uint32) bool {
+		return it == binary.LittleEndian.Uint32(packet)
+	})))
+	return packet, nil
+}
+
+func (o *AmneziaObfuscator) matchHeader(packet []byte, junkSize int, messageSize int, index int) bool {
+	return len(packet) == junkSize+messageSize && binary.LittleEndian.Uint32(packet[junkSize:]) == o.headers[index]
+}
+
+// JunkPackets returns the random packets sent before a handshake initiation.
+func (o *AmneziaObfuscator) JunkPackets() [][]byte {
+	packets := make([][]byte, o.junkCount)
+	for i := range packets {
+		packets[i] = make([]byte, o.junkMinSize+mRand.Intn(o.junkMaxSize-o.junkMinSize+1))
+		rand.Read(packets[i])
+	}
+	return packets
+}
+
+// AmneziaPacketConn obfuscates packets written to and deobfuscates packets read from the upstream,
+// invalid packets are dropped.
+type AmneziaPacketConn struct {
+	net.PacketConn
+	obfuscator *AmneziaObfuscator
+}
+
+func NewAmneziaPacketConn(conn net.PacketConn, obfuscator *AmneziaObfuscator) *AmneziaPacketConn {
+	return &AmneziaPacketConn{conn, obfuscator}
+}
+
+func (c *AmneziaPacketConn) ReadFrom(p []byte) (n int, addr net.Addr, err error) {
+	for {
+		n, addr, err = c.PacketConn.ReadFrom(p)
+		if err != nil {
+			return
+		}
+		packet, decodeErr := c.obfuscator.Decode(p[:n])
+		if decodeErr != nil {
+			continue
+		}
+		return copy(p, packet), addr, nil
+	}
+}
+
+func (c *AmneziaPacketConn) WriteTo(p []byte, addr net.Addr) (n int, err error) {
+	if len(p) == device.MessageInitiationSize && p[0] == device.MessageInitiationType {
+		for _, junkPacket := range c.obfuscator.JunkPackets() {
+			_, err = c.PacketConn.WriteTo(junkPacket, addr)
+			if err != nil {
+				return
+			}
+		}
+	}
+	_, err = c.PacketConn.WriteTo(c.obfuscator.Encode(p), addr)
+	if err != nil {
+		return
+	}
+	return len(p), nil
+}
+
+func (c *AmneziaPacketConn) Upstream() any {
+	return c.PacketConn
+}
+
+var _ N.Dialer = (*AmneziaDialer)(nil)
+
+// AmneziaDialer obfuscates UDP connections of the WireGuard bind.
+type AmneziaDialer struct {
+	N.Dialer
+	obfuscator *AmneziaObfuscator
+}
+
+func NewAmneziaDialer(dialer N.Dialer, obfuscator *AmneziaObfuscator) *AmneziaDialer {
+	return &AmneziaDialer{dialer, obfuscator}
+}
+
+func (d *AmneziaDialer) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
+	conn, err := d.Dialer.DialContext(ctx, network, destination)
+	if err != nil || N.NetworkName(network) != N.NetworkUDP {
+		return conn, err
+	}
+	return bufio.NewBindPacketConn(NewAmneziaPacketConn(bufio.NewUnbindPacketConn(conn), d.obfuscator), conn.RemoteAddr()), nil
+}
+
+func (d *AmneziaDialer) ListenPacket(ctx context.Context, destination M.Socksaddr) (net.PacketConn, error) {
+	conn, err := d.Dialer.ListenPacket(ctx, destination)
+	if err != nil {
+		return nil, err
+	}
+	return NewAmneziaPacketConn(conn, d.obfuscator), nil
+}
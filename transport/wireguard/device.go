@@ -1,6 +1,9 @@
 package wireguard
 
 import (
+	"context"
+	"time"
+
 	N "github.com/sagernet/sing/common/network"
 	"github.com/sagernet/wireguard-go/tun"
 )
@@ -11,3 +14,14 @@ type Device interface {
 	Start() error
 	// NewEndpoint() (stack.LinkEndpoint, error)
 }
+
+// ServerDevice accepts connections from peers to any destination.
+type ServerDevice interface {
+	Device
+	SetHandler(ctx context.Context, handler Handler, udpTimeout time.Duration) error
+}
+
+type Handler interface {
+	N.TCPConnectionHandler
+	N.UDPConnectionHandler
+}
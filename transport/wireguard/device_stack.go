@@ -43,10 +43,14 @@ type StackDevice struct {
 }
 
 func NewStackDevice(localAddresses []netip.Prefix, mtu uint32) (*StackDevice, error) {
+	return newStackDevice(localAddresses, mtu, true)
+}
+
+func newStackDevice(localAddresses []netip.Prefix, mtu uint32, handleLocal bool) (*StackDevice, error) {
 	ipStack := stack.New(stack.Options{
 		NetworkProtocols:   []stack.NetworkProtocolFactory{ipv4.NewProtocol, ipv6.NewProtocol},
 		TransportProtocols: []stack.TransportProtocolFactory{tcp.NewProtocol, udp.NewProtocol, icmp.NewProtocol4, icmp.NewProtocol6},
-		HandleLocal:        true,
+		HandleLocal:        handleLocal,
 	})
 	tunDevice := &StackDevice{
 		stack:          ipStack,
@@ -0,0 +1,92 @@
+//go:build with_gvisor
+
+package wireguard
+
+import (
+	"context"
+	"net/netip"
+	"time"
+
+	"github.com/sagernet/gvisor/pkg/tcpip/adapters/gonet"
+	"github.com/sagernet/gvisor/pkg/tcpip/transport/tcp"
+	"github.com/sagernet/gvisor/pkg/tcpip/transport/udp"
+	"github.com/sagernet/gvisor/pkg/waiter"
+	"github.com/sagernet/sing/common/bufio"
+	"github.com/sagernet/sing/common/canceler"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+)
+
+var _ ServerDevice = (*StackDevice)(nil)
+
+// NewStackServerDevice creates a device accepting connections to any destination,
+// local handling is disabled since all addresses are local in promiscuous mode.
+func NewStackServerDevice(localAddresses []netip.Prefix, mtu uint32) (*StackDevice, error) {
+	tunDevice, err := newStackDevice(localAddresses, mtu, false)
+	if err != nil {
+		return nil, err
+	}
+	if tErr := tunDevice.stack.SetPromiscuousMode(defaultNIC, true); tErr != nil {
+		return nil, E.New(tErr.String())
+	}
+	if tErr := tunDevice.stack.SetSpoofing(defaultNIC, true); tErr != nil {
+		return nil, E.New(tErr.String())
+	}
+	return tunDevice, nil
+}
+
+func (w *StackDevice) SetHandler(ctx context.Context, handler Handler, udpTimeout time.Duration) error {
+	tcpForwarder := tcp.NewForwarder(w.stack, 0, 1024, func(r *tcp.ForwarderRequest) {
+		var wq waiter.Queue
+		endpoint, err := r.CreateEndpoint(&wq)
+		if err != nil {
+			r.Complete(true)
+			return
+		}
+		r.Complete(false)
+		endpoint.SocketOptions().SetKeepAlive(true)
+		tcpConn := gonet.NewTCPConn(&wq, endpoint)
+		lAddr := tcpConn.RemoteAddr()
+		rAddr := tcpConn.LocalAddr()
+		if lAddr == nil || rAddr == nil {
+			tcpConn.Close()
+			return
+		}
+		go func() {
+			var metadata M.Metadata
+			metadata.Source = M.SocksaddrFromNet(lAddr)
+			metadata.Destination = M.SocksaddrFromNet(rAddr)
+			hErr := handler.NewConnection(ctx, tcpConn, metadata)
+			if hErr != nil {
+				endpoint.Abort()
+			}
+		}()
+	})
+	w.stack.SetTransportProtocolHandler(tcp.ProtocolNumber, tcpForwarder.HandlePacket)
+	udpForwarder := udp.NewForwarder(w.stack, func(request *udp.ForwarderRequest) {
+		var wq waiter.Queue
+		endpoint, err := request.CreateEndpoint(&wq)
+		if err != nil {
+			return
+		}
+		udpConn := gonet.NewUDPConn(&wq, endpoint)
+		lAddr := udpConn.RemoteAddr()
+		rAddr := udpConn.LocalAddr()
+		if lAddr == nil || rAddr == nil {
+			endpoint.Abort()
+			return
+		}
+		go func() {
+			var metadata M.Metadata
+			metadata.Source = M.SocksaddrFromNet(lAddr)
+			metadata.Destination = M.SocksaddrFromNet(rAddr)
+			packetCtx, conn := canceler.NewPacketConn(ctx, bufio.NewUnbindPacketConnWithAddr(udpConn, metadata.Destination), udpTimeout)
+			hErr := handler.NewPacketConnection(packetCtx, conn, metadata)
+			if hErr != nil {
+				endpoint.Abort()
+			}
+		}()
+	})
+	w.stack.SetTransportProtocolHandler(udp.ProtocolNumber, udpForwarder.HandlePacket)
+	return nil
+}
@@ -11,3 +11,7 @@ import (
 func NewStackDevice(localAddresses []netip.Prefix, mtu uint32) (Device, error) {
 	return nil, tun.ErrGVisorNotIncluded
 }
+
+func NewStackServerDevice(localAddresses []netip.Prefix, mtu uint32) (ServerDevice, error) {
+	return nil, tun.ErrGVisorNotIncluded
+}
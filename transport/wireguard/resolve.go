@@ -15,6 +15,7 @@ import (
 
 type PeerConfig struct {
 	destination    M.Socksaddr
+	destinations   []M.Socksaddr
 	domainStrategy dns.DomainStrategy
 	Endpoint       netip.AddrPort
 	PublicKey      string
@@ -25,7 +26,9 @@ type PeerConfig struct {
 
 func (c PeerConfig) GenerateIpcLines() string {
 	ipcLines := "\npublic_key=" + c.PublicKey
-	ipcLines += "\nendpoint=" + c.Endpoint.String()
+	if c.Endpoint.IsValid() {
+		ipcLines += "\nendpoint=" + c.Endpoint.String()
+	}
 	if c.PreSharedKey != "" {
 		ipcLines += "\npreshared_key=" + c.PreSharedKey
 	}
@@ -42,13 +45,11 @@ func ParsePeers(options option.WireGuardOutboundOptions) ([]PeerConfig, error) {
 			peer := PeerConfig{
 				AllowedIPs: rawPeer.AllowedIPs,
 			}
-			destination := rawPeer.ServerOptions.Build()
-			if destination.IsFqdn() {
-				peer.destination = destination
-				peer.domainStrategy = dns.DomainStrategy(options.DomainStrategy)
-			} else {
-				peer.Endpoint = destination.AddrPort()
+			destinations, err := parseDestinations(rawPeer.ServerOptions, rawPeer.Endpoints)
+			if err != nil {
+				return nil, E.Cause(err, "parse endpoints for peer ", peerIndex)
 			}
+			peer.setDestinations(destinations, dns.DomainStrategy(options.DomainStrategy))
 			{
 				bytes, err := base64.StdEncoding.DecodeString(rawPeer.PublicKey)
 				if err != nil {
@@ -93,13 +94,11 @@ func ParsePeers(options option.WireGuardOutboundOptions) ([]PeerConfig, error) {
 		if addressHas6 {
 			peer.AllowedIPs = append(peer.AllowedIPs, netip.PrefixFrom(netip.IPv6Unspecified(), 0).String())
 		}
-		destination := options.ServerOptions.Build()
-		if destination.IsFqdn() {
-			peer.destination = destination
-			peer.domainStrategy = dns.DomainStrategy(options.DomainStrategy)
-		} else {
-			peer.Endpoint = destination.AddrPort()
+		destinations, err := parseDestinations(options.ServerOptions, options.Endpoints)
+		if err != nil {
+			return nil, E.Cause(err, "parse endpoints")
 		}
+		peer.setDestinations(destinations, dns.DomainStrategy(options.DomainStrategy))
 		{
 			bytes, err := base64.StdEncoding.DecodeString(options.PeerPublicKey)
 			if err != nil {
@@ -125,6 +124,83 @@ func ParsePeers(options option.WireGuardOutboundOptions) ([]PeerConfig, error) {
 	return peers, nil
 }
 
+// parseDestinations returns the server followed by the fallback endpoints.
+func parseDestinations(server option.ServerOptions, endpoints []string) ([]M.Socksaddr, error) {
+	var destinations []M.Socksaddr
+	if server.Server != "" || len(endpoints) == 0 {
+		destinations = append(destinations, server.Build())
+	}
+	for _, endpoint := range endpoints {
+		destination := M.ParseSocksaddr(endpoint)
+		if !destination.IsValid() || destination.Port == 0 {
+			return nil, E.New("invalid endpoint: ", endpoint)
+		}
+		destinations = append(destinations, destination)
+	}
+	return destinations, nil
+}
+
+func (c *PeerConfig) setDestinations(destinations []M.Socksaddr, domainStrategy dns.DomainStrategy) {
+	c.destinations = destinations
+	c.domainStrategy = domainStrategy
+	if destinations[0].IsFqdn() {
+		c.destination = destinations[0]
+	} else {
+		c.Endpoint = destinations[0].AddrPort()
+	}
+}
+
+// EndpointCount returns the number of endpoints to fail over between.
+func (c PeerConfig) EndpointCount() int {
+	return len(c.destinations)
+}
+
+// ResolveEndpoint resolves the endpoint at index, the first one is the server.
+func (c PeerConfig) ResolveEndpoint(ctx context.Context, router adapter.Router, index int) (netip.AddrPort, error) {
+	destination := c.destinations[index]
+	if !destination.IsFqdn() {
+		return destination.AddrPort(), nil
+	}
+	destinationAddresses, err := router.Lookup(ctx, destination.Fqdn, c.domainStrategy)
+	if err != nil {
+		return netip.AddrPort{}, E.Cause(err, "resolve endpoint domain")
+	}
+	if len(destinationAddresses) == 0 {
+		return netip.AddrPort{}, E.New("no addresses found for endpoint domain: ", destination.Fqdn)
+	}
+	return netip.AddrPortFrom(destinationAddresses[0], destination.Port), nil
+}
+
+// ParseServerPeer parses a peer connecting to a server, the endpoint is learned from its packets if empty.
+func ParseServerPeer(rawPeer option.WireGuardPeer) (PeerConfig, error) {
+	peer := PeerConfig{
+		AllowedIPs: rawPeer.AllowedIPs,
+	}
+	if rawPeer.Server != "" {
+		destination := rawPeer.ServerOptions.Build()
+		if !destination.IsIP() {
+			return PeerConfig{}, E.New("peer endpoint must be an IP address")
+		}
+		peer.Endpoint = destination.AddrPort()
+	}
+	bytes, err := base64.StdEncoding.DecodeString(rawPeer.PublicKey)
+	if err != nil {
+		return PeerConfig{}, E.Cause(err, "decode public key")
+	}
+	peer.PublicKey = hex.EncodeToString(bytes)
+	if rawPeer.PreSharedKey != "" {
+		bytes, err = base64.StdEncoding.DecodeString(rawPeer.PreSharedKey)
+		if err != nil {
+			return PeerConfig{}, E.Cause(err, "decode pre shared key")
+		}
+		peer.PreSharedKey = hex.EncodeToString(bytes)
+	}
+	if len(rawPeer.AllowedIPs) == 0 {
+		return PeerConfig{}, E.New("missing allowed_ips")
+	}
+	return peer, nil
+}
+
 func ResolvePeers(ctx context.Context, router adapter.Router, peers []PeerConfig) error {
 	for peerIndex, peer := range peers {
 		if peer.Endpoint.IsValid() {
@@ -0,0 +1,52 @@
+package wireguard
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+
+	"github.com/sagernet/sing-box/option"
+	M "github.com/sagernet/sing/common/metadata"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDestinations(t *testing.T) {
+	t.Parallel()
+	destinations, err := parseDestinations(option.ServerOptions{Server: "example.com", ServerPort: 51820}, []string{"192.0.2.1:51821", "[2001:db8::1]:51822"})
+	require.NoError(t, err)
+	require.Equal(t, []M.Socksaddr{
+		M.ParseSocksaddrHostPort("example.com", 51820),
+		M.ParseSocksaddr("192.0.2.1:51821"),
+		M.ParseSocksaddr("[2001:db8::1]:51822"),
+	}, destinations)
+
+	destinations, err = parseDestinations(option.ServerOptions{}, []string{"192.0.2.1:51821"})
+	require.NoError(t, err)
+	require.Equal(t, []M.Socksaddr{M.ParseSocksaddr("192.0.2.1:51821")}, destinations)
+
+	_, err = parseDestinations(option.ServerOptions{}, []string{"192.0.2.1"})
+	require.Error(t, err)
+	_, err = parseDestinations(option.ServerOptions{}, []string{"invalid endpoint"})
+	require.Error(t, err)
+}
+
+func TestPeerEndpoints(t *testing.T) {
+	t.Parallel()
+	var peer PeerConfig
+	destinations, err := parseDestinations(option.ServerOptions{Server: "192.0.2.1", ServerPort: 51820}, []string{"192.0.2.2:51820"})
+	require.NoError(t, err)
+	peer.setDestinations(destinations, 0)
+	require.Equal(t, netip.MustParseAddrPort("192.0.2.1:51820"), peer.Endpoint)
+	require.Equal(t, 2, peer.EndpointCount())
+	endpoint, err := peer.ResolveEndpoint(context.Background(), nil, 1)
+	require.NoError(t, err)
+	require.Equal(t, netip.MustParseAddrPort("192.0.2.2:51820"), endpoint)
+
+	peer = PeerConfig{}
+	destinations, err = parseDestinations(option.ServerOptions{Server: "example.com", ServerPort: 51820}, nil)
+	require.NoError(t, err)
+	peer.setDestinations(destinations, 0)
+	require.False(t, peer.Endpoint.IsValid())
+	require.Equal(t, "example.com", peer.destination.Fqdn)
+}
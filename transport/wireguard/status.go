@@ -0,0 +1,65 @@
+package wireguard
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+// ParsePeerStatus parses peers from the output of the UAPI get operation.
+func ParsePeerStatus(ipcContent string) ([]adapter.PeerStatus, error) {
+	var (
+		peers         []adapter.PeerStatus
+		handshakeSec  int64
+		handshakeNsec int64
+	)
+	flushHandshake := func() {
+		if len(peers) > 0 && (handshakeSec != 0 || handshakeNsec != 0) {
+			peers[len(peers)-1].LastHandshake = time.Unix(handshakeSec, handshakeNsec)
+		}
+		handshakeSec, handshakeNsec = 0, 0
+	}
+	for _, line := range strings.Split(ipcContent, "\n") {
+		key, value, found := strings.Cut(line, "=")
+		if !found {
+			continue
+		}
+		if key == "public_key" {
+			flushHandshake()
+			publicKey, err := hex.DecodeString(value)
+			if err != nil {
+				return nil, E.Cause(err, "decode public key")
+			}
+			peers = append(peers, adapter.PeerStatus{PublicKey: base64.StdEncoding.EncodeToString(publicKey)})
+			continue
+		}
+		if len(peers) == 0 {
+			continue
+		}
+		peer := &peers[len(peers)-1]
+		switch key {
+		case "endpoint":
+			if endpoint, err := netip.ParseAddrPort(value); err == nil {
+				peer.Endpoint = netip.AddrPortFrom(endpoint.Addr().Unmap(), endpoint.Port()).String()
+			} else {
+				peer.Endpoint = value
+			}
+		case "last_handshake_time_sec":
+			handshakeSec, _ = strconv.ParseInt(value, 10, 64)
+		case "last_handshake_time_nsec":
+			handshakeNsec, _ = strconv.ParseInt(value, 10, 64)
+		case "tx_bytes":
+			peer.Upload, _ = strconv.ParseUint(value, 10, 64)
+		case "rx_bytes":
+			peer.Download, _ = strconv.ParseUint(value, 10, 64)
+		}
+	}
+	flushHandshake()
+	return peers, nil
+}
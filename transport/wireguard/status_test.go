@@ -0,0 +1,54 @@
+package wireguard
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePeerStatus(t *testing.T) {
+	t.Parallel()
+	peers, err := ParsePeerStatus(`private_key=e84b5a6d2717c1003a13b431570353dbaca9146cf150c5f8575680feba52027a
+listen_port=51820
+public_key=b85996fecc9c7f1fc6d2572a76eda11d59bcd20be8e543b15ce4bd85a8e75a33
+preshared_key=0000000000000000000000000000000000000000000000000000000000000000
+protocol_version=1
+endpoint=[::ffff:192.0.2.1]:51820
+last_handshake_time_sec=1700000000
+last_handshake_time_nsec=500
+tx_bytes=38333
+rx_bytes=2224
+persistent_keepalive_interval=0
+allowed_ip=0.0.0.0/0
+public_key=58402e695ba1772b1cc9309755f043251ea77fdcf10fbe63989ceb7e19321376
+endpoint=[2001:db8::1]:51820
+last_handshake_time_sec=0
+last_handshake_time_nsec=0
+tx_bytes=148
+rx_bytes=0
+errno=0
+`)
+	require.NoError(t, err)
+	require.Equal(t, []adapter.PeerStatus{
+		{
+			PublicKey:     "uFmW/sycfx/G0lcqdu2hHVm80gvo5UOxXOS9hajnWjM=",
+			Endpoint:      "192.0.2.1:51820",
+			LastHandshake: time.Unix(1700000000, 500),
+			Upload:        38333,
+			Download:      2224,
+		},
+		{
+			PublicKey: "WEAuaVuhdyscyTCXVfBDJR6nf9zxD75jmJzrfhkyE3Y=",
+			Endpoint:  "[2001:db8::1]:51820",
+			Upload:    148,
+		},
+	}, peers)
+	peers, err = ParsePeerStatus("private_key=e84b5a6d2717c1003a13b431570353dbaca9146cf150c5f8575680feba52027a\nerrno=0\n")
+	require.NoError(t, err)
+	require.Empty(t, peers)
+	_, err = ParsePeerStatus("public_key=b85996fecc9c7f1fc6d2572a76eda11d59bcd20be8e543b15ce4bd85a8e75a33\ntx_bytes=38333\npublic_key=invalid\ntx_bytes=148\nerrno=0\n")
+	require.Error(t, err)
+}